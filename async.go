@@ -0,0 +1,271 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/queue"
+)
+
+// ErrClosed is returned by Send after the Notify instance was closed.
+var ErrClosed = errors.New("notify is closed")
+
+// drainInterval is how often Flush checks whether the queue was drained.
+const drainInterval = 10 * time.Millisecond
+
+// AsyncErrorHandler is called with the ID of a queued notification and the error it finally failed with. Use WithRetry
+// to retry failed services before the handler gets called.
+type AsyncErrorHandler func(id string, err error)
+
+// async holds the state of the asynchronous mode of a Notify instance.
+type async struct {
+	queue        queue.Queue
+	workers      int
+	errorHandler AsyncErrorHandler
+
+	mu      sync.Mutex
+	started bool
+	closing bool
+	closed  bool
+	// pending counts the notifications that weren't acknowledged yet, no matter whether they're still queued or being
+	// sent. Unlike the length of the queue, it doesn't drop while a worker holds a notification it just took out.
+	pending int
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// WithAsync is an Option that makes Send enqueue notifications into q and return right away, instead of waiting for the
+// services. A pool of the given number of workers takes the notifications out of the queue and sends them, so at most
+// that many notifications are sent at the same time. Use queue.NewMemory for an in-memory queue, or queue.NewFile for
+// a durable one that redelivers pending notifications after a restart.
+//
+// Values bound to the context passed to Send are not available to the services in asynchronous mode. SendWithReport
+// always sends synchronously. Make sure to call Close before the program exits, so that queued notifications get sent.
+func WithAsync(q queue.Queue, workers int) Option {
+	return func(n *Notify) {
+		if n == nil || q == nil {
+			return
+		}
+		if workers < 1 {
+			workers = 1
+		}
+
+		n.async = &async{queue: q, workers: workers}
+	}
+}
+
+// WithAsyncErrorHandler is an Option that sets the handler for notifications that failed in asynchronous mode. It has
+// to be applied after WithAsync.
+func WithAsyncErrorHandler(handler AsyncErrorHandler) Option {
+	return func(n *Notify) {
+		if n != nil && n.async != nil {
+			n.async.errorHandler = handler
+		}
+	}
+}
+
+// start starts the workers of the asynchronous mode, unless they're already running.
+func (n *Notify) start() {
+	a := n.async
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.started || a.closed {
+		return
+	}
+	a.started = true
+	// Count the notifications restored from a durable queue. No worker took any of them out yet.
+	a.pending += a.queue.Len()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+
+	for i := 0; i < a.workers; i++ {
+		a.wg.Add(1)
+		go n.work(ctx)
+	}
+}
+
+// work takes notifications out of the queue and sends them until ctx is done or the queue is closed.
+func (n *Notify) work(ctx context.Context) {
+	a := n.async
+	defer a.wg.Done()
+
+	for {
+		item, err := a.queue.Get(ctx)
+		if err != nil {
+			return
+		}
+		n.emit(ctx, Event{Type: EventDequeued, QueueDepth: a.queue.Len()})
+
+		err = n.deliver(ctx, item)
+		if ctx.Err() != nil {
+			// Interrupted by Close, leave the item to be redelivered by durable queues.
+			return
+		}
+
+		if err != nil && a.errorHandler != nil {
+			a.errorHandler(item.ID, err)
+		}
+		if err = a.queue.Ack(item.ID); err != nil && a.errorHandler != nil {
+			a.errorHandler(item.ID, errors.Wrap(err, "acknowledge queued notification"))
+		}
+
+		a.mu.Lock()
+		a.pending--
+		a.mu.Unlock()
+	}
+}
+
+// deliver sends a single queued notification to all services.
+func (n *Notify) deliver(ctx context.Context, item queue.Item) error {
+	var qm queuedMessage
+	if err := json.Unmarshal(item.Payload, &qm); err != nil {
+		return errors.Wrap(err, "unmarshal queued notification")
+	}
+	if qm.Message == nil {
+		qm.Message = &Message{}
+	}
+
+	_, err := n.sendWithReport(extractTrace(ctx, qm.Trace), qm.Message, qm.Selector)
+
+	return err
+}
+
+// queuedMessage is the payload of a queued notification. The fields of the message are inlined, so that a payload
+// without selector is just the JSON encoded message.
+type queuedMessage struct {
+	*Message
+	Selector *Selector `json:"selector,omitempty"`
+	// Trace holds the trace context of the send, see WithTracerProvider.
+	Trace map[string]string `json:"trace,omitempty"`
+}
+
+// enqueue puts the given message and selector into the queue of the asynchronous mode.
+func (n *Notify) enqueue(ctx context.Context, m *Message, selector *Selector) error {
+	a := n.async
+
+	a.mu.Lock()
+	closing := a.closing
+	a.mu.Unlock()
+	if closing {
+		return ErrClosed
+	}
+
+	n.start()
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	qm := queuedMessage{Message: m, Selector: selector}
+	if n.telemetry != nil && n.telemetry.tracer != nil {
+		qm.Trace = injectTrace(ctx)
+	}
+	payload, err := json.Marshal(qm)
+	if err != nil {
+		return errors.Wrap(err, "marshal notification")
+	}
+
+	// Count the notification before putting it into the queue, so that it's never missed by Flush.
+	a.mu.Lock()
+	a.pending++
+	a.mu.Unlock()
+
+	if err = a.queue.Put(ctx, queue.Item{Payload: payload}); err != nil {
+		a.mu.Lock()
+		a.pending--
+		a.mu.Unlock()
+
+		return errors.Wrap(err, "enqueue notification")
+	}
+	n.emit(ctx, Event{Type: EventEnqueued, Message: m, QueueDepth: a.queue.Len()})
+
+	return nil
+}
+
+// idle reports whether all queued notifications were sent and acknowledged.
+func (a *async) idle() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.pending == 0
+}
+
+// Flush blocks until all queued notifications were sent or ctx is done. It returns right away if the Notify instance
+// isn't in asynchronous mode.
+func (n *Notify) Flush(ctx context.Context) error {
+	if n.async == nil {
+		return nil
+	}
+
+	// Deliver notifications that were restored from a durable queue, even if nothing was sent since.
+	n.start()
+
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+
+	for !n.async.idle() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
+// Close stops accepting new notifications, waits until all queued notifications were sent and releases the queue. If
+// ctx is done first, the notifications that are still being sent are interrupted and Close returns the context error.
+// Durable queues redeliver interrupted and pending notifications the next time they are opened. Close returns right
+// away if the Notify instance isn't in asynchronous mode.
+//
+// Before that, Close sends the summaries of all open suppression windows, see WithDedup, and flushes all services and
+// middleware with a Flush method, like Batcher. Their errors are returned if nothing else failed.
+func (n *Notify) Close(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	n.flushSuppressions(ctx)
+
+	a := n.async
+	if a == nil {
+		return n.flushServices(ctx)
+	}
+
+	a.mu.Lock()
+	if a.closing {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closing = true
+	a.mu.Unlock()
+
+	flushErr := n.Flush(ctx)
+	servicesErr := n.flushServices(ctx)
+
+	a.mu.Lock()
+	a.closed = true
+	cancel := a.cancel
+	a.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	a.wg.Wait()
+
+	if err := a.queue.Close(); err != nil {
+		return errors.Wrap(err, "close queue")
+	}
+	if flushErr != nil {
+		return flushErr
+	}
+
+	return servicesErr
+}
@@ -0,0 +1,169 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/casdoor/notify/queue"
+)
+
+func TestWithAsync(t *testing.T) {
+	t.Parallel()
+
+	var sent int32
+	release := make(chan struct{})
+	n := NewWithOptions(WithAsync(queue.NewMemory(10), 2))
+	n.UseServices(NotifierFunc(func(context.Context, string, string) error {
+		<-release
+		atomic.AddInt32(&sent, 1)
+		return nil
+	}))
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		if err := n.Send(ctx, "subject", "message"); err != nil {
+			t.Fatalf("Send() returned error: %v", err)
+		}
+	}
+	if atomic.LoadInt32(&sent) != 0 {
+		t.Error("Send() in asynchronous mode waited for the service")
+	}
+
+	close(release)
+	if err := n.Close(ctx); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+	if got := atomic.LoadInt32(&sent); got != 5 {
+		t.Errorf("Close() did not flush the queue, %d notifications were sent", got)
+	}
+
+	if err := n.Send(ctx, "subject", "message"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Send() after Close() returned %v, want ErrClosed", err)
+	}
+	if err := n.Close(ctx); err != nil {
+		t.Errorf("second Close() returned error: %v", err)
+	}
+}
+
+func TestWithAsyncErrorHandler(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var failed []string
+	n := NewWithOptions(
+		WithAsync(queue.NewMemory(10), 1),
+		WithAsyncErrorHandler(func(id string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			failed = append(failed, id)
+		}),
+	)
+	n.UseServices(NotifierFunc(func(context.Context, string, string) error {
+		return errors.New("some error")
+	}))
+
+	ctx := context.Background()
+	_ = n.Send(ctx, "subject", "message")
+	if err := n.Flush(ctx); err != nil {
+		t.Fatalf("Flush() returned error: %v", err)
+	}
+	_ = n.Close(ctx)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(failed) != 1 {
+		t.Errorf("error handler was expected to be called once but was called %d times", len(failed))
+	}
+}
+
+func TestWithAsync_Redelivery(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "queue.log")
+	ctx := context.Background()
+
+	q, err := queue.NewFile(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first instance gets shut down while its only service hangs.
+	started := make(chan struct{})
+	n := NewWithOptions(WithAsync(q, 1))
+	n.UseServices(NotifierFunc(func(ctx context.Context, _, _ string) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+	_ = n.Send(ctx, "subject", "message")
+	<-started
+
+	closeCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err = n.Close(closeCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Close() returned %v, want context.DeadlineExceeded", err)
+	}
+
+	// After the "restart", the notification gets delivered.
+	q, err = queue.NewFile(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var subjects []string
+	n = NewWithOptions(WithAsync(q, 1))
+	n.UseServices(NotifierFunc(func(_ context.Context, subject, _ string) error {
+		subjects = append(subjects, subject)
+		return nil
+	}))
+	if err = n.Close(ctx); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+	if len(subjects) != 1 || subjects[0] != "subject" {
+		t.Errorf("queued notification was not redelivered, got %v", subjects)
+	}
+}
+
+// slowQueue is a queue that lets some time pass between taking an item out and returning it.
+type slowQueue struct {
+	queue.Queue
+}
+
+func (q slowQueue) Get(ctx context.Context) (queue.Item, error) {
+	item, err := q.Queue.Get(ctx)
+	time.Sleep(20 * time.Millisecond)
+	return item, err
+}
+
+func TestWithAsync_FlushWhileDequeuing(t *testing.T) {
+	t.Parallel()
+
+	var sent int32
+	n := NewWithOptions(WithAsync(slowQueue{queue.NewMemory(10)}, 1))
+	n.UseServices(NotifierFunc(func(ctx context.Context, _, _ string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		atomic.AddInt32(&sent, 1)
+		return nil
+	}))
+
+	ctx := context.Background()
+	if err := n.Send(ctx, "subject", "message"); err != nil {
+		t.Fatalf("Send() returned error: %v", err)
+	}
+	// Give the worker the time to take the notification out of the queue.
+	time.Sleep(5 * time.Millisecond)
+
+	if err := n.Close(ctx); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+	if got := atomic.LoadInt32(&sent); got != 1 {
+		t.Errorf("Close() returned before the dequeued notification was sent, %d notifications were sent", got)
+	}
+}
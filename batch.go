@@ -0,0 +1,284 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/receiver"
+)
+
+// These are the defaults of BatchConfig.
+const (
+	DefaultBatchMaxSize  = 100
+	DefaultBatchInterval = 10 * time.Minute
+)
+
+var (
+	// DefaultDigestSubject is the template of the digest subject used by Batch, e.g. "12 notifications".
+	DefaultDigestSubject = template.Must(template.New("subject").Parse(
+		`{{len .Messages}} notification{{if gt (len .Messages) 1}}s{{end}}`,
+	))
+
+	// DefaultDigestBody is the template of the digest body used by Batch. It lists the subject and the body of every
+	// message.
+	DefaultDigestBody = template.Must(template.New("body").Parse(
+		`{{range $i, $m := .Messages}}{{if $i}}{{"\n\n"}}{{end}}{{$m.Subject}}{{if $m.Body}}{{"\n"}}{{$m.Body}}{{end}}{{end}}`,
+	))
+)
+
+// BatchConfig configures a Batcher.
+type BatchConfig struct {
+	// MaxSize is the number of buffered messages that causes a flush. It defaults to DefaultBatchMaxSize.
+	MaxSize int
+	// Interval is the time after the first buffered message that causes a flush. It defaults to DefaultBatchInterval.
+	Interval time.Duration
+	// Subject is the template of the digest subject. It's executed with a Digest. It defaults to DefaultDigestSubject.
+	Subject *template.Template
+	// Body is the template of the digest body. It's executed with a Digest. It defaults to DefaultDigestBody.
+	Body *template.Template
+	// Bypass reports whether a message is sent right away instead of being buffered. It defaults to bypassing messages
+	// with high or critical priority.
+	Bypass func(m *Message) bool
+	// ErrorHandler is called with the errors of flushes caused by the timer. Errors of other flushes are returned by
+	// the method that caused them.
+	ErrorHandler func(err error)
+}
+
+// Digest is the data the digest templates are executed with.
+type Digest struct {
+	// Messages are the buffered messages, in the order they were sent.
+	Messages []*Message
+	// First is the time the first message was buffered at.
+	First time.Time
+	// Last is the time the last message was buffered at.
+	Last time.Time
+}
+
+// Batcher is a Notifier that buffers messages and sends them to the wrapped service as a single digest message. Create
+// one with Batch.
+type Batcher struct {
+	service Notifier
+	config  BatchConfig
+
+	mu     sync.Mutex
+	groups map[string]*batch
+	closed bool
+}
+
+// batch holds the buffered messages of a single destination.
+type batch struct {
+	Digest
+	receivers map[string][]string
+	overrides []receiver.Binding
+	timer     *time.Timer
+}
+
+// Compile-time check to ensure Batcher implements MessageNotifier.
+var _ MessageNotifier = (*Batcher)(nil)
+
+// Batch wraps the given service, so that the messages sent to it are buffered and delivered as a single digest message,
+// e.g. one email every 10 minutes instead of 200. Messages are buffered per destination, i.e. messages with different
+// Receivers, or sent with different receivers bound to the context, see WithReceivers, are never merged. Digests are
+// sent to the receivers of their messages. A buffer is flushed when it reaches config.MaxSize messages or config.Interval after its
+// first message, whatever comes first. Messages with high or critical priority bypass the buffer by default.
+//
+// Call Flush or Close to send the buffered messages right away. Notify.Close flushes all services with a Flush method,
+// including batchers. Batch can be used as Middleware, too:
+//
+//	n.Use(func(next notify.Notifier) notify.Notifier {
+//		return notify.Batch(next, notify.BatchConfig{Interval: 10 * time.Minute})
+//	})
+func Batch(service Notifier, config BatchConfig) *Batcher {
+	if config.MaxSize < 1 {
+		config.MaxSize = DefaultBatchMaxSize
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultBatchInterval
+	}
+	if config.Subject == nil {
+		config.Subject = DefaultDigestSubject
+	}
+	if config.Body == nil {
+		config.Body = DefaultDigestBody
+	}
+	if config.Bypass == nil {
+		config.Bypass = func(m *Message) bool { return m.Priority >= PriorityHigh }
+	}
+
+	return &Batcher{service: service, config: config, groups: make(map[string]*batch)}
+}
+
+// Send buffers the given subject and message. It only sends right away if the message bypasses the buffer or the
+// buffer is full.
+func (b *Batcher) Send(ctx context.Context, subject, message string) error {
+	return b.SendMessage(ctx, messageFor(ctx, subject, message))
+}
+
+// Unwrap returns the wrapped service. It implements Wrapper.
+func (b *Batcher) Unwrap() []Notifier {
+	return []Notifier{b.service}
+}
+
+// SendMessage buffers the given message. It only sends right away if the message bypasses the buffer or the buffer is
+// full.
+func (b *Batcher) SendMessage(ctx context.Context, m *Message) error {
+	if b.config.Bypass(m) {
+		return sendTo(ctx, b.service, m)
+	}
+
+	key := destination(ctx, m)
+	now := time.Now()
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrClosed
+	}
+
+	g, ok := b.groups[key]
+	if !ok {
+		g = &batch{Digest: Digest{First: now}, receivers: m.Receivers, overrides: receiver.Bindings(ctx)}
+		g.timer = time.AfterFunc(b.config.Interval, func() {
+			if err := b.flush(context.Background(), key); err != nil && b.config.ErrorHandler != nil {
+				b.config.ErrorHandler(err)
+			}
+		})
+		b.groups[key] = g
+	}
+	g.Messages = append(g.Messages, m)
+	g.Last = now
+	full := len(g.Messages) >= b.config.MaxSize
+	b.mu.Unlock()
+
+	if full {
+		return b.flush(ctx, key)
+	}
+
+	return nil
+}
+
+// destination returns the key of the buffer a message belongs to.
+func destination(ctx context.Context, m *Message) string {
+	services := make([]string, 0, len(m.Receivers))
+	for service := range m.Receivers {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	var sb strings.Builder
+	for _, service := range services {
+		sb.WriteString(service)
+		sb.WriteByte('=')
+		sb.WriteString(strings.Join(m.Receivers[service], ","))
+		sb.WriteByte(';')
+	}
+	sb.WriteByte('|')
+	for _, b := range receiver.Bindings(ctx) {
+		sb.WriteString(b.Service)
+		sb.WriteByte('=')
+		sb.WriteString(strconv.Itoa(int(b.Mode)))
+		for _, r := range b.Receivers {
+			sb.WriteByte(',')
+			sb.WriteString(r.String())
+		}
+		sb.WriteByte(';')
+	}
+
+	return sb.String()
+}
+
+// flush sends the buffer with the given key as digest.
+func (b *Batcher) flush(ctx context.Context, key string) error {
+	b.mu.Lock()
+	g, ok := b.groups[key]
+	if ok {
+		delete(b.groups, key)
+		g.timer.Stop()
+	}
+	b.mu.Unlock()
+
+	if !ok || len(g.Messages) == 0 {
+		return nil
+	}
+
+	m, err := b.digest(g)
+	if err != nil {
+		return err
+	}
+
+	return sendTo(receiver.WithBindings(ctx, g.overrides...), b.service, m)
+}
+
+// digest builds the digest message of the given buffer.
+func (b *Batcher) digest(g *batch) (*Message, error) {
+	var subject, body bytes.Buffer
+	if err := b.config.Subject.Execute(&subject, g.Digest); err != nil {
+		return nil, errors.Wrap(err, "execute digest subject template")
+	}
+	if err := b.config.Body.Execute(&body, g.Digest); err != nil {
+		return nil, errors.Wrap(err, "execute digest body template")
+	}
+
+	m := NewMessage(subject.String(), body.String())
+	m.Format = g.Messages[0].Format
+	m.Priority = PriorityLow
+	m.Receivers = g.receivers
+	seen := make(map[string]bool)
+	for _, msg := range g.Messages {
+		if msg.Priority > m.Priority {
+			m.Priority = msg.Priority
+		}
+		if msg.Format != m.Format {
+			m.Format = FormatPlain
+		}
+		for _, tag := range msg.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				m.Tags = append(m.Tags, tag)
+			}
+		}
+		m.Links = append(m.Links, msg.Links...)
+		m.Attachments = append(m.Attachments, msg.Attachments...)
+	}
+
+	return m, nil
+}
+
+// Flush sends all buffered messages right away, one digest per destination. It returns the errors of all digests.
+func (b *Batcher) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	keys := make([]string, 0, len(b.groups))
+	for key := range b.groups {
+		keys = append(keys, key)
+	}
+	b.mu.Unlock()
+
+	var errs []error
+	for _, key := range keys {
+		if err := b.flush(ctx, key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return &SendError{Errors: errs}
+	}
+
+	return nil
+}
+
+// Close flushes all buffered messages and makes further sends fail with ErrClosed.
+func (b *Batcher) Close(ctx context.Context) error {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+
+	return b.Flush(ctx)
+}
@@ -0,0 +1,174 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/casdoor/notify/receiver"
+)
+
+func TestBatch_MaxSize(t *testing.T) {
+	t.Parallel()
+
+	c := &collector{}
+	b := Batch(c, BatchConfig{MaxSize: 3, Interval: time.Hour})
+
+	ctx := context.Background()
+	for _, subject := range []string{"a", "b", "c", "d"} {
+		if err := b.Send(ctx, subject, "body "+subject); err != nil {
+			t.Fatalf("Send() unexpected error: %v", err)
+		}
+	}
+
+	received := c.received()
+	if len(received) != 1 {
+		t.Fatalf("the service received %d messages, want 1", len(received))
+	}
+	if received[0].Subject != "3 notifications" {
+		t.Errorf("digest subject = %q", received[0].Subject)
+	}
+	if want := "a\nbody a\n\nb\nbody b\n\nc\nbody c"; received[0].Body != want {
+		t.Errorf("digest body = %q, want %q", received[0].Body, want)
+	}
+
+	if err := b.Close(ctx); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+	received = c.received()
+	if len(received) != 2 || received[1].Subject != "1 notification" {
+		t.Fatalf("Close() did not flush the remaining message: %+v", received)
+	}
+
+	if err := b.Send(ctx, "e", "body e"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Send() after Close() error = %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestBatch_Interval(t *testing.T) {
+	t.Parallel()
+
+	c := &collector{}
+	b := Batch(c, BatchConfig{
+		Interval: 20 * time.Millisecond,
+		Body:     template.Must(template.New("body").Parse(`{{range .Messages}}[{{.Subject}}]{{end}}`)),
+	})
+
+	ctx := context.Background()
+	_ = b.Send(ctx, "a", "")
+	_ = b.Send(ctx, "b", "")
+
+	deadline := time.Now().Add(time.Second)
+	for len(c.received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	received := c.received()
+	if len(received) != 1 || received[0].Body != "[a][b]" {
+		t.Fatalf("the timer did not flush the digest: %+v", received)
+	}
+}
+
+func TestBatch_Bypass(t *testing.T) {
+	t.Parallel()
+
+	c := &collector{}
+	b := Batch(c, BatchConfig{Interval: time.Hour})
+
+	ctx := context.Background()
+	m := NewMessage("disk full", "/dev/sda1")
+	m.Priority = PriorityCritical
+	if err := b.SendMessage(ctx, m); err != nil {
+		t.Fatalf("SendMessage() unexpected error: %v", err)
+	}
+	if received := c.received(); len(received) != 1 || received[0] != m {
+		t.Fatalf("the critical message was not sent right away: %+v", received)
+	}
+
+	// Messages to different receivers end up in different digests.
+	low := NewMessage("a", "")
+	low.Receivers = map[string][]string{"mail": {"a@example.com"}}
+	_ = b.SendMessage(ctx, low)
+	_ = b.Send(ctx, "b", "")
+
+	if err := b.Flush(ctx); err != nil {
+		t.Fatalf("Flush() unexpected error: %v", err)
+	}
+	if got := len(c.received()); got != 3 {
+		t.Errorf("Flush() sent %d digests, want 2", got-1)
+	}
+}
+
+func TestBatch_Middleware(t *testing.T) {
+	t.Parallel()
+
+	c := &collector{}
+	n := NewWithServices(c)
+	n.Use(
+		func(next Notifier) Notifier {
+			return NotifierFunc(func(ctx context.Context, subject, message string) error {
+				return next.Send(ctx, strings.ToUpper(subject), message)
+			})
+		},
+		func(next Notifier) Notifier {
+			return Batch(next, BatchConfig{Interval: time.Hour})
+		},
+	)
+
+	ctx := context.Background()
+	_ = n.Send(ctx, "a", "")
+	_ = n.Send(ctx, "b", "")
+	if got := len(c.received()); got != 0 {
+		t.Fatalf("the service received %d messages before Close(), want 0", got)
+	}
+
+	if err := n.Close(ctx); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+	received := c.received()
+	if len(received) != 1 || received[0].Body != "A\n\nB" {
+		t.Fatalf("Close() did not flush the batching middleware: %+v", received)
+	}
+}
+
+func TestBatch_Receivers(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu      sync.Mutex
+		digests = make(map[string]string)
+	)
+	b := Batch(NotifierFunc(func(ctx context.Context, _, message string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		digests[strings.Join(receiver.ResolveIDs(ctx, "slack", []string{"everyone"}), ",")] = message
+		return nil
+	}), BatchConfig{Interval: 20 * time.Millisecond})
+
+	// The timer flushes each digest to the receivers of its messages, not to the configured ones.
+	for _, to := range []string{"alice", "bob", "alice"} {
+		if err := b.Send(WithReceivers(context.Background(), "slack", to), "for "+to, ""); err != nil {
+			t.Fatalf("Send() unexpected error: %v", err)
+		}
+	}
+
+	received := func() map[string]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return maps.Clone(digests)
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(received()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	want := map[string]string{"alice": "for alice\n\nfor alice", "bob": "for bob"}
+	if got := received(); !maps.Equal(got, want) {
+		t.Errorf("the digests were sent as %q, want %q", got, want)
+	}
+}
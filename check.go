@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrCheckUnsupported is the error of services that don't implement Checker in the Report of Notify.CheckWithReport.
+var ErrCheckUnsupported = errors.New("service doesn't support checks")
+
+// check checks the given service, see Checker.
+func check(ctx context.Context, service Notifier) error {
+	c, ok := service.(Checker)
+	if !ok {
+		return ErrCheckUnsupported
+	}
+
+	return c.Check(ctx)
+}
+
+// CheckWithReport checks the configuration of all services concurrently and returns a Report with the outcome of every
+// single service, see Checker. Services that don't implement Checker fail with ErrCheckUnsupported in the Report, but
+// don't count as failed for the returned error.
+func (n *Notify) CheckWithReport(ctx context.Context) (*Report, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var wg sync.WaitGroup
+	report := &Report{Services: make([]ServiceResult, len(n.notifiers))}
+	for i, service := range n.notifiers {
+		report.Services[i] = ServiceResult{Index: i, Name: n.name(i, service), Service: service}
+		if service == nil {
+			report.Services[i].Err = ErrCheckUnsupported
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *ServiceResult) {
+			defer wg.Done()
+
+			start := time.Now()
+			result.Err = check(ctx, result.Service)
+			result.Duration = time.Since(start)
+			result.Attempts = 1
+		}(&report.Services[i])
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, result := range report.Services {
+		if result.Err != nil && !errors.Is(result.Err, ErrCheckUnsupported) {
+			errs = append(errs, errors.Wrap(result.Err, result.Name))
+		}
+	}
+	if len(errs) > 0 {
+		return report, &SendError{Errors: errs}
+	}
+
+	return report, nil
+}
+
+// Check checks the configuration of all services implementing Checker. It returns a *SendError holding the errors of
+// all services that failed their check.
+func (n *Notify) Check(ctx context.Context) error {
+	_, err := n.CheckWithReport(ctx)
+	return err
+}
+
+// Check checks the wrapped service.
+func (r *retryNotifier) Check(ctx context.Context) error {
+	return check(ctx, r.service)
+}
+
+// Check checks the wrapped service.
+func (b *Batcher) Check(ctx context.Context) error {
+	return check(ctx, b.service)
+}
+
+// Check checks all hops. It returns a *SendError holding the errors of the hops that failed their check, ignoring the
+// ones that don't support checks. It returns ErrCheckUnsupported if no hop supports checks.
+func (f *FallbackNotifier) Check(ctx context.Context) error {
+	checked := 0
+	var errs []error
+	for i, hop := range f.hops {
+		err := check(ctx, hop)
+		if errors.Is(err, ErrCheckUnsupported) {
+			continue
+		}
+		checked++
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "hop %d (%s)", i, serviceName(hop)))
+		}
+	}
+	if len(errs) > 0 {
+		return &SendError{Errors: errs}
+	}
+	if checked == 0 {
+		return ErrCheckUnsupported
+	}
+
+	return nil
+}
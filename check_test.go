@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/casdoor/notify/retry"
+)
+
+// checkedNotifier is a service that supports checks.
+type checkedNotifier struct {
+	NotifierFunc
+	err error
+}
+
+func (c checkedNotifier) Check(context.Context) error {
+	return c.err
+}
+
+func TestCheckWithReport(t *testing.T) {
+	t.Parallel()
+
+	errInvalid := errors.New("invalid token")
+	noop := NotifierFunc(func(context.Context, string, string) error { return nil })
+
+	n := New()
+	n.UseNamedService("ok", checkedNotifier{NotifierFunc: noop})
+	n.UseNamedService("invalid", Retry(checkedNotifier{NotifierFunc: noop, err: errInvalid}, retry.DefaultPolicy()))
+	n.UseNamedService("unsupported", noop)
+	n.UseNamedService("fallback", Fallback(noop, checkedNotifier{NotifierFunc: noop}))
+	n.UseNamedService("nested", NewWithServices(checkedNotifier{NotifierFunc: noop, err: errInvalid}))
+
+	report, err := n.CheckWithReport(context.Background())
+	if len(report.Services) != 5 {
+		t.Fatalf("CheckWithReport() reported %d services, want 5", len(report.Services))
+	}
+
+	wantErrs := []error{nil, errInvalid, ErrCheckUnsupported, nil, errInvalid}
+	for i, result := range report.Services {
+		if !errors.Is(result.Err, wantErrs[i]) || (wantErrs[i] == nil && result.Err != nil) {
+			t.Errorf("Services[%d] (%s).Err = %v, want %v", i, result.Name, result.Err, wantErrs[i])
+		}
+	}
+
+	var sendErr *SendError
+	if !errors.As(err, &sendErr) || len(sendErr.Errors) != 2 {
+		t.Fatalf("CheckWithReport() error = %v, want a SendError with 2 errors", err)
+	}
+	if want := "invalid: invalid token; nested: notify.checkedNotifier: invalid token: send notification: send notification"; err.Error() != want {
+		t.Errorf("CheckWithReport() error = %q, want %q", err, want)
+	}
+
+	if err = NewWithServices(noop).Check(context.Background()); err != nil {
+		t.Errorf("Check() without checkable services error = %v", err)
+	}
+	if err = Fallback(noop).Check(context.Background()); !errors.Is(err, ErrCheckUnsupported) {
+		t.Errorf("Fallback().Check() without checkable hops error = %v", err)
+	}
+}
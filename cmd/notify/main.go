@@ -0,0 +1,383 @@
+// Command notify sends notifications from shell scripts, cron jobs and CI pipelines. It reads the services to send to
+// from a configuration file, from environment variables or from notification URLs given as flags, see the config
+// package and notify.NewFromURLs.
+//
+// Usage:
+//
+//	notify [flags] [body...]
+//	notify test [flags]
+//
+// The body is taken from the -body flag, the file given with -file, the remaining arguments or the standard input, in
+// this order. The test subcommand checks the configuration of every service, usually its credentials, without sending
+// a message.
+//
+// Examples:
+//
+//	notify -config notify.yaml -tags ops -subject "Backup failed" < backup.log
+//	notify -url "tgram://123456:ABC-DEF/-100123456" -subject "Deployed" "Version 1.2.3 is live."
+//	NOTIFY_URLS="slack://xoxb-token/C123" notify test
+//
+// The exit code is 0 if all services succeeded, 1 if all of them failed or none was selected, 2 if the flags or the
+// configuration are invalid, and 3 if some services failed.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify"
+	"github.com/casdoor/notify/config"
+	"github.com/casdoor/notify/message"
+)
+
+// Exit codes of the command.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+	exitPartial = 3
+)
+
+// configEnv names the environment variable holding the path of the default configuration file.
+const configEnv = "NOTIFY_CONFIG"
+
+// urlList is a flag.Value collecting the values of a repeated flag.
+type urlList []string
+
+func (l *urlList) String() string {
+	return strings.Join(*l, " ")
+}
+
+func (l *urlList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// options are the flags shared by all subcommands.
+type options struct {
+	configFile string
+	envPrefix  string
+	urls       urlList
+	tags       string
+	timeout    time.Duration
+	quiet      bool
+}
+
+func (o *options) register(flags *flag.FlagSet, environ []string) {
+	flags.StringVar(&o.configFile, "config", getenv(environ, configEnv), "configuration `file`, defaults to $"+configEnv)
+	flags.StringVar(&o.envPrefix, "env-prefix", config.DefaultEnvPrefix,
+		"`prefix` of the environment variables configuring the services if no configuration file is given")
+	flags.Var(&o.urls, "url", "notification `URL` of a service to send to, may be repeated")
+	flags.StringVar(&o.tags, "tags", "", "tag `expression` selecting the services, e.g. \"ops !staging\"")
+	flags.DurationVar(&o.timeout, "timeout", time.Minute, "maximum `duration` of the whole run")
+	flags.BoolVar(&o.quiet, "quiet", false, "don't print the outcome of every service")
+}
+
+// build builds the Notify instance described by the configuration file, or by the environment variables if there is
+// none, and by the notification URLs.
+func (o *options) build(environ []string) (*notify.Notify, error) {
+	var (
+		cfg *config.Config
+		err error
+	)
+	if o.configFile != "" {
+		cfg, err = config.LoadFile(o.configFile)
+	} else {
+		cfg, err = config.FromEnviron(o.envPrefix, environ)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.URLs = append(cfg.URLs, o.urls...)
+	if len(cfg.Services) == 0 && len(cfg.URLs) == 0 {
+		return nil, errors.Errorf("no services configured, use -config, -url or $%sSERVICES", o.envPrefix)
+	}
+
+	return config.Build(cfg)
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Environ(), os.Stdin, os.Stdout, os.Stderr)
+	stop()
+
+	os.Exit(code)
+}
+
+// run runs the command with the given arguments and returns its exit code.
+func run(ctx context.Context, args, environ []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "test" {
+		return runTest(ctx, args[1:], environ, stdout, stderr)
+	}
+
+	return runSend(ctx, args, environ, stdin, stdout, stderr)
+}
+
+// runSend sends a message.
+func runSend(ctx context.Context, args, environ []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var (
+		opts     options
+		subject  string
+		body     string
+		file     string
+		format   string
+		priority string
+		dryRun   bool
+		version  bool
+	)
+
+	flags := flag.NewFlagSet("notify", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	opts.register(flags, environ)
+	flags.StringVar(&subject, "subject", "", "`subject` of the message")
+	flags.StringVar(&body, "body", "", "`body` of the message")
+	flags.StringVar(&file, "file", "", "read the body from `file`, \"-\" reads it from the standard input")
+	flags.StringVar(&format, "format", "", "body `format`: plain, markdown, html, mrkdwn or markdownv2")
+	flags.StringVar(&priority, "priority", "", "`priority` of the message: low, normal, high or critical")
+	flags.BoolVar(&dryRun, "dry-run", false, "print the messages the services would be sent instead of sending them")
+	flags.BoolVar(&version, "version", false, "print the version and exit")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: notify [flags] [body...]\n       notify test [flags]\n\nFlags:")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if version {
+		fmt.Fprintln(stdout, notify.Version)
+		return exitOK
+	}
+
+	m, err := newMessage(subject, body, file, flags.Args(), stdin)
+	if err == nil {
+		err = setMessageOptions(m, format, priority)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "notify:", err)
+		return exitUsage
+	}
+
+	n, err := opts.build(environ)
+	if err != nil {
+		fmt.Fprintln(stderr, "notify:", err)
+		return exitUsage
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+	if dryRun {
+		ctx = notify.WithDryRun(ctx, func(name string, m *message.Message) {
+			printMessage(stdout, name, m)
+		})
+	}
+
+	report, err := n.SendMessageToWithReport(ctx, opts.tags, m)
+	if err != nil && len(report.Services) == 0 {
+		// Only an invalid tag expression fails without any service.
+		fmt.Fprintln(stderr, "notify:", err)
+		return exitUsage
+	}
+	if len(report.Services) == 0 {
+		fmt.Fprintln(stderr, "notify: no service selected")
+		return exitFailure
+	}
+	if !opts.quiet && !dryRun {
+		printReport(stderr, report, "sent")
+	}
+
+	code := exitCode(len(report.Services), len(report.Failed()))
+	// Close flushes the messages buffered by batching middleware.
+	if err = n.Close(ctx); err != nil {
+		fmt.Fprintln(stderr, "notify:", err)
+		if code == exitOK {
+			code = exitPartial
+		}
+	}
+
+	return code
+}
+
+// runTest checks the configuration of all services.
+func runTest(ctx context.Context, args, environ []string, stdout, stderr io.Writer) int {
+	var opts options
+
+	flags := flag.NewFlagSet("notify test", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	opts.register(flags, environ)
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintln(stderr, "notify: test doesn't take arguments")
+		return exitUsage
+	}
+
+	n, err := opts.build(environ)
+	if err != nil {
+		fmt.Fprintln(stderr, "notify:", err)
+		return exitUsage
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+
+	report, _ := n.CheckWithReport(ctx)
+	printReport(stdout, report, "ok")
+
+	checked, failed := 0, 0
+	for _, result := range report.Services {
+		if errors.Is(result.Err, notify.ErrCheckUnsupported) {
+			continue
+		}
+		checked++
+		if result.Err != nil {
+			failed++
+		}
+	}
+	if checked == 0 {
+		return exitOK
+	}
+
+	return exitCode(checked, failed)
+}
+
+// getenv returns the value of the environment variable with the given name in environ, which holds "key=value" pairs
+// like os.Environ.
+func getenv(environ []string, name string) string {
+	for _, kv := range environ {
+		if key, value, ok := strings.Cut(kv, "="); ok && key == name {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// exitCode returns the exit code for the given number of services of which the given number failed.
+func exitCode(services, failed int) int {
+	switch {
+	case failed == 0:
+		return exitOK
+	case failed == services:
+		return exitFailure
+	default:
+		return exitPartial
+	}
+}
+
+// newMessage returns the message with the given subject and the body taken from the body flag, the file, the
+// remaining arguments or the standard input, in this order.
+func newMessage(subject, body, file string, args []string, stdin io.Reader) (*message.Message, error) {
+	switch {
+	case body != "":
+		if file != "" || len(args) > 0 {
+			return nil, errors.New("the body is given more than once")
+		}
+	case file != "":
+		if len(args) > 0 {
+			return nil, errors.New("the body is given more than once")
+		}
+		data, err := readFile(file, stdin)
+		if err != nil {
+			return nil, err
+		}
+		body = string(data)
+	case len(args) > 0:
+		body = strings.Join(args, " ")
+	case !isTerminal(stdin):
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, errors.Wrap(err, "read standard input")
+		}
+		body = string(data)
+	}
+
+	body = strings.TrimRight(body, "\r\n")
+	if subject == "" && body == "" {
+		return nil, errors.New("the message is empty, use -subject, -body, -file or the standard input")
+	}
+
+	return message.New(subject, body), nil
+}
+
+// readFile reads the given file, or the standard input if the name is "-".
+func readFile(name string, stdin io.Reader) ([]byte, error) {
+	if name == "-" {
+		data, err := io.ReadAll(stdin)
+		return data, errors.Wrap(err, "read standard input")
+	}
+
+	data, err := os.ReadFile(name)
+	return data, errors.Wrap(err, "read body")
+}
+
+// isTerminal reports whether r is a terminal, i.e. whether reading the body from it would wait for the user to type.
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// setMessageOptions sets the body format and the priority of m from the flags.
+func setMessageOptions(m *message.Message, format, priority string) error {
+	if format != "" {
+		f, err := message.ParseBodyFormat(format)
+		if err != nil {
+			return err
+		}
+		m.Format = f
+	}
+	if priority != "" {
+		p, err := message.ParsePriority(priority)
+		if err != nil {
+			return err
+		}
+		m.Priority = p
+	}
+
+	return nil
+}
+
+// printMessage prints the message the named service would be sent during a dry run.
+func printMessage(w io.Writer, name string, m *message.Message) {
+	format := m.Format
+	if format == "" {
+		format = message.FormatPlain
+	}
+
+	fmt.Fprintf(w, "==> %s (%s)\n", name, format)
+	if m.Subject != "" {
+		fmt.Fprintf(w, "Subject: %s\n", m.Subject)
+	}
+	if m.Priority != message.PriorityNormal {
+		fmt.Fprintf(w, "Priority: %s\n", m.Priority)
+	}
+	fmt.Fprintf(w, "\n%s\n\n", m.Body)
+}
+
+// printReport prints the outcome of every service of report. Services that succeeded are marked with the given word.
+func printReport(w io.Writer, report *notify.Report, success string) {
+	for _, result := range report.Services {
+		switch {
+		case result.Err == nil:
+			fmt.Fprintf(w, "%-7s %s (%s)\n", success, result.Name, result.Duration.Round(time.Millisecond))
+		case errors.Is(result.Err, notify.ErrCheckUnsupported):
+			fmt.Fprintf(w, "%-7s %s: %v\n", "skipped", result.Name, result.Err)
+		default:
+			fmt.Fprintf(w, "%-7s %s: %v\n", "failed", result.Name, result.Err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// webhook is a JSON webhook recording the messages it receives.
+type webhook struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   int
+	messages []map[string]string
+}
+
+func newWebhook(t *testing.T, status int) *webhook {
+	t.Helper()
+
+	w := &webhook{status: status}
+	w.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.messages = append(w.messages, payload)
+		rw.WriteHeader(w.status)
+	}))
+	t.Cleanup(w.Close)
+
+	return w
+}
+
+// url returns the notification URL of the webhook.
+func (w *webhook) url() string {
+	return "json://" + strings.TrimPrefix(w.URL, "http://") + "/hook"
+}
+
+func (w *webhook) received() []map[string]string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return append([]map[string]string(nil), w.messages...)
+}
+
+// runCommand runs the command and returns its exit code, standard output and standard error.
+func runCommand(environ []string, stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, environ, strings.NewReader(stdin), &stdout, &stderr)
+
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun_Send(t *testing.T) {
+	t.Parallel()
+
+	ok := newWebhook(t, http.StatusOK)
+	failing := newWebhook(t, http.StatusInternalServerError)
+
+	code, _, stderr := runCommand(nil, "", "-url", ok.url(), "-subject", "Deployed", "version", "1.2.3")
+	require.Equal(t, exitOK, code, stderr)
+	require.Contains(t, stderr, "sent    json")
+	require.Equal(t, []map[string]string{{"subject": "Deployed", "message": "version 1.2.3"}}, ok.received())
+
+	code, _, stderr = runCommand(nil, "", "-url", ok.url(), "-url", failing.url(), "-subject", "Deployed")
+	require.Equal(t, exitPartial, code, stderr)
+	require.Contains(t, stderr, "failed  json: send request")
+
+	code, _, _ = runCommand(nil, "", "-url", failing.url(), "-subject", "Deployed", "-quiet")
+	require.Equal(t, exitFailure, code)
+
+	// The body is read from the standard input, the environment configures the services.
+	code, _, stderr = runCommand([]string{"NOTIFY_URLS=" + ok.url()}, "backup failed\n", "-subject", "Backup")
+	require.Equal(t, exitOK, code, stderr)
+	require.Equal(t, map[string]string{"subject": "Backup", "message": "backup failed"}, ok.received()[2])
+
+	body := filepath.Join(t.TempDir(), "body.txt")
+	require.NoError(t, os.WriteFile(body, []byte("from a file"), 0o600))
+	code, _, stderr = runCommand(nil, "", "-url", ok.url(), "-file", body)
+	require.Equal(t, exitOK, code, stderr)
+	require.Equal(t, map[string]string{"subject": "", "message": "from a file"}, ok.received()[3])
+}
+
+func TestRun_SendTags(t *testing.T) {
+	t.Parallel()
+
+	ops := newWebhook(t, http.StatusOK)
+	dev := newWebhook(t, http.StatusOK)
+
+	file := filepath.Join(t.TempDir(), "notify.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+services:
+  - type: http
+    name: ops
+    receivers: [`+ops.URL+`]
+    tags: [ops]
+  - type: http
+    name: dev
+    receivers: [`+dev.URL+`]
+    tags: [dev]
+`), 0o600))
+
+	code, _, stderr := runCommand(nil, "", "-config", file, "-tags", "ops", "-subject", "Alert")
+	require.Equal(t, exitOK, code, stderr)
+	require.Contains(t, stderr, "sent    ops")
+	require.Len(t, ops.received(), 1)
+	require.Empty(t, dev.received())
+
+	code, _, stderr = runCommand([]string{configEnv + "=" + file}, "", "-tags", "staging", "-subject", "Alert")
+	require.Equal(t, exitFailure, code)
+	require.Contains(t, stderr, "no service selected")
+}
+
+func TestRun_DryRun(t *testing.T) {
+	t.Parallel()
+
+	hook := newWebhook(t, http.StatusOK)
+
+	code, stdout, stderr := runCommand(nil, "", "-url", hook.url(), "-dry-run", "-subject", "Deployed",
+		"-format", "markdown", "-priority", "high", "**version** 1.2.3")
+	require.Equal(t, exitOK, code, stderr)
+	require.Equal(t, "==> json (plain)\nSubject: Deployed\nPriority: high\n\nversion 1.2.3\n\n", stdout)
+	require.Empty(t, hook.received())
+}
+
+func TestRun_Usage(t *testing.T) {
+	t.Parallel()
+
+	hook := newWebhook(t, http.StatusOK)
+
+	tests := map[string][]string{
+		"no services configured":        {"-subject", "s"},
+		"flag provided but not defined": {"-unknown"},
+		"the message is empty":          {"-url", hook.url()},
+		"more than once":                {"-url", hook.url(), "-body", "a", "b"},
+		"unknown body format":           {"-url", hook.url(), "-format", "rtf", "body"},
+		"unknown notification URL":      {"-url", "rtf://x", "body"},
+		"invalid tag expression":        {"-url", hook.url(), "-tags", "!", "body"},
+	}
+	for want, args := range tests {
+		code, _, stderr := runCommand(nil, "", args...)
+		require.Equal(t, exitUsage, code, args)
+		require.Contains(t, stderr, want, args)
+	}
+	require.Empty(t, hook.received())
+}
+
+func TestRun_Test(t *testing.T) {
+	t.Parallel()
+
+	bark := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ping" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(bark.Close)
+	barkURL := "bark://key@" + strings.TrimPrefix(bark.URL, "http://") + "?tls=no"
+	brokenURL := "bark://key@" + strings.TrimPrefix(bark.URL, "http://") + "/broken?tls=no"
+
+	code, stdout, stderr := runCommand(nil, "", "test", "-url", barkURL, "-url", "json://example.com/hook")
+	require.Equal(t, exitOK, code, stderr)
+	require.Contains(t, stdout, "ok      bark")
+	require.Contains(t, stdout, "skipped json: service doesn't support checks")
+
+	code, stdout, _ = runCommand(nil, "", "test", "-url", barkURL, "-url", brokenURL)
+	require.Equal(t, exitPartial, code)
+	require.Contains(t, stdout, "failed  bark: failed to check bark server")
+
+	code, _, _ = runCommand(nil, "", "test", "-url", barkURL, "extra")
+	require.Equal(t, exitUsage, code)
+}
@@ -0,0 +1,321 @@
+// Command notifyd is a notification gateway. It exposes a small REST API in front of the services of a configuration
+// file, of environment variables or of notification URLs given as flags, see the config package and
+// notify.NewFromURLs, so that programs written in any language can send notifications.
+//
+// Usage:
+//
+//	notifyd [flags]
+//
+// Every API request, except for the health checks, needs one of the API keys listed in $NOTIFYD_API_KEYS, separated
+// by commas or whitespace, or in the file given with -api-keys-file, one per line. The key is passed as bearer token
+// in the Authorization header, or in the X-API-Key header.
+//
+// The API has the following endpoints:
+//
+//	POST /v1/notify                queues a notification, responds with 202 Accepted and its state
+//	GET  /v1/notifications/{id}    responds with the state of a notification
+//	GET  /healthz                  responds with 200 OK while the process is running
+//	GET  /readyz                   responds with 200 OK while notifications are accepted
+//
+// The body of POST /v1/notify is a JSON object with the fields subject, body, format, priority, tags, services,
+// receivers, links, attachments, template, data and metadata, see notify.Message. Tags select the services through the
+// configured routes, while services is a tag expression selecting them directly, see notify.ParseSelector. Receivers
+// map service names to the receivers to send to instead of the configured ones. They're only accepted for the services
+// listed with -allow-receivers, and never if they're URLs, so that clients can't make the gateway send to any host.
+//
+// A request with an Idempotency-Key header is only queued once: repeating it within the retention period responds with
+// 200 OK and the state of the notification created by the first request. Reusing the key for a different request
+// fails with 422 Unprocessable Entity.
+//
+// The state of a notification is queued, sending, sent, partial or failed, together with the outcome of every service.
+// States are kept in memory for the retention period. With -queue-file, queued notifications survive restarts.
+//
+// On SIGINT or SIGTERM, notifyd stops accepting notifications and sends the queued ones before it exits.
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify"
+	"github.com/casdoor/notify/config"
+	"github.com/casdoor/notify/queue"
+)
+
+// Exit codes of the command.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+const (
+	// configEnv names the environment variable holding the path of the default configuration file.
+	configEnv = "NOTIFY_CONFIG"
+	// apiKeysEnv names the environment variable holding the API keys.
+	apiKeysEnv = "NOTIFYD_API_KEYS"
+)
+
+// urlList is a flag.Value collecting the values of a repeated flag.
+type urlList []string
+
+func (l *urlList) String() string {
+	return strings.Join(*l, " ")
+}
+
+func (l *urlList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// options are the flags of the command.
+type options struct {
+	addr            string
+	configFile      string
+	envPrefix       string
+	urls            urlList
+	apiKeysFile     string
+	allowReceivers  string
+	queueFile       string
+	queueSize       int
+	workers         int
+	retention       time.Duration
+	sendTimeout     time.Duration
+	shutdownTimeout time.Duration
+}
+
+func (o *options) register(flags *flag.FlagSet, environ []string) {
+	flags.StringVar(&o.addr, "addr", ":8080", "`address` to listen on")
+	flags.StringVar(&o.configFile, "config", getenv(environ, configEnv), "configuration `file`, defaults to $"+configEnv)
+	flags.StringVar(&o.envPrefix, "env-prefix", config.DefaultEnvPrefix,
+		"`prefix` of the environment variables configuring the services if no configuration file is given")
+	flags.Var(&o.urls, "url", "notification `URL` of a service to send to, may be repeated")
+	flags.StringVar(&o.apiKeysFile, "api-keys-file", "", "`file` with one API key per line, in addition to $"+apiKeysEnv)
+	flags.StringVar(&o.allowReceivers, "allow-receivers", "",
+		"comma-separated service `names`, like slack, whose receivers requests may set")
+	flags.StringVar(&o.queueFile, "queue-file", "", "`file` keeping queued notifications across restarts")
+	flags.IntVar(&o.queueSize, "queue-size", queue.DefaultCapacity, "maximum `number` of queued notifications")
+	flags.IntVar(&o.workers, "workers", 4, "`number` of notifications sent at the same time")
+	flags.DurationVar(&o.retention, "retention", 24*time.Hour,
+		"`duration` for which notification states and idempotency keys are kept")
+	flags.DurationVar(&o.sendTimeout, "send-timeout", time.Minute, "maximum `duration` of sending a notification")
+	flags.DurationVar(&o.shutdownTimeout, "shutdown-timeout", 30*time.Second,
+		"maximum `duration` of sending the queued notifications on shutdown")
+}
+
+// build builds the Notify instance described by the configuration file, or by the environment variables if there is
+// none, and by the notification URLs.
+func (o *options) build(environ []string) (*notify.Notify, error) {
+	var (
+		cfg *config.Config
+		err error
+	)
+	if o.configFile != "" {
+		cfg, err = config.LoadFile(o.configFile)
+	} else {
+		cfg, err = config.FromEnviron(o.envPrefix, environ)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.URLs = append(cfg.URLs, o.urls...)
+	if len(cfg.Services) == 0 && len(cfg.URLs) == 0 {
+		return nil, errors.Errorf("no services configured, use -config, -url or $%sSERVICES", o.envPrefix)
+	}
+
+	return config.Build(cfg)
+}
+
+// apiKeys returns the API keys from the environment and the API keys file.
+func (o *options) apiKeys(environ []string) ([]string, error) {
+	keys := strings.FieldsFunc(getenv(environ, apiKeysEnv), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+
+	if o.apiKeysFile != "" {
+		f, err := os.Open(o.apiKeysFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "read API keys")
+		}
+		defer func() { _ = f.Close() }()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if key := strings.TrimSpace(scanner.Text()); key != "" && !strings.HasPrefix(key, "#") {
+				keys = append(keys, key)
+			}
+		}
+		if err = scanner.Err(); err != nil {
+			return nil, errors.Wrap(err, "read API keys")
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.Errorf("no API keys configured, use $%s or -api-keys-file", apiKeysEnv)
+	}
+
+	return keys, nil
+}
+
+// receiverServices returns the names of the services whose receivers requests may set.
+func (o *options) receiverServices() []string {
+	var services []string
+	for _, service := range strings.Split(o.allowReceivers, ",") {
+		if service = strings.TrimSpace(service); service != "" {
+			services = append(services, service)
+		}
+	}
+
+	return services
+}
+
+// queue opens the queue of the notifications.
+func (o *options) queue() (queue.Queue, error) {
+	if o.queueFile != "" {
+		return queue.NewFile(o.queueFile, o.queueSize)
+	}
+
+	return queue.NewMemory(o.queueSize), nil
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Environ(), os.Stderr, nil)
+	stop()
+
+	os.Exit(code)
+}
+
+// run runs the gateway until ctx is done and returns the exit code. If listening isn't nil, it's called with the
+// address the gateway listens on once it accepts requests.
+func run(ctx context.Context, args, environ []string, stderr io.Writer, listening func(addr net.Addr)) int {
+	var opts options
+
+	flags := flag.NewFlagSet("notifyd", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	opts.register(flags, environ)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: notifyd [flags]\n\nFlags:")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintln(stderr, "notifyd: no arguments expected")
+		return exitUsage
+	}
+
+	logger := log.New(stderr, "notifyd: ", log.LstdFlags)
+
+	keys, err := opts.apiKeys(environ)
+	if err != nil {
+		logger.Print(err)
+		return exitUsage
+	}
+	n, err := opts.build(environ)
+	if err != nil {
+		logger.Print(err)
+		return exitUsage
+	}
+	q, err := opts.queue()
+	if err != nil {
+		logger.Print(err)
+		return exitFailure
+	}
+
+	listener, err := net.Listen("tcp", opts.addr)
+	if err != nil {
+		_ = q.Close()
+		logger.Print(err)
+		return exitFailure
+	}
+
+	s := newServer(n, q, keys, opts.receiverServices(), opts.retention, opts.sendTimeout, logger)
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	s.start(workerCtx, opts.workers)
+
+	httpServer := &http.Server{
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.Serve(listener)
+	}()
+	logger.Printf("listening on %s", listener.Addr())
+	if listening != nil {
+		listening(listener.Addr())
+	}
+
+	code := exitOK
+	select {
+	case <-ctx.Done():
+	case err = <-serveErr:
+		logger.Print(err)
+		code = exitFailure
+	}
+
+	return shutdown(s, httpServer, n, stopWorkers, opts.shutdownTimeout, logger, code)
+}
+
+// shutdown stops accepting notifications, sends the queued ones and releases all resources. It returns the exit code,
+// which is code unless the shutdown fails.
+func shutdown(
+	s *server, httpServer *http.Server, n *notify.Notify, stopWorkers context.CancelFunc, timeout time.Duration,
+	logger *log.Logger, code int,
+) int {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	fail := func(err error) {
+		logger.Print(err)
+		code = exitFailure
+	}
+
+	if err := s.drain(ctx); err != nil {
+		fail(err)
+	}
+	if err := httpServer.Shutdown(ctx); err != nil {
+		fail(err)
+	}
+	stopWorkers()
+	s.wait()
+	if err := s.queue.Close(); err != nil {
+		fail(err)
+	}
+	// Close flushes the messages buffered by batching middleware.
+	if err := n.Close(ctx); err != nil {
+		fail(err)
+	}
+	logger.Print("stopped")
+
+	return code
+}
+
+// getenv returns the value of the environment variable with the given name in environ, which holds "key=value" pairs
+// like os.Environ.
+func getenv(environ []string, name string) string {
+	for _, kv := range environ {
+		if key, value, ok := strings.Cut(kv, "="); ok && key == name {
+			return value
+		}
+	}
+
+	return ""
+}
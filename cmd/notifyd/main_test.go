@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	hook := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	t.Cleanup(hook.Close)
+	hookURL := "json://" + strings.TrimPrefix(hook.URL, "http://") + "/hook"
+
+	keysFile := filepath.Join(t.TempDir(), "keys")
+	require.NoError(t, os.WriteFile(keysFile, []byte("# ops\nfile-key\n"), 0o600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	addr := make(chan net.Addr, 1)
+	code := make(chan int, 1)
+	var stderr bytes.Buffer
+	go func() {
+		code <- run(ctx, []string{"-addr", "127.0.0.1:0", "-url", hookURL, "-api-keys-file", keysFile},
+			[]string{apiKeysEnv + "=env-key"}, &stderr, func(a net.Addr) { addr <- a })
+	}()
+
+	base := "http://" + (<-addr).String()
+	for _, key := range []string{"env-key", "file-key"} {
+		resp, result := do(t, http.MethodPost, base+"/v1/notify", key, `{"subject": "Deployed"}`, nil)
+		require.Equal(t, http.StatusAccepted, resp.StatusCode, result)
+	}
+
+	cancel()
+	require.Equal(t, exitOK, <-code, stderr.String())
+	require.Contains(t, stderr.String(), "stopped")
+}
+
+func TestRun_Usage(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		args    []string
+		environ []string
+	}{
+		"no API keys configured":        {args: []string{"-url", "json://example.com"}},
+		"no services configured":        {environ: []string{apiKeysEnv + "=key"}},
+		"flag provided but not defined": {args: []string{"-unknown"}},
+		"no arguments expected":         {args: []string{"extra"}},
+	}
+	for want, tt := range tests {
+		var stderr bytes.Buffer
+		code := run(context.Background(), tt.args, tt.environ, &stderr, nil)
+		require.Equal(t, exitUsage, code, want)
+		require.Contains(t, stderr.String(), want)
+	}
+}
@@ -0,0 +1,450 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify"
+	"github.com/casdoor/notify/message"
+	"github.com/casdoor/notify/queue"
+)
+
+const (
+	// maxRequestSize is the maximum size of a request body, attachments included.
+	maxRequestSize = 4 << 20
+	// maxIdempotencyKeyLength is the maximum length of the Idempotency-Key header.
+	maxIdempotencyKeyLength = 255
+	// drainInterval is how often drain checks whether the queue was drained.
+	drainInterval = 10 * time.Millisecond
+)
+
+// notifyRequest is the body of a POST /v1/notify request.
+type notifyRequest struct {
+	Subject  string           `json:"subject"`
+	Body     string           `json:"body"`
+	Format   string           `json:"format"`
+	Priority message.Priority `json:"priority"`
+	// Tags are the tags of the message, they select the services through the configured routes.
+	Tags []string `json:"tags"`
+	// Services is a tag expression selecting the services directly, bypassing the routes.
+	Services    string               `json:"services"`
+	Receivers   map[string][]string  `json:"receivers"`
+	Links       []message.Link       `json:"links"`
+	Attachments []message.Attachment `json:"attachments"`
+	Template    string               `json:"template"`
+	Data        any                  `json:"data"`
+	Metadata    map[string]any       `json:"metadata"`
+}
+
+// job is the payload of a queued notification.
+type job struct {
+	Services string           `json:"services,omitempty"`
+	Message  *message.Message `json:"message"`
+}
+
+// newJob validates the request and returns the job sending it. Receivers may only be given for the services in
+// receiverServices.
+func (r *notifyRequest) newJob(receiverServices map[string]bool) (*job, error) {
+	if r.Subject == "" && r.Body == "" && r.Template == "" {
+		return nil, errors.New("the message is empty, set subject, body or template")
+	}
+	if _, err := notify.ParseSelector(r.Services); err != nil {
+		return nil, err
+	}
+	if err := checkReceivers(r.Receivers, receiverServices); err != nil {
+		return nil, err
+	}
+
+	m := &message.Message{
+		Subject:     r.Subject,
+		Body:        r.Body,
+		Priority:    r.Priority,
+		Tags:        r.Tags,
+		Attachments: r.Attachments,
+		Links:       r.Links,
+		Receivers:   r.Receivers,
+		Template:    r.Template,
+		Data:        r.Data,
+		Metadata:    r.Metadata,
+	}
+	if r.Format != "" {
+		format, err := message.ParseBodyFormat(r.Format)
+		if err != nil {
+			return nil, err
+		}
+		m.Format = format
+	}
+
+	return &job{Services: r.Services, Message: m}, nil
+}
+
+// checkReceivers returns an error unless all receivers belong to the services in allowed. Receivers that are URLs are
+// always rejected, since they would make the gateway send requests to any host the client likes.
+func checkReceivers(receivers map[string][]string, allowed map[string]bool) error {
+	services := make([]string, 0, len(receivers))
+	for service := range receivers {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	for _, service := range services {
+		if !allowed[service] {
+			return errors.Errorf("receivers of service %q can't be set, see -allow-receivers", service)
+		}
+		for _, receiver := range receivers[service] {
+			if isURL(receiver) {
+				return errors.Errorf("receiver %q of service %q is a URL", receiver, service)
+			}
+		}
+	}
+
+	return nil
+}
+
+// isURL reports whether the receiver names a host, like webhook URLs do.
+func isURL(receiver string) bool {
+	if strings.Contains(receiver, "://") {
+		return true
+	}
+	u, err := url.Parse(receiver)
+
+	return err == nil && u.Host != ""
+}
+
+// server serves the REST API of the gateway. Notifications are queued and sent by a pool of workers.
+type server struct {
+	notify      *notify.Notify
+	queue       queue.Queue
+	store       *store
+	keys        [][]byte
+	sendTimeout time.Duration
+	logger      *log.Logger
+	// receiverServices are the services whose receivers requests may set.
+	receiverServices map[string]bool
+
+	ready atomic.Bool
+	mu    sync.Mutex
+	// pending counts the notifications that weren't acknowledged yet, no matter whether they're still queued or being
+	// sent. Unlike the length of the queue, it doesn't drop while a worker holds a notification it just took out.
+	pending int
+	wg      sync.WaitGroup
+}
+
+func newServer(
+	n *notify.Notify, q queue.Queue, keys, receiverServices []string, retention, sendTimeout time.Duration,
+	logger *log.Logger,
+) *server {
+	s := &server{
+		notify:           n,
+		queue:            q,
+		store:            newStore(retention),
+		sendTimeout:      sendTimeout,
+		logger:           logger,
+		receiverServices: make(map[string]bool, len(receiverServices)),
+	}
+	for _, key := range keys {
+		s.keys = append(s.keys, []byte(key))
+	}
+	for _, service := range receiverServices {
+		s.receiverServices[service] = true
+	}
+
+	return s
+}
+
+// handler returns the handler of the REST API.
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/notify", s.handleNotify)
+	mux.HandleFunc("/v1/notifications/", s.handleNotification)
+	mux.HandleFunc("/healthz", s.handleHealth)
+	mux.HandleFunc("/readyz", s.handleReady)
+
+	return mux
+}
+
+// start starts the given number of workers. They stop when ctx is done or the queue is closed.
+func (s *server) start(ctx context.Context, workers int) {
+	// Count the notifications restored from a durable queue. No worker took any of them out yet.
+	s.mu.Lock()
+	s.pending += s.queue.Len()
+	s.mu.Unlock()
+
+	for i := 0; i < workers; i++ {
+		s.wg.Add(1)
+		go s.work(ctx)
+	}
+	s.ready.Store(true)
+}
+
+// drain stops accepting notifications and waits until all queued notifications were sent or ctx is done.
+func (s *server) drain(ctx context.Context) error {
+	s.ready.Store(false)
+
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+
+	for {
+		s.mu.Lock()
+		pending := s.pending
+		s.mu.Unlock()
+		if pending == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "%d notifications are still pending", pending)
+		case <-ticker.C:
+		}
+	}
+}
+
+// wait waits until all workers stopped.
+func (s *server) wait() {
+	s.wg.Wait()
+}
+
+// work takes notifications out of the queue and sends them until ctx is done or the queue is closed.
+func (s *server) work(ctx context.Context) {
+	defer s.wg.Done()
+
+	for {
+		item, err := s.queue.Get(ctx)
+		if err != nil {
+			return
+		}
+
+		s.deliver(ctx, item)
+
+		if ctx.Err() != nil {
+			// Interrupted by the shutdown, leave the item to be redelivered by durable queues.
+			return
+		}
+		if err = s.queue.Ack(item.ID); err != nil {
+			s.logger.Printf("notification %s: acknowledge: %v", item.ID, err)
+		}
+
+		s.mu.Lock()
+		s.pending--
+		s.mu.Unlock()
+	}
+}
+
+// deliver sends a single queued notification and records its outcome.
+func (s *server) deliver(ctx context.Context, item queue.Item) {
+	var j job
+	if err := json.Unmarshal(item.Payload, &j); err != nil || j.Message == nil {
+		s.store.update(item.ID, func(n *notification) {
+			n.Status, n.Error = statusFailed, "invalid queued notification"
+		})
+		s.logger.Printf("notification %s: invalid queued notification", item.ID)
+		return
+	}
+
+	s.store.update(item.ID, func(n *notification) {
+		n.Status = statusSending
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, s.sendTimeout)
+	defer cancel()
+
+	var (
+		report *notify.Report
+		err    error
+	)
+	if j.Services != "" {
+		report, err = s.notify.SendMessageToWithReport(ctx, j.Services, j.Message)
+	} else {
+		report, err = s.notify.SendMessageWithReport(ctx, j.Message)
+	}
+
+	var result status
+	s.store.update(item.ID, func(n *notification) {
+		n.finish(report, err)
+		result = n.Status
+	})
+	if result != statusSent {
+		s.logger.Printf("notification %s: %s: %v", item.ID, result, err)
+	}
+}
+
+// authorize reports whether the request carries a valid API key, either as bearer token or in the X-API-Key header.
+// It returns the key.
+func (s *server) authorize(r *http.Request) ([]byte, bool) {
+	key := r.Header.Get("X-API-Key")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		key = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if key == "" {
+		return nil, false
+	}
+
+	for _, valid := range s.keys {
+		if subtle.ConstantTimeCompare([]byte(key), valid) == 1 {
+			return valid, true
+		}
+	}
+
+	return nil, false
+}
+
+// handleNotify queues a notification. It responds with 202 Accepted and the queued notification. A request repeating
+// the Idempotency-Key of an earlier request gets the current state of the notification created back then, with 200 OK.
+func (s *server) handleNotify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	apiKey, ok := s.authorize(r)
+	if !ok {
+		unauthorized(w)
+		return
+	}
+	if !s.ready.Load() {
+		writeError(w, http.StatusServiceUnavailable, "the gateway is shutting down")
+		return
+	}
+
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		writeError(w, http.StatusBadRequest, "the idempotency key is too long")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "the request is too large")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "failed to read the request")
+		return
+	}
+
+	var req notifyRequest
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	j, err := req.newJob(s.receiverServices)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	payload, err := json.Marshal(j)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	// Idempotency keys are scoped to the API key, so that clients can't see each other's notifications through them.
+	if idempotencyKey != "" {
+		scope := sha256.Sum256(apiKey)
+		idempotencyKey = hex.EncodeToString(scope[:]) + ":" + idempotencyKey
+	}
+	n, created, err := s.store.create(idempotencyKey, sha256.Sum256(body))
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if !created {
+		w.Header().Set("Idempotent-Replayed", "true")
+		writeJSON(w, http.StatusOK, n)
+		return
+	}
+
+	// Count the notification before putting it into the queue, so that it's never missed by drain.
+	s.mu.Lock()
+	s.pending++
+	s.mu.Unlock()
+
+	if err = s.queue.Put(r.Context(), queue.Item{ID: n.ID, Payload: payload}); err != nil {
+		s.mu.Lock()
+		s.pending--
+		s.mu.Unlock()
+
+		s.store.remove(n.ID, idempotencyKey)
+		if errors.Is(err, queue.ErrFull) {
+			w.Header().Set("Retry-After", "1")
+		}
+		writeError(w, http.StatusServiceUnavailable, "failed to queue the notification: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Location", "/v1/notifications/"+n.ID)
+	writeJSON(w, http.StatusAccepted, n)
+}
+
+// handleNotification responds with the state of a notification.
+func (s *server) handleNotification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		methodNotAllowed(w, http.MethodGet, http.MethodHead)
+		return
+	}
+	if _, ok := s.authorize(r); !ok {
+		unauthorized(w)
+		return
+	}
+
+	n, ok := s.store.get(strings.TrimPrefix(r.URL.Path, "/v1/notifications/"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "notification not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, n)
+}
+
+// handleHealth responds with 200 OK as long as the process is running.
+func (s *server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReady responds with 200 OK if the gateway accepts notifications, and 503 Service Unavailable otherwise.
+func (s *server) handleReady(w http.ResponseWriter, _ *http.Request) {
+	if !s.ready.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="notifyd"`)
+	writeError(w, http.StatusUnauthorized, "missing or invalid API key")
+}
+
+func methodNotAllowed(w http.ResponseWriter, methods ...string) {
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+}
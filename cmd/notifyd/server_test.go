@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/casdoor/notify"
+	"github.com/casdoor/notify/queue"
+)
+
+const testKey = "secret"
+
+// recorder is a service recording the messages it sends.
+type recorder struct {
+	mu       sync.Mutex
+	err      error
+	messages []*notify.Message
+}
+
+func (r *recorder) Send(ctx context.Context, subject, message string) error {
+	return r.SendMessage(ctx, notify.NewMessage(subject, message))
+}
+
+func (r *recorder) SendMessage(_ context.Context, m *notify.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.messages = append(r.messages, m)
+
+	return r.err
+}
+
+func (r *recorder) received() []*notify.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]*notify.Message(nil), r.messages...)
+}
+
+// newTestServer starts a gateway in front of the given Notify instance.
+func newTestServer(t *testing.T, n *notify.Notify, q queue.Queue) (*server, *httptest.Server) {
+	t.Helper()
+
+	s := newServer(n, q, []string{testKey, "other"}, []string{"slack", "mail"}, time.Hour, time.Second,
+		log.New(io.Discard, "", 0))
+	ctx, cancel := context.WithCancel(context.Background())
+	s.start(ctx, 2)
+
+	ts := httptest.NewServer(s.handler())
+	t.Cleanup(func() {
+		ts.Close()
+		cancel()
+		s.wait()
+	})
+
+	return s, ts
+}
+
+// do sends a request with the given API key and returns the status code and the decoded response.
+func do(t *testing.T, method, url, key, body string, header http.Header) (*http.Response, map[string]any) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	var result map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+
+	return resp, result
+}
+
+// waitDone polls the state of the notification with the given ID until it reached a final state.
+func waitDone(t *testing.T, ts *httptest.Server, id string) map[string]any {
+	t.Helper()
+
+	var result map[string]any
+	require.Eventually(t, func() bool {
+		_, result = do(t, http.MethodGet, ts.URL+"/v1/notifications/"+id, testKey, "", nil)
+		return status(result["status"].(string)).done()
+	}, 5*time.Second, 10*time.Millisecond)
+
+	return result
+}
+
+func TestServer_Notify(t *testing.T) {
+	t.Parallel()
+
+	ops, dev := &recorder{}, &recorder{err: errors.New("dev is down")}
+	n := notify.New()
+	n.UseNamedService("ops", ops, "ops")
+	n.UseNamedService("dev", dev, "dev")
+	_, ts := newTestServer(t, n, queue.NewMemory(10))
+
+	resp, result := do(t, http.MethodPost, ts.URL+"/v1/notify", testKey,
+		`{"subject": "Deployed", "body": "**1.2.3**", "format": "markdown", "priority": "high", "services": "ops",
+		  "receivers": {"slack": ["C123"]}}`, nil)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode, result)
+	require.Equal(t, "queued", result["status"])
+	id := result["id"].(string)
+	require.Equal(t, "/v1/notifications/"+id, resp.Header.Get("Location"))
+
+	result = waitDone(t, ts, id)
+	require.Equal(t, "sent", result["status"])
+	require.Len(t, result["services"], 1)
+	require.Len(t, ops.received(), 1)
+	m := ops.received()[0]
+	require.Equal(t, "Deployed", m.Subject)
+	require.Equal(t, notify.PriorityHigh, m.Priority)
+	require.Equal(t, map[string][]string{"slack": {"C123"}}, m.Receivers)
+
+	// Without services, the message goes to all services.
+	_, result = do(t, http.MethodPost, ts.URL+"/v1/notify", testKey, `{"subject": "Alert"}`, nil)
+	result = waitDone(t, ts, result["id"].(string))
+	require.Equal(t, "partial", result["status"])
+	services := result["services"].([]any)
+	for _, service := range services {
+		delete(service.(map[string]any), "duration_ms")
+	}
+	require.Equal(t, []any{
+		map[string]any{"name": "ops", "status": "sent", "attempts": 1.0},
+		map[string]any{"name": "dev", "status": "failed", "error": "dev is down", "attempts": 1.0},
+	}, services)
+
+	_, result = do(t, http.MethodPost, ts.URL+"/v1/notify", testKey, `{"subject": "Alert", "services": "staging"}`, nil)
+	result = waitDone(t, ts, result["id"].(string))
+	require.Equal(t, "failed", result["status"])
+	require.Equal(t, "no service selected", result["error"])
+
+	resp, _ = do(t, http.MethodGet, ts.URL+"/v1/notifications/unknown", testKey, "", nil)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestServer_NotifyInvalid(t *testing.T) {
+	t.Parallel()
+
+	svc := &recorder{}
+	_, ts := newTestServer(t, notify.NewWithServices(svc), queue.NewMemory(10))
+
+	tests := map[string]struct {
+		key  string
+		body string
+		code int
+	}{
+		"no API key":       {key: "", body: `{"subject": "s"}`, code: http.StatusUnauthorized},
+		"invalid API key":  {key: "guess", body: `{"subject": "s"}`, code: http.StatusUnauthorized},
+		"empty message":    {key: testKey, body: `{}`, code: http.StatusBadRequest},
+		"invalid JSON":     {key: testKey, body: `{"subject": `, code: http.StatusBadRequest},
+		"unknown field":    {key: testKey, body: `{"subjct": "s"}`, code: http.StatusBadRequest},
+		"unknown format":   {key: testKey, body: `{"subject": "s", "format": "rtf"}`, code: http.StatusBadRequest},
+		"unknown priority": {key: testKey, body: `{"subject": "s", "priority": "asap"}`, code: http.StatusBadRequest},
+		"invalid services": {key: testKey, body: `{"subject": "s", "services": "!"}`, code: http.StatusBadRequest},
+		"receivers not allowed": {
+			key: testKey, body: `{"subject": "s", "receivers": {"http": ["10.0.0.1"]}}`, code: http.StatusBadRequest,
+		},
+		"URL receivers": {
+			key: testKey, body: `{"subject": "s", "receivers": {"slack": ["https://169.254.169.254/"]}}`,
+			code: http.StatusBadRequest,
+		},
+		"host receivers": {
+			key: testKey, body: `{"subject": "s", "receivers": {"mail": ["//internal.example.com"]}}`,
+			code: http.StatusBadRequest,
+		},
+	}
+	for name, tt := range tests {
+		resp, result := do(t, http.MethodPost, ts.URL+"/v1/notify", tt.key, tt.body, nil)
+		require.Equal(t, tt.code, resp.StatusCode, name)
+		require.NotEmpty(t, result["error"], name)
+	}
+
+	resp, _ := do(t, http.MethodGet, ts.URL+"/v1/notify", testKey, "", nil)
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	require.Equal(t, http.MethodPost, resp.Header.Get("Allow"))
+
+	resp, _ = do(t, http.MethodGet, ts.URL+"/v1/notifications/x", "", "", nil)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// The API key is also accepted in the X-API-Key header.
+	resp, _ = do(t, http.MethodPost, ts.URL+"/v1/notify", "", `{"subject": "s"}`, http.Header{"X-Api-Key": {testKey}})
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+}
+
+func TestServer_Idempotency(t *testing.T) {
+	t.Parallel()
+
+	svc := &recorder{}
+	_, ts := newTestServer(t, notify.NewWithServices(svc), queue.NewMemory(10))
+	header := http.Header{"Idempotency-Key": {"deploy-42"}}
+
+	resp, first := do(t, http.MethodPost, ts.URL+"/v1/notify", testKey, `{"subject": "Deployed"}`, header)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	waitDone(t, ts, first["id"].(string))
+
+	resp, replay := do(t, http.MethodPost, ts.URL+"/v1/notify", testKey, `{"subject": "Deployed"}`, header)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "true", resp.Header.Get("Idempotent-Replayed"))
+	require.Equal(t, first["id"], replay["id"])
+	require.Equal(t, "sent", replay["status"])
+	require.Len(t, svc.received(), 1)
+
+	resp, _ = do(t, http.MethodPost, ts.URL+"/v1/notify", testKey, `{"subject": "Rolled back"}`, header)
+	require.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	// Idempotency keys are scoped to the API key.
+	resp, other := do(t, http.MethodPost, ts.URL+"/v1/notify", "other", `{"subject": "Deployed"}`, header)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	require.NotEqual(t, first["id"], other["id"])
+}
+
+func TestServer_QueueFull(t *testing.T) {
+	t.Parallel()
+
+	s := newServer(notify.New(), queue.NewMemory(1), []string{testKey}, nil, time.Hour, time.Second,
+		log.New(io.Discard, "", 0))
+	s.ready.Store(true) // No workers, so the queue fills up.
+	ts := httptest.NewServer(s.handler())
+	t.Cleanup(ts.Close)
+	header := http.Header{"Idempotency-Key": {"k"}}
+
+	resp, _ := do(t, http.MethodPost, ts.URL+"/v1/notify", testKey, `{"subject": "1"}`, nil)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	resp, _ = do(t, http.MethodPost, ts.URL+"/v1/notify", testKey, `{"subject": "2"}`, header)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	require.Equal(t, "1", resp.Header.Get("Retry-After"))
+
+	// The idempotency key of the rejected notification can be used again.
+	_, err := s.queue.Get(context.Background())
+	require.NoError(t, err)
+	resp, _ = do(t, http.MethodPost, ts.URL+"/v1/notify", testKey, `{"subject": "2"}`, header)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+}
+
+// slowQueue is a queue that lets some time pass between taking an item out and returning it.
+type slowQueue struct {
+	queue.Queue
+}
+
+func (q slowQueue) Get(ctx context.Context) (queue.Item, error) {
+	item, err := q.Queue.Get(ctx)
+	time.Sleep(20 * time.Millisecond)
+	return item, err
+}
+
+func TestServer_DrainWhileDequeuing(t *testing.T) {
+	t.Parallel()
+
+	svc := &recorder{}
+	s, ts := newTestServer(t, notify.NewWithServices(svc), slowQueue{queue.NewMemory(10)})
+
+	resp, _ := do(t, http.MethodPost, ts.URL+"/v1/notify", testKey, `{"subject": "s"}`, nil)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	// Give a worker the time to take the notification out of the queue.
+	time.Sleep(5 * time.Millisecond)
+
+	require.NoError(t, s.drain(context.Background()))
+	require.Len(t, svc.received(), 1, "drain returned before the dequeued notification was sent")
+}
+
+func TestServer_Health(t *testing.T) {
+	t.Parallel()
+
+	s, ts := newTestServer(t, notify.New(), queue.NewMemory(10))
+
+	resp, _ := do(t, http.MethodGet, ts.URL+"/healthz", "", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = do(t, http.MethodGet, ts.URL+"/readyz", "", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.NoError(t, s.drain(context.Background()))
+
+	resp, _ = do(t, http.MethodGet, ts.URL+"/healthz", "", "", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = do(t, http.MethodGet, ts.URL+"/readyz", "", "", nil)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	resp, _ = do(t, http.MethodPost, ts.URL+"/v1/notify", testKey, `{"subject": "s"}`, nil)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
+
+func TestStore_Sweep(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	s := newStore(time.Hour)
+	s.now = func() time.Time { return now }
+
+	sent, _, err := s.create("key", [32]byte{1})
+	require.NoError(t, err)
+	s.update(sent.ID, func(n *notification) { n.Status = statusSent })
+	queued, _, err := s.create("", [32]byte{})
+	require.NoError(t, err)
+
+	now = now.Add(2 * time.Hour)
+	_, created, err := s.create("key", [32]byte{2})
+	require.NoError(t, err)
+	require.True(t, created, "the idempotency key expired")
+
+	_, ok := s.get(sent.ID)
+	require.False(t, ok)
+	_, ok = s.get(queued.ID)
+	require.True(t, ok, "notifications that weren't sent yet are kept")
+}
+
+func TestNotifyRequest_Attachments(t *testing.T) {
+	t.Parallel()
+
+	var req notifyRequest
+	require.NoError(t, json.NewDecoder(bytes.NewReader([]byte(
+		`{"body": "report", "attachments": [{"name": "a.txt", "data": "aGVsbG8="}]}`,
+	))).Decode(&req))
+
+	j, err := req.newJob(nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), j.Message.Attachments[0].Data)
+}
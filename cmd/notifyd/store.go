@@ -0,0 +1,200 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify"
+	"github.com/casdoor/notify/queue"
+)
+
+// sweepInterval is how often the store removes expired notifications and idempotency keys.
+const sweepInterval = time.Minute
+
+// errIdempotencyConflict is returned by store.create if an idempotency key is reused for a different request.
+var errIdempotencyConflict = errors.New("the idempotency key was already used for a different request")
+
+// status is the state of a notification.
+type status string
+
+const (
+	statusQueued  status = "queued"
+	statusSending status = "sending"
+	statusSent    status = "sent"
+	statusPartial status = "partial"
+	statusFailed  status = "failed"
+)
+
+// done reports whether the notification reached a final state.
+func (s status) done() bool {
+	return s == statusSent || s == statusPartial || s == statusFailed
+}
+
+// serviceStatus is the outcome of a notification for a single service.
+type serviceStatus struct {
+	Name       string `json:"name"`
+	Status     status `json:"status"`
+	Error      string `json:"error,omitempty"`
+	Attempts   int    `json:"attempts"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// notification is the state of a notification, as returned by the API.
+type notification struct {
+	ID        string          `json:"id"`
+	Status    status          `json:"status"`
+	Error     string          `json:"error,omitempty"`
+	Services  []serviceStatus `json:"services,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// finish sets the final state of the notification from the report of its send.
+func (n *notification) finish(report *notify.Report, err error) {
+	n.Services = make([]serviceStatus, 0, len(report.Services))
+	for _, result := range report.Services {
+		s := serviceStatus{
+			Name:       result.Name,
+			Status:     statusSent,
+			Attempts:   result.Attempts,
+			DurationMS: result.Duration.Milliseconds(),
+		}
+		if result.Err != nil {
+			s.Status, s.Error = statusFailed, result.Err.Error()
+		}
+		n.Services = append(n.Services, s)
+	}
+
+	failed := len(report.Failed())
+	switch {
+	case len(report.Services) == 0:
+		n.Status, n.Error = statusFailed, "no service selected"
+		if err != nil {
+			n.Error = err.Error()
+		}
+	case failed == 0:
+		n.Status = statusSent
+	case failed == len(report.Services):
+		n.Status = statusFailed
+	default:
+		n.Status = statusPartial
+	}
+}
+
+// idempotencyKey is an idempotency key given by a client.
+type idempotencyKey struct {
+	id          string
+	fingerprint [32]byte
+	expires     time.Time
+}
+
+// store holds the state of the notifications and the idempotency keys in memory. Both are kept for the retention
+// period after they were last updated, notifications that didn't reach a final state are kept forever.
+type store struct {
+	retention time.Duration
+	now       func() time.Time
+
+	mu            sync.Mutex
+	notifications map[string]*notification
+	keys          map[string]idempotencyKey
+	lastSweep     time.Time
+}
+
+func newStore(retention time.Duration) *store {
+	return &store{
+		retention:     retention,
+		now:           time.Now,
+		notifications: make(map[string]*notification),
+		keys:          make(map[string]idempotencyKey),
+	}
+}
+
+// create creates a new queued notification. If the idempotency key isn't empty and was used before, it returns the
+// notification created back then instead, and false. It returns errIdempotencyConflict if the key was used for a
+// request with a different fingerprint.
+func (s *store) create(key string, fingerprint [32]byte) (notification, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if k, ok := s.keys[key]; ok && key != "" {
+		if k.fingerprint != fingerprint {
+			return notification{}, false, errIdempotencyConflict
+		}
+		if n, ok := s.notifications[k.id]; ok {
+			return *n, false, nil
+		}
+	}
+
+	n := &notification{ID: queue.NewID(), Status: statusQueued, CreatedAt: now, UpdatedAt: now}
+	s.notifications[n.ID] = n
+	if key != "" {
+		s.keys[key] = idempotencyKey{id: n.ID, fingerprint: fingerprint, expires: now.Add(s.retention)}
+	}
+
+	return *n, true, nil
+}
+
+// remove removes a notification that couldn't be queued, together with its idempotency key.
+func (s *store) remove(id, key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.notifications, id)
+	if k, ok := s.keys[key]; ok && k.id == id {
+		delete(s.keys, key)
+	}
+}
+
+// update calls fn with the notification with the given ID and updates its modification time. Notifications that were
+// queued before a restart are unknown to the store, they're created.
+func (s *store) update(id string, fn func(n *notification)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	n, ok := s.notifications[id]
+	if !ok {
+		n = &notification{ID: id, CreatedAt: now}
+		s.notifications[id] = n
+	}
+	fn(n)
+	n.UpdatedAt = now
+}
+
+// get returns the notification with the given ID.
+func (s *store) get(id string) (notification, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.notifications[id]
+	if !ok {
+		return notification{}, false
+	}
+
+	return *n, true
+}
+
+// sweep removes expired notifications and idempotency keys, at most once per sweepInterval. The caller must hold the
+// lock.
+func (s *store) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for id, n := range s.notifications {
+		if n.Status.done() && now.Sub(n.UpdatedAt) > s.retention {
+			delete(s.notifications, id)
+		}
+	}
+	for key, k := range s.keys {
+		if now.After(k.expires) {
+			delete(s.keys, key)
+		}
+	}
+}
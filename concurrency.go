@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/casdoor/notify/receiver"
+)
+
+// WithMaxParallelism is an Option that limits the number of services the Notify instance calls at the same time. The
+// limit is shared by all sends, so that bursts of notifications don't open more connections than the given number.
+// Services waiting for a free slot fail with the context error if ctx is done first. A limit below 1 removes it again,
+// which is the default.
+func WithMaxParallelism(limit int) Option {
+	return func(n *Notify) {
+		if n == nil {
+			return
+		}
+
+		n.parallelism = nil
+		if limit > 0 {
+			n.parallelism = make(chan struct{}, limit)
+		}
+	}
+}
+
+// WithReceiverParallelism is an Option that lets services deliver a message to up to the given number of receivers at
+// the same time, instead of one after the other. It affects all services delivering through the receiver package. A
+// Delivery bound to the context with receiver.WithDelivery takes precedence.
+func WithReceiverParallelism(limit int) Option {
+	return func(n *Notify) {
+		if n != nil {
+			n.delivery.Parallelism = limit
+		}
+	}
+}
+
+// WithContinueOnError is an Option that makes services attempt every receiver, instead of stopping at the first one
+// that failed. The failures of a service are combined into a receiver.Errors value. It affects all services delivering
+// through the receiver package. A Delivery bound to the context with receiver.WithDelivery takes precedence.
+func WithContinueOnError() Option {
+	return func(n *Notify) {
+		if n != nil {
+			n.delivery.ContinueOnError = true
+		}
+	}
+}
+
+// withDelivery binds the delivery options of the Notify instance to ctx, unless ctx already carries some.
+func (n *Notify) withDelivery(ctx context.Context) context.Context {
+	if n.delivery == (receiver.Delivery{}) {
+		return ctx
+	}
+	if _, ok := receiver.DeliveryFromContext(ctx); ok {
+		return ctx
+	}
+
+	return receiver.WithDelivery(ctx, n.delivery)
+}
+
+// acquire blocks until the Notify instance may call one more service, see WithMaxParallelism. The returned function
+// releases the slot again.
+func (n *Notify) acquire(ctx context.Context) (release func(), err error) {
+	if n.parallelism == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case n.parallelism <- struct{}{}:
+		return func() { <-n.parallelism }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
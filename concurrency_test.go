@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/casdoor/notify/receiver"
+)
+
+func TestWithMaxParallelism(t *testing.T) {
+	t.Parallel()
+
+	var running, maxRunning int32
+	service := NotifierFunc(func(context.Context, string, string) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+
+	n := NewWithOptions(WithMaxParallelism(2))
+	n.UseServices(service, service, service, service, service)
+
+	if err := n.Send(context.Background(), "subject", "message"); err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+	if got := atomic.LoadInt32(&maxRunning); got != 2 {
+		t.Errorf("Send() called %d services at the same time, want 2", got)
+	}
+}
+
+func TestWithMaxParallelism_ContextDone(t *testing.T) {
+	t.Parallel()
+
+	n := NewWithOptions(WithMaxParallelism(1))
+	n.UseServices(NotifierFunc(func(context.Context, string, string) error { return nil }))
+
+	// Occupy the only slot.
+	n.parallelism <- struct{}{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := n.Send(ctx, "subject", "message"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Send() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestWithContinueOnError(t *testing.T) {
+	t.Parallel()
+
+	var sent int32
+	n := NewWithOptions(WithContinueOnError(), WithReceiverParallelism(2))
+	n.UseServices(NotifierFunc(func(ctx context.Context, _, _ string) error {
+		return receiver.Each(ctx, "fake", []string{"a", "b", "c"}, func(_ context.Context, r string) error {
+			atomic.AddInt32(&sent, 1)
+			if r != "b" {
+				return errors.New(r + " failed")
+			}
+			return nil
+		})
+	}))
+
+	report, err := n.SendWithReport(context.Background(), "subject", "message")
+	var errs receiver.Errors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Errorf("SendWithReport() error = %v, want two combined errors", err)
+	}
+	if got := atomic.LoadInt32(&sent); got != 3 {
+		t.Errorf("the service attempted %d receivers, want 3", got)
+	}
+	if got := len(report.Services[0].Receivers); got != 3 {
+		t.Errorf("SendWithReport() reported %d receivers, want 3", got)
+	}
+
+	// A delivery bound to the context takes precedence.
+	atomic.StoreInt32(&sent, 0)
+	ctx := receiver.WithDelivery(context.Background(), receiver.Delivery{})
+	if err := n.Send(ctx, "subject", "message"); err == nil {
+		t.Errorf("Send() expected an error")
+	}
+	if got := atomic.LoadInt32(&sent); got != 1 {
+		t.Errorf("the service attempted %d receivers, want 1", got)
+	}
+}
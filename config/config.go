@@ -0,0 +1,402 @@
+// Package config builds a Notify instance from a declarative configuration, written in YAML or JSON or given by
+// environment variables. Instead of calling the constructor of every service with positional arguments, each service
+// is described by its type, its settings, its receivers and its tags:
+//
+//	retry:
+//	  max_attempts: 5
+//	middleware:
+//	  - type: subject_prefix
+//	    prefix: "[prod] "
+//	services:
+//	  - type: telegram
+//	    token: ${TELEGRAM_TOKEN}
+//	    receivers: ["-100123456"]
+//	    tags: [ops]
+//	  - type: mail
+//	    sender: alerts@example.com
+//	    smtp_host: smtp.example.com:587
+//	    password: ${file:/run/secrets/smtp_password}
+//	    receivers: [oncall@example.com]
+//
+// String values may refer to environment variables and to files holding secrets, see Interpolate. Services and
+// middleware are created by the factories of a Registry; the default registry knows the built-in services and lets
+// third-party services register their own, see Register.
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/casdoor/notify"
+	"github.com/casdoor/notify/message"
+	"github.com/casdoor/notify/ratelimit"
+	"github.com/casdoor/notify/retry"
+	"github.com/casdoor/notify/template"
+)
+
+// Config describes a Notify instance.
+type Config struct {
+	// Disabled disables the Notify instance, see notify.Disable.
+	Disabled bool `yaml:"disabled"`
+	// Retry retries failed sends, see notify.WithRetry.
+	Retry *RetryConfig `yaml:"retry"`
+	// Dedup suppresses duplicate messages sent within the given window, see notify.WithDedup.
+	Dedup time.Duration `yaml:"dedup"`
+	// RateLimit paces the deliveries with the default limits of the platforms, see notify.WithRateLimiter.
+	RateLimit bool `yaml:"rate_limit"`
+	// MaxParallelism limits the number of concurrent sends, see notify.WithMaxParallelism.
+	MaxParallelism int `yaml:"max_parallelism"`
+	// ReceiverParallelism limits the number of receivers a service delivers to concurrently, see
+	// notify.WithReceiverParallelism.
+	ReceiverParallelism int `yaml:"receiver_parallelism"`
+	// ContinueOnError delivers to all receivers even if some fail, see notify.WithContinueOnError.
+	ContinueOnError bool `yaml:"continue_on_error"`
+	// Templates are glob patterns of template files, see template.Registry.ParseFiles.
+	Templates []string `yaml:"templates"`
+	// Middleware is wrapped around every service, see notify.Notify.Use.
+	Middleware []MiddlewareConfig `yaml:"middleware"`
+	// Routes are the routing rules, see notify.WithRoutes.
+	Routes []RouteConfig `yaml:"routes"`
+	// Services are the services to send to.
+	Services []ServiceConfig `yaml:"services"`
+	// URLs are notification URLs of further services to send to, see notify.ParseURL.
+	URLs []string `yaml:"urls"`
+}
+
+// ServiceConfig describes a service.
+type ServiceConfig struct {
+	// Type is the type of the service, e.g. "telegram". It selects the factory that creates the service.
+	Type string `yaml:"type"`
+	// Name names the service in error messages and reports, see notify.Notify.UseNamedService. It defaults to the type.
+	Name string `yaml:"name"`
+	// Disabled skips the service.
+	Disabled bool `yaml:"disabled"`
+	// Receivers are added to the service, e.g. chat IDs or email addresses.
+	Receivers []string `yaml:"receivers"`
+	// Tags are the tags of the service, see notify.Notify.UseServiceWithTags.
+	Tags []string `yaml:"tags"`
+	// Retry retries failed sends of this service only, see notify.Retry.
+	Retry *RetryConfig `yaml:"retry"`
+	// Middleware is wrapped around this service only. It runs inside the middleware of the Notify instance.
+	Middleware []MiddlewareConfig `yaml:"middleware"`
+	// Settings are all other keys of the service, like credentials. They're passed to the factory.
+	Settings Settings `yaml:",inline"`
+}
+
+// MiddlewareConfig describes a middleware.
+type MiddlewareConfig struct {
+	// Type is the type of the middleware, e.g. "subject_prefix".
+	Type string `yaml:"type"`
+	// Settings are all other keys of the middleware. They're passed to the factory.
+	Settings Settings `yaml:",inline"`
+}
+
+// RetryConfig describes a retry policy. Zero values keep the values of retry.DefaultPolicy.
+type RetryConfig struct {
+	MaxAttempts    int           `yaml:"max_attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	MaxRetryAfter  time.Duration `yaml:"max_retry_after"`
+	Multiplier     float64       `yaml:"multiplier"`
+	Jitter         float64       `yaml:"jitter"`
+	AttemptTimeout time.Duration `yaml:"attempt_timeout"`
+}
+
+// Policy returns the retry policy described by c.
+func (c *RetryConfig) Policy() retry.Policy {
+	p := retry.DefaultPolicy()
+	if c.MaxAttempts != 0 {
+		p.MaxAttempts = c.MaxAttempts
+	}
+	if c.InitialBackoff != 0 {
+		p.InitialBackoff = c.InitialBackoff
+	}
+	if c.MaxBackoff != 0 {
+		p.MaxBackoff = c.MaxBackoff
+	}
+	if c.MaxRetryAfter != 0 {
+		p.MaxRetryAfter = c.MaxRetryAfter
+	}
+	if c.Multiplier != 0 {
+		p.Multiplier = c.Multiplier
+	}
+	if c.Jitter != 0 {
+		p.Jitter = c.Jitter
+	}
+	if c.AttemptTimeout != 0 {
+		p.AttemptTimeout = c.AttemptTimeout
+	}
+
+	return p
+}
+
+// RouteConfig describes a routing rule, see notify.Route.
+type RouteConfig struct {
+	Priorities []string `yaml:"priorities"`
+	Subject    string   `yaml:"subject"`
+	Tags       []string `yaml:"tags"`
+	Services   string   `yaml:"services"`
+}
+
+// route returns the notify.Route described by c.
+func (c RouteConfig) route() (notify.Route, error) {
+	r := notify.Route{Tags: c.Tags, Services: c.Services}
+	if err := r.Validate(); err != nil {
+		return r, err
+	}
+	for _, name := range c.Priorities {
+		p, err := message.ParsePriority(name)
+		if err != nil {
+			return r, err
+		}
+		r.Priorities = append(r.Priorities, p)
+	}
+	if c.Subject != "" {
+		subject, err := regexp.Compile(c.Subject)
+		if err != nil {
+			return r, errors.Wrap(err, "invalid subject pattern")
+		}
+		r.Subject = subject
+	}
+
+	return r, nil
+}
+
+// Parse parses a configuration written in YAML or JSON, and interpolates its string values with the environment, see
+// Interpolate.
+func Parse(data []byte) (*Config, error) {
+	return ParseWithLookup(data, nil)
+}
+
+// ParseWithLookup is like Parse, but resolves the variables referred to by the configuration with lookup.
+func ParseWithLookup(data []byte, lookup Lookup) (*Config, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, errors.Wrap(err, "parse config")
+	}
+	if err := interpolateNode(&root, lookup); err != nil {
+		return nil, errors.Wrap(err, "interpolate config")
+	}
+
+	cfg := &Config{}
+	if len(root.Content) == 0 {
+		return cfg, nil
+	}
+	if err := root.Decode(cfg); err != nil {
+		return nil, errors.Wrap(err, "decode config")
+	}
+
+	return cfg, nil
+}
+
+// interpolateNode interpolates the scalars of the YAML document rooted at n. Plain scalars become numbers or booleans
+// after interpolation if their value is written like one, so that "${WORKERS}" may configure a number. Anything else,
+// like a phone number with leading zeros, stays a string.
+func interpolateNode(n *yaml.Node, lookup Lookup) error {
+	if n.Kind == yaml.ScalarNode && n.Tag != "!!binary" {
+		value, err := Interpolate(n.Value, lookup)
+		if err != nil {
+			return err
+		}
+		if value != n.Value {
+			n.Value = value
+			n.Tag = "!!str"
+			if n.Style == 0 && isCanonical(value) {
+				n.Tag = ""
+			}
+		}
+	}
+	for _, child := range n.Content {
+		if err := interpolateNode(child, lookup); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isCanonical reports whether value is a number or a boolean written the way it would be formatted.
+func isCanonical(value string) bool {
+	if value == "true" || value == "false" {
+		return true
+	}
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return strconv.FormatInt(i, 10) == value
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return strconv.FormatFloat(f, 'f', -1, 64) == value
+	}
+
+	return false
+}
+
+// LoadFile reads and parses the configuration file at path, see Parse.
+func LoadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read config")
+	}
+
+	return Parse(data)
+}
+
+// Load reads the configuration file at path and builds a Notify instance from it with the default registry.
+func Load(path string) (*notify.Notify, error) {
+	cfg, err := LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Build(cfg)
+}
+
+// Build builds a Notify instance from cfg with the default registry.
+func Build(cfg *Config) (*notify.Notify, error) {
+	return defaultRegistry.Build(cfg)
+}
+
+// Build builds a Notify instance from cfg. It fails if any service or middleware can't be created, naming the one
+// that failed.
+func (r *Registry) Build(cfg *Config) (*notify.Notify, error) {
+	options, err := r.options(cfg)
+	if err != nil {
+		return nil, err
+	}
+	n := notify.NewWithOptions(options...)
+
+	for i, mc := range cfg.Middleware {
+		m, err := r.buildMiddleware(mc)
+		if err != nil {
+			return nil, errors.Wrapf(err, "middleware #%d (%s)", i+1, mc.Type)
+		}
+		n.Use(m)
+	}
+
+	for i, sc := range cfg.Services {
+		if sc.Disabled {
+			continue
+		}
+
+		name := sc.Name
+		if name == "" {
+			name = sc.Type
+		}
+		service, err := r.buildService(sc)
+		if err != nil {
+			return nil, errors.Wrapf(err, "service #%d (%s)", i+1, name)
+		}
+		n.UseNamedService(name, service, sc.Tags...)
+	}
+
+	if err = n.UseURLs(cfg.URLs...); err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+// options returns the options of the Notify instance described by cfg.
+func (r *Registry) options(cfg *Config) ([]notify.Option, error) {
+	var options []notify.Option
+	if cfg.Disabled {
+		options = append(options, notify.Disable)
+	}
+	if cfg.Retry != nil {
+		options = append(options, notify.WithRetry(cfg.Retry.Policy()))
+	}
+	if cfg.Dedup > 0 {
+		options = append(options, notify.WithDedup(cfg.Dedup))
+	}
+	if cfg.RateLimit {
+		options = append(options, notify.WithRateLimiter(ratelimit.New()))
+	}
+	if cfg.MaxParallelism > 0 {
+		options = append(options, notify.WithMaxParallelism(cfg.MaxParallelism))
+	}
+	if cfg.ReceiverParallelism > 0 {
+		options = append(options, notify.WithReceiverParallelism(cfg.ReceiverParallelism))
+	}
+	if cfg.ContinueOnError {
+		options = append(options, notify.WithContinueOnError())
+	}
+
+	if len(cfg.Templates) > 0 {
+		registry := template.New()
+		for _, pattern := range cfg.Templates {
+			filenames, err := filepath.Glob(pattern)
+			if err != nil {
+				return nil, errors.Wrapf(err, "match templates %q", pattern)
+			}
+			if len(filenames) == 0 {
+				return nil, errors.Errorf("pattern %q matches no templates", pattern)
+			}
+			if err = registry.ParseFiles(filenames...); err != nil {
+				return nil, err
+			}
+		}
+		options = append(options, notify.WithTemplates(registry))
+	}
+
+	routes := make([]notify.Route, 0, len(cfg.Routes))
+	for i, rc := range cfg.Routes {
+		route, err := rc.route()
+		if err != nil {
+			return nil, errors.Wrapf(err, "route #%d", i+1)
+		}
+		routes = append(routes, route)
+	}
+	if len(routes) > 0 {
+		options = append(options, notify.WithRoutes(routes...))
+	}
+
+	return options, nil
+}
+
+// buildService creates the service described by sc, wrapped by its retry policy and middleware.
+func (r *Registry) buildService(sc ServiceConfig) (notify.Notifier, error) {
+	factory, ok := r.factory(sc.Type)
+	if !ok {
+		return nil, errors.Errorf("unknown service type %q", sc.Type)
+	}
+
+	service, err := factory(sc.Settings, sc.Receivers)
+	if err != nil {
+		return nil, err
+	}
+
+	if sc.Retry != nil {
+		service = notify.Retry(service, sc.Retry.Policy())
+	}
+
+	if len(sc.Middleware) == 0 {
+		return service, nil
+	}
+
+	// The first middleware is the outermost one, like with Notify.Use.
+	middleware := make([]notify.Middleware, len(sc.Middleware))
+	for i, mc := range sc.Middleware {
+		m, err := r.buildMiddleware(mc)
+		if err != nil {
+			return nil, errors.Wrapf(err, "middleware #%d (%s)", i+1, mc.Type)
+		}
+		middleware[i] = m
+	}
+
+	return notify.Chain(service, middleware...), nil
+}
+
+// buildMiddleware creates the middleware described by mc.
+func (r *Registry) buildMiddleware(mc MiddlewareConfig) (notify.Middleware, error) {
+	factory, ok := r.middlewareFactory(mc.Type)
+	if !ok {
+		return nil, errors.Errorf("unknown middleware type %q", mc.Type)
+	}
+
+	return factory(mc.Settings)
+}
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultEnvPrefix is the prefix of the environment variables read by FromEnv.
+const DefaultEnvPrefix = "NOTIFY_"
+
+// fileSuffix marks an environment variable naming a file that holds the value of the setting, e.g.
+// NOTIFY_TELEGRAM_TOKEN_FILE=/run/secrets/telegram_token. Its value becomes a File, see File.
+const fileSuffix = "_FILE"
+
+// FromEnv reads a configuration from the environment variables starting with prefix, e.g. DefaultEnvPrefix:
+//
+//	NOTIFY_SERVICES=ops,mail
+//	NOTIFY_OPS_TYPE=telegram
+//	NOTIFY_OPS_TOKEN_FILE=/run/secrets/telegram_token
+//	NOTIFY_OPS_RECEIVERS=-100123456
+//	NOTIFY_OPS_TAGS=ops,critical
+//	NOTIFY_MAIL_SENDER=alerts@example.com
+//	NOTIFY_MAIL_SMTP_HOST=smtp.example.com:587
+//	NOTIFY_MAIL_RECEIVERS=oncall@example.com
+//
+// SERVICES lists the names of the services. The variables of a service start with its name in upper case. TYPE sets
+// its type and defaults to its name, RECEIVERS and TAGS are comma-separated lists, and DISABLED skips it. Every other
+// variable is a setting, named by the rest of the variable name in lower case. A variable ending in _FILE names a file
+// holding the value of the setting without the suffix, unless the service declares a setting ending in _file itself,
+// like the credentials_file of googlechat, see File. The file is read when the setting is decoded.
+//
+// URLS lists notification URLs of further services, separated by whitespace, see notify.ParseURL.
+//
+// The options of the Notify instance are named like the keys of a configuration file in upper case, e.g.
+// NOTIFY_CONTINUE_ON_ERROR=true, NOTIFY_DEDUP=5m or NOTIFY_RETRY_MAX_ATTEMPTS=5. Middleware can only be configured in
+// files.
+func FromEnv(prefix string) (*Config, error) {
+	return FromEnviron(prefix, os.Environ())
+}
+
+// FromEnviron is like FromEnv, but reads the variables from environ, which holds "key=value" pairs like os.Environ.
+func FromEnviron(prefix string, environ []string) (*Config, error) {
+	vars := make(map[string]string)
+	for _, kv := range environ {
+		key, value, ok := strings.Cut(kv, "=")
+		if ok && strings.HasPrefix(key, prefix) {
+			vars[strings.TrimPrefix(key, prefix)] = value
+		}
+	}
+
+	settings := make(Settings, len(vars))
+	for key, value := range vars {
+		settings[key] = envValue(key, value)
+	}
+
+	var global struct {
+		Disabled            bool     `config:"disabled"`
+		Dedup               string   `config:"dedup"`
+		RateLimit           bool     `config:"rate_limit"`
+		MaxParallelism      int      `config:"max_parallelism"`
+		ReceiverParallelism int      `config:"receiver_parallelism"`
+		ContinueOnError     bool     `config:"continue_on_error"`
+		Templates           []string `config:"templates"`
+		Services            []string `config:"services"`
+		URLs                string   `config:"urls"`
+	}
+	// The variables of the services are in there as well.
+	if err := settings.decode(&global, false); err != nil {
+		return nil, errors.Wrapf(err, "read %s variables", prefix)
+	}
+
+	cfg := &Config{
+		Disabled:            global.Disabled,
+		RateLimit:           global.RateLimit,
+		MaxParallelism:      global.MaxParallelism,
+		ReceiverParallelism: global.ReceiverParallelism,
+		ContinueOnError:     global.ContinueOnError,
+		Templates:           global.Templates,
+		URLs:                strings.Fields(global.URLs),
+	}
+	if global.Dedup != "" {
+		dedup, err := toDuration(global.Dedup)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %sDEDUP", prefix)
+		}
+		cfg.Dedup = dedup
+	}
+
+	retrySettings := subset(vars, "RETRY_", nil)
+	if len(retrySettings) > 0 {
+		cfg.Retry = &RetryConfig{}
+		if err := retrySettings.Decode(cfg.Retry); err != nil {
+			return nil, errors.Wrapf(err, "read %sRETRY variables", prefix)
+		}
+	}
+
+	names := make([]string, len(global.Services))
+	for i, name := range global.Services {
+		names[i] = envName(name)
+	}
+	for i, name := range global.Services {
+		sc, err := serviceFromEnv(name, subset(vars, names[i]+"_", names))
+		if err != nil {
+			return nil, errors.Wrapf(err, "read %s%s variables", prefix, names[i])
+		}
+		cfg.Services = append(cfg.Services, sc)
+	}
+
+	return cfg, nil
+}
+
+// serviceFromEnv returns the service with the given name described by the given variables.
+func serviceFromEnv(name string, vars Settings) (ServiceConfig, error) {
+	var s struct {
+		Type      string   `config:"type"`
+		Disabled  bool     `config:"disabled"`
+		Receivers []string `config:"receivers"`
+		Tags      []string `config:"tags"`
+	}
+	// The other variables are the settings of the service.
+	if err := vars.decode(&s, false); err != nil {
+		return ServiceConfig{}, err
+	}
+	if s.Type == "" {
+		s.Type = name
+	}
+
+	sc := ServiceConfig{
+		Type:      s.Type,
+		Name:      name,
+		Disabled:  s.Disabled,
+		Receivers: s.Receivers,
+		Tags:      s.Tags,
+		Settings:  make(Settings),
+	}
+	for key, value := range vars {
+		switch key {
+		case "type", "disabled", "receivers", "tags", "receivers_file", "tags_file":
+		default:
+			sc.Settings[key] = value
+		}
+	}
+
+	return sc, nil
+}
+
+// subset returns the variables starting with prefix as settings, keyed by the rest of their names in lower case.
+// Variables belonging to one of the other service names, because it's longer and starts with prefix as well, are
+// skipped.
+func subset(vars map[string]string, prefix string, names []string) Settings {
+	// Check longer names first, so that NOTIFY_OPS_MAIL_X belongs to "ops_mail" rather than "ops".
+	longer := make([]string, 0, len(names))
+	for _, name := range names {
+		if len(name)+1 > len(prefix) && strings.HasPrefix(name+"_", prefix) {
+			longer = append(longer, name+"_")
+		}
+	}
+	sort.Slice(longer, func(i, j int) bool { return len(longer[i]) > len(longer[j]) })
+
+	settings := make(Settings)
+outer:
+	for key, value := range vars {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		for _, other := range longer {
+			if strings.HasPrefix(key, other) {
+				continue outer
+			}
+		}
+		settings[strings.ToLower(strings.TrimPrefix(key, prefix))] = envValue(key, value)
+	}
+
+	return settings
+}
+
+// envValue returns the value of the variable with the given key as setting.
+func envValue(key, value string) any {
+	if strings.HasSuffix(key, fileSuffix) {
+		return File(value)
+	}
+
+	return value
+}
+
+// envName returns the name of a service as used in environment variables.
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(name))
+}
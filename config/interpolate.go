@@ -0,0 +1,87 @@
+package config
+
+import (
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Lookup resolves the references in configuration values, see Interpolate. It returns false if name isn't set.
+type Lookup func(name string) (string, bool)
+
+// filePrefix marks a reference to a file holding a secret, e.g. ${file:/run/secrets/telegram_token}.
+const filePrefix = "file:"
+
+// Interpolate replaces the references in s:
+//
+//   - ${NAME} is replaced by the value of the variable NAME. It's an error if NAME isn't set.
+//   - ${NAME:-default} is replaced by the value of NAME, or by default if NAME isn't set or empty.
+//   - ${file:/path/to/secret} is replaced by the content of the file, without trailing line breaks. It's meant for
+//     secrets mounted as files, like Docker or Kubernetes secrets.
+//   - $$ is replaced by a single $.
+//
+// Variables are resolved with lookup, or with os.LookupEnv if lookup is nil.
+func Interpolate(s string, lookup Lookup) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+
+		switch s[i+1] {
+		case '$':
+			sb.WriteByte('$')
+			i++
+		case '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return "", errors.Errorf("unterminated reference in %q", s)
+			}
+			value, err := resolve(s[i+2:i+end], lookup)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(value)
+			i += end
+		default:
+			sb.WriteByte('$')
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// resolve returns the value of the given reference, without ${ and }.
+func resolve(ref string, lookup Lookup) (string, error) {
+	if strings.HasPrefix(ref, filePrefix) {
+		data, err := os.ReadFile(strings.TrimPrefix(ref, filePrefix))
+		if err != nil {
+			return "", errors.Wrap(err, "read secret")
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	name, fallback, hasFallback := strings.Cut(ref, ":-")
+	if name == "" {
+		return "", errors.Errorf("empty reference ${%s}", ref)
+	}
+
+	value, ok := lookup(name)
+	switch {
+	case hasFallback && value == "":
+		return fallback, nil
+	case !ok:
+		return "", errors.Errorf("variable %q is not set", name)
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify"
+)
+
+// builtinMiddleware are the middleware types known to the default registry:
+//
+//   - subject_prefix puts the setting "prefix" in front of every subject.
+//   - redact replaces the matches of the regular expressions in the setting "patterns" in subjects and bodies by the
+//     setting "replacement", which defaults to "[REDACTED]".
+//   - batch buffers messages and sends them as digests, see notify.Batch. The settings "max_size" and "interval"
+//     correspond to the fields of notify.BatchConfig.
+var builtinMiddleware = map[string]MiddlewareFactory{
+	"subject_prefix": newSubjectPrefix,
+	"redact":         newRedact,
+	"batch":          newBatch,
+}
+
+func newSubjectPrefix(settings Settings) (notify.Middleware, error) {
+	var s struct {
+		Prefix string `config:"prefix,required"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	return func(next notify.Notifier) notify.Notifier {
+		return notify.NotifierFunc(func(ctx context.Context, subject, message string) error {
+			return next.Send(ctx, s.Prefix+subject, message)
+		})
+	}, nil
+}
+
+func newRedact(settings Settings) (notify.Middleware, error) {
+	var s struct {
+		Patterns    []string `config:"patterns,required"`
+		Replacement string   `config:"replacement"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+	if s.Replacement == "" {
+		s.Replacement = "[REDACTED]"
+	}
+
+	patterns := make([]*regexp.Regexp, len(s.Patterns))
+	for i, pattern := range s.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid pattern %q", pattern)
+		}
+		patterns[i] = re
+	}
+
+	redact := func(text string) string {
+		for _, re := range patterns {
+			text = re.ReplaceAllString(text, s.Replacement)
+		}
+		return text
+	}
+
+	return func(next notify.Notifier) notify.Notifier {
+		return notify.NotifierFunc(func(ctx context.Context, subject, message string) error {
+			return next.Send(ctx, redact(subject), redact(message))
+		})
+	}, nil
+}
+
+func newBatch(settings Settings) (notify.Middleware, error) {
+	var s struct {
+		MaxSize  int           `config:"max_size"`
+		Interval time.Duration `config:"interval"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	return func(next notify.Notifier) notify.Notifier {
+		return notify.Batch(next, notify.BatchConfig{MaxSize: s.MaxSize, Interval: s.Interval})
+	}, nil
+}
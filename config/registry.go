@@ -0,0 +1,110 @@
+package config
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/casdoor/notify"
+)
+
+// Factory creates a service from its settings and adds the given receivers to it, e.g. chat IDs or email addresses.
+type Factory func(settings Settings, receivers []string) (notify.Notifier, error)
+
+// MiddlewareFactory creates a middleware from its settings.
+type MiddlewareFactory func(settings Settings) (notify.Middleware, error)
+
+// Registry maps service types and middleware types to their factories. It's safe for concurrent use.
+type Registry struct {
+	mu         sync.RWMutex
+	services   map[string]Factory
+	middleware map[string]MiddlewareFactory
+}
+
+// NewRegistry returns a new, empty registry. Use DefaultRegistry for a registry that knows the built-in services.
+func NewRegistry() *Registry {
+	return &Registry{
+		services:   make(map[string]Factory),
+		middleware: make(map[string]MiddlewareFactory),
+	}
+}
+
+// defaultRegistry knows the built-in services and middleware, and everything added with Register and
+// RegisterMiddleware.
+var defaultRegistry = newDefaultRegistry()
+
+func newDefaultRegistry() *Registry {
+	r := NewRegistry()
+	for typ, factory := range builtinServices {
+		r.Register(typ, factory)
+	}
+	for typ, factory := range builtinMiddleware {
+		r.RegisterMiddleware(typ, factory)
+	}
+
+	return r
+}
+
+// DefaultRegistry returns the registry used by the package-level functions. It knows the built-in services and
+// middleware.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Register adds the factory of a service type. It replaces the factory if the type is already registered.
+func (r *Registry) Register(typ string, factory Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.services[typ] = factory
+}
+
+// RegisterMiddleware adds the factory of a middleware type. It replaces the factory if the type is already registered.
+func (r *Registry) RegisterMiddleware(typ string, factory MiddlewareFactory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.middleware[typ] = factory
+}
+
+// Types returns the registered service types, sorted alphabetically.
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	types := make([]string, 0, len(r.services))
+	for typ := range r.services {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+
+	return types
+}
+
+// factory returns the factory of the given service type.
+func (r *Registry) factory(typ string) (Factory, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	f, ok := r.services[typ]
+	return f, ok
+}
+
+// middlewareFactory returns the factory of the given middleware type.
+func (r *Registry) middlewareFactory(typ string) (MiddlewareFactory, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	f, ok := r.middleware[typ]
+	return f, ok
+}
+
+// Register adds the factory of a service type to the default registry. Third-party services call it, typically from
+// an init function, to become available to configuration files.
+func Register(typ string, factory Factory) {
+	defaultRegistry.Register(typ, factory)
+}
+
+// RegisterMiddleware adds the factory of a middleware type to the default registry.
+func RegisterMiddleware(typ string, factory MiddlewareFactory) {
+	defaultRegistry.RegisterMiddleware(typ, factory)
+}
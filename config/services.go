@@ -0,0 +1,651 @@
+package config
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/silenceper/wechat/v2/cache"
+	"google.golang.org/api/option"
+	"maunium.net/go/mautrix/id"
+
+	"github.com/casdoor/notify"
+	"github.com/casdoor/notify/service/amazonses"
+	"github.com/casdoor/notify/service/amazonsns"
+	"github.com/casdoor/notify/service/bark"
+	"github.com/casdoor/notify/service/cucloud"
+	"github.com/casdoor/notify/service/dingding"
+	"github.com/casdoor/notify/service/discord"
+	"github.com/casdoor/notify/service/fcm"
+	"github.com/casdoor/notify/service/googlechat"
+	"github.com/casdoor/notify/service/http"
+	"github.com/casdoor/notify/service/lark"
+	"github.com/casdoor/notify/service/line"
+	"github.com/casdoor/notify/service/mail"
+	"github.com/casdoor/notify/service/mailgun"
+	"github.com/casdoor/notify/service/matrix"
+	"github.com/casdoor/notify/service/mattermost"
+	"github.com/casdoor/notify/service/msteams"
+	"github.com/casdoor/notify/service/plivo"
+	"github.com/casdoor/notify/service/pushbullet"
+	"github.com/casdoor/notify/service/pushover"
+	"github.com/casdoor/notify/service/reddit"
+	"github.com/casdoor/notify/service/rocketchat"
+	"github.com/casdoor/notify/service/sendgrid"
+	"github.com/casdoor/notify/service/slack"
+	"github.com/casdoor/notify/service/telegram"
+	"github.com/casdoor/notify/service/textmagic"
+	"github.com/casdoor/notify/service/twilio"
+	"github.com/casdoor/notify/service/twitter"
+	"github.com/casdoor/notify/service/viber"
+	"github.com/casdoor/notify/service/wechat"
+)
+
+// builtinServices are the service types known to the default registry. The settings of every type are documented by
+// the struct its factory decodes them into.
+var builtinServices = map[string]Factory{
+	"amazonses":      newAmazonSES,
+	"amazonsns":      newAmazonSNS,
+	"bark":           newBark,
+	"cucloud":        newCuCloud,
+	"dingding":       newDingDing,
+	"discord":        newDiscord,
+	"fcm":            newFCM,
+	"googlechat":     newGoogleChat,
+	"http":           newHTTP,
+	"lark":           newLark,
+	"line":           newLine,
+	"line_notify":    newLineNotify,
+	"mail":           newMail,
+	"mailgun":        newMailgun,
+	"matrix":         newMatrix,
+	"mattermost":     newMattermost,
+	"msteams":        newMSTeams,
+	"plivo":          newPlivo,
+	"pushbullet":     newPushbullet,
+	"pushbullet_sms": newPushbulletSMS,
+	"pushover":       newPushover,
+	"reddit":         newReddit,
+	"rocketchat":     newRocketChat,
+	"sendgrid":       newSendGrid,
+	"slack":          newSlack,
+	"telegram":       newTelegram,
+	"textmagic":      newTextMagic,
+	"twilio":         newTwilio,
+	"twitter":        newTwitter,
+	"viber":          newViber,
+	"wechat":         newWeChat,
+}
+
+func newAmazonSES(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		AccessKeyID string `config:"access_key_id,required"`
+		SecretKey   string `config:"secret_key,required"`
+		Region      string `config:"region,required"`
+		Sender      string `config:"sender,required"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	service, err := amazonses.New(s.AccessKeyID, s.SecretKey, s.Region, s.Sender)
+	if err != nil {
+		return nil, err
+	}
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+func newAmazonSNS(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		AccessKeyID string `config:"access_key_id,required"`
+		SecretKey   string `config:"secret_key,required"`
+		Region      string `config:"region,required"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	service, err := amazonsns.New(s.AccessKeyID, s.SecretKey, s.Region)
+	if err != nil {
+		return nil, err
+	}
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+// newBark creates a Bark service. Its receivers are the URLs of Bark servers; the default server is used if there are
+// none.
+func newBark(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		DeviceKey string `config:"device_key,required"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	return bark.NewWithServers(s.DeviceKey, receivers...), nil
+}
+
+func newCuCloud(settings Settings, _ []string) (notify.Notifier, error) {
+	var s struct {
+		AccessKey       string `config:"access_key,required"`
+		SecretKey       string `config:"secret_key,required"`
+		TopicName       string `config:"topic_name,required"`
+		MessageTitle    string `config:"message_title"`
+		CloudRegionCode string `config:"cloud_region_code,required"`
+		AccountID       string `config:"account_id,required"`
+		NotifyType      string `config:"notify_type,required"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	return cucloud.New(s.AccessKey, s.SecretKey, s.TopicName, s.MessageTitle, s.CloudRegionCode, s.AccountID,
+		s.NotifyType), nil
+}
+
+func newDingDing(settings Settings, _ []string) (notify.Notifier, error) {
+	var s struct {
+		Token  string `config:"token,required"`
+		Secret string `config:"secret"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	return dingding.New(&dingding.Config{Token: s.Token, Secret: s.Secret}), nil
+}
+
+// newDiscord creates a Discord service authenticated with either a bot token or an OAuth2 token.
+func newDiscord(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		BotToken    string `config:"bot_token"`
+		OAuth2Token string `config:"oauth2_token"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	service := discord.New()
+	var err error
+	switch {
+	case s.BotToken != "":
+		err = service.AuthenticateWithBotToken(s.BotToken)
+	case s.OAuth2Token != "":
+		err = service.AuthenticateWithOAuth2Token(s.OAuth2Token)
+	default:
+		err = errors.New("missing required settings: bot_token or oauth2_token")
+	}
+	if err != nil {
+		return nil, err
+	}
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+func newFCM(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		ServerAPIKey string `config:"server_api_key,required"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	service, err := fcm.New(s.ServerAPIKey)
+	if err != nil {
+		return nil, err
+	}
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+// newGoogleChat creates a Google Chat service. Without credentials file, the application default credentials are used.
+func newGoogleChat(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		CredentialsFile string `config:"credentials_file"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	var options []option.ClientOption
+	if s.CredentialsFile != "" {
+		options = append(options, option.WithCredentialsFile(s.CredentialsFile))
+	}
+	service, err := googlechat.NewWithContext(context.Background(), options...)
+	if err != nil {
+		return nil, err
+	}
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+// newHTTP creates an HTTP service posting to the webhook URLs given as receivers.
+func newHTTP(_ Settings, receivers []string) (notify.Notifier, error) {
+	service := http.New()
+	service.AddReceiversURLs(receivers...)
+
+	return service, nil
+}
+
+func newLark(settings Settings, _ []string) (notify.Notifier, error) {
+	var s struct {
+		WebhookURL string `config:"webhook_url,required"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	return lark.NewWebhookService(s.WebhookURL), nil
+}
+
+func newLine(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		ChannelSecret      string `config:"channel_secret,required"`
+		ChannelAccessToken string `config:"channel_access_token,required"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	service, err := line.New(s.ChannelSecret, s.ChannelAccessToken)
+	if err != nil {
+		return nil, err
+	}
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+// newLineNotify creates a LINE Notify service sending with the access tokens given as receivers.
+func newLineNotify(_ Settings, receivers []string) (notify.Notifier, error) {
+	service := line.NewNotify()
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+// newMail creates an SMTP mail service. It authenticates if a username is set; the authentication host defaults to the
+// host of the SMTP server.
+func newMail(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		Sender    string `config:"sender,required"`
+		SMTPHost  string `config:"smtp_host,required"`
+		Identity  string `config:"identity"`
+		Username  string `config:"username"`
+		Password  string `config:"password"`
+		AuthHost  string `config:"auth_host"`
+		PlainText bool   `config:"plain_text"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	service := mail.New(s.Sender, s.SMTPHost)
+	if s.Username != "" {
+		if s.AuthHost == "" {
+			s.AuthHost = s.SMTPHost
+			if host, _, err := net.SplitHostPort(s.SMTPHost); err == nil {
+				s.AuthHost = host
+			}
+		}
+		service.AuthenticateSMTP(s.Identity, s.Username, s.Password, s.AuthHost)
+	}
+	if s.PlainText {
+		service.BodyFormat(mail.PlainText)
+	}
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+func newMailgun(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		Domain string `config:"domain,required"`
+		APIKey string `config:"api_key,required"`
+		Sender string `config:"sender,required"`
+		Europe bool   `config:"europe"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	var options []mailgun.Option
+	if s.Europe {
+		options = append(options, mailgun.WithEurope())
+	}
+	service := mailgun.New(s.Domain, s.APIKey, s.Sender, options...)
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+// newMatrix creates a Matrix service sending to a single room.
+func newMatrix(settings Settings, _ []string) (notify.Notifier, error) {
+	var s struct {
+		UserID      string `config:"user_id,required"`
+		RoomID      string `config:"room_id,required"`
+		HomeServer  string `config:"home_server,required"`
+		AccessToken string `config:"access_token,required"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	return matrix.New(id.UserID(s.UserID), id.RoomID(s.RoomID), s.HomeServer, s.AccessToken)
+}
+
+// newMattermost creates a Mattermost service and logs in right away.
+func newMattermost(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		URL      string `config:"url,required"`
+		LoginID  string `config:"login_id,required"`
+		Password string `config:"password,required"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	service := mattermost.New(s.URL)
+	if err := service.LoginWithCredentials(context.Background(), s.LoginID, s.Password); err != nil {
+		return nil, err
+	}
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+// newMSTeams creates a Microsoft Teams service posting to the webhook URLs given as receivers.
+func newMSTeams(_ Settings, receivers []string) (notify.Notifier, error) {
+	service := msteams.New()
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+func newPlivo(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		AuthID         string `config:"auth_id"`
+		AuthToken      string `config:"auth_token"`
+		Source         string `config:"source,required"`
+		CallbackURL    string `config:"callback_url"`
+		CallbackMethod string `config:"callback_method"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	service, err := plivo.New(
+		&plivo.ClientOptions{AuthID: s.AuthID, AuthToken: s.AuthToken},
+		&plivo.MessageOptions{Source: s.Source, CallbackURL: s.CallbackURL, CallbackMethod: s.CallbackMethod},
+	)
+	if err != nil {
+		return nil, err
+	}
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+func newPushbullet(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		APIToken string `config:"api_token,required"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	service := pushbullet.New(s.APIToken)
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+func newPushbulletSMS(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		APIToken       string `config:"api_token,required"`
+		DeviceNickname string `config:"device_nickname,required"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	service, err := pushbullet.NewSMS(s.APIToken, s.DeviceNickname)
+	if err != nil {
+		return nil, err
+	}
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+func newPushover(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		AppToken string `config:"app_token,required"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	service := pushover.New(s.AppToken)
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+func newReddit(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		ClientID     string `config:"client_id,required"`
+		ClientSecret string `config:"client_secret,required"`
+		Username     string `config:"username,required"`
+		Password     string `config:"password,required"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	service, err := reddit.New(s.ClientID, s.ClientSecret, s.Username, s.Password)
+	if err != nil {
+		return nil, err
+	}
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+func newRocketChat(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		Server string `config:"server,required"`
+		Scheme string `config:"scheme"`
+		UserID string `config:"user_id,required"`
+		Token  string `config:"token,required"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+	if s.Scheme == "" {
+		s.Scheme = "https"
+	}
+
+	service, err := rocketchat.New(s.Server, s.Scheme, s.UserID, s.Token)
+	if err != nil {
+		return nil, err
+	}
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+func newSendGrid(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		APIKey     string `config:"api_key,required"`
+		Sender     string `config:"sender,required"`
+		SenderName string `config:"sender_name"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	service := sendgrid.New(s.APIKey, s.Sender, s.SenderName)
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+func newSlack(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		Token string `config:"token,required"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	service := slack.New(s.Token)
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+// newTelegram creates a Telegram service. Its receivers are chat IDs.
+func newTelegram(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		Token     string `config:"token,required"`
+		ParseMode string `config:"parse_mode"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	chatIDs := make([]int64, len(receivers))
+	for i, r := range receivers {
+		chatID, err := strconv.ParseInt(r, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid Telegram chat ID %q", r)
+		}
+		chatIDs[i] = chatID
+	}
+
+	service, err := telegram.New(s.Token)
+	if err != nil {
+		return nil, err
+	}
+	if s.ParseMode != "" {
+		service.SetParseMode(s.ParseMode)
+	}
+	service.AddReceivers(chatIDs...)
+
+	return service, nil
+}
+
+func newTextMagic(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		Username string `config:"username,required"`
+		APIKey   string `config:"api_key,required"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	service := textmagic.New(s.Username, s.APIKey)
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+func newTwilio(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		AccountSID string `config:"account_sid,required"`
+		AuthToken  string `config:"auth_token,required"`
+		Sender     string `config:"sender,required"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	service, err := twilio.New(s.AccountSID, s.AuthToken, s.Sender)
+	if err != nil {
+		return nil, err
+	}
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+func newTwitter(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		ConsumerKey       string `config:"consumer_key,required"`
+		ConsumerSecret    string `config:"consumer_secret,required"`
+		AccessToken       string `config:"access_token,required"`
+		AccessTokenSecret string `config:"access_token_secret,required"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	service, err := twitter.New(twitter.Credentials{
+		ConsumerKey:       s.ConsumerKey,
+		ConsumerSecret:    s.ConsumerSecret,
+		AccessToken:       s.AccessToken,
+		AccessTokenSecret: s.AccessTokenSecret,
+	})
+	if err != nil {
+		return nil, err
+	}
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+// newViber creates a Viber service. The webhook is only set if it's configured.
+func newViber(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		AppKey       string `config:"app_key,required"`
+		SenderName   string `config:"sender_name,required"`
+		SenderAvatar string `config:"sender_avatar"`
+		WebhookURL   string `config:"webhook_url"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	service := viber.New(s.AppKey, s.SenderName, s.SenderAvatar)
+	if s.WebhookURL != "" {
+		if err := service.SetWebhook(s.WebhookURL); err != nil {
+			return nil, err
+		}
+	}
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
+
+// newWeChat creates a WeChat service caching its access token in memory.
+func newWeChat(settings Settings, receivers []string) (notify.Notifier, error) {
+	var s struct {
+		AppID          string `config:"app_id,required"`
+		AppSecret      string `config:"app_secret,required"`
+		Token          string `config:"token"`
+		EncodingAESKey string `config:"encoding_aes_key"`
+	}
+	if err := settings.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	service := wechat.New(&wechat.Config{
+		AppID:          s.AppID,
+		AppSecret:      s.AppSecret,
+		Token:          s.Token,
+		EncodingAESKey: s.EncodingAESKey,
+		Cache:          cache.NewMemory(),
+	})
+	service.AddReceivers(receivers...)
+
+	return service, nil
+}
@@ -0,0 +1,306 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Settings are the settings of a service or a middleware, like credentials. Keys are matched case-insensitively and
+// ignoring underscores and dashes, so that "api_token", "apiToken" and the environment variable suffix "API_TOKEN" all
+// name the same setting.
+type Settings map[string]any
+
+// File is the value of a setting naming a file that holds the value of another setting, like the environment variable
+// NOTIFY_OPS_TOKEN_FILE, see FromEnv. A setting "token_file" holding a File provides the setting "token", unless the
+// settings are decoded into a struct that declares "token_file" itself, which then gets the path of the file.
+type File string
+
+// String returns the path of the file.
+func (f File) String() string {
+	return string(f)
+}
+
+// key returns the key of the setting with the given key, and whether it is set.
+func (s Settings) key(key string) (string, bool) {
+	if _, ok := s[key]; ok {
+		return key, true
+	}
+
+	want := normalizeKey(key)
+	for k := range s {
+		if normalizeKey(k) == want {
+			return k, true
+		}
+	}
+
+	return "", false
+}
+
+// lookup returns the value of the given key.
+func (s Settings) lookup(key string) (any, bool) {
+	k, ok := s.key(key)
+	if !ok {
+		return nil, false
+	}
+
+	return s[k], true
+}
+
+// file returns the contents of the file named by the File setting with the given key and the suffix "_file", along
+// with that setting's key. It reports false if there is no such setting.
+func (s Settings) file(key string) (string, string, bool, error) {
+	k, ok := s.key(key + "_file")
+	if !ok {
+		return "", "", false, nil
+	}
+	f, ok := s[k].(File)
+	if !ok {
+		return "", "", false, nil
+	}
+
+	data, err := os.ReadFile(string(f))
+	if err != nil {
+		return "", k, true, errors.Wrapf(err, "read setting %q", k)
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), k, true, nil
+}
+
+// normalizeKey returns key in lower case without underscores and dashes.
+func normalizeKey(key string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+}
+
+// String returns the setting with the given key as a string, or an empty string if it isn't set.
+func (s Settings) String(key string) string {
+	v, ok := s.lookup(key)
+	if !ok || v == nil {
+		return ""
+	}
+	if str, ok := v.(string); ok {
+		return str
+	}
+
+	return fmt.Sprint(v)
+}
+
+// Has reports whether the setting with the given key is set.
+func (s Settings) Has(key string) bool {
+	_, ok := s.lookup(key)
+	return ok
+}
+
+// Decode stores the settings in the struct pointed to by v. The settings are mapped to the exported fields of the
+// struct by the "config" field tag, which holds the key of the setting and optionally ",required":
+//
+//	type settings struct {
+//		Token   string        `config:"token,required"`
+//		Timeout time.Duration `config:"timeout"`
+//	}
+//
+// Fields without tag use their name as key. Values are converted leniently, so that settings read from environment
+// variables, which are always strings, decode into numbers, booleans, durations and lists as well. Lists may be given
+// as comma-separated strings. Settings that are missing are read from a File, see File.
+//
+// Decode returns an error naming the settings that don't belong to any field, e.g. because of a typo.
+func (s Settings) Decode(v any) error {
+	return s.decode(v, true)
+}
+
+// decode works like Decode. Unless strict is set, settings that don't belong to any field are ignored.
+func (s Settings) decode(v any, strict bool) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return errors.Errorf("decode settings into %T: not a pointer to a struct", v)
+	}
+	rv = rv.Elem()
+
+	type field struct {
+		index    int
+		key      string
+		required bool
+	}
+	var fields []field
+	declared := make(map[string]bool, rv.NumField())
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Type().Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		key, required := f.Name, false
+		if tag, ok := f.Tag.Lookup("config"); ok {
+			parts := strings.Split(tag, ",")
+			if parts[0] == "-" {
+				continue
+			}
+			if parts[0] != "" {
+				key = parts[0]
+			}
+			for _, option := range parts[1:] {
+				required = required || option == "required"
+			}
+		}
+		fields = append(fields, field{index: i, key: key, required: required})
+		declared[normalizeKey(key)] = true
+	}
+
+	var missing []string
+	known := make(map[string]bool, len(s))
+	for _, f := range fields {
+		key := f.key
+		k, ok := s.key(key)
+		if ok {
+			known[k] = true
+		}
+		value := s[k]
+		if (!ok || value == nil || value == "") && !declared[normalizeKey(key+"_file")] {
+			contents, fileKey, ok, err := s.file(key)
+			if err != nil {
+				return err
+			}
+			if ok {
+				known[fileKey] = true
+				value = contents
+			}
+		}
+		if value == nil || value == "" {
+			if f.required {
+				missing = append(missing, key)
+			}
+			continue
+		}
+		if err := assign(rv.Field(f.index), value); err != nil {
+			return errors.Wrapf(err, "invalid setting %q", key)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.Errorf("missing required settings: %s", strings.Join(missing, ", "))
+	}
+
+	if strict {
+		var unknown []string
+		for k := range s {
+			if !known[k] {
+				unknown = append(unknown, k)
+			}
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return errors.Errorf("unknown settings: %s", strings.Join(unknown, ", "))
+		}
+	}
+
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// assign converts value to the type of dst and stores it.
+func assign(dst reflect.Value, value any) error {
+	if dst.Type() == durationType {
+		d, err := toDuration(value)
+		if err != nil {
+			return err
+		}
+		dst.SetInt(int64(d))
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.String:
+		dst.SetString(fmt.Sprint(value))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(fmt.Sprint(value))
+		if err != nil {
+			return err
+		}
+		dst.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(fmt.Sprint(value), 10, 64)
+		if err != nil {
+			return err
+		}
+		dst.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(fmt.Sprint(value), 10, 64)
+		if err != nil {
+			return err
+		}
+		dst.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(fmt.Sprint(value), 64)
+		if err != nil {
+			return err
+		}
+		dst.SetFloat(f)
+	case reflect.Slice:
+		items := toList(value)
+		slice := reflect.MakeSlice(dst.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := assign(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		dst.Set(slice)
+	default:
+		// Anything else, like maps or nested structs, takes the detour through JSON.
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, dst.Addr().Interface())
+	}
+
+	return nil
+}
+
+// toDuration converts a duration string like "1m30s" or a number of seconds to a duration.
+func toDuration(value any) (time.Duration, error) {
+	switch v := value.(type) {
+	case time.Duration:
+		return v, nil
+	case int:
+		return time.Duration(v) * time.Second, nil
+	case float64:
+		return time.Duration(v * float64(time.Second)), nil
+	}
+
+	s := fmt.Sprint(value)
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+
+	return time.ParseDuration(s)
+}
+
+// toList converts a list or a comma-separated string to a list.
+func toList(value any) []any {
+	switch v := value.(type) {
+	case []any:
+		return v
+	case []string:
+		items := make([]any, len(v))
+		for i, s := range v {
+			items[i] = s
+		}
+		return items
+	}
+
+	var items []any
+	for _, s := range strings.Split(fmt.Sprint(value), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			items = append(items, s)
+		}
+	}
+
+	return items
+}
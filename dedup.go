@@ -0,0 +1,241 @@
+package notify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/casdoor/notify/receiver"
+)
+
+// Suppression describes a notification whose repeats are being suppressed, see WithDedup.
+type Suppression struct {
+	// Key is the fingerprint of the notification.
+	Key string
+	// Message is the first message with this fingerprint, the one that was sent.
+	Message *Message
+	// Selector is the selector the message was sent with, if any, see SendTo.
+	Selector *Selector
+	// Overrides are the receivers bound to the context of the first message, see WithReceivers. The summary is sent to
+	// them as well.
+	Overrides []receiver.Binding
+	// First is the time the first message was sent at.
+	First time.Time
+	// Last is the time the last repeat was suppressed at. It equals First if there were no repeats yet.
+	Last time.Time
+	// Suppressed is the number of repeats that were suppressed.
+	Suppressed int
+	// Until is the time the suppression window closes at.
+	Until time.Time
+}
+
+// DedupSummaryFn builds the summary message that is sent when a suppression window with suppressed repeats closes. It
+// may return nil to send no summary.
+type DedupSummaryFn func(s Suppression) *Message
+
+// DefaultDedupSummary is the DedupSummaryFn used by WithDedup. It returns a message with the subject of the suppressed
+// notification and a body like "Suppressed 143 similar notifications in the last 5m0s.".
+func DefaultDedupSummary(s Suppression) *Message {
+	m := NewMessage(s.Message.Subject, fmt.Sprintf(
+		"Suppressed %d similar notifications in the last %s.", s.Suppressed, s.Until.Sub(s.First),
+	))
+	m.Priority = s.Message.Priority
+	m.Tags = append([]string(nil), s.Message.Tags...)
+	m.Receivers = s.Message.Receivers
+
+	return m
+}
+
+// dedup holds the state of the deduplication, see WithDedup.
+type dedup struct {
+	window  time.Duration
+	summary DedupSummaryFn
+
+	mu      sync.Mutex
+	entries map[string]*suppression
+}
+
+// suppression is a Suppression with the timer closing its window.
+type suppression struct {
+	Suppression
+	timer *time.Timer
+}
+
+// WithDedup is an Option that suppresses repeats of a notification within the given window. Notifications are
+// fingerprinted by their subject, body and receivers, see Message.Receivers and WithReceivers, or by the key bound to
+// the context with WithDedupKey. The first notification is sent right away and opens the window; repeats are counted
+// but not sent. When the window closes, a summary like "Suppressed 143 similar notifications in the last 5m0s." is sent
+// to the same services and receivers, unless there were no repeats. Errors of summaries are dropped. Use Suppressions
+// to inspect the open windows. SendWithReport and SendMessageWithReport aren't deduplicated.
+//
+// A window of zero disables the deduplication again.
+func WithDedup(window time.Duration) Option {
+	return func(n *Notify) {
+		if n == nil {
+			return
+		}
+		if window <= 0 {
+			n.dedup = nil
+			return
+		}
+
+		n.dedup = &dedup{
+			window:  window,
+			summary: DefaultDedupSummary,
+			entries: make(map[string]*suppression),
+		}
+	}
+}
+
+// WithDedupSummary is an Option that sets the function building the summary messages of the deduplication. It has to
+// be applied after WithDedup.
+func WithDedupSummary(fn DedupSummaryFn) Option {
+	return func(n *Notify) {
+		if n != nil && n.dedup != nil && fn != nil {
+			n.dedup.summary = fn
+		}
+	}
+}
+
+type dedupKey struct{}
+
+// WithDedupKey returns a copy of ctx that makes the deduplication fingerprint the notification by the given key instead
+// of its subject and body, e.g. to treat errors with varying details as the same notification.
+func WithDedupKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, dedupKey{}, key)
+}
+
+// fingerprint returns the fingerprint of a notification.
+func fingerprint(ctx context.Context, m *Message, selector *Selector) string {
+	if key, ok := ctx.Value(dedupKey{}).(string); ok && key != "" {
+		return key
+	}
+
+	h := sha256.New()
+	for _, s := range []string{m.Subject, m.Body} {
+		_, _ = h.Write([]byte(s))
+		_, _ = h.Write([]byte{0})
+	}
+	if selector != nil {
+		_, _ = h.Write([]byte(selector.String()))
+	}
+	_, _ = h.Write([]byte{0})
+
+	// Notifications to different receivers are different notifications, no matter in which order they are listed.
+	write := func(service string, mode receiver.Mode, receivers []string) {
+		receivers = append([]string(nil), receivers...)
+		sort.Strings(receivers)
+		_, _ = fmt.Fprintf(h, "%q %d %q\n", service, mode, receivers)
+	}
+	services := make([]string, 0, len(m.Receivers))
+	for service := range m.Receivers {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	for _, service := range services {
+		write(service, receiver.Replace, m.Receivers[service])
+	}
+	_, _ = h.Write([]byte{0})
+	for _, service := range receiver.Overridden(ctx) {
+		mode, receivers, _ := receiver.Override(ctx, service)
+		names := make([]string, len(receivers))
+		for i, r := range receivers {
+			names[i] = r.String()
+		}
+		write(service, mode, names)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// suppress reports whether the given notification is a repeat that must not be sent. Otherwise, it opens a new window.
+func (n *Notify) suppress(ctx context.Context, m *Message, selector *Selector) bool {
+	d := n.dedup
+	if d == nil {
+		return false
+	}
+
+	key := fingerprint(ctx, m, selector)
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if s, ok := d.entries[key]; ok {
+		s.Suppressed++
+		s.Last = now
+		return true
+	}
+
+	s := &suppression{Suppression: Suppression{
+		Key:       key,
+		Message:   m.Clone(),
+		Selector:  selector,
+		Overrides: receiver.Bindings(ctx),
+		First:     now,
+		Last:      now,
+		Until:     now.Add(d.window),
+	}}
+	s.timer = time.AfterFunc(d.window, func() {
+		n.closeWindow(context.Background(), key)
+	})
+	d.entries[key] = s
+
+	return false
+}
+
+// closeWindow closes the suppression window with the given key and sends its summary, if there were repeats.
+func (n *Notify) closeWindow(ctx context.Context, key string) {
+	d := n.dedup
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	s, ok := d.entries[key]
+	if ok {
+		delete(d.entries, key)
+		s.timer.Stop()
+	}
+	d.mu.Unlock()
+
+	if !ok || s.Suppressed == 0 {
+		return
+	}
+	if summary := d.summary(s.Suppression); summary != nil {
+		_ = n.sendMessage(receiver.WithBindings(ctx, s.Overrides...), summary, s.Selector)
+	}
+}
+
+// Suppressions returns the open suppression windows of the deduplication, ordered by the time they were opened. It
+// returns nil if the deduplication is disabled, see WithDedup.
+func (n *Notify) Suppressions() []Suppression {
+	d := n.dedup
+	if d == nil {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	suppressions := make([]Suppression, 0, len(d.entries))
+	for _, s := range d.entries {
+		suppressions = append(suppressions, s.Suppression)
+	}
+	sort.Slice(suppressions, func(i, j int) bool {
+		return suppressions[i].First.Before(suppressions[j].First)
+	})
+
+	return suppressions
+}
+
+// flushSuppressions closes all open suppression windows early and sends their summaries.
+func (n *Notify) flushSuppressions(ctx context.Context) {
+	for _, s := range n.Suppressions() {
+		n.closeWindow(ctx, s.Key)
+	}
+}
@@ -0,0 +1,174 @@
+package notify
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/casdoor/notify/receiver"
+)
+
+// collector is a test helper service that collects the messages it receives.
+type collector struct {
+	mu       sync.Mutex
+	messages []*Message
+}
+
+func (c *collector) Send(ctx context.Context, subject, message string) error {
+	return c.SendMessage(ctx, NewMessage(subject, message))
+}
+
+func (c *collector) SendMessage(_ context.Context, m *Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, m)
+	return nil
+}
+
+func (c *collector) received() []*Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*Message(nil), c.messages...)
+}
+
+func TestWithDedup(t *testing.T) {
+	t.Parallel()
+
+	c := &collector{}
+	n := NewWithOptions(WithDedup(50 * time.Millisecond))
+	n.UseServices(c)
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		if err := n.Send(ctx, "disk full", "/dev/sda1"); err != nil {
+			t.Fatalf("Send() unexpected error: %v", err)
+		}
+	}
+	if err := n.Send(ctx, "disk full", "/dev/sdb1"); err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+
+	if got := len(c.received()); got != 2 {
+		t.Fatalf("the service received %d messages, want 2", got)
+	}
+
+	suppressions := n.Suppressions()
+	if len(suppressions) != 2 {
+		t.Fatalf("Suppressions() returned %d windows, want 2", len(suppressions))
+	}
+	if suppressions[0].Suppressed != 4 || suppressions[0].Message.Body != "/dev/sda1" {
+		t.Errorf("Suppressions()[0] = %+v", suppressions[0])
+	}
+
+	// Wait for the windows to close. Only the one with repeats sends a summary.
+	deadline := time.Now().Add(time.Second)
+	for len(n.Suppressions()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	received := c.received()
+	if len(received) != 3 {
+		t.Fatalf("the service received %d messages, want 3", len(received))
+	}
+	if summary := received[2]; summary.Subject != "disk full" || !strings.HasPrefix(summary.Body, "Suppressed 4 similar") {
+		t.Errorf("unexpected summary %q: %q", summary.Subject, summary.Body)
+	}
+
+	// The next notification opens a new window.
+	if err := n.Send(ctx, "disk full", "/dev/sda1"); err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+	if got := len(c.received()); got != 4 {
+		t.Errorf("the service received %d messages, want 4", got)
+	}
+}
+
+func TestWithDedup_Receivers(t *testing.T) {
+	t.Parallel()
+
+	c := &collector{}
+	n := NewWithOptions(WithDedup(time.Hour))
+	n.UseServices(c)
+
+	ctx := context.Background()
+	send := func(ctx context.Context, receivers map[string][]string) {
+		m := NewMessage("disk full", "message")
+		m.Receivers = receivers
+		_ = n.SendMessage(ctx, m)
+	}
+	send(ctx, map[string][]string{"slack": {"C1", "C2"}})
+	send(ctx, map[string][]string{"slack": {"C2", "C1"}})
+	send(ctx, map[string][]string{"slack": {"C3"}})
+	send(WithReceivers(ctx, "slack", "C4"), nil)
+	send(WithReceivers(ctx, "slack", "C4"), nil)
+	send(WithReceivers(ctx, "telegram", "C4"), nil)
+
+	if got := len(c.received()); got != 4 {
+		t.Errorf("the service received %d messages, want 4", got)
+	}
+}
+
+func TestWithDedup_SummaryReceivers(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu        sync.Mutex
+		summaries []string
+	)
+	n := NewWithOptions(WithDedup(time.Hour))
+	n.UseServices(NotifierFunc(func(ctx context.Context, _, message string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.HasPrefix(message, "Suppressed") {
+			summaries = append(summaries, strings.Join(receiver.ResolveIDs(ctx, "slack", []string{"everyone"}), ","))
+		}
+		return nil
+	}))
+
+	for _, to := range []string{"alice", "alice", "bob", "bob"} {
+		if err := n.Send(WithReceivers(context.Background(), "slack", to), "disk full", "/dev/sda1"); err != nil {
+			t.Fatalf("Send() unexpected error: %v", err)
+		}
+	}
+
+	// The summaries are sent to the receivers of the suppressed notifications, not to the configured ones.
+	if err := n.Close(context.Background()); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(summaries) != 2 || summaries[0] != "alice" || summaries[1] != "bob" {
+		t.Errorf("the summaries were sent to %v, want [alice bob]", summaries)
+	}
+}
+
+func TestWithDedupKey(t *testing.T) {
+	t.Parallel()
+
+	c := &collector{}
+	n := NewWithOptions(
+		WithDedup(time.Hour),
+		WithDedupSummary(func(s Suppression) *Message {
+			return NewMessage("summary", s.Key)
+		}),
+	)
+	n.UseServices(c)
+
+	ctx := WithDedupKey(context.Background(), "db-down")
+	_ = n.Send(ctx, "database down", "connection refused")
+	_ = n.Send(ctx, "database down", "timeout")
+
+	if got := len(c.received()); got != 1 {
+		t.Fatalf("the service received %d messages, want 1", got)
+	}
+
+	// Close sends the summaries of open windows right away.
+	if err := n.Close(context.Background()); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+	received := c.received()
+	if len(received) != 2 || received[1].Subject != "summary" || received[1].Body != "db-down" {
+		t.Errorf("Close() did not send the custom summary: %+v", received)
+	}
+}
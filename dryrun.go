@@ -0,0 +1,93 @@
+package notify
+
+import "context"
+
+// DryRunFunc receives the messages services would have been sent during a dry run, see WithDryRun. The name identifies
+// the service like in a Report.
+type DryRunFunc func(name string, m *Message)
+
+type dryRunKey struct{}
+
+// dryRun is bound to the context of a dry run.
+type dryRun struct {
+	fn DryRunFunc
+	// name is the name of the service being sent to, see Notify.name.
+	name string
+	// chained is true while the message passes the middleware chain of the service, see Use and Chain. Middleware
+	// isn't a service, so the message is only handed to fn once it left the chain, see messageService.
+	chained bool
+}
+
+// WithDryRun returns a copy of ctx that turns sends with it into dry runs: instead of calling the services, Notify
+// hands the messages they would have been sent to fn and treats them as sent. Routes, middleware and templates apply
+// as usual, so fn gets the subject and body after all of them, with the body converted to the format preferred by the
+// service, see FormatPreferrer. Dry runs are never deduplicated nor queued, see WithDedup and WithAsync.
+//
+// Services wrapped by other services, like the hops of Fallback, are reported by the name of the outer one. Messages
+// buffered by Batch are handed to fn when they're flushed with a dry run context, e.g. by Close.
+func WithDryRun(ctx context.Context, fn DryRunFunc) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, &dryRun{fn: fn})
+}
+
+// dryRunFrom returns the dry run bound to ctx, or nil if there is none.
+func dryRunFrom(ctx context.Context) *dryRun {
+	if ctx == nil {
+		return nil
+	}
+	d, _ := ctx.Value(dryRunKey{}).(*dryRun)
+
+	return d
+}
+
+// withDryRunService returns a copy of ctx whose dry run, if any, reports the service with the given name. chained tells
+// whether the service is wrapped by middleware.
+func withDryRunService(ctx context.Context, name string, chained bool) context.Context {
+	d := dryRunFrom(ctx)
+	if d == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, dryRunKey{}, &dryRun{fn: d.fn, name: name, chained: chained})
+}
+
+// enterChain returns a copy of ctx marking that the message enters a middleware chain, see Chain.
+func enterChain(ctx context.Context) context.Context {
+	d := dryRunFrom(ctx)
+	if d == nil || d.chained {
+		return ctx
+	}
+
+	return context.WithValue(ctx, dryRunKey{}, &dryRun{fn: d.fn, name: d.name, chained: true})
+}
+
+// leaveChain returns a copy of ctx marking that the message left the middleware chain, see messageService.
+func leaveChain(ctx context.Context) context.Context {
+	d := dryRunFrom(ctx)
+	if d == nil || !d.chained {
+		return ctx
+	}
+
+	return context.WithValue(ctx, dryRunKey{}, &dryRun{fn: d.fn, name: d.name})
+}
+
+// dryRunSend hands m to the dry run bound to ctx instead of sending it to service. It reports false if there is no dry
+// run, or if service isn't the one the message would finally be sent to.
+func dryRunSend(ctx context.Context, service Notifier, m *Message) bool {
+	d := dryRunFrom(ctx)
+	if d == nil || d.chained || passesOn(service) {
+		return false
+	}
+
+	name := d.name
+	if name == "" {
+		name = serviceName(service)
+	}
+	if m.Format != "" {
+		m = m.Clone()
+		m.Body = negotiate(service, m)
+		m.Format = preferredFormat(service)
+	}
+	d.fn(name, m)
+
+	return true
+}
@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithDryRun(t *testing.T) {
+	t.Parallel()
+
+	slack := &preferringCollector{format: FormatMrkdwn}
+	mail := &preferringCollector{format: FormatHTML}
+	plain := &collector{}
+
+	n := NewWithOptions(WithDedup(time.Hour))
+	n.UseNamedService("slack", slack, "chat")
+	n.UseNamedService("mail", mail, "mail")
+	n.UseServiceWithTags(plain, "chat")
+
+	var (
+		mu  sync.Mutex
+		got []string
+	)
+	ctx := WithDryRun(context.Background(), func(name string, m *Message) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, name+": "+m.Subject+" | "+m.Body+" | "+string(m.Format))
+	})
+
+	m := NewMessage("deploy", "**done**")
+	m.Format = FormatMarkdown
+	for i := 0; i < 2; i++ {
+		if err := n.SendMessage(ctx, m); err != nil {
+			t.Fatalf("SendMessage() unexpected error: %v", err)
+		}
+	}
+
+	sort.Strings(got)
+	want := []string{
+		"*notify.collector: deploy | done | plain",
+		"*notify.collector: deploy | done | plain",
+		"mail: deploy | <p><strong>done</strong></p> | html",
+		"mail: deploy | <p><strong>done</strong></p> | html",
+		"slack: deploy | *done* | mrkdwn",
+		"slack: deploy | *done* | mrkdwn",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("dry run got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Middleware applies, but isn't mistaken for a service.
+	got = nil
+	n.Use(func(next Notifier) Notifier {
+		return NotifierFunc(func(ctx context.Context, subject, message string) error {
+			return next.Send(ctx, "[test] "+subject, message)
+		})
+	})
+	if err := n.SendTo(ctx, "mail", "deploy", "done"); err != nil {
+		t.Fatalf("SendTo() unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0] != "mail: [test] deploy | done | " {
+		t.Errorf("dry run with middleware got %q", got)
+	}
+	if len(slack.received()) != 0 || len(mail.received()) != 0 || len(plain.received()) != 0 {
+		t.Error("dry run sent messages to the services")
+	}
+
+	// Nested instances report their own services.
+	got = nil
+	outer := NewWithServices(n)
+	if _, err := outer.SendMessageToWithReport(ctx, "", NewMessage("subject", "body")); err != nil {
+		t.Fatalf("SendMessageToWithReport() unexpected error: %v", err)
+	}
+	if len(got) != 3 {
+		t.Errorf("dry run of nested instance got %v", got)
+	}
+}
+
+func TestSendMessageToWithReport(t *testing.T) {
+	t.Parallel()
+
+	n := New()
+	n.UseNamedService("ops", NotifierFunc(func(context.Context, string, string) error { return nil }), "ops")
+	n.UseNamedService("dev", NotifierFunc(func(context.Context, string, string) error { return nil }), "dev")
+	n.UseServiceWithTags(NotifierFunc(func(context.Context, string, string) error { return nil }), "ops")
+
+	report, err := n.SendMessageToWithReport(context.Background(), "ops", NewMessage("subject", "message"))
+	if err != nil {
+		t.Fatalf("SendMessageToWithReport() unexpected error: %v", err)
+	}
+	if len(report.Services) != 2 {
+		t.Fatalf("SendMessageToWithReport() reported %d services, want 2", len(report.Services))
+	}
+	if report.Services[0].Name != "ops" || report.Services[1].Name != "notify.NotifierFunc" {
+		t.Errorf("SendMessageToWithReport() reported services %q and %q", report.Services[0].Name,
+			report.Services[1].Name)
+	}
+
+	if _, err = n.SendMessageToWithReport(context.Background(), "!", NewMessage("subject", "message")); err == nil {
+		t.Error("SendMessageToWithReport() with an invalid expression was expected to fail")
+	}
+}
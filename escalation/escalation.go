@@ -0,0 +1,381 @@
+// Package escalation implements time-based escalation policies on top of notify.Notify, as known from on-call alerting:
+// notify the primary via Pushover, and if nobody acknowledges within 5 minutes, text the secondary via Twilio, then
+// call the team lead.
+//
+// A Policy consists of stages. Every stage selects services of the Notify instance by their tags, see
+// notify.UseServiceWithTags, and may override their receivers. Triggering an alert runs the stages one after another,
+// each after its delay, until the alert is acknowledged with Escalator.Ack, e.g. through the HTTP handler returned by
+// Escalator.Handler. The state of every alert is kept in a Store, so that in-flight escalations survive a restart.
+package escalation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify"
+)
+
+// Stage is a single step of an escalation policy.
+type Stage struct {
+	// Name describes the stage, e.g. "primary". It's added to the metadata of the message as "escalation_stage".
+	Name string `json:"name,omitempty"`
+	// Delay is the time to wait after the previous stage, or after the alert was triggered for the first stage.
+	Delay time.Duration `json:"delay,omitempty"`
+	// Services is the tag expression selecting the services of the stage, see notify.ParseSelector. The empty
+	// expression selects all services.
+	Services string `json:"services,omitempty"`
+	// Receivers replace the configured receivers of the services named by the keys, see notify.Message.Receivers.
+	Receivers map[string][]string `json:"receivers,omitempty"`
+}
+
+// Policy is a named list of stages.
+type Policy struct {
+	// Name identifies the policy when triggering an alert.
+	Name string
+	// Stages are run one after another until the alert is acknowledged.
+	Stages []Stage
+}
+
+// State is the state of an alert.
+type State string
+
+const (
+	// StateActive is the state of an alert that is being escalated.
+	StateActive State = "active"
+	// StateAcknowledged is the state of an alert that was acknowledged.
+	StateAcknowledged State = "acknowledged"
+	// StateExhausted is the state of an alert that ran all stages without being acknowledged.
+	StateExhausted State = "exhausted"
+)
+
+// Alert is a triggered escalation.
+type Alert struct {
+	// ID identifies the alert, e.g. when acknowledging it.
+	ID string `json:"id"`
+	// Policy is the name of the escalation policy.
+	Policy string `json:"policy"`
+	// Stages are the stages of the policy at the time the alert was triggered.
+	Stages []Stage `json:"stages"`
+	// Message is the message sent by every stage.
+	Message *notify.Message `json:"message"`
+	// State is the state of the alert.
+	State State `json:"state"`
+	// Stage is the index of the next stage to run.
+	Stage int `json:"stage"`
+	// NextAt is the time the next stage runs at.
+	NextAt time.Time `json:"next_at"`
+	// CreatedAt is the time the alert was triggered at.
+	CreatedAt time.Time `json:"created_at"`
+	// AcknowledgedAt is the time the alert was acknowledged at.
+	AcknowledgedAt time.Time `json:"acknowledged_at,omitempty"`
+}
+
+// clone returns a copy of the alert that doesn't share any state with it.
+func (a *Alert) clone() *Alert {
+	c := *a
+	c.Stages = append([]Stage(nil), a.Stages...)
+	c.Message = a.Message.Clone()
+
+	return &c
+}
+
+var (
+	// ErrUnknownPolicy is returned when triggering an alert with a policy that wasn't added to the Escalator.
+	ErrUnknownPolicy = errors.New("unknown escalation policy")
+	// ErrClosed is returned by an Escalator that was closed.
+	ErrClosed = errors.New("escalator is closed")
+)
+
+// ErrorHandler is called with the errors that occur while running stages in the background.
+type ErrorHandler func(alertID string, err error)
+
+// Escalator triggers, escalates and acknowledges alerts.
+type Escalator struct {
+	notify       *notify.Notify
+	store        Store
+	policies     map[string]Policy
+	errorHandler ErrorHandler
+	ackURL       string
+	ackSecret    []byte
+
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// Option is a function that can be used to configure an Escalator. It is used by the New function.
+type Option func(*Escalator)
+
+// WithPolicy is an Option that adds an escalation policy. A policy with the same name replaces an earlier one.
+func WithPolicy(policy Policy) Option {
+	return func(e *Escalator) {
+		e.policies[policy.Name] = policy
+	}
+}
+
+// WithStore is an Option that sets the store of the alerts. It defaults to a MemoryStore.
+func WithStore(store Store) Option {
+	return func(e *Escalator) {
+		if store != nil {
+			e.store = store
+		}
+	}
+}
+
+// WithErrorHandler is an Option that sets the handler for errors that occur while running stages in the background.
+func WithErrorHandler(handler ErrorHandler) Option {
+	return func(e *Escalator) {
+		e.errorHandler = handler
+	}
+}
+
+// New returns a new Escalator sending through the given Notify instance. Call Start to resume the escalations kept in
+// the store.
+func New(n *notify.Notify, options ...Option) *Escalator {
+	e := &Escalator{
+		notify:   n,
+		store:    NewMemoryStore(),
+		policies: make(map[string]Policy),
+		timers:   make(map[string]*time.Timer),
+	}
+
+	for _, option := range options {
+		if option != nil {
+			option(e)
+		}
+	}
+
+	return e
+}
+
+// Start resumes the escalation of all active alerts kept in the store. Stages that became due while the Escalator
+// wasn't running are run right away.
+func (e *Escalator) Start(ctx context.Context) error {
+	alerts, err := e.store.List(ctx)
+	if err != nil {
+		return errors.Wrap(err, "list alerts")
+	}
+
+	for _, alert := range alerts {
+		if alert.State == StateActive {
+			e.schedule(alert.ID, alert.NextAt)
+		}
+	}
+
+	return nil
+}
+
+// newAlertID returns a random alert ID.
+func newAlertID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "generate alert ID")
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// Trigger starts escalating the given message according to the policy with the given name and returns the ID of the
+// new alert. A first stage without delay runs right away, in the background.
+func (e *Escalator) Trigger(ctx context.Context, policy string, m *notify.Message) (string, error) {
+	p, ok := e.policies[policy]
+	if !ok {
+		return "", errors.Wrapf(ErrUnknownPolicy, "%q", policy)
+	}
+	if len(p.Stages) == 0 {
+		return "", errors.Errorf("escalation policy %q has no stages", policy)
+	}
+
+	id, err := newAlertID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	alert := &Alert{
+		ID:        id,
+		Policy:    policy,
+		Stages:    append([]Stage(nil), p.Stages...),
+		Message:   m.Clone(),
+		State:     StateActive,
+		NextAt:    now.Add(p.Stages[0].Delay),
+		CreatedAt: now,
+	}
+	if err := e.store.Save(ctx, alert); err != nil {
+		return "", errors.Wrap(err, "save alert")
+	}
+
+	e.schedule(id, alert.NextAt)
+
+	return id, nil
+}
+
+// schedule runs the next stage of the alert with the given ID at the given time.
+func (e *Escalator) schedule(id string, at time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.scheduleLocked(id, at)
+}
+
+// scheduleLocked works like schedule, but expects the caller to hold e.mu.
+func (e *Escalator) scheduleLocked(id string, at time.Time) {
+	if e.closed {
+		return
+	}
+	if timer, ok := e.timers[id]; ok && timer.Stop() {
+		e.wg.Done()
+	}
+
+	e.wg.Add(1)
+	e.timers[id] = time.AfterFunc(time.Until(at), func() {
+		defer e.wg.Done()
+		e.run(id)
+	})
+}
+
+// run runs the next stage of the alert with the given ID, if it's still active, and schedules the stage after it.
+//
+// The stage is sent before its completion is saved, so that a crash in between repeats it after a restart rather than
+// skipping it.
+func (e *Escalator) run(id string) {
+	ctx := context.Background()
+
+	e.mu.Lock()
+	delete(e.timers, id)
+	closed := e.closed
+	e.mu.Unlock()
+	if closed {
+		return
+	}
+
+	alert, err := e.store.Load(ctx, id)
+	if err != nil {
+		e.handleError(id, errors.Wrap(err, "load alert"))
+		return
+	}
+	if alert.State != StateActive || alert.Stage >= len(alert.Stages) {
+		return
+	}
+
+	stage := alert.Stages[alert.Stage]
+	if err := e.notify.SendMessageTo(ctx, stage.Services, e.stageMessage(alert, stage)); err != nil {
+		e.handleError(id, errors.Wrapf(err, "run stage %d of alert", alert.Stage))
+	}
+
+	// Reload the alert, it may have been acknowledged while the stage was sent. Holding the lock keeps Ack from
+	// acknowledging it while it's being advanced.
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	alert, err = e.store.Load(ctx, id)
+	if err != nil {
+		e.handleError(id, errors.Wrap(err, "load alert"))
+		return
+	}
+	if alert.State != StateActive {
+		return
+	}
+
+	alert.Stage++
+	if alert.Stage >= len(alert.Stages) {
+		alert.State = StateExhausted
+	} else {
+		alert.NextAt = time.Now().Add(alert.Stages[alert.Stage].Delay)
+	}
+	if err := e.store.Save(ctx, alert); err != nil {
+		e.handleError(id, errors.Wrap(err, "save alert"))
+		return
+	}
+
+	if alert.State == StateActive {
+		e.scheduleLocked(id, alert.NextAt)
+	}
+}
+
+// stageMessage returns the message sent by the given stage of the alert.
+func (e *Escalator) stageMessage(alert *Alert, stage Stage) *notify.Message {
+	m := alert.Message.Clone()
+	if m == nil {
+		m = notify.NewMessage("", "")
+	}
+
+	if len(stage.Receivers) > 0 {
+		m.Receivers = stage.Receivers
+	}
+	if m.Metadata == nil {
+		m.Metadata = make(map[string]any)
+	}
+	m.Metadata["alert_id"] = alert.ID
+	m.Metadata["escalation_stage"] = stage.Name
+	if url := e.AckURL(alert.ID); url != "" {
+		m.Links = append(m.Links, notify.Link{Title: "Acknowledge", URL: url})
+	}
+
+	return m
+}
+
+func (e *Escalator) handleError(id string, err error) {
+	if e.errorHandler != nil {
+		e.errorHandler(id, err)
+	}
+}
+
+// Ack acknowledges the alert with the given ID, which stops its escalation. Acknowledging an alert that isn't active
+// anymore is not an error. It returns ErrNotFound for unknown alerts.
+func (e *Escalator) Ack(alertID string) error {
+	ctx := context.Background()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	alert, err := e.store.Load(ctx, alertID)
+	if err != nil {
+		return err
+	}
+	if alert.State != StateActive {
+		return nil
+	}
+
+	alert.State = StateAcknowledged
+	alert.AcknowledgedAt = time.Now()
+	if err := e.store.Save(ctx, alert); err != nil {
+		return errors.Wrap(err, "save alert")
+	}
+
+	if timer, ok := e.timers[alertID]; ok && timer.Stop() {
+		delete(e.timers, alertID)
+		e.wg.Done()
+	}
+
+	return nil
+}
+
+// Alert returns the alert with the given ID, or ErrNotFound.
+func (e *Escalator) Alert(ctx context.Context, alertID string) (*Alert, error) {
+	return e.store.Load(ctx, alertID)
+}
+
+// Close stops all escalations without changing their state, so that Start resumes them later. It waits for stages that
+// are being sent.
+func (e *Escalator) Close() error {
+	e.mu.Lock()
+	e.closed = true
+	for id, timer := range e.timers {
+		if timer.Stop() {
+			e.wg.Done()
+		}
+		delete(e.timers, id)
+	}
+	e.mu.Unlock()
+
+	e.wg.Wait()
+
+	return nil
+}
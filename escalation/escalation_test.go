@@ -0,0 +1,146 @@
+package escalation
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/casdoor/notify"
+)
+
+// recorder is a test service that records the escalation stages it was called for.
+type recorder struct {
+	mu     sync.Mutex
+	stages []string
+}
+
+func (r *recorder) Send(context.Context, string, string) error {
+	return nil
+}
+
+func (r *recorder) SendMessage(_ context.Context, m *notify.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stage, _ := m.MetadataString("escalation_stage")
+	r.stages = append(r.stages, stage)
+
+	return nil
+}
+
+func (r *recorder) calls() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.stages...)
+}
+
+func newTestNotify() (*notify.Notify, *recorder, *recorder) {
+	primary, secondary := &recorder{}, &recorder{}
+
+	n := notify.New()
+	n.UseServiceWithTags(primary, "primary")
+	n.UseServiceWithTags(secondary, "secondary")
+
+	return n, primary, secondary
+}
+
+var testPolicy = Policy{
+	Name: "on-call",
+	Stages: []Stage{
+		{Name: "primary", Services: "primary"},
+		{Name: "secondary", Delay: 50 * time.Millisecond, Services: "secondary"},
+	},
+}
+
+func TestEscalator(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	n, primary, secondary := newTestNotify()
+	e := New(n, WithPolicy(testPolicy))
+	defer e.Close()
+
+	ctx := context.Background()
+	id, err := e.Trigger(ctx, "on-call", notify.NewMessage("disk full", "/dev/sda1"))
+	assert.NoError(err)
+
+	assert.Eventually(func() bool {
+		alert, err := e.Alert(ctx, id)
+		return err == nil && alert.State == StateExhausted
+	}, time.Second, 5*time.Millisecond)
+
+	assert.Equal([]string{"primary"}, primary.calls())
+	assert.Equal([]string{"secondary"}, secondary.calls())
+
+	_, err = e.Trigger(ctx, "unknown", notify.NewMessage("", ""))
+	assert.ErrorIs(err, ErrUnknownPolicy)
+}
+
+func TestEscalator_Ack(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	n, primary, secondary := newTestNotify()
+	e := New(n, WithPolicy(testPolicy))
+	defer e.Close()
+
+	ctx := context.Background()
+	id, err := e.Trigger(ctx, "on-call", notify.NewMessage("disk full", "/dev/sda1"))
+	assert.NoError(err)
+
+	assert.Eventually(func() bool {
+		return len(primary.calls()) == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.NoError(e.Ack(id))
+	assert.NoError(e.Ack(id), "acknowledging twice must not fail")
+	assert.ErrorIs(e.Ack("unknown"), ErrNotFound)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(secondary.calls(), "the secondary must not be notified after the acknowledgement")
+
+	alert, err := e.Alert(ctx, id)
+	assert.NoError(err)
+	assert.Equal(StateAcknowledged, alert.State)
+	assert.False(alert.AcknowledgedAt.IsZero())
+}
+
+func TestEscalator_Restart(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	store, err := NewFileStore(t.TempDir())
+	assert.NoError(err)
+
+	policy := Policy{Name: "on-call", Stages: []Stage{
+		{Name: "primary", Delay: time.Hour, Services: "primary"},
+	}}
+
+	n, primary, _ := newTestNotify()
+	e := New(n, WithPolicy(policy), WithStore(store))
+
+	ctx := context.Background()
+	id, err := e.Trigger(ctx, "on-call", notify.NewMessage("disk full", "/dev/sda1"))
+	assert.NoError(err)
+	assert.NoError(e.Close())
+
+	// Pretend the stage became due while the process was down.
+	alert, err := store.Load(ctx, id)
+	assert.NoError(err)
+	alert.NextAt = time.Now().Add(-time.Minute)
+	assert.NoError(store.Save(ctx, alert))
+
+	e = New(n, WithPolicy(policy), WithStore(store))
+	defer e.Close()
+	assert.NoError(e.Start(ctx))
+
+	assert.Eventually(func() bool {
+		return len(primary.calls()) == 1
+	}, time.Second, 5*time.Millisecond)
+}
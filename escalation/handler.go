@@ -0,0 +1,141 @@
+package escalation
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"html/template"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/pkg/errors"
+)
+
+// confirmPage is the page rendered for GET requests to the handler. It asks to confirm the acknowledgement with a POST
+// request, so that link previews and mail scanners following the link don't acknowledge the alert.
+var confirmPage = template.Must(template.New("confirm").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Acknowledge alert</title></head>
+<body>
+<form method="post">
+<p>Acknowledge the alert <strong>{{.Subject}}</strong>?</p>
+<input type="hidden" name="id" value="{{.ID}}">
+<input type="hidden" name="sig" value="{{.Signature}}">
+<button type="submit">Acknowledge</button>
+</form>
+</body>
+</html>
+`))
+
+// WithAckURL is an Option that adds an "Acknowledge" link to the messages of every stage. The link points to baseURL,
+// where the handler returned by Handler is expected to be mounted, e.g. "https://example.com/alerts/ack". The link is
+// signed with secret and the handler rejects links without a valid signature, so that only the receivers of an alert
+// can acknowledge it.
+//
+// WARNING: If secret is empty, the links aren't signed, and anyone who knows or guesses an alert ID can acknowledge the
+// alert, which stops its escalation. Only leave secret empty if the handler is protected otherwise.
+func WithAckURL(baseURL, secret string) Option {
+	return func(e *Escalator) {
+		e.ackURL = baseURL
+		e.ackSecret = []byte(secret)
+	}
+}
+
+// sign returns the signature of the given alert ID.
+func (e *Escalator) sign(alertID string) string {
+	mac := hmac.New(sha256.New, e.ackSecret)
+	_, _ = mac.Write([]byte(alertID))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// AckURL returns the link acknowledging the alert with the given ID, or the empty string if no URL was set with
+// WithAckURL.
+func (e *Escalator) AckURL(alertID string) string {
+	if e.ackURL == "" {
+		return ""
+	}
+
+	u, err := url.Parse(e.ackURL)
+	if err != nil {
+		return ""
+	}
+
+	query := u.Query()
+	query.Set("id", alertID)
+	if len(e.ackSecret) > 0 {
+		query.Set("sig", e.sign(alertID))
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// Handler returns a HTTP handler acknowledging alerts. Only POST requests acknowledge alerts, e.g. from webhooks of
+// other systems. GET requests, like those of the links added by WithAckURL, render a page asking to confirm the
+// acknowledgement, which then gets posted. This way, link previews and mail scanners following the links don't
+// acknowledge alerts. The alert ID is taken from the "id" query or form parameter, or from the last segment of the
+// path. The handler responds with 200 OK if the alert was acknowledged or the page was rendered, 404 Not Found for
+// unknown alerts and 403 Forbidden for invalid signatures.
+func (e *Escalator) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		id := r.FormValue("id")
+		if id == "" {
+			id = path.Base(r.URL.Path)
+		}
+		if id == "" || id == "/" || id == "." {
+			http.Error(w, "missing alert ID", http.StatusBadRequest)
+			return
+		}
+
+		if len(e.ackSecret) > 0 && !hmac.Equal([]byte(r.FormValue("sig")), []byte(e.sign(id))) {
+			http.Error(w, "invalid signature", http.StatusForbidden)
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			e.confirm(w, r, id)
+			return
+		}
+
+		err := e.Ack(id)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			http.Error(w, "alert not found", http.StatusNotFound)
+		case err != nil:
+			http.Error(w, "failed to acknowledge alert", http.StatusInternalServerError)
+		default:
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = w.Write([]byte("alert acknowledged\n"))
+		}
+	})
+}
+
+// confirm renders the page asking to confirm the acknowledgement of the alert with the given ID.
+func (e *Escalator) confirm(w http.ResponseWriter, r *http.Request, id string) {
+	alert, err := e.Alert(r.Context(), id)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "alert not found", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, "failed to load alert", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct{ ID, Subject, Signature string }{ID: id, Signature: r.FormValue("sig")}
+	if alert.Message != nil {
+		data.Subject = alert.Message.Subject
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = confirmPage.Execute(w, data)
+}
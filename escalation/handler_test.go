@@ -0,0 +1,69 @@
+package escalation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/casdoor/notify"
+)
+
+func TestEscalator_Handler(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	policy := Policy{Name: "on-call", Stages: []Stage{{Delay: time.Hour}}}
+	e := New(notify.New(), WithPolicy(policy), WithAckURL("https://example.com/ack", "secret"))
+	defer e.Close()
+
+	ctx := context.Background()
+	id, err := e.Trigger(ctx, "on-call", notify.NewMessage("disk full", ""))
+	assert.NoError(err)
+
+	ackURL, err := url.Parse(e.AckURL(id))
+	assert.NoError(err)
+	assert.Equal(id, ackURL.Query().Get("id"))
+
+	handler := e.Handler()
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, nil)
+		if method == http.MethodPost {
+			req = httptest.NewRequest(method, "/ack", strings.NewReader(ackURL.RawQuery))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(http.StatusForbidden, serve(http.MethodGet, "/ack?id="+id+"&sig=invalid").Code)
+	assert.Equal(http.StatusNotFound, serve(http.MethodGet, "/ack/unknown?sig="+e.sign("unknown")).Code)
+	assert.Equal(http.StatusMethodNotAllowed, serve(http.MethodDelete, "/ack").Code)
+
+	// Following the link only renders the confirmation form.
+	rec := serve(http.MethodGet, "/ack?"+ackURL.RawQuery)
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Contains(rec.Body.String(), `<form method="post">`)
+	assert.Contains(rec.Body.String(), "disk full")
+	alert, err := e.Alert(ctx, id)
+	assert.NoError(err)
+	assert.NotEqual(StateAcknowledged, alert.State)
+
+	assert.Equal(http.StatusOK, serve(http.MethodPost, "/ack").Code)
+	alert, err = e.Alert(ctx, id)
+	assert.NoError(err)
+	assert.Equal(StateAcknowledged, alert.State)
+
+	// The acknowledge link is added to the messages of every stage.
+	m := e.stageMessage(alert, alert.Stages[0])
+	assert.Len(m.Links, 1)
+	assert.Equal(e.AckURL(id), m.Links[0].URL)
+	assert.Equal(id, m.Metadata["alert_id"])
+}
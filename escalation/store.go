@@ -0,0 +1,232 @@
+package escalation
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotFound is returned for alerts that don't exist.
+var ErrNotFound = errors.New("alert not found")
+
+// Store persists the state of alerts, so that a restart doesn't lose in-flight escalations. Implementations must be safe
+// for concurrent use.
+type Store interface {
+	// Save creates or replaces the given alert.
+	Save(ctx context.Context, alert *Alert) error
+	// Load returns the alert with the given ID, or ErrNotFound.
+	Load(ctx context.Context, id string) (*Alert, error)
+	// Delete removes the alert with the given ID. Deleting an alert that doesn't exist is not an error.
+	Delete(ctx context.Context, id string) error
+	// List returns all alerts.
+	List(ctx context.Context) ([]*Alert, error)
+}
+
+// MemoryStore is a Store that keeps alerts in memory. It doesn't survive a restart, use FileStore for that.
+type MemoryStore struct {
+	mu     sync.Mutex
+	alerts map[string]*Alert
+}
+
+// Compile-time check to ensure MemoryStore implements Store.
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore returns a new, empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{alerts: make(map[string]*Alert)}
+}
+
+// Save creates or replaces the given alert.
+func (s *MemoryStore) Save(_ context.Context, alert *Alert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.alerts[alert.ID] = alert.clone()
+
+	return nil
+}
+
+// Load returns the alert with the given ID, or ErrNotFound.
+func (s *MemoryStore) Load(_ context.Context, id string) (*Alert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alert, ok := s.alerts[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return alert.clone(), nil
+}
+
+// Delete removes the alert with the given ID.
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.alerts, id)
+
+	return nil
+}
+
+// List returns all alerts, ordered by their creation time.
+func (s *MemoryStore) List(_ context.Context) ([]*Alert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alerts := make([]*Alert, 0, len(s.alerts))
+	for _, alert := range s.alerts {
+		alerts = append(alerts, alert.clone())
+	}
+	sortAlerts(alerts)
+
+	return alerts, nil
+}
+
+// FileStore is a Store that keeps every alert in a JSON file of its own within a directory. Files are replaced
+// atomically, so a crash never leaves a torn alert behind.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// Compile-time check to ensure FileStore implements Store.
+var _ Store = (*FileStore)(nil)
+
+// alertFileExt is the extension of the files of a FileStore.
+const alertFileExt = ".json"
+
+// NewFileStore returns a FileStore using the given directory. The directory is created if it doesn't exist.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, errors.Wrap(err, "create alert directory")
+	}
+
+	return &FileStore{dir: dir}, nil
+}
+
+// path returns the path of the file of the alert with the given ID.
+func (s *FileStore) path(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return "", errors.Errorf("invalid alert ID %q", id)
+	}
+
+	return filepath.Join(s.dir, id+alertFileExt), nil
+}
+
+// Save creates or replaces the given alert.
+func (s *FileStore) Save(_ context.Context, alert *Alert) error {
+	path, err := s.path(alert.ID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return errors.Wrap(err, "marshal alert")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp, err := os.CreateTemp(s.dir, alert.ID+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "create alert file")
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "write alert file")
+	}
+
+	return errors.Wrap(os.Rename(tmp.Name(), path), "replace alert file")
+}
+
+// Load returns the alert with the given ID, or ErrNotFound.
+func (s *FileStore) Load(_ context.Context, id string) (*Alert, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return readAlert(path)
+}
+
+// readAlert reads the alert stored in the file at path.
+func readAlert(path string) (*Alert, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "read alert file")
+	}
+
+	var alert Alert
+	if err := json.Unmarshal(data, &alert); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal alert file %q", path)
+	}
+
+	return &alert, nil
+}
+
+// Delete removes the alert with the given ID.
+func (s *FileStore) Delete(_ context.Context, id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Wrap(err, "delete alert file")
+	}
+
+	return nil
+}
+
+// List returns all alerts, ordered by their creation time.
+func (s *FileStore) List(_ context.Context) ([]*Alert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*"+alertFileExt))
+	if err != nil {
+		return nil, errors.Wrap(err, "list alert files")
+	}
+
+	alerts := make([]*Alert, 0, len(paths))
+	for _, path := range paths {
+		alert, err := readAlert(path)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+	sortAlerts(alerts)
+
+	return alerts, nil
+}
+
+func sortAlerts(alerts []*Alert) {
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].CreatedAt.Before(alerts[j].CreatedAt)
+	})
+}
@@ -0,0 +1,59 @@
+package escalation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/casdoor/notify"
+)
+
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+
+	assert := require.New(t)
+	ctx := context.Background()
+
+	_, err := store.Load(ctx, "a")
+	assert.ErrorIs(err, ErrNotFound)
+
+	now := time.Now().Round(0)
+	first := &Alert{ID: "a", Policy: "p", State: StateActive, CreatedAt: now, Message: notify.NewMessage("s", "b")}
+	second := &Alert{ID: "b", Policy: "p", State: StateActive, CreatedAt: now.Add(time.Second)}
+	assert.NoError(store.Save(ctx, second))
+	assert.NoError(store.Save(ctx, first))
+
+	loaded, err := store.Load(ctx, "a")
+	assert.NoError(err)
+	assert.Equal("s", loaded.Message.Subject)
+	assert.True(loaded.CreatedAt.Equal(now))
+
+	alerts, err := store.List(ctx)
+	assert.NoError(err)
+	assert.Len(alerts, 2)
+	assert.Equal("a", alerts[0].ID)
+
+	assert.NoError(store.Delete(ctx, "a"))
+	assert.NoError(store.Delete(ctx, "a"))
+	alerts, err = store.List(ctx)
+	assert.NoError(err)
+	assert.Len(alerts, 1)
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	t.Parallel()
+
+	store, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	testStore(t, store)
+
+	require.Error(t, store.Save(context.Background(), &Alert{ID: "../escape"}))
+}
@@ -0,0 +1,88 @@
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+// EventType identifies the kind of an Event.
+type EventType string
+
+// Types of the events emitted by Notify, see WithListener.
+const (
+	// EventSent is emitted when a service sent a message successfully.
+	EventSent EventType = "sent"
+	// EventFailed is emitted when a service finally failed to send a message, after all retries.
+	EventFailed EventType = "failed"
+	// EventSuppressed is emitted when a repeated message was suppressed, see WithDedup.
+	EventSuppressed EventType = "suppressed"
+	// EventEnqueued is emitted when a message was put into the queue of the asynchronous mode, see WithAsync.
+	EventEnqueued EventType = "enqueued"
+	// EventDequeued is emitted when a worker took a message out of the queue of the asynchronous mode.
+	EventDequeued EventType = "dequeued"
+)
+
+// Event describes something that happened during a send. Which fields are set depends on the type of the event.
+type Event struct {
+	// Type is the kind of the event.
+	Type EventType
+	// Message is the message the event is about. It's nil for EventDequeued. Listeners must not modify it.
+	Message *Message
+	// Service is the name of the service, see ServiceResult.Name. It's only set for EventSent and EventFailed.
+	Service string
+	// Err is the error the service failed with. It's only set for EventFailed.
+	Err error
+	// Duration is the time it took the service to send the message, including all retries. It's only set for EventSent
+	// and EventFailed.
+	Duration time.Duration
+	// Attempts is the number of times the service was called, see ServiceResult.Attempts. It's only set for EventSent
+	// and EventFailed.
+	Attempts int
+	// QueueDepth is the number of messages waiting in the queue of the asynchronous mode right after the event. It's only
+	// set for EventEnqueued and EventDequeued.
+	QueueDepth int
+}
+
+// Listener is called with every event emitted by a Notify instance. Listeners are called synchronously, from the
+// goroutines sending the messages, so they must be fast and safe for concurrent use.
+type Listener func(ctx context.Context, e Event)
+
+// WithListener is an Option that adds a listener for the events of the Notify instance. Listeners are called in the
+// order they were added. They are the hook for metric backends, see the metrics/prometheus package for an example.
+//
+// Sends during a dry run don't emit events, see WithDryRun.
+func WithListener(listener Listener) Option {
+	return func(n *Notify) {
+		if n != nil && listener != nil {
+			n.listeners = append(n.listeners, listener)
+		}
+	}
+}
+
+// emit calls all listeners with the given event.
+func (n *Notify) emit(ctx context.Context, e Event) {
+	for _, listener := range n.listeners {
+		listener(ctx, e)
+	}
+}
+
+// emitResult emits the event for the outcome of a send to a single service.
+func (n *Notify) emitResult(ctx context.Context, m *Message, result *ServiceResult) {
+	if len(n.listeners) == 0 {
+		return
+	}
+
+	e := Event{
+		Type:     EventSent,
+		Message:  m,
+		Service:  result.Name,
+		Duration: result.Duration,
+		Attempts: result.Attempts,
+	}
+	if result.Err != nil {
+		e.Type = EventFailed
+		e.Err = result.Err
+	}
+
+	n.emit(ctx, e)
+}
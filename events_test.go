@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/casdoor/notify/queue"
+	"github.com/casdoor/notify/retry"
+)
+
+// eventRecorder records the events passed to its listener.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *eventRecorder) listen(_ context.Context, e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *eventRecorder) byType() map[EventType][]Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	byType := make(map[EventType][]Event)
+	for _, e := range r.events {
+		byType[e.Type] = append(byType[e.Type], e)
+	}
+
+	return byType
+}
+
+func TestWithListener(t *testing.T) {
+	t.Parallel()
+
+	errDown := errors.New("down")
+	var first, second eventRecorder
+	n := NewWithOptions(
+		WithListener(first.listen),
+		WithListener(second.listen),
+		WithListener(nil),
+		WithRetry(retry.Policy{MaxAttempts: 2}),
+		WithDedup(time.Hour),
+	)
+	n.UseNamedService("ok", receiversService{"a"})
+	n.UseNamedService("down", NotifierFunc(func(context.Context, string, string) error {
+		return errDown
+	}))
+
+	if err := n.Send(context.Background(), "s", "m"); err == nil {
+		t.Fatal("Send() error = nil, want the error of down")
+	}
+	if err := n.Send(context.Background(), "s", "m"); err != nil {
+		t.Fatalf("Send() of a repeat error = %v, want nil", err)
+	}
+
+	events := first.byType()
+	if len(events[EventSent]) != 1 || len(events[EventFailed]) != 1 || len(events[EventSuppressed]) != 1 {
+		t.Fatalf("got events %v, want one sent, one failed and one suppressed event", first.events)
+	}
+	if e := events[EventSent][0]; e.Service != "ok" || e.Attempts != 1 || e.Err != nil || e.Message.Subject != "s" {
+		t.Errorf("sent event = %+v", e)
+	}
+	if e := events[EventFailed][0]; e.Service != "down" || e.Attempts != 2 || !errors.Is(e.Err, errDown) {
+		t.Errorf("failed event = %+v", e)
+	}
+	if e := events[EventSuppressed][0]; e.Message.Subject != "s" || e.Service != "" {
+		t.Errorf("suppressed event = %+v", e)
+	}
+	if len(second.events) != len(first.events) {
+		t.Errorf("second listener got %d events, want %d", len(second.events), len(first.events))
+	}
+
+	// Dry runs don't emit events.
+	ctx := WithDryRun(context.Background(), func(string, *Message) {})
+	if err := n.Send(ctx, "dry", "run"); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(first.byType()[EventSent]); got != 1 {
+		t.Errorf("got %d sent events after a dry run, want 1", got)
+	}
+}
+
+func TestWithListener_Async(t *testing.T) {
+	t.Parallel()
+
+	var recorder eventRecorder
+	n := NewWithOptions(WithAsync(queue.NewMemory(10), 1), WithListener(recorder.listen))
+	n.UseServices(receiversService{"a"})
+
+	for i := 0; i < 3; i++ {
+		if err := n.Send(context.Background(), "s", "m"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := n.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	events := recorder.byType()
+	if len(events[EventEnqueued]) != 3 || len(events[EventDequeued]) != 3 || len(events[EventSent]) != 3 {
+		t.Fatalf("got events %v, want 3 enqueued, dequeued and sent events", recorder.events)
+	}
+	for _, e := range append(events[EventEnqueued], events[EventDequeued]...) {
+		if e.QueueDepth < 0 || e.QueueDepth > 3 {
+			t.Errorf("%s event has queue depth %d", e.Type, e.QueueDepth)
+		}
+	}
+	if last := events[EventDequeued][2]; last.QueueDepth != 0 {
+		t.Errorf("queue depth after the last dequeue = %d, want 0", last.QueueDepth)
+	}
+}
@@ -0,0 +1,244 @@
+package notify
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// HopResult describes the outcome of a single hop of a composite service, see Fallback.
+type HopResult struct {
+	// Index is the position of the hop in the order it was passed to the composite.
+	Index int
+	// Name identifies the hop, see ServiceResult.Name.
+	Name string
+	// Err is the error returned by the hop. It's nil if the hop delivered the message.
+	Err error
+	// Duration is the time it took the hop to send the message.
+	Duration time.Duration
+}
+
+type hopRecorderKey struct{}
+
+// withHopRecorder returns a copy of ctx that makes composite services report their hops to fn.
+func withHopRecorder(ctx context.Context, fn func(HopResult)) context.Context {
+	return context.WithValue(ctx, hopRecorderKey{}, fn)
+}
+
+// recordHop reports the given hop to the recorder bound to ctx, if any.
+func recordHop(ctx context.Context, hop HopResult) {
+	if fn, ok := ctx.Value(hopRecorderKey{}).(func(HopResult)); ok {
+		fn(hop)
+	}
+}
+
+// strategy tells how a FallbackNotifier picks its hops.
+type strategy int
+
+const (
+	inOrder strategy = iota
+	weightedRoundRobin
+	firstSuccess
+)
+
+// WeightedService is a service with a weight, see WeightedRoundRobin.
+type WeightedService struct {
+	Service Notifier
+	Weight  int
+}
+
+// FallbackNotifier is a composite service that sends a message through one of several hops. Create one with Fallback,
+// WeightedRoundRobin or FirstSuccess.
+type FallbackNotifier struct {
+	hops       []Notifier
+	weights    []int
+	strategy   strategy
+	hopTimeout time.Duration
+
+	mu      sync.Mutex
+	current []int // Current weights of the smooth weighted round-robin.
+}
+
+// Compile-time check to ensure FallbackNotifier implements MessageNotifier.
+var _ MessageNotifier = (*FallbackNotifier)(nil)
+
+// Fallback returns a composite service that tries the given services one after another until one of them delivers the
+// message, e.g. Slack first, then Telegram, then SMS. It fails only if all of them fail. Use WithHopTimeout to give up
+// on a hop after a while. SendWithReport reports every hop that was tried in ServiceResult.Hops.
+func Fallback(services ...Notifier) *FallbackNotifier {
+	return &FallbackNotifier{hops: nonNil(services), strategy: inOrder}
+}
+
+// WeightedRoundRobin returns a composite service that spreads the messages over the given services according to their
+// weights, e.g. to balance the load between two SMS providers. If the picked service fails, the others are tried in
+// order like with Fallback. Services with a weight below 1 are only used as fallback.
+func WeightedRoundRobin(services ...WeightedService) *FallbackNotifier {
+	f := &FallbackNotifier{strategy: weightedRoundRobin}
+	for _, s := range services {
+		if s.Service != nil {
+			f.hops = append(f.hops, s.Service)
+			f.weights = append(f.weights, s.Weight)
+		}
+	}
+	f.current = make([]int, len(f.hops))
+
+	return f
+}
+
+// FirstSuccess returns a composite service that sends a message through all given services at once and succeeds as
+// soon as the first of them delivers it. The remaining sends are canceled, but may have delivered the message already.
+// Use it to minimize the latency of important messages with services that honor the context.
+func FirstSuccess(services ...Notifier) *FallbackNotifier {
+	return &FallbackNotifier{hops: nonNil(services), strategy: firstSuccess}
+}
+
+func nonNil(services []Notifier) []Notifier {
+	hops := make([]Notifier, 0, len(services))
+	for _, s := range services {
+		if s != nil {
+			hops = append(hops, s)
+		}
+	}
+
+	return hops
+}
+
+// WithHopTimeout makes every hop give up after the given duration, so that the next hop is tried early enough. It
+// returns the FallbackNotifier to allow chaining.
+func (f *FallbackNotifier) WithHopTimeout(timeout time.Duration) *FallbackNotifier {
+	f.hopTimeout = timeout
+	return f
+}
+
+// Send sends the given subject and message through the hops. See SendMessage.
+func (f *FallbackNotifier) Send(ctx context.Context, subject, message string) error {
+	return f.SendMessage(ctx, messageFor(ctx, subject, message))
+}
+
+// Unwrap returns the hops. It implements Wrapper.
+func (f *FallbackNotifier) Unwrap() []Notifier {
+	return append([]Notifier(nil), f.hops...)
+}
+
+// SendMessage sends the given message through the hops. If all hops fail, it returns a *SendError holding the errors of
+// all of them.
+func (f *FallbackNotifier) SendMessage(ctx context.Context, m *Message) error {
+	if len(f.hops) == 0 {
+		return nil
+	}
+	if f.strategy == firstSuccess {
+		return f.race(ctx, m)
+	}
+
+	errs := make([]error, 0, len(f.hops))
+	for _, i := range f.order() {
+		err := f.try(ctx, i, m)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return &SendError{Errors: errs}
+}
+
+// order returns the indexes of the hops in the order they should be tried.
+func (f *FallbackNotifier) order() []int {
+	start := 0
+	if f.strategy == weightedRoundRobin {
+		start = f.next()
+	}
+
+	order := make([]int, 0, len(f.hops))
+	order = append(order, start)
+	for i := range f.hops {
+		if i != start {
+			order = append(order, i)
+		}
+	}
+
+	return order
+}
+
+// next picks the hop to start with using the smooth weighted round-robin algorithm, as known from nginx.
+func (f *FallbackNotifier) next() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	best, total := 0, 0
+	for i, weight := range f.weights {
+		if weight < 1 {
+			continue
+		}
+		f.current[i] += weight
+		total += weight
+		if f.current[i] > f.current[best] || f.weights[best] < 1 {
+			best = i
+		}
+	}
+	f.current[best] -= total
+
+	return best
+}
+
+// try sends the given message through the hop at index i and reports the outcome.
+func (f *FallbackNotifier) try(ctx context.Context, i int, m *Message) error {
+	hopCtx := ctx
+	if f.hopTimeout > 0 {
+		var cancel context.CancelFunc
+		hopCtx, cancel = context.WithTimeout(ctx, f.hopTimeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := sendTo(hopCtx, f.hops[i], m)
+	if err != nil {
+		err = errors.Wrapf(err, "hop %d (%s)", i, serviceName(f.hops[i]))
+	}
+
+	recordHop(ctx, HopResult{
+		Index:    i,
+		Name:     serviceName(f.hops[i]),
+		Err:      err,
+		Duration: time.Since(start),
+	})
+
+	return err
+}
+
+// race sends the given message through all hops at once and cancels the remaining hops as soon as one of them
+// succeeded. It waits for the canceled hops to return, so that their outcome is still reported.
+func (f *FallbackNotifier) race(ctx context.Context, m *Message) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan error, len(f.hops))
+	for i := range f.hops {
+		go func(i int) {
+			results <- f.try(ctx, i, m)
+		}(i)
+	}
+
+	succeeded := false
+	errs := make([]error, 0, len(f.hops))
+	for range f.hops {
+		err := <-results
+		if err == nil {
+			succeeded = true
+			cancel()
+			continue
+		}
+		errs = append(errs, err)
+	}
+	if succeeded {
+		return nil
+	}
+
+	return &SendError{Errors: errs}
+}
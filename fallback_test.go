@@ -0,0 +1,122 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// hopService returns a service that counts its calls and fails with err.
+func hopService(calls *int, err error) Notifier {
+	return NotifierFunc(func(context.Context, string, string) error {
+		*calls++
+		return err
+	})
+}
+
+func TestFallback(t *testing.T) {
+	t.Parallel()
+
+	errSlack := errors.New("slack down")
+	var slack, telegram, twilio int
+
+	n := NewWithServices(Fallback(
+		hopService(&slack, errSlack),
+		hopService(&telegram, nil),
+		hopService(&twilio, nil),
+	))
+
+	report, err := n.SendWithReport(context.Background(), "subject", "message")
+	if err != nil {
+		t.Fatalf("SendWithReport() unexpected error: %v", err)
+	}
+	if slack != 1 || telegram != 1 || twilio != 0 {
+		t.Errorf("hops were called %d, %d, %d times, want 1, 1, 0", slack, telegram, twilio)
+	}
+
+	result := report.Services[0]
+	if len(result.Hops) != 2 || !errors.Is(result.Hops[0].Err, errSlack) {
+		t.Errorf("Hops = %+v", result.Hops)
+	}
+	if hop, ok := result.DeliveredBy(); !ok || hop.Index != 1 {
+		t.Errorf("DeliveredBy() = %+v, %v, want hop 1", hop, ok)
+	}
+}
+
+func TestFallback_AllFail(t *testing.T) {
+	t.Parallel()
+
+	errFirst := errors.New("first")
+	errSecond := errors.New("second")
+	var first, second int
+
+	err := Fallback(hopService(&first, errFirst), hopService(&second, errSecond)).
+		Send(context.Background(), "subject", "message")
+	if !errors.Is(err, errFirst) || !errors.Is(err, errSecond) {
+		t.Errorf("Send() error = %v, want both hop errors", err)
+	}
+}
+
+func TestFallback_HopTimeout(t *testing.T) {
+	t.Parallel()
+
+	slow := NotifierFunc(func(ctx context.Context, _, _ string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	var fast int
+
+	start := time.Now()
+	err := Fallback(slow, hopService(&fast, nil)).
+		WithHopTimeout(20*time.Millisecond).
+		Send(context.Background(), "subject", "message")
+	if err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+	if fast != 1 || time.Since(start) > time.Second {
+		t.Errorf("the slow hop was not given up on time")
+	}
+}
+
+func TestWeightedRoundRobin(t *testing.T) {
+	t.Parallel()
+
+	var a, b, c int
+	f := WeightedRoundRobin(
+		WeightedService{Service: hopService(&a, nil), Weight: 3},
+		WeightedService{Service: hopService(&b, nil), Weight: 1},
+		WeightedService{Service: hopService(&c, nil), Weight: 0},
+	)
+
+	for i := 0; i < 8; i++ {
+		if err := f.Send(context.Background(), "subject", "message"); err != nil {
+			t.Fatalf("Send() unexpected error: %v", err)
+		}
+	}
+	if a != 6 || b != 2 || c != 0 {
+		t.Errorf("hops were called %d, %d, %d times, want 6, 2, 0", a, b, c)
+	}
+}
+
+func TestFirstSuccess(t *testing.T) {
+	t.Parallel()
+
+	slow := NotifierFunc(func(ctx context.Context, _, _ string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	var fast int
+
+	n := NewWithServices(FirstSuccess(slow, hopService(&fast, nil)))
+	report, err := n.SendWithReport(context.Background(), "subject", "message")
+	if err != nil {
+		t.Fatalf("SendWithReport() unexpected error: %v", err)
+	}
+	if hop, ok := report.Services[0].DeliveredBy(); !ok || hop.Index != 1 {
+		t.Errorf("DeliveredBy() = %+v, %v, want hop 1", hop, ok)
+	}
+	if len(report.Services[0].Hops) != 2 {
+		t.Errorf("Hops = %+v, want both hops", report.Services[0].Hops)
+	}
+}
@@ -0,0 +1,73 @@
+// Package apiurl points API clients that hard-code the URL of their platform at another server, e.g. at a fake server
+// in tests. It's used by services whose client libraries don't let callers configure the API URL.
+package apiurl
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Transport is an http.RoundTripper that sends all requests to the server at URL instead of the server of the request
+// URL. The scheme and host of the request URL are replaced by the ones of URL, and the path of URL is prepended to the
+// path of the request URL.
+type Transport struct {
+	// URL is the server to send all requests to.
+	URL *url.URL
+	// Base is the http.RoundTripper that sends the rewritten requests. If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+}
+
+// RoundTrip rewrites the URL of the request and sends it with the base http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.URL.Scheme
+	req.URL.Host = t.URL.Host
+	req.URL.Path = strings.TrimSuffix(t.URL.Path, "/") + req.URL.Path
+	req.URL.RawPath = ""
+	req.Host = ""
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return base.RoundTrip(req)
+}
+
+// Parse parses the given API URL. It must be an absolute URL, e.g. "http://127.0.0.1:8080".
+func Parse(apiURL string) (*url.URL, error) {
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse API URL")
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, errors.Errorf("API URL %q is not absolute", apiURL)
+	}
+
+	return u, nil
+}
+
+// Client returns a copy of client that sends all requests to the server at apiURL, see Transport. A nil client is
+// treated like an empty http.Client. If client already uses a Transport, its URL is replaced.
+func Client(client *http.Client, apiURL string) (*http.Client, error) {
+	u, err := Parse(apiURL)
+	if err != nil {
+		return nil, err
+	}
+
+	rewritten := &http.Client{}
+	if client != nil {
+		*rewritten = *client
+	}
+
+	base := rewritten.Transport
+	if t, ok := base.(*Transport); ok {
+		base = t.Base
+	}
+	rewritten.Transport = &Transport{URL: u, Base: base}
+
+	return rewritten, nil
+}
@@ -0,0 +1,59 @@
+package apiurl
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.URL.RequestURI())
+	}))
+	defer server.Close()
+
+	original := &http.Client{Timeout: time.Second}
+	client, err := Client(original, server.URL+"/prefix/")
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, client.Timeout)
+	assert.Nil(t, original.Transport, "the given client is left unchanged")
+
+	get := func(client *http.Client, url string) string {
+		resp, err := client.Get(url)
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return string(body)
+	}
+
+	assert.Equal(t, "/prefix/api/v1/send?a=b", get(client, "https://api.example.com/api/v1/send?a=b"))
+
+	// Pointing the client at another URL replaces the previous one.
+	client, err = Client(client, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "/api/v1/send", get(client, "https://api.example.com/api/v1/send"))
+	assert.NotNil(t, client.Transport)
+	assert.Nil(t, client.Transport.(*Transport).Base)
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	_, err := Parse("http://127.0.0.1:8080")
+	require.NoError(t, err)
+
+	for _, apiURL := range []string{"", "127.0.0.1:8080", "/api", "http://%zz"} {
+		_, err := Parse(apiURL)
+		assert.Error(t, err, apiURL)
+	}
+}
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/casdoor/notify/logging"
+)
+
+// WithLogger is an Option that sets the logger of the Notify instance. The logger is passed on to all services, which
+// log every delivery to a single receiver through it, unless a service was given its own logger. Notify itself logs
+// the outcome of every service: successful sends at debug level, failed ones at error level, and every failed attempt
+// at warn level if retries are enabled, see WithRetry. All logs use the keys of the logging package and redact
+// credentials.
+//
+// Sends during a dry run aren't logged, see WithDryRun.
+func WithLogger(logger *slog.Logger) Option {
+	return func(n *Notify) {
+		if n != nil {
+			n.logger = logger
+		}
+	}
+}
+
+// logAttempt logs a failed attempt of the named service that gets retried.
+func logAttempt(ctx context.Context, name string, attempt int, err error) {
+	logger := logging.FromContext(ctx)
+	if !logger.Enabled(ctx, slog.LevelWarn) {
+		return
+	}
+
+	attrs := append([]slog.Attr{slog.String(logging.KeyService, name), slog.Int(logging.KeyAttempt, attempt)},
+		logging.ErrorAttrs(err)...)
+	logger.LogAttrs(ctx, slog.LevelWarn, "notification attempt failed", attrs...)
+}
+
+// logResult logs the outcome of a send to a single service.
+func logResult(ctx context.Context, result *ServiceResult) {
+	logger := logging.FromContext(ctx)
+	level := slog.LevelDebug
+	if result.Err != nil {
+		level = slog.LevelError
+	}
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String(logging.KeyService, result.Name),
+		slog.Int(logging.KeyAttempt, result.Attempts),
+		slog.Duration(logging.KeyDuration, result.Duration),
+	}
+	if result.Err == nil {
+		logger.LogAttrs(ctx, level, "sent notification", attrs...)
+		return
+	}
+
+	logger.LogAttrs(ctx, level, "failed to send notification", append(attrs, logging.ErrorAttrs(result.Err)...)...)
+}
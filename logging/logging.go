@@ -0,0 +1,169 @@
+// Package logging provides the structured logging shared by notify and the notification services. Loggers are
+// standard *slog.Logger values. They're bound to the context of a send, so that every service logs through the logger
+// of the Notify instance unless it was given its own one, see WithLogger and Bind.
+//
+// All loggers bound by this package redact credentials: the secrets registered with the context, like the bot token of
+// Telegram or the device key of Bark, as well as credentials recognized by their format, e.g. passwords in URLs,
+// bearer tokens and sensitive query parameters. See NewRedactHandler.
+//
+// This package deliberately doesn't depend on the notify package itself, so that every service is able to use it.
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/casdoor/notify/retry"
+)
+
+// Keys of the attributes logged by notify and the services. Use them for custom logs, so that all logs of a send can be
+// correlated.
+const (
+	// KeyService is the name of the service.
+	KeyService = "service"
+	// KeyReceiver is the receiver a message was delivered to, e.g. a chat ID.
+	KeyReceiver = "receiver"
+	// KeyAttempt is the number of the attempt, starting at 1, see retry.Attempt.
+	KeyAttempt = "attempt"
+	// KeyStatus is the HTTP status code of the response of a platform.
+	KeyStatus = "status"
+	// KeyDuration is the time a delivery or a send took.
+	KeyDuration = "duration"
+	// KeyError is the error a delivery or a send failed with.
+	KeyError = "error"
+	// KeyResponse is the beginning of the body of an unexpected response of a platform.
+	KeyResponse = "response"
+)
+
+type (
+	loggerKey  struct{}
+	secretsKey struct{}
+)
+
+// discard is the logger returned by FromContext if no logger is bound to the context.
+var discard = slog.New(discardHandler{})
+
+// WithLogger returns a copy of ctx that carries the given logger, wrapped to redact credentials. A nil logger leaves
+// ctx unchanged.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	if logger == nil {
+		return ctx
+	}
+	if _, ok := logger.Handler().(*redactHandler); !ok {
+		logger = slog.New(NewRedactHandler(logger.Handler()))
+	}
+
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// WithSecrets returns a copy of ctx that makes the logger bound to it redact the given secrets, in addition to the ones
+// registered before.
+func WithSecrets(ctx context.Context, secrets ...string) context.Context {
+	if len(secrets) == 0 {
+		return ctx
+	}
+
+	parent, _ := ctx.Value(secretsKey{}).([]string)
+	all := make([]string, 0, len(parent)+len(secrets))
+	all = append(append(all, parent...), secrets...)
+
+	return context.WithValue(ctx, secretsKey{}, all)
+}
+
+// Bind returns a copy of ctx for a send of a service. If logger isn't nil, it replaces the logger bound to ctx, so that
+// a service given its own logger logs through it. The secrets are redacted from the logs either way. Services call Bind
+// before they deliver a message. Without any logger, nothing gets logged, so ctx is returned unchanged.
+func Bind(ctx context.Context, logger *slog.Logger, secrets ...string) context.Context {
+	ctx = WithLogger(ctx, logger)
+	if _, ok := ctx.Value(loggerKey{}).(*slog.Logger); !ok {
+		return ctx
+	}
+
+	return WithSecrets(ctx, secrets...)
+}
+
+// FromContext returns the logger bound to ctx, redacting the secrets registered with ctx. It returns a logger that
+// discards everything if none is bound.
+func FromContext(ctx context.Context) *slog.Logger {
+	logger, ok := ctx.Value(loggerKey{}).(*slog.Logger)
+	if !ok {
+		return discard
+	}
+	if secrets, _ := ctx.Value(secretsKey{}).([]string); len(secrets) > 0 {
+		return slog.New(NewRedactHandler(logger.Handler(), secrets...))
+	}
+
+	return logger
+}
+
+// ErrorAttrs returns the attributes describing err: the error itself, and the HTTP status code if err carries one, see
+// retry.HTTPError.
+func ErrorAttrs(err error) []slog.Attr {
+	if status, ok := retry.StatusCode(err); ok {
+		return []slog.Attr{slog.Int(KeyStatus, status), slog.Any(KeyError, err)}
+	}
+
+	return []slog.Attr{slog.Any(KeyError, err)}
+}
+
+// Delivery logs the outcome of a delivery of the given service to a single receiver with the logger bound to ctx.
+// Successful deliveries are logged at debug level, failed ones at warn level. An empty receiver is omitted, for services
+// delivering to a fixed destination. The receiver package logs every delivery made with receiver.Each; services
+// delivering on their own call Delivery themselves.
+func Delivery(ctx context.Context, service, receiver string, err error, duration time.Duration) {
+	logger := FromContext(ctx)
+	level := slog.LevelDebug
+	if err != nil {
+		level = slog.LevelWarn
+	}
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{slog.String(KeyService, service)}
+	if receiver != "" {
+		attrs = append(attrs, slog.String(KeyReceiver, receiver))
+	}
+	attrs = append(attrs, slog.Duration(KeyDuration, duration))
+	if attempt := retry.Attempt(ctx); attempt > 0 {
+		attrs = append(attrs, slog.Int(KeyAttempt, attempt))
+	}
+	if err == nil {
+		logger.LogAttrs(ctx, level, "delivered notification", attrs...)
+		return
+	}
+
+	logger.LogAttrs(ctx, level, "failed to deliver notification", append(attrs, ErrorAttrs(err)...)...)
+}
+
+// maxResponseLength is the maximum number of bytes of a response body logged by Response.
+const maxResponseLength = 1024
+
+// Response logs an unexpected response of a platform to a delivery of the given service with the logger bound to ctx,
+// at warn level. It logs the status code and the beginning of the body, which often explains why a platform rejected a
+// message. Services call Response before they turn the response into an error.
+func Response(ctx context.Context, service, receiver string, status int, body []byte) {
+	logger := FromContext(ctx)
+	if !logger.Enabled(ctx, slog.LevelWarn) {
+		return
+	}
+
+	if len(body) > maxResponseLength {
+		body = body[:maxResponseLength]
+	}
+	logger.LogAttrs(ctx, slog.LevelWarn, "unexpected response",
+		slog.String(KeyService, service),
+		slog.String(KeyReceiver, receiver),
+		slog.Int(KeyStatus, status),
+		slog.String(KeyResponse, string(body)),
+	)
+}
+
+// discardHandler is a slog.Handler that discards everything.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (d discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return d }
+func (d discardHandler) WithGroup(string) slog.Handler           { return d }
@@ -0,0 +1,67 @@
+// Package markup converts message bodies between body formats, e.g. from Markdown to the MarkdownV2 dialect of
+// Telegram or to the mrkdwn dialect of Slack. Services use it to deliver a body in the markup language of their
+// platform, whatever format it was written in, see message.BodyFormat.
+//
+// Conversions are best effort: the converter understands the common subset of the formats, i.e. bold, italic and
+// struck through text, inline code and code blocks, links, headings, quotes and lists. Everything else is kept as
+// text. Reserved characters are always escaped, so that a converted body never fails to parse on the platform, and links
+// are only kept if they point to http, https or mailto URLs.
+//
+// This package deliberately doesn't depend on the notify package itself, so that every service is able to use it.
+package markup
+
+import "github.com/casdoor/notify/message"
+
+// Convert converts body from one body format to another. An empty format is treated as plain text. The body is
+// returned unchanged if both formats are the same.
+func Convert(body string, from, to message.BodyFormat) string {
+	if from == "" {
+		from = message.FormatPlain
+	}
+	if to == "" {
+		to = message.FormatPlain
+	}
+	if from == to {
+		return body
+	}
+
+	return render(parse(body, from), dialectFor(to))
+}
+
+// Escape escapes the reserved characters of the given body format in text, so that the platform shows text as is. It's
+// meant for short texts, like subjects, that are put in front of a converted body.
+func Escape(text string, format message.BodyFormat) string {
+	return dialectFor(format).text(text)
+}
+
+// parse parses body written in the given body format.
+func parse(body string, format message.BodyFormat) []block {
+	switch format {
+	case message.FormatMarkdown:
+		return parseMarkdown(body, markdownSyntax)
+	case message.FormatMrkdwn:
+		return parseMarkdown(body, mrkdwnSyntax)
+	case message.FormatMarkdownV2:
+		return parseMarkdown(body, markdownV2Syntax)
+	case message.FormatHTML:
+		return parseHTML(body)
+	default:
+		return parsePlain(body)
+	}
+}
+
+// dialectFor returns the dialect that renders the given body format.
+func dialectFor(format message.BodyFormat) dialect {
+	switch format {
+	case message.FormatMarkdown:
+		return markdownDialect{}
+	case message.FormatMrkdwn:
+		return mrkdwnDialect{}
+	case message.FormatMarkdownV2:
+		return markdownV2Dialect{}
+	case message.FormatHTML:
+		return htmlDialect{}
+	default:
+		return plainDialect{}
+	}
+}
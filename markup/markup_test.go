@@ -0,0 +1,157 @@
+package markup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/casdoor/notify/message"
+)
+
+const markdownBody = "# Deploy\n\n" +
+	"**api** was _deployed_ to `prod-1` by ~~bob~~ alice.\n" +
+	"See [the logs](https://example.com/logs?a=1&b=(2)).\n\n" +
+	"- first item\n" +
+	"- second item\n\n" +
+	"1. one\n" +
+	"2. two\n\n" +
+	"> quoted\n> text\n\n" +
+	"```\nif a < b {}\n```"
+
+func TestConvert_Markdown(t *testing.T) {
+	t.Parallel()
+
+	tests := map[message.BodyFormat]string{
+		message.FormatPlain: "Deploy\n\n" +
+			"api was deployed to prod-1 by bob alice.\n" +
+			"See the logs (https://example.com/logs?a=1&b=(2)).\n\n" +
+			"- first item\n- second item\n\n" +
+			"1. one\n2. two\n\n" +
+			"> quoted\n> text\n\n" +
+			"if a < b {}",
+		message.FormatHTML: "<h1>Deploy</h1>\n" +
+			"<p><strong>api</strong> was <em>deployed</em> to <code>prod-1</code> by <del>bob</del> alice.<br>\n" +
+			`See <a href="https://example.com/logs?a=1&amp;b=(2)">the logs</a>.</p>` + "\n" +
+			"<ul>\n<li>first item</li>\n<li>second item</li>\n</ul>\n" +
+			"<ol>\n<li>one</li>\n<li>two</li>\n</ol>\n" +
+			"<blockquote>quoted<br>\ntext</blockquote>\n" +
+			"<pre><code>if a &lt; b {}</code></pre>",
+		message.FormatMrkdwn: "*Deploy*\n\n" +
+			"*api* was _deployed_ to `prod-1` by ~bob~ alice.\n" +
+			"See <https://example.com/logs?a=1&b=(2)|the logs>.\n\n" +
+			"• first item\n• second item\n\n" +
+			"1. one\n2. two\n\n" +
+			"> quoted\n> text\n\n" +
+			"```\nif a &lt; b {}\n```",
+		message.FormatMarkdownV2: "*Deploy*\n\n" +
+			"*api* was _deployed_ to `prod-1` by ~bob~ alice\\.\n" +
+			"See [the logs](https://example.com/logs?a=1&b=(2\\))\\.\n\n" +
+			"• first item\n• second item\n\n" +
+			"1\\. one\n2\\. two\n\n" +
+			">quoted\n>text\n\n" +
+			"```\nif a < b {}\n```",
+	}
+	for format, want := range tests {
+		require.Equal(t, want, Convert(markdownBody, message.FormatMarkdown, format), format)
+	}
+}
+
+func TestConvert_HTML(t *testing.T) {
+	t.Parallel()
+
+	body := `<html><head><title>x</title><style>p {}</style></head><body>
+<h2>Disk &amp; memory</h2>
+<p>Only <b>5%</b> left on <a href='https://example.com/host?a=1&amp;b=2'>host-1</a>.<br/>
+Act <i>now</i>!</p>
+<!-- comment -->
+<ol><li>Clean up</li><li>Resize</li></ol>
+<pre>df -h
+du -sh *</pre>
+<p>a < b</p>
+</body></html>`
+
+	require.Equal(t, "Disk & memory\n\n"+
+		"Only 5% left on host-1 (https://example.com/host?a=1&b=2).\n"+
+		"Act now!\n\n"+
+		"1. Clean up\n2. Resize\n\n"+
+		"df -h\ndu -sh *\n\n"+
+		"a < b", Convert(body, message.FormatHTML, message.FormatPlain))
+
+	require.Equal(t, "*Disk & memory*\n\n"+
+		"Only *5%* left on [host\\-1](https://example.com/host?a=1&b=2)\\.\n"+
+		"Act _now_\\!\n\n"+
+		"1\\. Clean up\n2\\. Resize\n\n"+
+		"```\ndf -h\ndu -sh *\n```\n\n"+
+		"a < b", Convert(body, message.FormatHTML, message.FormatMarkdownV2))
+}
+
+func TestConvert_Plain(t *testing.T) {
+	t.Parallel()
+
+	body := "Price: 5.00 (-10%) *today*\n<b>not bold</b>\n\nsecond_paragraph"
+
+	tests := map[message.BodyFormat]string{
+		message.FormatMarkdownV2: "Price: 5\\.00 \\(\\-10%\\) \\*today\\*\n<b\\>not bold</b\\>\n\nsecond\\_paragraph",
+		message.FormatMrkdwn:     "Price: 5.00 (-10%) *today*\n&lt;b&gt;not bold&lt;/b&gt;\n\nsecond_paragraph",
+		message.FormatMarkdown:   "Price: 5.00 (-10%) \\*today\\*\n\\<b\\>not bold\\</b\\>\n\nsecond\\_paragraph",
+		message.FormatHTML:       "<p>Price: 5.00 (-10%) *today*<br>\n&lt;b&gt;not bold&lt;/b&gt;</p>\n<p>second_paragraph</p>",
+	}
+	for format, want := range tests {
+		require.Equal(t, want, Convert(body, message.FormatPlain, format), format)
+	}
+}
+
+func TestConvert_Dialects(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "**bold** _italic_ ~~gone~~ [site](https://example.com)",
+		Convert("*bold* _italic_ ~gone~ <https://example.com|site>", message.FormatMrkdwn, message.FormatMarkdown))
+	require.Equal(t, "<p><strong>bold</strong> costs 5.00!</p>",
+		Convert("*bold* costs 5\\.00\\!", message.FormatMarkdownV2, message.FormatHTML))
+	require.Equal(t, "*Title bold*", Convert("## Title **bold**", message.FormatMarkdown, message.FormatMrkdwn))
+	require.Equal(t, "<p><strong><em>both</em></strong></p>",
+		Convert("***both***", message.FormatMarkdown, message.FormatHTML))
+	require.Equal(t, `<p>click <a href="MAILTO:ops@example.com">ops</a></p>`,
+		Convert(`[click](javascript:alert(1)) [ops](MAILTO:ops@example.com)`, message.FormatMarkdown, message.FormatHTML))
+	require.Equal(t, "**click**", Convert(`<b><a href=" javascript:alert(1)">click</a></b>`, message.FormatHTML,
+		message.FormatMarkdown))
+}
+
+func TestConvert_EdgeCases(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name, body, want string
+	}{
+		{"unmatched markers", "2 * 3 = 6 and a_b_c", "2 \\* 3 \\= 6 and a\\_b\\_c"},
+		{"escaped markers", "\\*not italic\\*", "\\*not italic\\*"},
+		{"nested", "**bold _and italic_**", "*bold _and italic_*"},
+		{"empty markers", "****", "\\*\\*\\*\\*"},
+		{"broken link", "[label](no url", "\\[label\\]\\(no url"},
+		{"unicode", "**Grüße** 👋 _日本_", "*Grüße* 👋 _日本_"},
+		{"code with markers", "`a*b*c`", "`a*b*c`"},
+		{"bold in heading", "# Title **bold** _it_", "*Title bold _it_*"},
+		{"bold italic", "a ***b*** c", "a *_b_* c"},
+		{"unsafe link", "[click](javascript:alert(1))", "click"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, Convert(tt.body, message.FormatMarkdown, message.FormatMarkdownV2), tt.name)
+	}
+}
+
+func TestConvert_SameFormat(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "*x*", Convert("*x*", message.FormatMrkdwn, message.FormatMrkdwn))
+	require.Equal(t, "a < b", Convert("a < b", "", message.FormatPlain))
+}
+
+func TestEscape(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "Disk 95% full\\!", Escape("Disk 95% full!", message.FormatMarkdownV2))
+	require.Equal(t, "a &lt;b&gt; &amp; c", Escape("a <b> & c", message.FormatHTML))
+	require.Equal(t, "Deploy v1\\.2", Escape("Deploy v1.2", message.FormatMarkdownV2))
+	require.Equal(t, "a &lt; b", Escape("a < b", message.FormatMrkdwn))
+	require.Equal(t, "a < b", Escape("a < b", message.FormatPlain))
+}
@@ -0,0 +1,648 @@
+package markup
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// kind is the kind of an inline node.
+type kind int
+
+const (
+	textNode kind = iota
+	boldNode
+	italicNode
+	strikeNode
+	codeNode
+	linkNode
+	breakNode
+)
+
+// node is an inline element of a block.
+type node struct {
+	kind     kind
+	text     string // Content of text and code nodes.
+	url      string // Target of link nodes.
+	children []node // Content of bold, italic, struck through and link nodes.
+}
+
+// blockKind is the kind of a block.
+type blockKind int
+
+const (
+	paragraphBlock blockKind = iota
+	headingBlock
+	codeBlock
+	quoteBlock
+	itemBlock
+)
+
+// block is a top-level element of a body.
+type block struct {
+	kind   blockKind
+	level  int    // Level of headings.
+	number int    // Number of ordered list items, 0 for bullets.
+	code   string // Content of code blocks.
+	nodes  []node // Content of all other blocks.
+}
+
+// syntax describes the inline markers of a Markdown dialect.
+type syntax struct {
+	single     map[byte]kind
+	double     map[string]kind
+	slackLinks bool
+}
+
+var (
+	markdownSyntax = syntax{
+		single: map[byte]kind{'*': italicNode, '_': italicNode},
+		double: map[string]kind{"**": boldNode, "__": boldNode, "~~": strikeNode},
+	}
+	mrkdwnSyntax = syntax{
+		single:     map[byte]kind{'*': boldNode, '_': italicNode, '~': strikeNode},
+		slackLinks: true,
+	}
+	markdownV2Syntax = syntax{
+		single: map[byte]kind{'*': boldNode, '_': italicNode, '~': strikeNode},
+		double: map[string]kind{"__": italicNode},
+	}
+)
+
+var (
+	headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	bulletPattern  = regexp.MustCompile(`^[-*+•]\s+(.*)$`)
+	orderedPattern = regexp.MustCompile(`^(\d{1,9})[.)]\s+(.*)$`)
+)
+
+// parsePlain parses plain text. Paragraphs are separated by blank lines.
+func parsePlain(body string) []block {
+	var blocks []block
+	for _, paragraph := range splitParagraphs(body) {
+		var nodes []node
+		for i, line := range strings.Split(paragraph, "\n") {
+			if i > 0 {
+				nodes = append(nodes, node{kind: breakNode})
+			}
+			nodes = append(nodes, node{kind: textNode, text: line})
+		}
+		blocks = append(blocks, block{kind: paragraphBlock, nodes: nodes})
+	}
+
+	return blocks
+}
+
+// splitParagraphs splits body at blank lines and drops empty paragraphs.
+func splitParagraphs(body string) []string {
+	var paragraphs, lines []string
+	flush := func() {
+		if len(lines) > 0 {
+			paragraphs = append(paragraphs, strings.Join(lines, "\n"))
+			lines = nil
+		}
+	}
+
+	for _, line := range strings.Split(normalizeNewlines(body), "\n") {
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, " \t"))
+	}
+	flush()
+
+	return paragraphs
+}
+
+func normalizeNewlines(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\r", "\n")
+}
+
+// parseMarkdown parses a body written in Markdown, or in a dialect described by s.
+func parseMarkdown(body string, s syntax) []block {
+	lines := strings.Split(normalizeNewlines(body), "\n")
+
+	var blocks []block
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, block{kind: paragraphBlock, nodes: parseInlines(strings.Join(paragraph, "\n"), s)})
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			blocks = append(blocks, block{kind: codeBlock, code: strings.Join(code, "\n")})
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			var quoted []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quoted = append(quoted, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")))
+			}
+			i--
+			blocks = append(blocks, block{kind: quoteBlock, nodes: parseInlines(strings.Join(quoted, "\n"), s)})
+		case headingPattern.MatchString(trimmed):
+			flush()
+			match := headingPattern.FindStringSubmatch(trimmed)
+			blocks = append(blocks, block{kind: headingBlock, level: len(match[1]), nodes: parseInlines(match[2], s)})
+		case bulletPattern.MatchString(trimmed):
+			flush()
+			match := bulletPattern.FindStringSubmatch(trimmed)
+			blocks = append(blocks, block{kind: itemBlock, nodes: parseInlines(match[1], s)})
+		case orderedPattern.MatchString(trimmed):
+			flush()
+			match := orderedPattern.FindStringSubmatch(trimmed)
+			number, _ := strconv.Atoi(match[1])
+			blocks = append(blocks, block{kind: itemBlock, number: number, nodes: parseInlines(match[2], s)})
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+
+	return blocks
+}
+
+// parseInlines parses the inline elements of a block written in a Markdown dialect. Markers without a matching closing
+// marker are kept as text.
+func parseInlines(src string, s syntax) []node {
+	var nodes []node
+	var text strings.Builder
+	add := func(n node) {
+		if text.Len() > 0 {
+			nodes = append(nodes, node{kind: textNode, text: text.String()})
+			text.Reset()
+		}
+		nodes = append(nodes, n)
+	}
+
+	for i := 0; i < len(src); {
+		c := src[i]
+
+		switch {
+		case c == '\\' && i+1 < len(src) && isPunct(src[i+1]):
+			text.WriteByte(src[i+1])
+			i += 2
+			continue
+		case c == '\n':
+			add(node{kind: breakNode})
+			i++
+			continue
+		case c == '`':
+			if end := strings.IndexByte(src[i+1:], '`'); end > 0 {
+				add(node{kind: codeNode, text: src[i+1 : i+1+end]})
+				i += end + 2
+				continue
+			}
+		case c == '[':
+			if label, url, n, ok := parseLink(src[i:]); ok {
+				add(node{kind: linkNode, url: url, children: parseInlines(label, s)})
+				i += n
+				continue
+			}
+		case c == '<':
+			if label, url, n, ok := parseAngleLink(src[i:], s.slackLinks); ok {
+				add(node{kind: linkNode, url: url, children: []node{{kind: textNode, text: label}}})
+				i += n
+				continue
+			}
+		}
+
+		if i+2 < len(src) && src[i+1] == c && src[i+2] == c {
+			// A triple marker like ***text*** opens both a double and a single marker.
+			outer, doubled := s.double[src[i:i+2]]
+			inner, single := s.single[c]
+			if end := closing(src, i+3, src[i:i+3]); doubled && single && end >= 0 {
+				add(node{kind: outer, children: []node{{kind: inner, children: parseInlines(src[i+3:end], s)}}})
+				i = end + 3
+				continue
+			}
+		}
+		if i+1 < len(src) {
+			if k, ok := s.double[src[i:i+2]]; ok {
+				if end := closing(src, i+2, src[i:i+2]); end >= 0 {
+					add(node{kind: k, children: parseInlines(src[i+2:end], s)})
+					i = end + 2
+					continue
+				}
+			}
+		}
+		if k, ok := s.single[c]; ok && i+1 < len(src) && src[i+1] != c && !(c == '_' && i > 0 && isWord(src[i-1])) {
+			if end := closing(src, i+1, src[i:i+1]); end >= 0 && (c != '_' || end+1 >= len(src) || !isWord(src[end+1])) {
+				add(node{kind: k, children: parseInlines(src[i+1:end], s)})
+				i = end + 1
+				continue
+			}
+		}
+
+		text.WriteByte(c)
+		i++
+	}
+	if text.Len() > 0 {
+		nodes = append(nodes, node{kind: textNode, text: text.String()})
+	}
+
+	return nodes
+}
+
+// closing returns the index of the marker closing the one that ends right before from, or -1 if there is none. The
+// content between the markers must neither be empty nor start or end with white space. Single character markers that
+// are part of a double marker are skipped.
+func closing(src string, from int, marker string) int {
+	if from >= len(src) || isSpace(src[from]) {
+		return -1
+	}
+
+	for i := from; i < len(src); i++ {
+		switch {
+		case src[i] == '\\':
+			i++
+		case src[i] == '`':
+			// Markers inside inline code don't count.
+			if end := strings.IndexByte(src[i+1:], '`'); end >= 0 {
+				i += end + 1
+			}
+		case strings.HasPrefix(src[i:], marker):
+			if len(marker) == 1 && i+1 < len(src) && src[i+1] == marker[0] {
+				i++
+				continue
+			}
+			if i > from && !isSpace(src[i-1]) {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// parseLink parses a link of the form [label](url) at the start of src. It returns the number of bytes it consumed.
+func parseLink(src string) (label, url string, n int, ok bool) {
+	depth := 0
+	for i := 0; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth > 0 {
+				continue
+			}
+			if i+1 >= len(src) || src[i+1] != '(' {
+				return "", "", 0, false
+			}
+			end := closingParen(src[i+2:])
+			if end < 0 {
+				return "", "", 0, false
+			}
+			url = strings.TrimSpace(src[i+2 : i+2+end])
+			if url == "" || strings.ContainsAny(url, " \n") {
+				return "", "", 0, false
+			}
+			return src[1:i], url, i + 3 + end, true
+		}
+	}
+
+	return "", "", 0, false
+}
+
+// closingParen returns the index of the parenthesis closing the one right before s, or -1 if there is none. Balanced
+// parentheses in between are skipped, since they're common in URLs.
+func closingParen(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+
+	return -1
+}
+
+// parseAngleLink parses an autolink of the form <url> at the start of src, or a Slack link of the form <url|label> if
+// slack is set. It returns the number of bytes it consumed.
+func parseAngleLink(src string, slack bool) (label, url string, n int, ok bool) {
+	end := strings.IndexByte(src, '>')
+	if end < 0 {
+		return "", "", 0, false
+	}
+
+	url, label = src[1:end], src[1:end]
+	if i := strings.IndexByte(url, '|'); slack && i >= 0 {
+		url, label = url[:i], url[i+1:]
+	}
+	if !isURL(url) || strings.ContainsAny(url, " \n") {
+		return "", "", 0, false
+	}
+
+	return label, url, end + 1, true
+}
+
+// isURL reports whether s is an absolute URL with one of the schemes links may point to: http, https and mailto. Links
+// to other targets, like javascript: URLs, are rendered as their label only.
+func isURL(s string) bool {
+	s = strings.ToLower(s)
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "mailto:")
+}
+
+func isPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+func isWord(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// htmlParser parses HTML into blocks. It only knows the elements matching the ones of the Markdown dialects and keeps
+// the text of all other elements.
+type htmlParser struct {
+	blocks []block
+	open   bool     // Whether a block is being parsed.
+	block  block    // The block being parsed.
+	stack  []node   // The open inline elements, the first one holds the content of the block.
+	lists  []int    // Counters of the open lists, -1 for unordered ones.
+	pre    int      // Depth of open pre elements.
+	skip   string   // Name of the element whose content is skipped, like script.
+	code   []string // Text of the open code element.
+}
+
+var (
+	tagPattern  = regexp.MustCompile(`^(/?)([a-zA-Z][a-zA-Z0-9]*)`)
+	hrefPattern = regexp.MustCompile(`(?i)\bhref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	spaces      = regexp.MustCompile(`\s+`)
+)
+
+// parseHTML parses a body written in HTML.
+func parseHTML(body string) []block {
+	p := &htmlParser{}
+
+	for len(body) > 0 {
+		i := strings.IndexByte(body, '<')
+		if i < 0 {
+			p.text(body)
+			break
+		}
+		p.text(body[:i])
+		body = body[i:]
+
+		if strings.HasPrefix(body, "<!--") {
+			end := strings.Index(body, "-->")
+			if end < 0 {
+				break
+			}
+			body = body[end+3:]
+			continue
+		}
+
+		match := tagPattern.FindStringSubmatch(body[1:])
+		end := strings.IndexByte(body, '>')
+		if match == nil || end < 0 {
+			// Not a tag, e.g. "a < b".
+			p.text("<")
+			body = body[1:]
+			continue
+		}
+
+		p.tag(strings.ToLower(match[2]), match[1] == "/", body[1:end])
+		body = body[end+1:]
+	}
+	p.finish()
+
+	return p.blocks
+}
+
+// text adds a text in the current element.
+func (p *htmlParser) text(s string) {
+	if s == "" || p.skip != "" {
+		return
+	}
+
+	s = html.UnescapeString(s)
+	if p.pre > 0 {
+		p.begin(block{kind: codeBlock})
+		p.block.code += s
+		return
+	}
+	if p.code != nil {
+		p.code = append(p.code, s)
+		return
+	}
+
+	s = spaces.ReplaceAllString(s, " ")
+	if !p.open && strings.TrimSpace(s) == "" {
+		return
+	}
+	p.begin(block{kind: paragraphBlock})
+	p.add(node{kind: textNode, text: s})
+}
+
+// tag handles the start or end tag of the element with the given name. raw is the content of the tag without angle
+// brackets.
+func (p *htmlParser) tag(name string, end bool, raw string) {
+	if p.skip != "" {
+		if end && name == p.skip {
+			p.skip = ""
+		}
+		return
+	}
+
+	inline := map[string]kind{
+		"b": boldNode, "strong": boldNode,
+		"i": italicNode, "em": italicNode,
+		"s": strikeNode, "del": strikeNode, "strike": strikeNode,
+		"a": linkNode,
+	}
+
+	switch {
+	case name == "script" || name == "style" || name == "head" || name == "title":
+		if !end {
+			p.skip = name
+		}
+	case name == "br":
+		if p.pre > 0 {
+			p.text("\n")
+		} else {
+			p.begin(block{kind: paragraphBlock})
+			p.add(node{kind: breakNode})
+		}
+	case name == "code" && p.pre == 0:
+		if !end {
+			p.code = []string{}
+		} else if p.code != nil {
+			text := strings.Join(p.code, "")
+			p.code = nil
+			p.begin(block{kind: paragraphBlock})
+			p.add(node{kind: codeNode, text: text})
+		}
+	case inline[name] != 0:
+		if end {
+			p.close(inline[name])
+			return
+		}
+		n := node{kind: inline[name]}
+		if name == "a" {
+			if match := hrefPattern.FindStringSubmatch(raw); match != nil {
+				n.url = html.UnescapeString(match[1] + match[2] + match[3])
+			}
+		}
+		p.begin(block{kind: paragraphBlock})
+		p.stack = append(p.stack, n)
+	case name == "pre":
+		p.finish()
+		if end {
+			p.pre--
+		} else {
+			p.pre++
+			p.begin(block{kind: codeBlock})
+		}
+	case name == "ul" || name == "ol":
+		p.finish()
+		switch {
+		case !end && name == "ul":
+			p.lists = append(p.lists, -1)
+		case !end:
+			p.lists = append(p.lists, 0)
+		case len(p.lists) > 0:
+			p.lists = p.lists[:len(p.lists)-1]
+		}
+	case name == "li":
+		p.finish()
+		if !end {
+			b := block{kind: itemBlock}
+			if n := len(p.lists); n > 0 && p.lists[n-1] >= 0 {
+				p.lists[n-1]++
+				b.number = p.lists[n-1]
+			}
+			p.begin(b)
+		}
+	case len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6':
+		p.finish()
+		if !end {
+			p.begin(block{kind: headingBlock, level: int(name[1] - '0')})
+		}
+	case name == "blockquote":
+		p.finish()
+		if !end {
+			p.begin(block{kind: quoteBlock})
+		}
+	case isBlockElement(name):
+		p.finish()
+	}
+}
+
+func isBlockElement(name string) bool {
+	switch name {
+	case "p", "div", "section", "article", "header", "footer", "main", "aside", "nav", "table", "tr", "hr", "body",
+		"html", "dl", "dt", "dd", "figure", "figcaption", "address":
+		return true
+	default:
+		return false
+	}
+}
+
+// begin starts a new block of the given kind, unless a block is being parsed already.
+func (p *htmlParser) begin(b block) {
+	if p.open {
+		return
+	}
+	p.open, p.block, p.stack = true, b, []node{{}}
+}
+
+// add adds n to the innermost open element.
+func (p *htmlParser) add(n node) {
+	top := &p.stack[len(p.stack)-1]
+	top.children = append(top.children, n)
+}
+
+// pop closes the innermost open inline element.
+func (p *htmlParser) pop() {
+	n := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+	p.add(n)
+}
+
+// close closes the innermost open inline element of the given kind and all elements opened after it.
+func (p *htmlParser) close(k kind) {
+	for i := len(p.stack) - 1; i > 0; i-- {
+		if p.stack[i].kind == k {
+			for len(p.stack) > i {
+				p.pop()
+			}
+			return
+		}
+	}
+}
+
+// finish finishes the block being parsed, if any.
+func (p *htmlParser) finish() {
+	if !p.open {
+		return
+	}
+	for len(p.stack) > 1 {
+		p.pop()
+	}
+
+	b := p.block
+	b.nodes = trimNodes(p.stack[0].children)
+	if b.kind == codeBlock {
+		b.code = strings.Trim(b.code, "\n")
+	}
+	p.open, p.stack = false, nil
+
+	if len(b.nodes) > 0 || b.kind == codeBlock && b.code != "" {
+		p.blocks = append(p.blocks, b)
+	}
+}
+
+// trimNodes removes the white space at the start and the end of every line of nodes.
+func trimNodes(nodes []node) []node {
+	trimmed := make([]node, 0, len(nodes))
+	lineStart := true
+	for i, n := range nodes {
+		if n.kind == textNode {
+			if lineStart {
+				n.text = strings.TrimLeft(n.text, " ")
+			}
+			if i+1 == len(nodes) || nodes[i+1].kind == breakNode {
+				n.text = strings.TrimRight(n.text, " ")
+			}
+			if n.text == "" {
+				continue
+			}
+		}
+		lineStart = n.kind == breakNode
+		trimmed = append(trimmed, n)
+	}
+
+	// Drop trailing line breaks.
+	for len(trimmed) > 0 && trimmed[len(trimmed)-1].kind == breakNode {
+		trimmed = trimmed[:len(trimmed)-1]
+	}
+
+	return trimmed
+}
@@ -0,0 +1,435 @@
+package markup
+
+import (
+	"html"
+	"strconv"
+	"strings"
+)
+
+// dialect renders the elements of a body in a specific body format.
+type dialect interface {
+	// text escapes text.
+	text(s string) string
+	// inline wraps the rendered content of a bold, italic or struck through element.
+	inline(k kind, content string) string
+	code(s string) string
+	link(label, url string) string
+	lineBreak() string
+	paragraph(content string) string
+	heading(level int, content string) string
+	codeBlock(code string) string
+	quote(content string) string
+	item(number int, content string) string
+	list(ordered bool, items []string) string
+	// separator separates blocks.
+	separator() string
+}
+
+// boldHeadings is implemented by dialects without headings, which render them as bold text instead. Bold text within
+// such headings is rendered as normal text, since bold markers can't be nested.
+type boldHeadings interface {
+	boldHeadings()
+}
+
+// unbold replaces the bold elements among nodes and their children with their content.
+func unbold(nodes []node) []node {
+	unbolded := make([]node, 0, len(nodes))
+	for _, n := range nodes {
+		n.children = unbold(n.children)
+		if n.kind == boldNode {
+			unbolded = append(unbolded, n.children...)
+			continue
+		}
+		unbolded = append(unbolded, n)
+	}
+
+	return unbolded
+}
+
+// render renders blocks in the given dialect.
+func render(blocks []block, d dialect) string {
+	var rendered []string
+	for i := 0; i < len(blocks); i++ {
+		b := blocks[i]
+		switch b.kind {
+		case headingBlock:
+			nodes := b.nodes
+			if _, ok := d.(boldHeadings); ok {
+				nodes = unbold(nodes)
+			}
+			rendered = append(rendered, d.heading(b.level, renderNodes(nodes, d)))
+		case codeBlock:
+			rendered = append(rendered, d.codeBlock(b.code))
+		case quoteBlock:
+			rendered = append(rendered, d.quote(renderNodes(b.nodes, d)))
+		case itemBlock:
+			// Consecutive items form a list.
+			ordered := b.number > 0
+			var items []string
+			for ; i < len(blocks) && blocks[i].kind == itemBlock && (blocks[i].number > 0) == ordered; i++ {
+				items = append(items, d.item(blocks[i].number, renderNodes(blocks[i].nodes, d)))
+			}
+			i--
+			rendered = append(rendered, d.list(ordered, items))
+		default:
+			rendered = append(rendered, d.paragraph(renderNodes(b.nodes, d)))
+		}
+	}
+
+	return strings.Join(rendered, d.separator())
+}
+
+// renderNodes renders inline nodes in the given dialect. Elements without content are dropped.
+func renderNodes(nodes []node, d dialect) string {
+	var sb strings.Builder
+	for _, n := range nodes {
+		switch n.kind {
+		case textNode:
+			sb.WriteString(d.text(n.text))
+		case breakNode:
+			sb.WriteString(d.lineBreak())
+		case codeNode:
+			if n.text != "" {
+				sb.WriteString(d.code(n.text))
+			}
+		case linkNode:
+			label := renderNodes(n.children, d)
+			switch {
+			case !isURL(n.url):
+				sb.WriteString(label)
+			case label == "":
+				sb.WriteString(d.link(d.text(n.url), n.url))
+			default:
+				sb.WriteString(d.link(label, n.url))
+			}
+		default:
+			if content := renderNodes(n.children, d); content != "" {
+				sb.WriteString(d.inline(n.kind, content))
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// escapeWith puts a backslash in front of every character of s that is contained in reserved.
+func escapeWith(s, reserved string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(reserved, s[i]) >= 0 {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(s[i])
+	}
+
+	return sb.String()
+}
+
+// prefixLines puts prefix in front of every line of s.
+func prefixLines(s, prefix string) string {
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
+
+// plainDialect renders plain text.
+type plainDialect struct{}
+
+func (plainDialect) text(s string) string {
+	return s
+}
+
+func (plainDialect) inline(_ kind, c string) string {
+	return c
+}
+
+func (plainDialect) code(s string) string {
+	return s
+}
+
+func (plainDialect) lineBreak() string {
+	return "\n"
+}
+
+func (plainDialect) paragraph(c string) string {
+	return c
+}
+
+func (plainDialect) heading(_ int, c string) string {
+	return c
+}
+
+func (plainDialect) codeBlock(code string) string {
+	return code
+}
+
+func (plainDialect) quote(c string) string {
+	return prefixLines(c, "> ")
+}
+
+func (plainDialect) list(_ bool, i []string) string {
+	return strings.Join(i, "\n")
+}
+
+func (plainDialect) separator() string {
+	return "\n\n"
+}
+
+func (plainDialect) link(label, url string) string {
+	if label == url || strings.TrimPrefix(url, "mailto:") == label {
+		return url
+	}
+
+	return label + " (" + url + ")"
+}
+
+func (plainDialect) item(number int, c string) string {
+	if number > 0 {
+		return strconv.Itoa(number) + ". " + c
+	}
+
+	return "- " + c
+}
+
+// markdownDialect renders CommonMark.
+type markdownDialect struct{}
+
+var markdownMarkers = map[kind]string{boldNode: "**", italicNode: "_", strikeNode: "~~"}
+
+func (markdownDialect) text(s string) string {
+	return escapeWith(s, "\\`*_~[]<>#|")
+}
+
+func (markdownDialect) inline(k kind, c string) string {
+	return markdownMarkers[k] + c + markdownMarkers[k]
+}
+func (markdownDialect) lineBreak() string {
+	return "\n"
+}
+
+func (markdownDialect) paragraph(c string) string {
+	return c
+}
+
+func (markdownDialect) codeBlock(code string) string {
+	return "```\n" + code + "\n```"
+}
+
+func (markdownDialect) quote(c string) string {
+	return prefixLines(c, "> ")
+}
+
+func (markdownDialect) list(_ bool, items []string) string {
+	return strings.Join(items, "\n")
+}
+
+func (markdownDialect) separator() string {
+	return "\n\n"
+}
+
+func (markdownDialect) heading(level int, c string) string {
+	return strings.Repeat("#", level) + " " + c
+}
+
+func (markdownDialect) code(s string) string {
+	if strings.Contains(s, "`") {
+		return "`` " + s + " ``"
+	}
+
+	return "`" + s + "`"
+}
+
+func (markdownDialect) link(label, url string) string {
+	return "[" + label + "](" + strings.NewReplacer("(", "%28", ")", "%29", " ", "%20").Replace(url) + ")"
+}
+
+func (markdownDialect) item(number int, c string) string {
+	if number > 0 {
+		return strconv.Itoa(number) + ". " + c
+	}
+
+	return "- " + c
+}
+
+// mrkdwnDialect renders mrkdwn, the Markdown dialect of Slack. Slack only requires &, < and > to be escaped.
+type mrkdwnDialect struct{}
+
+var mrkdwnMarkers = map[kind]string{boldNode: "*", italicNode: "_", strikeNode: "~"}
+
+var mrkdwnEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func (mrkdwnDialect) text(s string) string {
+	return mrkdwnEscaper.Replace(s)
+}
+
+func (mrkdwnDialect) inline(k kind, c string) string {
+	return mrkdwnMarkers[k] + c + mrkdwnMarkers[k]
+}
+
+func (mrkdwnDialect) code(s string) string {
+	return "`" + mrkdwnEscaper.Replace(s) + "`"
+}
+
+func (mrkdwnDialect) lineBreak() string {
+	return "\n"
+}
+
+func (mrkdwnDialect) paragraph(c string) string {
+	return c
+}
+
+func (mrkdwnDialect) heading(_ int, c string) string {
+	return "*" + c + "*"
+}
+
+func (mrkdwnDialect) boldHeadings() {}
+
+func (mrkdwnDialect) quote(c string) string {
+	return prefixLines(c, "> ")
+}
+
+func (mrkdwnDialect) list(_ bool, items []string) string {
+	return strings.Join(items, "\n")
+}
+
+func (mrkdwnDialect) separator() string {
+	return "\n\n"
+}
+
+func (mrkdwnDialect) codeBlock(code string) string {
+	return "```\n" + mrkdwnEscaper.Replace(code) + "\n```"
+}
+
+func (mrkdwnDialect) link(label, url string) string {
+	url = strings.NewReplacer("|", "%7C", ">", "%3E").Replace(url)
+	if label == mrkdwnEscaper.Replace(url) {
+		return "<" + url + ">"
+	}
+
+	return "<" + url + "|" + strings.ReplaceAll(label, "|", "¦") + ">"
+}
+
+func (mrkdwnDialect) item(number int, c string) string {
+	if number > 0 {
+		return strconv.Itoa(number) + ". " + c
+	}
+
+	return "• " + c
+}
+
+// markdownV2Dialect renders MarkdownV2, the Markdown dialect of Telegram. Telegram rejects messages with unescaped
+// reserved characters, see https://core.telegram.org/bots/api#markdownv2-style.
+type markdownV2Dialect struct{}
+
+// markdownV2Reserved are the characters Telegram requires to be escaped outside of code and links.
+const markdownV2Reserved = "_*[]()~`>#+-=|{}.!\\"
+
+var markdownV2Markers = map[kind]string{boldNode: "*", italicNode: "_", strikeNode: "~"}
+
+func (markdownV2Dialect) text(s string) string {
+	return escapeWith(s, markdownV2Reserved)
+}
+
+func (markdownV2Dialect) code(s string) string {
+	return "`" + escapeWith(s, "`\\") + "`"
+}
+
+func (markdownV2Dialect) link(label, url string) string {
+	return "[" + label + "](" + escapeWith(url, ")\\") + ")"
+}
+func (markdownV2Dialect) lineBreak() string {
+	return "\n"
+}
+
+func (markdownV2Dialect) paragraph(c string) string {
+	return c
+}
+
+func (markdownV2Dialect) heading(_ int, c string) string {
+	return "*" + c + "*"
+}
+
+func (markdownV2Dialect) boldHeadings() {}
+
+func (markdownV2Dialect) codeBlock(code string) string {
+	return "```\n" + escapeWith(code, "`\\") + "\n```"
+}
+func (markdownV2Dialect) quote(c string) string {
+	return prefixLines(c, ">")
+}
+
+func (markdownV2Dialect) list(_ bool, items []string) string {
+	return strings.Join(items, "\n")
+}
+
+func (markdownV2Dialect) separator() string {
+	return "\n\n"
+}
+
+func (markdownV2Dialect) inline(k kind, c string) string {
+	return markdownV2Markers[k] + c + markdownV2Markers[k]
+}
+
+func (markdownV2Dialect) item(number int, c string) string {
+	if number > 0 {
+		return strconv.Itoa(number) + "\\. " + c
+	}
+
+	return "• " + c
+}
+
+// htmlDialect renders HTML.
+type htmlDialect struct{}
+
+var htmlTags = map[kind]string{boldNode: "strong", italicNode: "em", strikeNode: "del"}
+
+func (htmlDialect) text(s string) string {
+	return html.EscapeString(s)
+}
+
+func (htmlDialect) code(s string) string {
+	return "<code>" + html.EscapeString(s) + "</code>"
+}
+
+func (htmlDialect) lineBreak() string {
+	return "<br>\n"
+}
+
+func (htmlDialect) paragraph(c string) string {
+	return "<p>" + c + "</p>"
+}
+
+func (htmlDialect) quote(c string) string {
+	return "<blockquote>" + c + "</blockquote>"
+}
+
+func (htmlDialect) item(_ int, c string) string {
+	return "<li>" + c + "</li>"
+}
+
+func (htmlDialect) separator() string {
+	return "\n"
+}
+
+func (htmlDialect) codeBlock(code string) string {
+	return "<pre><code>" + html.EscapeString(code) + "</code></pre>"
+}
+func (htmlDialect) inline(k kind, c string) string {
+	return "<" + htmlTags[k] + ">" + c + "</" + htmlTags[k] + ">"
+}
+
+func (htmlDialect) link(label, url string) string {
+	return `<a href="` + html.EscapeString(url) + `">` + label + "</a>"
+}
+
+func (htmlDialect) heading(level int, c string) string {
+	tag := "h" + strconv.Itoa(level)
+	return "<" + tag + ">" + c + "</" + tag + ">"
+}
+
+func (htmlDialect) list(ordered bool, items []string) string {
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+
+	return "<" + tag + ">\n" + strings.Join(items, "\n") + "\n</" + tag + ">"
+}
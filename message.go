@@ -0,0 +1,173 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/casdoor/notify/markup"
+	"github.com/casdoor/notify/message"
+)
+
+type (
+	// Message is a rich notification. See the message package for details.
+	Message = message.Message
+
+	// Attachment is a file attached to a Message.
+	Attachment = message.Attachment
+
+	// Link is an action link attached to a Message.
+	Link = message.Link
+
+	// BodyFormat describes the markup language of a Message body.
+	BodyFormat = message.BodyFormat
+
+	// Priority describes how urgent a Message is.
+	Priority = message.Priority
+)
+
+// These are the body formats and priorities of the message package.
+const (
+	FormatPlain      = message.FormatPlain
+	FormatMarkdown   = message.FormatMarkdown
+	FormatHTML       = message.FormatHTML
+	FormatMrkdwn     = message.FormatMrkdwn
+	FormatMarkdownV2 = message.FormatMarkdownV2
+
+	PriorityLow      = message.PriorityLow
+	PriorityNormal   = message.PriorityNormal
+	PriorityHigh     = message.PriorityHigh
+	PriorityCritical = message.PriorityCritical
+)
+
+// NewMessage returns a new Message with the given subject and plain text body.
+func NewMessage(subject, body string) *Message {
+	return message.New(subject, body)
+}
+
+type messageKey struct{}
+
+// contextWithMessage returns a copy of ctx that carries the given message.
+func contextWithMessage(ctx context.Context, m *Message) context.Context {
+	return context.WithValue(ctx, messageKey{}, m)
+}
+
+// MessageFromContext returns the Message that is currently being sent, if any. It's available to every service and to
+// every Notifier wrapping a service, even if they only implement the plain Send method.
+func MessageFromContext(ctx context.Context) (*Message, bool) {
+	m, ok := ctx.Value(messageKey{}).(*Message)
+	return m, ok && m != nil
+}
+
+// Forward sends the given message to a single service, just like Notify does: it renders the template of the message for
+// the service, hands the message to the dry run bound to ctx instead, if any, and binds the message and its receivers to
+// ctx. Services implementing Wrapper use it to pass messages on to the services they wrap.
+func Forward(ctx context.Context, service Notifier, m *Message) error {
+	if service == nil || m == nil {
+		return nil
+	}
+
+	return sendTo(ctx, service, m)
+}
+
+// sendTo sends the given message to a single service. It prefers SendMessage if the service implements the
+// MessageNotifier interface and falls back to Send otherwise. Services only implementing Send get the body in the format
+// they prefer.
+func sendTo(ctx context.Context, service Notifier, m *Message) error {
+	m, err := renderFor(ctx, service, m)
+	if err != nil {
+		return err
+	}
+
+	if dryRunSend(ctx, service, m) {
+		return nil
+	}
+
+	ctx = contextWithMessage(ctx, m)
+	for name, receivers := range m.Receivers {
+		ctx = WithReceivers(ctx, name, receivers...)
+	}
+
+	if mn, ok := service.(MessageNotifier); ok {
+		return mn.SendMessage(ctx, m)
+	}
+
+	return service.Send(ctx, m.Subject, negotiate(service, m))
+}
+
+// negotiate returns the body of m converted to the body format preferred by the given service, or to plain text if it
+// has no preference, see FormatPreferrer. The body is returned unchanged if m doesn't set a body format.
+func negotiate(service Notifier, m *Message) string {
+	if m.Format == "" {
+		return m.Body
+	}
+
+	return markup.Convert(m.Body, m.Format, preferredFormat(service))
+}
+
+// preferredFormat returns the body format preferred by the given service, or plain text if it has no preference.
+func preferredFormat(service Notifier) BodyFormat {
+	if p, ok := service.(FormatPreferrer); ok {
+		return p.PreferredFormat()
+	}
+
+	return FormatPlain
+}
+
+// sendMessage calls the underlying notification services to send the given message to their respective endpoints. In
+// asynchronous mode, the message is only queued, see WithAsync. A nil selector lets the routes pick the services, see
+// WithRoutes.
+func (n *Notify) sendMessage(ctx context.Context, m *Message, selector *Selector) error {
+	if n.Disabled {
+		return nil
+	}
+
+	m, err := render(n.registry(ctx), m, FormatPlain)
+	if err != nil {
+		return err
+	}
+	if dryRunFrom(ctx) != nil {
+		_, err = n.sendWithReport(ctx, m, selector)
+		return err
+	}
+	if selector == nil {
+		// Fail before queuing if the message matches an invalid route.
+		if _, err = n.selector(m); err != nil {
+			return err
+		}
+	}
+	if n.suppress(ctx, m, selector) {
+		n.emit(ctx, Event{Type: EventSuppressed, Message: m})
+		return nil
+	}
+	if n.async != nil {
+		return n.enqueue(ctx, m, selector)
+	}
+
+	_, err = n.sendWithReport(ctx, m, selector)
+
+	return err
+}
+
+// SendMessage calls the underlying notification services to send the given message to their respective endpoints.
+// Services implementing the MessageNotifier interface receive the full message, all others only its subject and body.
+func (n *Notify) SendMessage(ctx context.Context, m *Message) error {
+	if m == nil {
+		return nil
+	}
+
+	return n.sendMessage(ctx, m, nil)
+}
+
+// SendMessageWithReport works like SendMessage, but returns a Report with the outcome of every single service. It
+// always sends synchronously.
+func (n *Notify) SendMessageWithReport(ctx context.Context, m *Message) (*Report, error) {
+	if m == nil {
+		return &Report{}, nil
+	}
+
+	return n.sendWithReport(ctx, m, nil)
+}
+
+// SendMessage calls the underlying notification services to send the given message to their respective endpoints.
+func SendMessage(ctx context.Context, m *Message) error {
+	return std.SendMessage(ctx, m)
+}
@@ -0,0 +1,246 @@
+// Package message defines Message, the rich representation of a notification. Next to a subject and a body, a message
+// carries a body format, a priority, tags, attachments, action links and arbitrary metadata. Services that support
+// these features implement notify.MessageNotifier to receive the full message.
+//
+// This package deliberately doesn't depend on the notify package itself, so that every service is able to use it. The
+// notify package re-exports its types.
+package message
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// BodyFormat describes the markup language of a message body.
+type BodyFormat string
+
+const (
+	// FormatPlain is a body without any markup. It's the default.
+	FormatPlain BodyFormat = "plain"
+	// FormatMarkdown is a body written in Markdown.
+	FormatMarkdown BodyFormat = "markdown"
+	// FormatHTML is a body written in HTML.
+	FormatHTML BodyFormat = "html"
+	// FormatMrkdwn is a body written in mrkdwn, the Markdown dialect of Slack.
+	FormatMrkdwn BodyFormat = "mrkdwn"
+	// FormatMarkdownV2 is a body written in MarkdownV2, the Markdown dialect of Telegram.
+	FormatMarkdownV2 BodyFormat = "markdownv2"
+)
+
+var bodyFormatAliases = map[string]BodyFormat{
+	"txt":  FormatPlain,
+	"text": FormatPlain,
+	"md":   FormatMarkdown,
+	"htm":  FormatHTML,
+}
+
+// ParseBodyFormat returns the body format with the given name, e.g. "markdown". It also accepts the common file
+// extensions "txt", "text", "md" and "htm".
+func ParseBodyFormat(name string) (BodyFormat, error) {
+	name = strings.ToLower(name)
+	switch format := BodyFormat(name); format {
+	case FormatPlain, FormatMarkdown, FormatHTML, FormatMrkdwn, FormatMarkdownV2:
+		return format, nil
+	}
+	if format, ok := bodyFormatAliases[name]; ok {
+		return format, nil
+	}
+
+	return FormatPlain, errors.Errorf("unknown body format %q", name)
+}
+
+// Priority describes how urgent a message is. Services map it to their own priority levels, if they have any.
+type Priority int
+
+const (
+	// PriorityLow is meant for messages that may be delivered silently.
+	PriorityLow Priority = iota - 1
+	// PriorityNormal is the default priority.
+	PriorityNormal
+	// PriorityHigh is meant for messages that need attention soon.
+	PriorityHigh
+	// PriorityCritical is meant for messages that need immediate attention.
+	PriorityCritical
+)
+
+var priorityNames = map[Priority]string{
+	PriorityLow:      "low",
+	PriorityNormal:   "normal",
+	PriorityHigh:     "high",
+	PriorityCritical: "critical",
+}
+
+// String returns the name of the priority, e.g. "high". It implements the fmt.Stringer interface.
+func (p Priority) String() string {
+	if name, ok := priorityNames[p]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+// ParsePriority returns the priority with the given name. It's the inverse of Priority.String.
+func ParsePriority(name string) (Priority, error) {
+	for p, n := range priorityNames {
+		if strings.EqualFold(n, name) {
+			return p, nil
+		}
+	}
+
+	return PriorityNormal, errors.Errorf("unknown priority %q", name)
+}
+
+// MarshalText encodes the priority as its name. It implements the encoding.TextMarshaler interface.
+func (p Priority) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText decodes a priority from its name. It implements the encoding.TextUnmarshaler interface.
+func (p *Priority) UnmarshalText(text []byte) error {
+	parsed, err := ParsePriority(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+
+	return nil
+}
+
+// Attachment is a file attached to a message. Either Data or URL should be set; services that can only handle one of
+// them skip attachments they can't deliver.
+type Attachment struct {
+	// Name is the file name of the attachment.
+	Name string `json:"name,omitempty"`
+	// ContentType is the MIME type of the attachment, e.g. "image/png".
+	ContentType string `json:"content_type,omitempty"`
+	// Data is the content of the attachment.
+	Data []byte `json:"data,omitempty"`
+	// URL points to the content of the attachment.
+	URL string `json:"url,omitempty"`
+}
+
+// Link is an action link attached to a message, e.g. a link to a dashboard.
+type Link struct {
+	// Title is the text of the link. It may be empty.
+	Title string `json:"title,omitempty"`
+	// URL is the target of the link.
+	URL string `json:"url"`
+}
+
+// Message is a rich notification.
+type Message struct {
+	// Subject is the title of the message.
+	Subject string `json:"subject"`
+	// Body is the content of the message.
+	Body string `json:"body"`
+	// Format is the markup language of the body. Services convert the body to the markup language of their platform,
+	// see the markup package. The zero value is treated as FormatPlain by BodyFormat, but services send bodies without
+	// format as is, using the markup they were configured with, e.g. the parse mode of Telegram.
+	Format BodyFormat `json:"format,omitempty"`
+	// Priority is the urgency of the message.
+	Priority Priority `json:"priority,omitempty"`
+	// Tags are free-form labels of the message.
+	Tags []string `json:"tags,omitempty"`
+	// Attachments are files attached to the message.
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// Links are action links attached to the message.
+	Links []Link `json:"links,omitempty"`
+	// Receivers replace the configured receivers of the services named by the keys, e.g. "slack" or "telegram", for
+	// this message only. See receiver.Receiver for the format of typed receivers.
+	Receivers map[string][]string `json:"receivers,omitempty"`
+	// Template names a template that replaces the subject and the body, rendered in the body format each service
+	// prefers. See notify.WithTemplates.
+	Template string `json:"template,omitempty"`
+	// Data is passed to the template when it's rendered. Like Metadata, it loses its Go type when the message is
+	// queued.
+	Data any `json:"data,omitempty"`
+	// Metadata holds arbitrary, service specific data. Services document the keys they understand. Keep in mind that
+	// values lose their Go type when a message is queued, see notify.WithAsync.
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// New returns a new message with the given subject and plain text body.
+func New(subject, body string) *Message {
+	return &Message{Subject: subject, Body: body}
+}
+
+// BodyFormat returns the format of the body, defaulting to FormatPlain.
+func (m *Message) BodyFormat() BodyFormat {
+	if m.Format == "" {
+		return FormatPlain
+	}
+
+	return m.Format
+}
+
+// Text returns the subject and the body separated by a newline. It's the representation used by services that don't
+// know the concept of a subject.
+func (m *Message) Text() string {
+	return m.Subject + "\n" + m.Body
+}
+
+// HasTag reports whether the message is tagged with tag.
+func (m *Message) HasTag(tag string) bool {
+	for _, t := range m.Tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MetadataString returns the metadata value stored under key if it's a string.
+func (m *Message) MetadataString(key string) (string, bool) {
+	value, ok := m.Metadata[key].(string)
+	return value, ok
+}
+
+// MetadataInt returns the metadata value stored under key if it's a number. Floating point numbers, as produced by
+// decoding JSON, are truncated.
+func (m *Message) MetadataInt(key string) (int, bool) {
+	switch value := m.Metadata[key].(type) {
+	case int:
+		return value, true
+	case int64:
+		return int(value), true
+	case float64:
+		return int(value), true
+	default:
+		return 0, false
+	}
+}
+
+// Clone returns a deep copy of the message, except for the metadata values, which are copied shallowly.
+func (m *Message) Clone() *Message {
+	if m == nil {
+		return nil
+	}
+
+	clone := *m
+	clone.Tags = append([]string(nil), m.Tags...)
+	clone.Links = append([]Link(nil), m.Links...)
+	clone.Attachments = make([]Attachment, len(m.Attachments))
+	for i, a := range m.Attachments {
+		a.Data = append([]byte(nil), a.Data...)
+		clone.Attachments[i] = a
+	}
+	if m.Attachments == nil {
+		clone.Attachments = nil
+	}
+	if m.Receivers != nil {
+		clone.Receivers = make(map[string][]string, len(m.Receivers))
+		for k, v := range m.Receivers {
+			clone.Receivers[k] = append([]string(nil), v...)
+		}
+	}
+	if m.Metadata != nil {
+		clone.Metadata = make(map[string]any, len(m.Metadata))
+		for k, v := range m.Metadata {
+			clone.Metadata[k] = v
+		}
+	}
+
+	return &clone
+}
@@ -0,0 +1,128 @@
+package message
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPriority_Text(t *testing.T) {
+	t.Parallel()
+
+	for _, p := range []Priority{PriorityLow, PriorityNormal, PriorityHigh, PriorityCritical} {
+		text, err := p.MarshalText()
+		if err != nil {
+			t.Fatalf("MarshalText(%d) unexpected error: %v", p, err)
+		}
+
+		var parsed Priority
+		if err := parsed.UnmarshalText(text); err != nil {
+			t.Fatalf("UnmarshalText(%q) unexpected error: %v", text, err)
+		}
+		if parsed != p {
+			t.Errorf("UnmarshalText(%q) = %d, want %d", text, parsed, p)
+		}
+	}
+
+	if _, err := ParsePriority("urgent"); err == nil {
+		t.Error("ParsePriority(\"urgent\") was expected to fail")
+	}
+	if p, err := ParsePriority("HIGH"); err != nil || p != PriorityHigh {
+		t.Errorf("ParsePriority(\"HIGH\") = %v, %v", p, err)
+	}
+}
+
+func TestMessage_JSON(t *testing.T) {
+	t.Parallel()
+
+	m := &Message{
+		Subject:     "subject",
+		Body:        "*body*",
+		Format:      FormatMarkdown,
+		Priority:    PriorityCritical,
+		Tags:        []string{"ops"},
+		Attachments: []Attachment{{Name: "a.txt", ContentType: "text/plain", Data: []byte("a")}},
+		Links:       []Link{{Title: "Dashboard", URL: "https://example.com"}},
+		Metadata:    map[string]any{"badge": 3},
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error: %v", err)
+	}
+
+	var decoded Message
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() unexpected error: %v", err)
+	}
+	if decoded.Priority != PriorityCritical || decoded.Format != FormatMarkdown || !decoded.HasTag("ops") {
+		t.Errorf("json round trip lost fields: %+v", decoded)
+	}
+	if string(decoded.Attachments[0].Data) != "a" || decoded.Links[0].URL != "https://example.com" {
+		t.Errorf("json round trip lost attachments or links: %+v", decoded)
+	}
+	if badge, ok := decoded.MetadataInt("badge"); !ok || badge != 3 {
+		t.Errorf("MetadataInt(\"badge\") = %d, %v", badge, ok)
+	}
+}
+
+func TestMessage_Defaults(t *testing.T) {
+	t.Parallel()
+
+	m := New("subject", "body")
+	if m.BodyFormat() != FormatPlain {
+		t.Errorf("BodyFormat() = %q, want %q", m.BodyFormat(), FormatPlain)
+	}
+	if m.Priority != PriorityNormal {
+		t.Errorf("Priority = %v, want %v", m.Priority, PriorityNormal)
+	}
+	if m.Text() != "subject\nbody" {
+		t.Errorf("Text() = %q", m.Text())
+	}
+	if _, ok := m.MetadataString("missing"); ok {
+		t.Error("MetadataString() found a missing key")
+	}
+}
+
+func TestMessage_Clone(t *testing.T) {
+	t.Parallel()
+
+	m := &Message{
+		Tags:        []string{"a"},
+		Attachments: []Attachment{{Data: []byte("a")}},
+		Metadata:    map[string]any{"k": "v"},
+	}
+
+	clone := m.Clone()
+	clone.Tags[0] = "b"
+	clone.Attachments[0].Data[0] = 'b'
+	clone.Metadata["k"] = "w"
+
+	if m.Tags[0] != "a" || string(m.Attachments[0].Data) != "a" || m.Metadata["k"] != "v" {
+		t.Errorf("Clone() shares state with the original: %+v", m)
+	}
+	if (*Message)(nil).Clone() != nil {
+		t.Error("Clone() of nil message was expected to return nil")
+	}
+}
+
+func TestParseBodyFormat(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]BodyFormat{
+		"plain":      FormatPlain,
+		"txt":        FormatPlain,
+		"Markdown":   FormatMarkdown,
+		"md":         FormatMarkdown,
+		"html":       FormatHTML,
+		"mrkdwn":     FormatMrkdwn,
+		"MarkdownV2": FormatMarkdownV2,
+	}
+	for name, want := range tests {
+		if got, err := ParseBodyFormat(name); err != nil || got != want {
+			t.Errorf("ParseBodyFormat(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseBodyFormat("rtf"); err == nil {
+		t.Error("ParseBodyFormat(\"rtf\") was expected to fail")
+	}
+}
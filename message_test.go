@@ -0,0 +1,149 @@
+package notify
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/casdoor/notify/queue"
+)
+
+// messageNotifierFunc is a test helper that turns a function into a MessageNotifier.
+type messageNotifierFunc func(ctx context.Context, m *Message) error
+
+func (f messageNotifierFunc) Send(context.Context, string, string) error {
+	panic("Send must not be called on a MessageNotifier")
+}
+
+func (f messageNotifierFunc) SendMessage(ctx context.Context, m *Message) error {
+	return f(ctx, m)
+}
+
+func TestSendMessage(t *testing.T) {
+	t.Parallel()
+
+	msg := NewMessage("subject", "body")
+	msg.Priority = PriorityHigh
+	msg.Tags = []string{"ops"}
+
+	var rich, plain *Message
+	var plainSubject, plainBody string
+
+	n := NewWithServices(
+		messageNotifierFunc(func(_ context.Context, m *Message) error {
+			rich = m
+			return nil
+		}),
+		NotifierFunc(func(ctx context.Context, subject, body string) error {
+			plainSubject, plainBody = subject, body
+			plain, _ = MessageFromContext(ctx)
+			return nil
+		}),
+	)
+
+	if err := n.SendMessage(context.Background(), msg); err != nil {
+		t.Fatalf("SendMessage() unexpected error: %v", err)
+	}
+	if rich != msg {
+		t.Error("MessageNotifier did not receive the message")
+	}
+	if plainSubject != "subject" || plainBody != "body" {
+		t.Errorf("Notifier received %q, %q", plainSubject, plainBody)
+	}
+	if plain != msg {
+		t.Error("MessageFromContext() did not return the message")
+	}
+
+	if err := n.SendMessage(context.Background(), nil); err != nil {
+		t.Errorf("SendMessage(nil) unexpected error: %v", err)
+	}
+}
+
+func TestSendMessage_Async(t *testing.T) {
+	t.Parallel()
+
+	received := make(chan *Message, 1)
+
+	n := NewWithOptions(WithAsync(queue.NewMemory(10), 1))
+	n.UseServices(messageNotifierFunc(func(_ context.Context, m *Message) error {
+		received <- m
+		return nil
+	}))
+
+	msg := NewMessage("subject", "body")
+	msg.Priority = PriorityCritical
+	msg.Links = []Link{{URL: "https://example.com"}}
+	msg.Metadata = map[string]any{"badge": 1}
+
+	if err := n.SendMessage(context.Background(), msg); err != nil {
+		t.Fatalf("SendMessage() unexpected error: %v", err)
+	}
+	if err := n.Close(context.Background()); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+
+	m := <-received
+	if m.Priority != PriorityCritical || len(m.Links) != 1 || m.Links[0].URL != "https://example.com" {
+		t.Errorf("queued message lost fields: %+v", m)
+	}
+	if badge, ok := m.MetadataInt("badge"); !ok || badge != 1 {
+		t.Errorf("queued message lost metadata: %+v", m.Metadata)
+	}
+}
+
+// preferringNotifier is a Send-only service that prefers a body format.
+type preferringNotifier struct {
+	NotifierFunc
+	format BodyFormat
+}
+
+func (p preferringNotifier) PreferredFormat() BodyFormat {
+	return p.format
+}
+
+func TestSendMessage_NegotiatesBodyFormat(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	received := make(map[string]string)
+	record := func(name string) NotifierFunc {
+		return func(_ context.Context, _, message string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			received[name] = message
+			return nil
+		}
+	}
+
+	n := New()
+	n.UseServices(
+		record("plain"),
+		preferringNotifier{NotifierFunc: record("slack"), format: FormatMrkdwn},
+		preferringNotifier{NotifierFunc: record("html"), format: FormatHTML},
+	)
+
+	m := NewMessage("subject", "**a** < b")
+	m.Format = FormatMarkdown
+	if err := n.SendMessage(context.Background(), m); err != nil {
+		t.Fatalf("SendMessage() unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"plain": "a < b",
+		"slack": "*a* &lt; b",
+		"html":  "<p><strong>a</strong> &lt; b</p>",
+	}
+	for name, body := range want {
+		if received[name] != body {
+			t.Errorf("service %s received %q, want %q", name, received[name], body)
+		}
+	}
+
+	// Without body format, services get the body as is.
+	if err := n.Send(context.Background(), "subject", "**a** < b"); err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+	if received["slack"] != "**a** < b" {
+		t.Errorf("service received %q, want the unchanged body", received["slack"])
+	}
+}
@@ -0,0 +1,163 @@
+// Package prometheus exports metrics of notify deliveries to Prometheus. The Collector listens to the events of a
+// notify.Notify instance and implements prometheus.Collector:
+//
+//	collector := prometheus.NewCollector(prometheus.WithQueue(q))
+//	registry.MustRegister(collector)
+//	n := notify.NewWithOptions(notify.WithAsync(q, 4), notify.WithListener(collector.Listen))
+//
+// It exports the following metrics:
+//
+//   - notify_messages_total{service,status}: the messages sent by every service; status is "sent" or "failed".
+//   - notify_send_duration_seconds{service}: the time it took every service to send a message, including retries.
+//   - notify_queue_depth: the number of messages waiting in the queue of the asynchronous mode, see notify.WithAsync
+//     and WithQueue.
+//   - notify_retries_total{service}: the attempts of every service that were retried, see notify.WithRetry.
+//   - notify_suppressed_total: the repeated messages that were suppressed, see notify.WithDedup.
+package prometheus
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/casdoor/notify"
+	"github.com/casdoor/notify/queue"
+)
+
+// Labels of the metrics.
+const (
+	labelService = "service"
+	labelStatus  = "status"
+)
+
+// Compile-time check to ensure that Collector implements the prometheus.Collector interface.
+var _ prometheus.Collector = (*Collector)(nil)
+
+// Collector collects the metrics of notify deliveries. Pass its Listen method to notify.WithListener to feed it, and
+// register it with a prometheus.Registerer to export the metrics.
+type Collector struct {
+	buckets     []float64
+	constLabels prometheus.Labels
+	queue       queue.Queue
+	// lastDepth is the queue depth reported by the latest event, used without queue.
+	lastDepth atomic.Int64
+
+	messages   *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+	queueDepth prometheus.GaugeFunc
+	retries    *prometheus.CounterVec
+	suppressed prometheus.Counter
+}
+
+// Option configures a Collector, see NewCollector.
+type Option func(*Collector)
+
+// WithBuckets is an Option that sets the buckets of the notify_send_duration_seconds histogram, in seconds. By default,
+// prometheus.DefBuckets are used.
+func WithBuckets(buckets ...float64) Option {
+	return func(c *Collector) {
+		if len(buckets) > 0 {
+			c.buckets = buckets
+		}
+	}
+}
+
+// WithConstLabels is an Option that adds the given labels to all metrics, e.g. to tell several Notify instances apart
+// that are collected by separate Collectors.
+func WithConstLabels(labels prometheus.Labels) Option {
+	return func(c *Collector) {
+		c.constLabels = labels
+	}
+}
+
+// WithQueue is an Option that makes notify_queue_depth report the length of the given queue, the one passed to
+// notify.WithAsync, at the time of the scrape. Without it, the depth is the one reported by the latest enqueued or
+// dequeued event, which stays as is while nothing is sent and may lag behind with several workers.
+func WithQueue(q queue.Queue) Option {
+	return func(c *Collector) {
+		c.queue = q
+	}
+}
+
+// NewCollector returns a new Collector with the given options.
+func NewCollector(options ...Option) *Collector {
+	c := &Collector{buckets: prometheus.DefBuckets}
+	for _, option := range options {
+		if option != nil {
+			option(c)
+		}
+	}
+
+	c.messages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:        "notify_messages_total",
+		Help:        "Number of messages sent by a service, by status.",
+		ConstLabels: c.constLabels,
+	}, []string{labelService, labelStatus})
+	c.duration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:        "notify_send_duration_seconds",
+		Help:        "Time it took a service to send a message, including all retries.",
+		Buckets:     c.buckets,
+		ConstLabels: c.constLabels,
+	}, []string{labelService})
+	c.queueDepth = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "notify_queue_depth",
+		Help:        "Number of messages waiting in the queue of the asynchronous mode.",
+		ConstLabels: c.constLabels,
+	}, c.depth)
+	c.retries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:        "notify_retries_total",
+		Help:        "Number of retried attempts of a service.",
+		ConstLabels: c.constLabels,
+	}, []string{labelService})
+	c.suppressed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name:        "notify_suppressed_total",
+		Help:        "Number of repeated messages that were suppressed.",
+		ConstLabels: c.constLabels,
+	})
+
+	return c
+}
+
+// Listen updates the metrics with the given event. It's a notify.Listener.
+func (c *Collector) Listen(_ context.Context, e notify.Event) {
+	switch e.Type {
+	case notify.EventSent, notify.EventFailed:
+		c.messages.WithLabelValues(e.Service, string(e.Type)).Inc()
+		c.duration.WithLabelValues(e.Service).Observe(e.Duration.Seconds())
+		if e.Attempts > 1 {
+			c.retries.WithLabelValues(e.Service).Add(float64(e.Attempts - 1))
+		}
+	case notify.EventSuppressed:
+		c.suppressed.Inc()
+	case notify.EventEnqueued, notify.EventDequeued:
+		c.lastDepth.Store(int64(e.QueueDepth))
+	}
+}
+
+// depth returns the number of messages waiting in the queue.
+func (c *Collector) depth() float64 {
+	if c.queue != nil {
+		return float64(c.queue.Len())
+	}
+
+	return float64(c.lastDepth.Load())
+}
+
+// Describe sends the descriptors of all metrics to ch. It implements the prometheus.Collector interface.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.messages.Describe(ch)
+	c.duration.Describe(ch)
+	c.queueDepth.Describe(ch)
+	c.retries.Describe(ch)
+	c.suppressed.Describe(ch)
+}
+
+// Collect sends all metrics to ch. It implements the prometheus.Collector interface.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.messages.Collect(ch)
+	c.duration.Collect(ch)
+	c.queueDepth.Collect(ch)
+	c.retries.Collect(ch)
+	c.suppressed.Collect(ch)
+}
@@ -0,0 +1,109 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/casdoor/notify"
+	"github.com/casdoor/notify/queue"
+	"github.com/casdoor/notify/retry"
+)
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+
+	collector := NewCollector(WithBuckets(1, 10), WithConstLabels(prometheus.Labels{"instance": "test"}))
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	calls := 0
+	n := notify.NewWithOptions(
+		notify.WithListener(collector.Listen),
+		notify.WithRetry(retry.Policy{MaxAttempts: 3}),
+		notify.WithDedup(time.Hour),
+	)
+	n.UseNamedService("ok", notify.NotifierFunc(func(context.Context, string, string) error {
+		return nil
+	}))
+	n.UseNamedService("flaky", notify.NotifierFunc(func(context.Context, string, string) error {
+		calls++
+		if calls < 2 {
+			return retry.Transient(errors.New("unavailable"))
+		}
+		return nil
+	}))
+	n.UseNamedService("down", notify.NotifierFunc(func(context.Context, string, string) error {
+		return errors.New("down")
+	}))
+
+	require.Error(t, n.Send(context.Background(), "subject", "message"))
+	require.NoError(t, n.Send(context.Background(), "subject", "message"))
+	require.NoError(t, n.Send(context.Background(), "subject", "message"))
+
+	expected := `
+# HELP notify_messages_total Number of messages sent by a service, by status.
+# TYPE notify_messages_total counter
+notify_messages_total{instance="test",service="down",status="failed"} 1
+notify_messages_total{instance="test",service="flaky",status="sent"} 1
+notify_messages_total{instance="test",service="ok",status="sent"} 1
+# HELP notify_retries_total Number of retried attempts of a service.
+# TYPE notify_retries_total counter
+notify_retries_total{instance="test",service="down"} 2
+notify_retries_total{instance="test",service="flaky"} 1
+# HELP notify_suppressed_total Number of repeated messages that were suppressed.
+# TYPE notify_suppressed_total counter
+notify_suppressed_total{instance="test"} 2
+# HELP notify_queue_depth Number of messages waiting in the queue of the asynchronous mode.
+# TYPE notify_queue_depth gauge
+notify_queue_depth{instance="test"} 0
+`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"notify_messages_total", "notify_retries_total", "notify_suppressed_total", "notify_queue_depth"))
+
+	require.Equal(t, 3, testutil.CollectAndCount(collector, "notify_send_duration_seconds"))
+}
+
+func TestCollector_QueueDepth(t *testing.T) {
+	t.Parallel()
+
+	q := queue.NewMemory(10)
+	collector := NewCollector(WithQueue(q))
+	n := notify.NewWithOptions(notify.WithAsync(q, 1), notify.WithListener(collector.Listen))
+
+	block := make(chan struct{})
+	n.UseServices(notify.NotifierFunc(func(context.Context, string, string) error {
+		<-block
+		return nil
+	}))
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, n.Send(context.Background(), "subject", "message"))
+	}
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(collector.queueDepth) == 2
+	}, time.Second, time.Millisecond)
+
+	close(block)
+	require.NoError(t, n.Close(context.Background()))
+	require.Equal(t, float64(0), testutil.ToFloat64(collector.queueDepth))
+}
+
+func TestCollector_QueueDepthAtScrape(t *testing.T) {
+	t.Parallel()
+
+	q := queue.NewMemory(10)
+	collector := NewCollector(WithQueue(q))
+
+	// The depth is read from the queue when it's scraped, no matter which events were seen.
+	require.NoError(t, q.Put(context.Background(), queue.Item{Payload: []byte("{}")}))
+	require.Equal(t, float64(1), testutil.ToFloat64(collector.queueDepth))
+	collector.Listen(context.Background(), notify.Event{Type: notify.EventDequeued, QueueDepth: 0})
+	require.Equal(t, float64(1), testutil.ToFloat64(collector.queueDepth))
+}
@@ -0,0 +1,279 @@
+package notify
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/casdoor/notify/logging"
+)
+
+// Middleware wraps a Notifier to add behavior around its sends, e.g. logging, redaction or subject prefixing. The
+// returned Notifier should call next to pass the message on; by not calling it, the middleware short-circuits the send.
+//
+// Middleware only sees the subject and the body of a message. The full Message is available through
+// MessageFromContext, and changes to the subject or the body that a middleware passes on to next are applied to it
+// before it reaches a service implementing MessageNotifier. Middleware may implement MessageNotifier itself to get and
+// pass on the full message instead.
+type Middleware func(next Notifier) Notifier
+
+// NotifierFunc turns a function into a Notifier. It comes in handy for writing Middleware.
+type NotifierFunc func(ctx context.Context, subject, message string) error
+
+// Send calls f.
+func (f NotifierFunc) Send(ctx context.Context, subject, message string) error {
+	return f(ctx, subject, message)
+}
+
+// Use adds the given middleware to the chain that is wrapped around every single service. The first middleware added is
+// the outermost one, i.e. it sees a send first and its result last:
+//
+//	n.Use(a, b)
+//	n.Use(c)
+//	// A send to service s runs a(b(c(s))).
+//
+// If a middleware short-circuits by not calling next, neither the remaining middleware nor the service are called, and
+// whatever the middleware returned is reported as the result of the service. The chain runs separately for every
+// service and every retry attempt, see WithRetry. Middleware added after a send has started doesn't apply to that send.
+//
+// The chain of a service is built on its first send and reused afterwards, so middleware may keep state per service,
+// like Batch does. Adding middleware rebuilds all chains: the layers of the old chains that buffer messages are flushed
+// before they're dropped, so that no message is lost. Flush errors are logged, see WithLogger.
+func (n *Notify) Use(middleware ...Middleware) {
+	if n.middleware == nil {
+		n.middleware = &chain{}
+	}
+
+	c := n.middleware
+	c.mu.Lock()
+	for _, m := range middleware {
+		if m != nil {
+			c.middleware = append(c.middleware, m)
+		}
+	}
+	old := c.flushers
+	c.wrapped = nil
+	c.flushers = nil
+	c.mu.Unlock()
+
+	ctx := logging.WithLogger(context.Background(), n.logger)
+	for _, flushers := range old {
+		for i := len(flushers) - 1; i >= 0; i-- {
+			if err := flushers[i].Flush(ctx); err != nil {
+				logging.FromContext(ctx).LogAttrs(ctx, slog.LevelError, "failed to flush middleware",
+					logging.ErrorAttrs(err)...)
+			}
+		}
+	}
+}
+
+// Use adds the given middleware to the chain that is wrapped around every single service.
+func Use(middleware ...Middleware) {
+	std.Use(middleware...)
+}
+
+// chain holds the middleware of a Notify instance and the chains built from it.
+type chain struct {
+	mu         sync.Mutex
+	middleware []Middleware
+	wrapped    map[int]Notifier
+	flushers   map[int][]flusher
+}
+
+// chained reports whether the services are wrapped by middleware.
+func (n *Notify) chained() bool {
+	c := n.middleware
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.middleware) > 0
+}
+
+// flusher is implemented by services and middleware that buffer messages, like Batcher and Notify.
+type flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// wrap returns the service at index i wrapped by the middleware chain.
+func (n *Notify) wrap(i int, service Notifier) Notifier {
+	c := n.middleware
+	if c == nil {
+		return service
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if wrapped, ok := c.wrapped[i]; ok {
+		return wrapped
+	}
+
+	wrapped, flushers := buildChain(service, c.middleware)
+	if c.wrapped == nil {
+		c.wrapped = make(map[int]Notifier)
+		c.flushers = make(map[int][]flusher)
+	}
+	c.wrapped[i] = wrapped
+	c.flushers[i] = flushers
+
+	return wrapped
+}
+
+// buildChain returns the service wrapped by the given middleware, the first one being the outermost, along with the
+// layers that buffer messages, innermost first, so that they can be flushed from the outside in.
+func buildChain(service Notifier, middleware []Middleware) (Notifier, []flusher) {
+	var flushers []flusher
+	wrapped := Notifier(messageService{service})
+	for i := len(middleware) - 1; i >= 0; i-- {
+		if middleware[i] == nil {
+			continue
+		}
+		if next := middleware[i](wrapped); next != nil {
+			wrapped = next
+			if f, ok := next.(flusher); ok {
+				flushers = append(flushers, f)
+			}
+		}
+	}
+
+	return wrapped, flushers
+}
+
+// Chain returns the service wrapped by the given middleware, the first one being the outermost, like Use does for all
+// services of a Notify instance. Use it to wrap a single service in middleware of its own.
+//
+// Unlike the bare middleware, the chain keeps the service visible: it implements Wrapper and, if the service
+// implements Checker, Checker, and it flushes the layers buffering messages, like Batch, on Close. Dry runs report the
+// message the service would have been sent after all middleware applied.
+func Chain(service Notifier, middleware ...Middleware) Notifier {
+	outer, flushers := buildChain(service, middleware)
+	if f, ok := service.(flusher); ok {
+		flushers = append([]flusher{f}, flushers...)
+	}
+
+	c := chainService{service: service, outer: outer, flushers: flushers}
+	if checker, ok := service.(Checker); ok {
+		return checkedChain{chainService: c, checker: checker}
+	}
+
+	return c
+}
+
+// chainService is a service wrapped by middleware of its own, see Chain.
+type chainService struct {
+	service  Notifier
+	outer    Notifier
+	flushers []flusher
+}
+
+// Send sends the message from the context, updated with the given subject and body, through the middleware.
+func (s chainService) Send(ctx context.Context, subject, message string) error {
+	return s.SendMessage(ctx, messageFor(ctx, subject, message))
+}
+
+// SendMessage sends the given message through the middleware.
+func (s chainService) SendMessage(ctx context.Context, m *Message) error {
+	return sendTo(enterChain(ctx), s.outer, m)
+}
+
+// Unwrap returns the service. It implements Wrapper.
+func (s chainService) Unwrap() []Notifier {
+	return []Notifier{s.service}
+}
+
+// Flush flushes the service and the middleware buffering messages, from the outside in.
+func (s chainService) Flush(ctx context.Context) error {
+	var errs []error
+	for i := len(s.flushers) - 1; i >= 0; i-- {
+		if err := s.flushers[i].Flush(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return &SendError{Errors: errs}
+	}
+
+	return nil
+}
+
+// checkedChain is a chain whose service implements Checker.
+type checkedChain struct {
+	chainService
+	checker Checker
+}
+
+// Check checks the service behind the middleware.
+func (s checkedChain) Check(ctx context.Context) error {
+	return s.checker.Check(ctx)
+}
+
+// flushServices flushes all services and middleware that buffer messages, see Batch. It returns the errors of all of
+// them.
+func (n *Notify) flushServices(ctx context.Context) error {
+	var errs []error
+	for i, service := range n.notifiers {
+		var flushers []flusher
+		if c := n.middleware; c != nil {
+			c.mu.Lock()
+			flushers = append(flushers, c.flushers[i]...)
+			c.mu.Unlock()
+		}
+		if f, ok := service.(flusher); ok {
+			flushers = append([]flusher{f}, flushers...)
+		}
+
+		for j := len(flushers) - 1; j >= 0; j-- {
+			if err := flushers[j].Flush(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return &SendError{Errors: errs}
+	}
+
+	return nil
+}
+
+// messageService sits between the middleware chain and a service. It restores the full message for services
+// implementing MessageNotifier when a middleware only passed on the subject and the body.
+type messageService struct {
+	service Notifier
+}
+
+// Send sends the message from the context, updated with the given subject and body, to the service.
+func (s messageService) Send(ctx context.Context, subject, message string) error {
+	return s.SendMessage(ctx, messageFor(ctx, subject, message))
+}
+
+// SendMessage sends the given message to the service.
+func (s messageService) SendMessage(ctx context.Context, m *Message) error {
+	return sendTo(leaveChain(ctx), s.service, m)
+}
+
+// Unwrap returns the service. It implements Wrapper.
+func (s messageService) Unwrap() []Notifier {
+	return []Notifier{s.service}
+}
+
+// messageFor returns the message from the context with the given subject and body. It returns a copy if the subject or
+// the body differ, and a new message if there is no message in the context.
+func messageFor(ctx context.Context, subject, body string) *Message {
+	m, ok := MessageFromContext(ctx)
+	if !ok {
+		return NewMessage(subject, body)
+	}
+	if m.Subject == subject && m.Body == body {
+		return m
+	}
+
+	m = m.Clone()
+	m.Subject, m.Body = subject, body
+	m.Template = "" // The subject and the body were changed on purpose, don't render them again.
+
+	return m
+}
@@ -0,0 +1,224 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/casdoor/notify/retry"
+)
+
+// recordingMiddleware returns a middleware that appends its name to calls before and after calling next.
+func recordingMiddleware(name string, calls *[]string) Middleware {
+	return func(next Notifier) Notifier {
+		return NotifierFunc(func(ctx context.Context, subject, message string) error {
+			*calls = append(*calls, name+">")
+			err := next.Send(ctx, subject, message)
+			*calls = append(*calls, "<"+name)
+			return err
+		})
+	}
+}
+
+func TestUse(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+	n := NewWithServices(NotifierFunc(func(context.Context, string, string) error {
+		calls = append(calls, "service")
+		return nil
+	}))
+	n.Use(recordingMiddleware("a", &calls), recordingMiddleware("b", &calls))
+	n.Use(recordingMiddleware("c", &calls))
+
+	if err := n.Send(context.Background(), "subject", "message"); err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+
+	want := "a> b> c> service <c <b <a"
+	if got := strings.Join(calls, " "); got != want {
+		t.Errorf("Send() called %q, want %q", got, want)
+	}
+}
+
+func TestUse_ShortCircuit(t *testing.T) {
+	t.Parallel()
+
+	errBlocked := errors.New("blocked")
+	called := false
+
+	n := NewWithServices(NotifierFunc(func(context.Context, string, string) error {
+		called = true
+		return nil
+	}))
+	n.Use(func(next Notifier) Notifier {
+		return NotifierFunc(func(context.Context, string, string) error {
+			return errBlocked
+		})
+	})
+
+	report, err := n.SendWithReport(context.Background(), "subject", "message")
+	if !errors.Is(err, errBlocked) {
+		t.Errorf("SendWithReport() error = %v, want %v", err, errBlocked)
+	}
+	if called {
+		t.Error("the service was called although the middleware short-circuited")
+	}
+	if len(report.Failed()) != 1 {
+		t.Errorf("SendWithReport() was expected to report 1 failed service but reported %d", len(report.Failed()))
+	}
+}
+
+func TestUse_MessageNotifier(t *testing.T) {
+	t.Parallel()
+
+	var got *Message
+	n := NewWithServices(messageNotifierFunc(func(_ context.Context, m *Message) error {
+		got = m
+		return nil
+	}))
+	n.Use(func(next Notifier) Notifier {
+		return NotifierFunc(func(ctx context.Context, subject, message string) error {
+			return next.Send(ctx, "[prod] "+subject, message)
+		})
+	})
+
+	msg := NewMessage("subject", "message")
+	msg.Priority = PriorityHigh
+	if err := n.SendMessage(context.Background(), msg); err != nil {
+		t.Fatalf("SendMessage() unexpected error: %v", err)
+	}
+
+	if got == nil || got.Subject != "[prod] subject" || got.Priority != PriorityHigh {
+		t.Errorf("MessageNotifier received %+v", got)
+	}
+	if msg.Subject != "subject" {
+		t.Errorf("the middleware modified the original message: %+v", msg)
+	}
+}
+
+func TestUse_Retry(t *testing.T) {
+	t.Parallel()
+
+	attempts := 0
+	n := NewWithOptions(WithRetry(retry.Policy{MaxAttempts: 3}))
+	n.UseServices(NotifierFunc(func(context.Context, string, string) error {
+		return errors.New("failure")
+	}))
+	n.Use(func(next Notifier) Notifier {
+		return NotifierFunc(func(ctx context.Context, subject, message string) error {
+			attempts++
+			return next.Send(ctx, subject, message)
+		})
+	})
+
+	_ = n.Send(context.Background(), "subject", "message")
+	if attempts != 3 {
+		t.Errorf("the middleware ran %d times, want once per attempt (3)", attempts)
+	}
+}
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+	service := checkedNotifier{NotifierFunc: func(_ context.Context, subject, _ string) error {
+		calls = append(calls, "service "+subject)
+		return nil
+	}}
+	prefix := func(next Notifier) Notifier {
+		return NotifierFunc(func(ctx context.Context, subject, message string) error {
+			return next.Send(ctx, "[prod] "+subject, message)
+		})
+	}
+
+	n := New()
+	n.UseNamedService("ops", Chain(service, recordingMiddleware("a", &calls), prefix))
+	if err := n.Send(context.Background(), "subject", "message"); err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+	want := "a> service [prod] subject <a"
+	if got := strings.Join(calls, " "); got != want {
+		t.Errorf("Send() called %q, want %q", got, want)
+	}
+
+	// Dry runs report the service by its name, after the middleware applied.
+	calls = nil
+	var got []string
+	ctx := WithDryRun(context.Background(), func(name string, m *Message) {
+		got = append(got, name+": "+m.Subject)
+	})
+	if err := n.Send(ctx, "subject", "message"); err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0] != "ops: [prod] subject" {
+		t.Errorf("dry run got %q", got)
+	}
+	if strings.Join(calls, " ") != "a> <a" {
+		t.Errorf("dry run called %q", calls)
+	}
+
+	// The service stays checkable.
+	errInvalid := errors.New("invalid token")
+	service.err = errInvalid
+	n = NewWithServices(Chain(service, prefix))
+	if err := n.Check(context.Background()); !errors.Is(err, errInvalid) {
+		t.Errorf("Check() error = %v, want %v", err, errInvalid)
+	}
+	if _, ok := Chain(NotifierFunc(nil), prefix).(Checker); ok {
+		t.Error("Chain() of a service without Check implements Checker")
+	}
+}
+
+func TestChain_Flush(t *testing.T) {
+	t.Parallel()
+
+	c := &collector{}
+	prefix := func(next Notifier) Notifier {
+		return NotifierFunc(func(ctx context.Context, subject, message string) error {
+			return next.Send(ctx, "[prod] "+subject, message)
+		})
+	}
+	batch := func(next Notifier) Notifier {
+		return Batch(next, BatchConfig{MaxSize: 10, Interval: time.Hour})
+	}
+
+	// Close flushes the batch, although it's hidden by the outer middleware.
+	n := NewWithServices(Chain(c, prefix, batch))
+	if err := n.Send(context.Background(), "subject", "message"); err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+	if len(c.received()) != 0 {
+		t.Fatalf("the batch was sent before Close")
+	}
+	if err := n.Close(context.Background()); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+	if len(c.received()) != 1 {
+		t.Errorf("Close() sent %d messages, want 1", len(c.received()))
+	}
+}
+
+func TestUse_FlushesOldChains(t *testing.T) {
+	t.Parallel()
+
+	c := &collector{}
+	n := NewWithServices(c)
+	n.Use(func(next Notifier) Notifier {
+		return Batch(next, BatchConfig{MaxSize: 10})
+	})
+	if err := n.Send(context.Background(), "subject", "message"); err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+	if len(c.received()) != 0 {
+		t.Fatalf("the batch was sent before Use")
+	}
+
+	// Rebuilding the chains flushes the batch buffered by the old one.
+	n.Use(func(next Notifier) Notifier { return next })
+	if len(c.received()) != 1 {
+		t.Errorf("Use() sent %d messages, want the buffered batch", len(c.received()))
+	}
+}
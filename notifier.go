@@ -10,3 +10,34 @@ import "context"
 type Notifier interface {
 	Send(context.Context, string, string) error
 }
+
+// MessageNotifier is an optional interface for notification services that support the rich Message type, e.g. to
+// deliver attachments, links or priorities. Notify prefers SendMessage over Send for services implementing it.
+type MessageNotifier interface {
+	Notifier
+	SendMessage(context.Context, *Message) error
+}
+
+// FormatPreferrer is an optional interface for notification services that prefer a specific body format, e.g. because
+// the platform renders Markdown. Templates are rendered in the preferred format of every service, see WithTemplates.
+// Services that don't implement MessageNotifier get message bodies with a body format converted to their preferred
+// format, see the markup package. Services that don't implement FormatPreferrer get plain text.
+type FormatPreferrer interface {
+	PreferredFormat() BodyFormat
+}
+
+// Checker is an optional interface for notification services that are able to check their configuration without
+// sending a message, usually by validating their credentials with the platform, see Notify.Check.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// Wrapper is an optional interface for services that don't deliver messages themselves, but pass them on to other
+// services, like Retry, Batch, Fallback or a Notify instance used as a service. Templates are rendered for, and dry runs
+// report, the services behind a Wrapper instead of the Wrapper itself, see WithTemplates and WithDryRun. For that to
+// work, a Wrapper has to pass messages on with Forward.
+type Wrapper interface {
+	Notifier
+	// Unwrap returns the services the messages are passed on to.
+	Unwrap() []Notifier
+}
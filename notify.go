@@ -1,6 +1,15 @@
 package notify
 
-import "github.com/pkg/errors"
+import (
+	"log/slog"
+
+	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/ratelimit"
+	"github.com/casdoor/notify/receiver"
+	"github.com/casdoor/notify/retry"
+	"github.com/casdoor/notify/template"
+)
 
 // Compile-time check to ensure Notify implements Notifier.
 var _ Notifier = (*Notify)(nil)
@@ -10,8 +19,22 @@ var ErrSendNotification = errors.New("send notification")
 
 // Notify is the central struct for managing notification services and sending messages to them.
 type Notify struct {
-	Disabled  bool
-	notifiers []Notifier
+	Disabled    bool
+	notifiers   []Notifier
+	tags        map[int][]string
+	names       map[int]string
+	routes      []route
+	middleware  *chain
+	rateLimiter *ratelimit.Limiter
+	dedup       *dedup
+	retryPolicy *retry.Policy
+	parallelism chan struct{}
+	delivery    receiver.Delivery
+	templates   *template.Registry
+	async       *async
+	telemetry   *telemetry
+	logger      *slog.Logger
+	listeners   []Listener
 }
 
 // Option is a function that can be used to configure a Notify instance. It is used by the WithOptions and
@@ -82,3 +105,17 @@ var std = New()
 func Default() *Notify {
 	return std
 }
+
+// SetDefault replaces the standard Notify instance used by the package-level functions with n and returns the previous
+// one, so that it can be restored. A nil n is replaced by a new Notify instance. It's meant for the setup of a program
+// or a test, see the notifytest package, and must not be called concurrently with the package-level functions.
+func SetDefault(n *Notify) *Notify {
+	if n == nil {
+		n = New()
+	}
+
+	previous := std
+	std = n
+
+	return previous
+}
@@ -68,6 +68,28 @@ func TestDefault(t *testing.T) {
 	}
 }
 
+// TestSetDefault doesn't run in parallel, since it replaces the default Notifier.
+func TestSetDefault(t *testing.T) {
+	original := Default()
+
+	n := New()
+	if previous := SetDefault(n); previous != original {
+		t.Error("SetDefault() did not return the previous default Notifier")
+	}
+	if Default() != n {
+		t.Error("Default() did not return the Notifier passed to SetDefault()")
+	}
+
+	if previous := SetDefault(nil); previous != n {
+		t.Error("SetDefault(nil) did not return the previous default Notifier")
+	}
+	if Default() == nil || Default() == n {
+		t.Error("SetDefault(nil) did not replace the default Notifier with a new one")
+	}
+
+	SetDefault(original)
+}
+
 func TestNewWithServices(t *testing.T) {
 	t.Parallel()
 
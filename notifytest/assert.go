@@ -0,0 +1,96 @@
+package notifytest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/casdoor/notify"
+)
+
+// AssertSent checks that a message with the given subject was sent successfully and reports an error to t otherwise.
+// It returns whether the assertion succeeded.
+func (r *Recorder) AssertSent(t testing.TB, subject string) bool {
+	t.Helper()
+
+	if r.sent(subject) {
+		return true
+	}
+
+	t.Errorf("no message with subject %q was sent, got subjects %q", subject, r.subjects())
+
+	return false
+}
+
+// AssertNotSent checks that no message with the given subject was sent successfully and reports an error to t
+// otherwise. It returns whether the assertion succeeded.
+func (r *Recorder) AssertNotSent(t testing.TB, subject string) bool {
+	t.Helper()
+
+	if !r.sent(subject) {
+		return true
+	}
+
+	t.Errorf("a message with subject %q was sent", subject)
+
+	return false
+}
+
+// WaitForN waits until the Recorder was called at least n times, including the failed calls, and returns all calls.
+// It's meant for code that sends asynchronously, see notify.WithAsync. If the timeout expires before, it reports an
+// error to t and returns nil.
+func (r *Recorder) WaitForN(t testing.TB, n int, timeout time.Duration) []Call {
+	t.Helper()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		calls, recorded := r.wait(n)
+		if recorded == nil {
+			return calls
+		}
+
+		select {
+		case <-recorded:
+		case <-timer.C:
+			t.Errorf("got %d calls after %s, want at least %d", len(r.Calls()), timeout, n)
+			return nil
+		}
+	}
+}
+
+// sent returns whether a message with the given subject was sent successfully.
+func (r *Recorder) sent(subject string) bool {
+	for _, m := range r.Messages() {
+		if m.Subject == subject {
+			return true
+		}
+	}
+
+	return false
+}
+
+// subjects returns the subjects of the messages that were sent successfully.
+func (r *Recorder) subjects() []string {
+	var subjects []string
+	for _, m := range r.Messages() {
+		subjects = append(subjects, m.Subject)
+	}
+
+	return subjects
+}
+
+// UseDefault swaps a new Recorder with the given options into notify.Default for the duration of the test, so that the
+// package-level functions of notify, like notify.Send, send to it. The previous default Notify instance is restored when
+// the test and its subtests completed. Tests using it must not run in parallel with other tests that use notify.Default.
+func UseDefault(t testing.TB, options ...Option) *Recorder {
+	t.Helper()
+
+	r := NewRecorder(options...)
+	previous := notify.SetDefault(notify.NewWithServices(r))
+	t.Cleanup(func() {
+		notify.SetDefault(previous)
+	})
+
+	return r
+}
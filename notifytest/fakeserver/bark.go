@@ -0,0 +1,42 @@
+package fakeserver
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// NewBark starts a fake Bark server. It implements the push and ping endpoints. Point the service at it with
+// bark.NewWithServers(deviceKey, server.APIURL()).
+func NewBark() *Server {
+	return newServer(platform{
+		path:   "/",
+		handle: handleBark,
+		fail: func(w http.ResponseWriter, status int, message string) {
+			writeJSON(w, status, barkResponse(status, message))
+		},
+		rateLimit: func(w http.ResponseWriter, retryAfter time.Duration) {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(retryAfter)))
+			writeJSON(w, http.StatusTooManyRequests, barkResponse(http.StatusTooManyRequests, "too many requests"))
+		},
+	})
+}
+
+// barkResponse returns the body of a response of a Bark server.
+func barkResponse(code int, message string) map[string]any {
+	return map[string]any{"code": code, "message": message, "timestamp": time.Now().Unix()}
+}
+
+// handleBark responds to the endpoints of a Bark server.
+func handleBark(w http.ResponseWriter, r Request) bool {
+	switch {
+	case r.Path == "/push" && r.Method == http.MethodPost:
+		writeJSON(w, http.StatusOK, barkResponse(http.StatusOK, "success"))
+	case r.Path == "/ping" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, barkResponse(http.StatusOK, "pong"))
+	default:
+		return false
+	}
+
+	return true
+}
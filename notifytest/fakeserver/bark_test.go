@@ -0,0 +1,40 @@
+package fakeserver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/casdoor/notify/retry"
+	"github.com/casdoor/notify/service/bark"
+)
+
+func TestBark(t *testing.T) {
+	t.Parallel()
+
+	server := NewBark()
+	defer server.Close()
+
+	ctx := context.Background()
+	service := bark.NewWithServers("device-key", server.APIURL())
+
+	require.NoError(t, service.Check(ctx))
+	require.NoError(t, service.Send(ctx, "subject", "body"))
+
+	requests := server.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "/ping", requests[0].Path)
+	var push map[string]any
+	require.NoError(t, requests[1].JSON(&push))
+	assert.Equal(t, "device-key", push["device_key"])
+	assert.Equal(t, "subject", push["title"])
+	assert.Equal(t, "body", push["body"])
+
+	server.Fail(1, http.StatusServiceUnavailable, "maintenance")
+	err := service.Send(ctx, "subject", "body")
+	require.ErrorContains(t, err, "maintenance")
+	assert.True(t, retry.IsTransient(err))
+}
@@ -0,0 +1,63 @@
+package fakeserver
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// discordMessagesPath matches the path of the endpoint creating messages in a channel.
+var discordMessagesPath = regexp.MustCompile(`^/api/v\d+/channels/([^/]+)/messages$`)
+
+// NewDiscord starts a fake server of the Discord API. It implements the endpoint creating messages in a channel. Point
+// the service at it with Discord.SetAPIURL(server.APIURL()).
+//
+// The Discord client retries rate limited requests after the requested duration, so keep it short. It also retries
+// requests failing with status code 502.
+func NewDiscord() *Server {
+	var messageIDs atomic.Int64
+
+	return newServer(platform{
+		handle: func(w http.ResponseWriter, r Request) bool {
+			return handleDiscord(w, r, &messageIDs)
+		},
+		fail: func(w http.ResponseWriter, status int, message string) {
+			writeJSON(w, status, map[string]any{"code": 0, "message": message})
+		},
+		rateLimit: func(w http.ResponseWriter, retryAfter time.Duration) {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(retryAfter)))
+			w.Header().Set("X-RateLimit-Scope", "user")
+			writeJSON(w, http.StatusTooManyRequests, map[string]any{
+				"message":     "You are being rate limited.",
+				"retry_after": retryAfter.Seconds(),
+				"global":      false,
+			})
+		},
+	})
+}
+
+// handleDiscord responds to the endpoints of the Discord API.
+func handleDiscord(w http.ResponseWriter, r Request, messageIDs *atomic.Int64) bool {
+	match := discordMessagesPath.FindStringSubmatch(r.Path)
+	if match == nil || r.Method != http.MethodPost {
+		return false
+	}
+
+	var message struct {
+		Content string `json:"content"`
+	}
+	if err := r.JSON(&message); err != nil {
+		return false
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":         strconv.FormatInt(messageIDs.Add(1), 10),
+		"channel_id": match[1],
+		"content":    message.Content,
+		"type":       0,
+	})
+
+	return true
+}
@@ -0,0 +1,63 @@
+package fakeserver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/casdoor/notify/retry"
+	"github.com/casdoor/notify/service/discord"
+)
+
+func TestDiscord(t *testing.T) {
+	t.Parallel()
+
+	server := NewDiscord()
+	defer server.Close()
+
+	ctx := context.Background()
+	service := discord.New()
+	require.NoError(t, service.SetAPIURL(server.APIURL()))
+	require.NoError(t, service.AuthenticateWithBotToken("token"))
+	service.AddReceivers("123")
+
+	require.NoError(t, service.Send(ctx, "subject", "body"))
+
+	requests := server.Requests()
+	require.Len(t, requests, 1)
+	assert.Regexp(t, `^/api/v\d+/channels/123/messages$`, requests[0].Path)
+	assert.Equal(t, "Bot token", requests[0].Header.Get("Authorization"))
+	var message struct {
+		Content string `json:"content"`
+	}
+	require.NoError(t, requests[0].JSON(&message))
+	assert.Equal(t, "subject\nbody", message.Content)
+
+	// The Discord client waits for rate limits itself.
+	server.RateLimit(1, 10*time.Millisecond)
+	require.NoError(t, service.Send(ctx, "subject", "body"))
+	requests = server.Requests()
+	require.Len(t, requests, 3)
+	assert.Equal(t, http.StatusTooManyRequests, requests[1].Status)
+	assert.Equal(t, http.StatusOK, requests[2].Status)
+
+	server.Fail(1, http.StatusForbidden, "Missing Access")
+	err := service.Send(ctx, "subject", "body")
+	require.ErrorContains(t, err, "Missing Access")
+	status, ok := retry.StatusCode(err)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusForbidden, status)
+
+	// The API URL can be set after authenticating, too.
+	service = discord.New()
+	require.NoError(t, service.AuthenticateWithBotToken("other"))
+	require.Error(t, service.SetAPIURL("not a URL"))
+	require.NoError(t, service.SetAPIURL(server.APIURL()))
+	service.AddReceivers("456")
+	require.NoError(t, service.Send(ctx, "subject", "body"))
+	assert.Equal(t, "Bot other", server.Requests()[4].Header.Get("Authorization"))
+}
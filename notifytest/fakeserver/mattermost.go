@@ -0,0 +1,74 @@
+package fakeserver
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// MattermostToken is the session token the fake Mattermost server hands out on login.
+const MattermostToken = "fake-mattermost-token"
+
+// NewMattermost starts a fake server of the Mattermost API. It implements the endpoints to log in and to create posts.
+// Point the service at it with mattermost.New(server.APIURL()).
+//
+// Logins succeed with any credentials and return MattermostToken. Posts aren't checked for the token, look at the
+// Authorization header of the recorded requests instead.
+func NewMattermost() *Server {
+	var postIDs atomic.Int64
+
+	return newServer(platform{
+		handle: func(w http.ResponseWriter, r Request) bool {
+			return handleMattermost(w, r, &postIDs)
+		},
+		fail: func(w http.ResponseWriter, status int, message string) {
+			writeJSON(w, status, map[string]any{"id": "fakeserver.error", "message": message, "status_code": status})
+		},
+		rateLimit: func(w http.ResponseWriter, retryAfter time.Duration) {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(retryAfter)))
+			w.Header().Set("X-Ratelimit-Remaining", "0")
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = io.WriteString(w, "limit exceeded\n")
+		},
+	})
+}
+
+// handleMattermost responds to the endpoints of the Mattermost API.
+func handleMattermost(w http.ResponseWriter, r Request, postIDs *atomic.Int64) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+
+	switch r.Path {
+	case "/api/v4/users/login":
+		var login struct {
+			LoginID string `json:"login_id"`
+		}
+		if err := r.JSON(&login); err != nil {
+			return false
+		}
+		w.Header().Set("Token", MattermostToken)
+		writeJSON(w, http.StatusOK, map[string]any{"id": "fakeuser", "username": login.LoginID})
+	case "/api/v4/posts":
+		var post struct {
+			ChannelID string `json:"channel_id"`
+			Message   string `json:"message"`
+		}
+		if err := r.JSON(&post); err != nil {
+			return false
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{
+			"id":         strconv.FormatInt(postIDs.Add(1), 10),
+			"channel_id": post.ChannelID,
+			"message":    post.Message,
+			"create_at":  time.Now().UnixMilli(),
+		})
+	default:
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,103 @@
+package fakeserver
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/casdoor/notify/receiver"
+	"github.com/casdoor/notify/retry"
+	"github.com/casdoor/notify/service/mattermost"
+)
+
+func TestMattermost(t *testing.T) {
+	t.Parallel()
+
+	server := NewMattermost()
+	defer server.Close()
+
+	ctx := context.Background()
+	service := mattermost.New(server.APIURL())
+	service.AddReceivers("channel")
+
+	require.NoError(t, service.LoginWithCredentials(ctx, "user", "password"))
+	require.NoError(t, service.Send(ctx, "subject", "body"))
+
+	requests := server.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "/api/v4/users/login", requests[0].Path)
+	assert.Equal(t, "/api/v4/posts", requests[1].Path)
+	assert.Equal(t, http.StatusCreated, requests[1].Status)
+	assert.Equal(t, "Bearer "+MattermostToken, requests[1].Header.Get("Authorization"))
+	var post map[string]string
+	require.NoError(t, requests[1].JSON(&post))
+	assert.Equal(t, map[string]string{"channel_id": "channel", "message": "subject\nbody"}, post)
+
+	server.Fail(1, http.StatusUnauthorized, "invalid login")
+	err := service.LoginWithCredentials(ctx, "user", "wrong")
+	require.ErrorContains(t, err, "invalid login")
+	assert.True(t, retry.IsPermanent(err))
+
+	server.RateLimit(1, 2*time.Second)
+	err = service.Send(ctx, "subject", "body")
+	require.ErrorContains(t, err, "limit exceeded")
+	retryAfter, ok := retry.RetryAfter(err)
+	require.True(t, ok)
+	assert.Equal(t, 2*time.Second, retryAfter)
+}
+
+func TestMattermost_HTTPReceivers(t *testing.T) {
+	t.Parallel()
+
+	server := NewMattermost()
+	defer server.Close()
+	other := NewMattermost()
+	defer other.Close()
+
+	// Receivers meant for the HTTP service don't redirect the requests Mattermost makes through it.
+	ctx := receiver.WithOverride(context.Background(), "http", receiver.Replace, receiver.New(other.APIURL()))
+	service := mattermost.New(server.APIURL())
+	service.AddReceivers("channel")
+
+	require.NoError(t, service.LoginWithCredentials(ctx, "user", "password"))
+	require.NoError(t, service.Send(ctx, "subject", "body"))
+	assert.Len(t, server.Requests(), 2)
+	assert.Empty(t, other.Requests())
+}
+
+func TestMattermost_LoginWhileSending(t *testing.T) {
+	t.Parallel()
+
+	server := NewMattermost()
+	defer server.Close()
+
+	ctx := receiver.WithDelivery(context.Background(), receiver.Delivery{Parallelism: 4})
+	service := mattermost.New(server.APIURL())
+	service.AddReceivers("a", "b", "c", "d")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, service.LoginWithCredentials(ctx, "user", "password"))
+		}()
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, service.Send(ctx, "subject", "body"))
+		}()
+	}
+	wg.Wait()
+
+	// Logins replace the token, they don't pile up hooks.
+	server.Reset()
+	require.NoError(t, service.Send(ctx, "subject", "body"))
+	for _, r := range server.Requests() {
+		assert.Equal(t, []string{"Bearer " + MattermostToken}, r.Header.Values("Authorization"))
+	}
+}
@@ -0,0 +1,43 @@
+package fakeserver
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewMSTeams starts a fake server of Microsoft Teams incoming webhooks. It accepts message cards posted to any path
+// below /webhookb2/. Point the service at it by disabling the webhook validation, see MSTeams.DisableWebhookValidation,
+// and adding server.APIURL(), or any other URL below /webhookb2/ of the server, as receiver.
+func NewMSTeams() *Server {
+	return newServer(platform{
+		path:   "/webhookb2/fake",
+		handle: handleMSTeams,
+		fail:   writeText,
+		rateLimit: func(w http.ResponseWriter, retryAfter time.Duration) {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(retryAfter)))
+			writeText(w, http.StatusTooManyRequests, "Microsoft Teams endpoint has been throttled")
+		},
+	})
+}
+
+// handleMSTeams responds to messages posted to an incoming webhook.
+func handleMSTeams(w http.ResponseWriter, r Request) bool {
+	if !strings.HasPrefix(r.Path, "/webhookb2/") || r.Method != http.MethodPost {
+		return false
+	}
+
+	// Incoming webhooks confirm a message with the text "1".
+	writeText(w, http.StatusOK, "1")
+
+	return true
+}
+
+// writeText writes a plain text response with the given status code.
+func writeText(w http.ResponseWriter, status int, text string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, text)
+}
@@ -0,0 +1,41 @@
+package fakeserver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/casdoor/notify/service/msteams"
+)
+
+func TestMSTeams(t *testing.T) {
+	t.Parallel()
+
+	server := NewMSTeams()
+	defer server.Close()
+
+	ctx := context.Background()
+	service := msteams.New()
+	service.DisableWebhookValidation()
+	service.AddReceivers(server.APIURL(), server.URL+"/webhookb2/other")
+
+	require.NoError(t, service.Send(ctx, "subject", "body"))
+
+	requests := server.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "/webhookb2/other", requests[1].Path)
+	var card map[string]any
+	require.NoError(t, requests[0].JSON(&card))
+	assert.Equal(t, "subject", card["title"])
+	assert.Equal(t, "body", card["text"])
+
+	server.Fail(1, http.StatusBadRequest, "Summary or Text is required.")
+	require.ErrorContains(t, service.Send(ctx, "subject", "body"), "Summary or Text is required.")
+
+	server.RateLimit(1, time.Second)
+	require.ErrorContains(t, service.Send(ctx, "subject", "body"), "throttled")
+}
@@ -0,0 +1,44 @@
+package fakeserver
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// NewPushover starts a fake server of the Pushover API. It implements the endpoint to send messages. Point the service
+// at it with pushover.NewWithAPIURL(appToken, server.APIURL()).
+func NewPushover() *Server {
+	var requestIDs atomic.Int64
+	requestID := func() string {
+		return "fake-request-" + strconv.FormatInt(requestIDs.Add(1), 10)
+	}
+
+	return newServer(platform{
+		path: "/1",
+		handle: func(w http.ResponseWriter, r Request) bool {
+			if r.Path != "/1/messages.json" || r.Method != http.MethodPost {
+				return false
+			}
+
+			w.Header().Set("X-Limit-App-Limit", "10000")
+			w.Header().Set("X-Limit-App-Remaining", "9999")
+			w.Header().Set("X-Limit-App-Reset", strconv.FormatInt(time.Now().Add(24*time.Hour).Unix(), 10))
+			writeJSON(w, http.StatusOK, map[string]any{"status": 1, "request": requestID()})
+
+			return true
+		},
+		fail: func(w http.ResponseWriter, status int, message string) {
+			writeJSON(w, status, map[string]any{"status": 0, "request": requestID(), "errors": []string{message}})
+		},
+		rateLimit: func(w http.ResponseWriter, retryAfter time.Duration) {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(retryAfter)))
+			writeJSON(w, http.StatusTooManyRequests, map[string]any{
+				"status":  0,
+				"request": requestID(),
+				"errors":  []string{"application is over its message quota"},
+			})
+		},
+	})
+}
@@ -0,0 +1,62 @@
+package fakeserver
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	notifymsg "github.com/casdoor/notify/message"
+	"github.com/casdoor/notify/retry"
+	"github.com/casdoor/notify/service/pushover"
+)
+
+func TestPushover(t *testing.T) {
+	t.Parallel()
+
+	server := NewPushover()
+	defer server.Close()
+
+	ctx := context.Background()
+	service := pushover.NewWithAPIURL("app-token", server.APIURL())
+	service.AddReceivers("user-key")
+
+	require.NoError(t, service.Send(ctx, "subject", "body"))
+	m := notifymsg.New("photo", "see attachment")
+	m.Priority = notifymsg.PriorityCritical
+	m.Attachments = []notifymsg.Attachment{{Name: "photo.png", Data: []byte("png")}}
+	require.NoError(t, service.SendMessage(ctx, m))
+
+	requests := server.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "/1/messages.json", requests[0].Path)
+	form, err := requests[0].Form()
+	require.NoError(t, err)
+	assert.Equal(t, "app-token", form.Get("token"))
+	assert.Equal(t, "user-key", form.Get("user"))
+	assert.Equal(t, "subject", form.Get("title"))
+	assert.Equal(t, "body", form.Get("message"))
+
+	assert.True(t, strings.HasPrefix(requests[1].Header.Get("Content-Type"), "multipart/form-data"))
+	assert.Contains(t, string(requests[1].Body), "png")
+	form, err = requests[1].Form()
+	require.NoError(t, err)
+	assert.Equal(t, "2", form.Get("priority"))
+	assert.Equal(t, "60", form.Get("retry"))
+
+	server.Fail(1, http.StatusBadRequest, "user identifier is invalid")
+	err = service.Send(ctx, "subject", "body")
+	require.ErrorContains(t, err, "user identifier is invalid")
+	assert.True(t, retry.IsPermanent(err))
+
+	server.RateLimit(1, time.Minute)
+	err = service.Send(ctx, "subject", "body")
+	require.Error(t, err)
+	retryAfter, ok := retry.RetryAfter(err)
+	require.True(t, ok)
+	assert.Equal(t, time.Minute, retryAfter)
+}
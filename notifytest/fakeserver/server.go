@@ -0,0 +1,219 @@
+// Package fakeserver provides fake servers of the platforms behind the HTTP based services, for end-to-end tests without
+// real credentials. Every server is an httptest.Server that mimics the API endpoints the service uses and records all
+// requests it receives:
+//
+//	server := fakeserver.NewSlack()
+//	defer server.Close()
+//
+//	service := slack.NewWithAPIURL("token", server.APIURL())
+//	service.AddReceivers("C123")
+//	// ... send through service ...
+//	requests := server.Requests()
+//
+// Servers can simulate errors and rate limits of their platform, see Server.Fail and Server.RateLimit. The constructor
+// of every server documents how to point the service at it.
+package fakeserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Request is a request received by a Server.
+type Request struct {
+	// Method is the HTTP method of the request.
+	Method string
+	// Path is the path of the request URL.
+	Path string
+	// Query holds the query parameters of the request URL.
+	Query url.Values
+	// Header holds the headers of the request.
+	Header http.Header
+	// Body is the body of the request.
+	Body []byte
+	// Status is the HTTP status code the server responded with.
+	Status int
+}
+
+// JSON decodes the JSON body of the request into v.
+func (r Request) JSON(v any) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// Form returns the form parameters of the request, for bodies that are URL encoded or multipart forms. Files of
+// multipart forms aren't included.
+func (r Request) Form() (url.Values, error) {
+	req, err := http.NewRequest(r.Method, r.Path, bytes.NewReader(r.Body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = r.Header.Clone()
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err = req.ParseMultipartForm(int64(len(r.Body))); err != nil {
+			return nil, err
+		}
+		return req.MultipartForm.Value, nil
+	}
+
+	if err = req.ParseForm(); err != nil {
+		return nil, err
+	}
+
+	return req.PostForm, nil
+}
+
+// platform describes the API of a platform mimicked by a Server.
+type platform struct {
+	// path is the path of the API URL, see Server.APIURL.
+	path string
+	// handle writes the response to a successful request. It returns false if the request doesn't match an endpoint.
+	handle func(w http.ResponseWriter, r Request) bool
+	// fail writes an error response with the given status code and message.
+	fail func(w http.ResponseWriter, status int, message string)
+	// rateLimit writes a rate limit response that asks the client to retry after the given duration.
+	rateLimit func(w http.ResponseWriter, retryAfter time.Duration)
+}
+
+// fault is a failure injected into a Server, see Server.Fail and Server.RateLimit.
+type fault struct {
+	status     int
+	message    string
+	rateLimit  bool
+	retryAfter time.Duration
+}
+
+// Server is a fake server of a platform. It's safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	platform platform
+	mu       sync.Mutex
+	requests []Request
+	faults   []fault
+}
+
+// newServer starts a new Server for the given platform.
+func newServer(p platform) *Server {
+	s := &Server{platform: p}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// APIURL returns the URL to point the service at, see the constructor of the server.
+func (s *Server) APIURL() string {
+	return s.URL + s.platform.path
+}
+
+// Fail makes the next n requests fail with the given HTTP status code. The response carries the given message in the
+// error format of the platform. Failures apply to requests of any endpoint, in the order they were added.
+func (s *Server) Fail(n, status int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i < n; i++ {
+		s.faults = append(s.faults, fault{status: status, message: message})
+	}
+}
+
+// RateLimit makes the next n requests fail with the rate limit response of the platform, which asks the client to
+// retry after the given duration. Rate limits apply to requests of any endpoint, in the order they were added.
+func (s *Server) RateLimit(n int, retryAfter time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i < n; i++ {
+		s.faults = append(s.faults, fault{rateLimit: true, retryAfter: retryAfter})
+	}
+}
+
+// Requests returns all requests the server received, in the order they were received, including the failed ones.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// Reset forgets all received requests and all failures that weren't applied yet.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = nil
+	s.faults = nil
+}
+
+// nextFault removes the next injected failure and returns it, if any.
+func (s *Server) nextFault() (fault, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.faults) == 0 {
+		return fault{}, false
+	}
+
+	f := s.faults[0]
+	s.faults = s.faults[1:]
+
+	return f, true
+}
+
+// serveHTTP records the request and responds with the next injected failure or with the response of the platform.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	req := Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.Query(),
+		Header: r.Header.Clone(),
+		Body:   body,
+	}
+
+	// Buffer the response, so that the request is recorded before the client gets the response.
+	rec := httptest.NewRecorder()
+	switch f, ok := s.nextFault(); {
+	case ok && f.rateLimit:
+		s.platform.rateLimit(rec, f.retryAfter)
+	case ok:
+		s.platform.fail(rec, f.status, f.message)
+	case !s.platform.handle(rec, req):
+		s.platform.fail(rec, http.StatusNotFound, "not found")
+	}
+
+	req.Status = rec.Code
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+
+	for key, values := range rec.Header() {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(rec.Code)
+	_, _ = w.Write(rec.Body.Bytes())
+}
+
+// writeJSON writes v as JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// retryAfterSeconds returns d in whole seconds for the Retry-After header, rounded up and at least 1.
+func retryAfterSeconds(d time.Duration) int {
+	seconds := int((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	return seconds
+}
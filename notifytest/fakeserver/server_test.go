@@ -0,0 +1,93 @@
+package fakeserver
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	server := NewBark()
+	defer server.Close()
+
+	get := func(path string) int {
+		resp, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	server.Fail(1, http.StatusBadGateway, "bad gateway")
+	server.RateLimit(1, 1500*time.Millisecond)
+	assert.Equal(t, http.StatusBadGateway, get("/ping"))
+	assert.Equal(t, http.StatusTooManyRequests, get("/ping"))
+	assert.Equal(t, http.StatusOK, get("/ping"))
+	assert.Equal(t, http.StatusNotFound, get("/unknown?a=b"))
+
+	requests := server.Requests()
+	require.Len(t, requests, 4)
+	assert.Equal(t, http.StatusBadGateway, requests[0].Status)
+	assert.Equal(t, http.StatusTooManyRequests, requests[1].Status)
+	assert.Equal(t, "/unknown", requests[3].Path)
+	assert.Equal(t, "b", requests[3].Query.Get("a"))
+
+	server.Fail(1, http.StatusInternalServerError, "down")
+	server.Reset()
+	assert.Empty(t, server.Requests())
+	assert.Equal(t, http.StatusOK, get("/ping"), "Reset drops pending failures")
+}
+
+func TestRequest_Form(t *testing.T) {
+	t.Parallel()
+
+	r := Request{
+		Method: http.MethodPost,
+		Path:   "/form",
+		Header: http.Header{"Content-Type": {"application/x-www-form-urlencoded"}},
+		Body:   []byte("a=1&b=2"),
+	}
+	form, err := r.Form()
+	require.NoError(t, err)
+	assert.Equal(t, "1", form.Get("a"))
+	assert.Equal(t, "2", form.Get("b"))
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	require.NoError(t, w.WriteField("a", "1"))
+	fw, err := w.CreateFormFile("file", "file")
+	require.NoError(t, err)
+	_, err = fw.Write([]byte("data"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	r.Header.Set("Content-Type", w.FormDataContentType())
+	r.Body = body.Bytes()
+	form, err = r.Form()
+	require.NoError(t, err)
+	assert.Equal(t, "1", form.Get("a"))
+	assert.NotContains(t, form, "file")
+
+	var v map[string]any
+	r.Body = []byte(`{"a":1}`)
+	require.NoError(t, r.JSON(&v))
+	assert.EqualValues(t, 1, v["a"])
+	assert.True(t, strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data"))
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 1, retryAfterSeconds(0))
+	assert.Equal(t, 1, retryAfterSeconds(10*time.Millisecond))
+	assert.Equal(t, 2, retryAfterSeconds(1500*time.Millisecond))
+	assert.Equal(t, 3, retryAfterSeconds(3*time.Second))
+}
@@ -0,0 +1,58 @@
+package fakeserver
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewSlack starts a fake server of the Slack Web API. It implements the chat.postMessage and auth.test methods. Point
+// the service at it with slack.NewWithAPIURL(token, server.APIURL()).
+//
+// Slack reports most errors with status code 200 and an error code in the body, so use Fail with status code 200 and an
+// error code like "channel_not_found" to simulate them. Other status codes simulate HTTP errors.
+func NewSlack() *Server {
+	return newServer(platform{
+		path:   "/api/",
+		handle: handleSlack,
+		fail: func(w http.ResponseWriter, status int, message string) {
+			writeJSON(w, status, map[string]any{"ok": false, "error": message})
+		},
+		rateLimit: func(w http.ResponseWriter, retryAfter time.Duration) {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(retryAfter)))
+			writeJSON(w, http.StatusTooManyRequests, map[string]any{"ok": false, "error": "ratelimited"})
+		},
+	})
+}
+
+// handleSlack responds to the methods of the Slack Web API.
+func handleSlack(w http.ResponseWriter, r Request) bool {
+	switch strings.TrimPrefix(r.Path, "/api/") {
+	case "chat.postMessage":
+		form, err := r.Form()
+		if err != nil {
+			return false
+		}
+		now := time.Now()
+		writeJSON(w, http.StatusOK, map[string]any{
+			"ok":      true,
+			"channel": form.Get("channel"),
+			"ts":      fmt.Sprintf("%d.%06d", now.Unix(), now.Nanosecond()/int(time.Microsecond)),
+		})
+	case "auth.test":
+		writeJSON(w, http.StatusOK, map[string]any{
+			"ok":      true,
+			"url":     "https://fake.slack.com/",
+			"team":    "Fake",
+			"user":    "fake",
+			"team_id": "T00000000",
+			"user_id": "U00000000",
+		})
+	default:
+		return false
+	}
+
+	return true
+}
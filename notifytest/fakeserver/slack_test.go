@@ -0,0 +1,49 @@
+package fakeserver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/casdoor/notify/retry"
+	"github.com/casdoor/notify/service/slack"
+)
+
+func TestSlack(t *testing.T) {
+	t.Parallel()
+
+	server := NewSlack()
+	defer server.Close()
+
+	ctx := context.Background()
+	service := slack.NewWithAPIURL("xoxb-token", server.APIURL())
+	service.AddReceivers("C1", "C2")
+
+	require.NoError(t, service.Check(ctx))
+	require.NoError(t, service.Send(ctx, "subject", "body"))
+
+	requests := server.Requests()
+	require.Len(t, requests, 3)
+	assert.Equal(t, "/api/auth.test", requests[0].Path)
+	form, err := requests[2].Form()
+	require.NoError(t, err)
+	assert.Equal(t, "xoxb-token", form.Get("token"))
+	assert.Equal(t, "C2", form.Get("channel"))
+	assert.Equal(t, "subject\nbody", form.Get("text"))
+
+	server.Fail(1, http.StatusOK, "channel_not_found")
+	err = service.Send(ctx, "subject", "body")
+	require.ErrorContains(t, err, "channel_not_found")
+	assert.Equal(t, http.StatusOK, server.Requests()[3].Status)
+
+	server.RateLimit(1, 3*time.Second)
+	err = service.Check(ctx)
+	require.Error(t, err)
+	retryAfter, ok := retry.RetryAfter(err)
+	require.True(t, ok)
+	assert.Equal(t, 3*time.Second, retryAfter)
+}
@@ -0,0 +1,80 @@
+package fakeserver
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// telegramPath matches the paths of the Telegram Bot API, which carry the bot token and the method.
+var telegramPath = regexp.MustCompile(`^/bot([^/]+)/(\w+)$`)
+
+// NewTelegram starts a fake server of the Telegram Bot API. It implements the getMe and sendMessage methods. Point the
+// service at it with telegram.NewWithAPIURL(token, server.APIURL()).
+func NewTelegram() *Server {
+	var messageIDs atomic.Int64
+
+	return newServer(platform{
+		handle: func(w http.ResponseWriter, r Request) bool {
+			return handleTelegram(w, r, &messageIDs)
+		},
+		fail: func(w http.ResponseWriter, status int, message string) {
+			writeJSON(w, status, map[string]any{"ok": false, "error_code": status, "description": message})
+		},
+		rateLimit: func(w http.ResponseWriter, retryAfter time.Duration) {
+			seconds := retryAfterSeconds(retryAfter)
+			writeJSON(w, http.StatusTooManyRequests, map[string]any{
+				"ok":          false,
+				"error_code":  http.StatusTooManyRequests,
+				"description": fmt.Sprintf("Too Many Requests: retry after %d", seconds),
+				"parameters":  map[string]any{"retry_after": seconds},
+			})
+		},
+	})
+}
+
+// handleTelegram responds to the methods of the Telegram Bot API.
+func handleTelegram(w http.ResponseWriter, r Request, messageIDs *atomic.Int64) bool {
+	match := telegramPath.FindStringSubmatch(r.Path)
+	if match == nil {
+		return false
+	}
+
+	switch match[2] {
+	case "getMe":
+		writeJSON(w, http.StatusOK, map[string]any{
+			"ok": true,
+			"result": map[string]any{
+				"id":         1,
+				"is_bot":     true,
+				"first_name": "Fake",
+				"username":   "fake_bot",
+			},
+		})
+	case "sendMessage":
+		form, err := r.Form()
+		if err != nil {
+			return false
+		}
+		chatID, err := strconv.ParseInt(form.Get("chat_id"), 10, 64)
+		if err != nil {
+			return false
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"ok": true,
+			"result": map[string]any{
+				"message_id": messageIDs.Add(1),
+				"date":       time.Now().Unix(),
+				"chat":       map[string]any{"id": chatID, "type": "private"},
+				"text":       form.Get("text"),
+			},
+		})
+	default:
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,83 @@
+package fakeserver
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/casdoor/notify/retry"
+	"github.com/casdoor/notify/service/telegram"
+)
+
+func TestTelegram(t *testing.T) {
+	t.Parallel()
+
+	server := NewTelegram()
+	defer server.Close()
+
+	ctx := context.Background()
+	service, err := telegram.NewWithAPIURL("123:token", server.APIURL())
+	require.NoError(t, err)
+	service.AddReceivers(42)
+
+	require.NoError(t, service.Send(ctx, "subject", "body"))
+
+	requests := server.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "/bot123:token/getMe", requests[0].Path)
+	assert.Equal(t, "/bot123:token/sendMessage", requests[1].Path)
+	form, err := requests[1].Form()
+	require.NoError(t, err)
+	assert.Equal(t, "42", form.Get("chat_id"))
+	assert.Equal(t, "subject\nbody", form.Get("text"))
+
+	server.Fail(1, http.StatusBadRequest, "Bad Request: chat not found")
+	require.ErrorContains(t, service.Send(ctx, "subject", "body"), "chat not found")
+
+	server.RateLimit(1, 2*time.Second)
+	err = service.Send(ctx, "subject", "body")
+	require.Error(t, err)
+	retryAfter, ok := retry.RetryAfter(err)
+	require.True(t, ok)
+	assert.Equal(t, 2*time.Second, retryAfter)
+
+	// The token is validated on creation.
+	server.Fail(1, http.StatusUnauthorized, "Unauthorized")
+	_, err = telegram.NewWithAPIURL("123:token", server.APIURL())
+	require.ErrorContains(t, err, "Unauthorized")
+}
+
+func TestTelegram_ParseMode(t *testing.T) {
+	t.Parallel()
+
+	server := NewTelegram()
+	defer server.Close()
+
+	ctx := context.Background()
+	html, err := telegram.NewWithAPIURL("123:token", server.APIURL())
+	require.NoError(t, err)
+	html.AddReceivers(42)
+	markdown, err := telegram.NewWithAPIURL("123:token", server.APIURL())
+	require.NoError(t, err)
+	markdown.AddReceivers(42)
+	markdown.SetParseMode(telegram.ModeMarkdownV2)
+
+	// Setting the parse mode of one instance leaves the others alone.
+	require.NoError(t, html.Send(ctx, "subject", "body"))
+	require.NoError(t, markdown.Send(ctx, "subject", "body"))
+
+	var modes []string
+	for _, request := range server.Requests() {
+		if request.Path != "/bot123:token/sendMessage" {
+			continue
+		}
+		form, err := request.Form()
+		require.NoError(t, err)
+		modes = append(modes, form.Get("parse_mode"))
+	}
+	assert.Equal(t, []string{telegram.ModeHTML, telegram.ModeMarkdownV2}, modes)
+}
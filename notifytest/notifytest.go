@@ -0,0 +1,213 @@
+// Package notifytest provides test helpers for applications that send notifications with notify. Its Recorder is a
+// notify.Notifier that records every message instead of delivering it, together with the context it was sent with:
+//
+//	recorder := notifytest.NewRecorder()
+//	n := notify.NewWithServices(recorder)
+//	// ... code under test sends through n ...
+//	recorder.AssertSent(t, "Deployment finished")
+//
+// Failures can be injected to test how an application handles them, see FailNth, WithLatency and WithFlakiness. Code
+// that uses the package-level functions of notify can be tested by swapping a Recorder into notify.Default, see
+// UseDefault.
+package notifytest
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify"
+)
+
+// ErrInjected is the error returned by the failures injected into a Recorder, unless another error was given.
+var ErrInjected = errors.New("injected failure")
+
+// Compile-time check to ensure that Recorder implements the notify.MessageNotifier interface.
+var _ notify.MessageNotifier = (*Recorder)(nil)
+
+// Call is a single call of a Recorder.
+type Call struct {
+	// Message is a copy of the message that was sent.
+	Message *notify.Message
+	// Context is the context the message was sent with. Use it to inspect the values set by the application or by
+	// notify, e.g. with notify.MessageFromContext.
+	Context context.Context
+	// Err is the error the Recorder returned, or nil if the message counts as sent.
+	Err error
+	// Time is the time the call returned.
+	Time time.Time
+}
+
+// Value returns the value associated with key in the context of the call.
+func (c Call) Value(key any) any {
+	return c.Context.Value(key)
+}
+
+// Recorder is a notify.Notifier that records all messages sent to it. It's safe for concurrent use.
+type Recorder struct {
+	failNth   map[int]error
+	latency   time.Duration
+	flakiness int
+	flakyErr  error
+	seed      int64
+	rand      *rand.Rand
+	mu        sync.Mutex
+	calls     []Call
+	n         int
+	recorded  chan struct{}
+}
+
+// Option configures a Recorder, see NewRecorder.
+type Option func(*Recorder)
+
+// FailNth is an Option that makes the nth call of the Recorder fail with the given error, or with ErrInjected if err is
+// nil. Calls are counted from 1. The option may be given several times to fail several calls.
+func FailNth(n int, err error) Option {
+	return func(r *Recorder) {
+		if err == nil {
+			err = ErrInjected
+		}
+		r.failNth[n] = err
+	}
+}
+
+// WithLatency is an Option that delays every call of the Recorder by d. A call returns the error of its context if the
+// context is done before.
+func WithLatency(d time.Duration) Option {
+	return func(r *Recorder) {
+		r.latency = d
+	}
+}
+
+// WithFlakiness is an Option that makes the given percentage of the calls of the Recorder fail at random with the given
+// error, or with ErrInjected if err is nil. The failures are reproducible with WithSeed.
+func WithFlakiness(percent int, err error) Option {
+	return func(r *Recorder) {
+		if err == nil {
+			err = ErrInjected
+		}
+		r.flakiness = percent
+		r.flakyErr = err
+	}
+}
+
+// WithSeed is an Option that seeds the random failures of WithFlakiness. By default, the current time is used.
+func WithSeed(seed int64) Option {
+	return func(r *Recorder) {
+		r.seed = seed
+	}
+}
+
+// NewRecorder returns a new Recorder with the given options. Without options, every call succeeds immediately.
+func NewRecorder(options ...Option) *Recorder {
+	r := &Recorder{
+		failNth:  make(map[int]error),
+		seed:     time.Now().UnixNano(),
+		recorded: make(chan struct{}),
+	}
+	for _, option := range options {
+		if option != nil {
+			option(r)
+		}
+	}
+	r.rand = rand.New(rand.NewSource(r.seed)) //nolint:gosec // Reproducible failures don't need a secure source.
+
+	return r
+}
+
+// Send records a message with the given subject and body. It implements the notify.Notifier interface.
+func (r *Recorder) Send(ctx context.Context, subject, body string) error {
+	return r.SendMessage(ctx, notify.NewMessage(subject, body))
+}
+
+// SendMessage records a copy of the given message. It implements the notify.MessageNotifier interface.
+func (r *Recorder) SendMessage(ctx context.Context, m *notify.Message) error {
+	err := r.inject()
+	if r.latency > 0 {
+		timer := time.NewTimer(r.latency)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			err = ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	r.record(Call{Message: m.Clone(), Context: ctx, Err: err, Time: time.Now()})
+
+	return err
+}
+
+// inject counts a new call and returns the error it should fail with, if any.
+func (r *Recorder) inject() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.n++
+	if err, ok := r.failNth[r.n]; ok {
+		return err
+	}
+	if r.flakiness > 0 && r.rand.Intn(100) < r.flakiness {
+		return r.flakyErr
+	}
+
+	return nil
+}
+
+// record adds the given call and wakes up all waiting WaitForN calls.
+func (r *Recorder) record(call Call) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, call)
+	close(r.recorded)
+	r.recorded = make(chan struct{})
+}
+
+// Calls returns all calls of the Recorder in the order they returned, including the failed ones.
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Call(nil), r.calls...)
+}
+
+// Messages returns the messages that were sent successfully, in the order they were sent.
+func (r *Recorder) Messages() []*notify.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var messages []*notify.Message
+	for _, call := range r.calls {
+		if call.Err == nil {
+			messages = append(messages, call.Message)
+		}
+	}
+
+	return messages
+}
+
+// Reset forgets all recorded calls. Calls are counted from 1 again for FailNth.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = nil
+	r.n = 0
+}
+
+// wait returns the recorded calls if there are at least n of them. Otherwise, it returns a channel that is closed when
+// the next call is recorded.
+func (r *Recorder) wait(n int) ([]Call, <-chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.calls) >= n {
+		return append([]Call(nil), r.calls...), nil
+	}
+
+	return nil, r.recorded
+}
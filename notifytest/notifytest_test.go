@@ -0,0 +1,177 @@
+package notifytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/casdoor/notify"
+	"github.com/casdoor/notify/queue"
+)
+
+// fakeT records the errors reported by the assertions instead of failing the test.
+type fakeT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+type ctxKey struct{}
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	recorder := NewRecorder()
+	n := notify.NewWithServices(recorder)
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	require.NoError(t, n.Send(ctx, "first", "body"))
+	m := notify.NewMessage("second", "**bold**")
+	m.Format = notify.FormatMarkdown
+	require.NoError(t, n.SendMessage(ctx, m))
+	require.NoError(t, recorder.Send(context.Background(), "third", "plain"))
+
+	calls := recorder.Calls()
+	require.Len(t, calls, 3)
+	assert.Equal(t, "value", calls[0].Value(ctxKey{}))
+	assert.Equal(t, notify.FormatMarkdown, calls[1].Message.Format)
+	sent, ok := notify.MessageFromContext(calls[1].Context)
+	require.True(t, ok)
+	assert.Equal(t, "second", sent.Subject)
+	assert.Nil(t, calls[2].Value(ctxKey{}))
+
+	// The recorded messages are copies.
+	m.Subject = "changed"
+	assert.Equal(t, "second", recorder.Messages()[1].Subject)
+
+	assert.True(t, recorder.AssertSent(t, "first"))
+	assert.True(t, recorder.AssertNotSent(t, "fourth"))
+
+	ft := &fakeT{}
+	assert.False(t, recorder.AssertSent(ft, "fourth"))
+	assert.False(t, recorder.AssertNotSent(ft, "first"))
+	require.Len(t, ft.errors, 2)
+	assert.Contains(t, ft.errors[0], `["first" "second" "third"]`)
+
+	recorder.Reset()
+	assert.Empty(t, recorder.Calls())
+	assert.Empty(t, recorder.Messages())
+}
+
+func TestFailNth(t *testing.T) {
+	t.Parallel()
+
+	errDown := errors.New("down")
+	recorder := NewRecorder(FailNth(2, nil), FailNth(3, errDown))
+
+	ctx := context.Background()
+	require.NoError(t, recorder.Send(ctx, "1", ""))
+	require.ErrorIs(t, recorder.Send(ctx, "2", ""), ErrInjected)
+	require.ErrorIs(t, recorder.Send(ctx, "3", ""), errDown)
+	require.NoError(t, recorder.Send(ctx, "4", ""))
+
+	assert.Len(t, recorder.Calls(), 4)
+	assert.Len(t, recorder.Messages(), 2)
+	recorder.AssertSent(t, "1")
+	recorder.AssertNotSent(t, "2")
+	recorder.AssertNotSent(t, "3")
+
+	// Calls are counted from 1 again after a reset.
+	recorder.Reset()
+	require.NoError(t, recorder.Send(ctx, "1", ""))
+	require.Error(t, recorder.Send(ctx, "2", ""))
+}
+
+func TestWithLatency(t *testing.T) {
+	t.Parallel()
+
+	recorder := NewRecorder(WithLatency(20 * time.Millisecond))
+
+	start := time.Now()
+	require.NoError(t, recorder.Send(context.Background(), "slow", ""))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, recorder.Send(ctx, "canceled", ""), context.Canceled)
+	recorder.AssertNotSent(t, "canceled")
+}
+
+func TestWithFlakiness(t *testing.T) {
+	t.Parallel()
+
+	failures := func(options ...Option) []bool {
+		recorder := NewRecorder(options...)
+		var failed []bool
+		for i := 0; i < 100; i++ {
+			failed = append(failed, recorder.Send(context.Background(), "flaky", "") != nil)
+		}
+
+		return failed
+	}
+
+	count := func(failed []bool) int {
+		n := 0
+		for _, f := range failed {
+			if f {
+				n++
+			}
+		}
+
+		return n
+	}
+
+	first := failures(WithFlakiness(30, nil), WithSeed(1))
+	assert.Equal(t, first, failures(WithFlakiness(30, nil), WithSeed(1)), "the same seed gives the same failures")
+	assert.InDelta(t, 30, count(first), 15)
+	assert.Zero(t, count(failures(WithFlakiness(0, nil))))
+	assert.Equal(t, 100, count(failures(WithFlakiness(100, nil))))
+}
+
+func TestWaitForN(t *testing.T) {
+	t.Parallel()
+
+	recorder := NewRecorder()
+	n := notify.NewWithOptions(notify.WithAsync(queue.NewMemory(10), 2))
+	n.UseServices(recorder)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, n.Send(context.Background(), fmt.Sprintf("async %d", i), ""))
+	}
+
+	calls := recorder.WaitForN(t, 3, time.Second)
+	require.Len(t, calls, 3)
+	recorder.AssertSent(t, "async 2")
+	require.NoError(t, n.Close(context.Background()))
+
+	ft := &fakeT{}
+	assert.Nil(t, recorder.WaitForN(ft, 4, 10*time.Millisecond))
+	require.Len(t, ft.errors, 1)
+	assert.Contains(t, ft.errors[0], "got 3 calls")
+}
+
+// TestUseDefault doesn't run in parallel, since it replaces notify.Default.
+func TestUseDefault(t *testing.T) {
+	original := notify.Default()
+
+	t.Run("swap", func(t *testing.T) {
+		recorder := UseDefault(t, FailNth(1, nil))
+
+		require.ErrorIs(t, notify.Send(context.Background(), "first", ""), ErrInjected)
+		require.NoError(t, notify.Send(context.Background(), "second", ""))
+		recorder.AssertSent(t, "second")
+		assert.Len(t, recorder.Calls(), 2)
+	})
+
+	assert.Same(t, original, notify.Default(), "the default Notify instance is restored")
+}
@@ -0,0 +1,271 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Compile-time check to ensure File implements Queue.
+var _ Queue = (*File)(nil)
+
+const (
+	opPut = "put"
+	opAck = "ack"
+)
+
+// minCompactAcks is the number of acknowledged items the file has to hold at least before Ack compacts it.
+const minCompactAcks = 64
+
+// record is a single line of the append-only file of a File queue.
+type record struct {
+	Op   string `json:"op"`
+	Item *Item  `json:"item,omitempty"`
+	ID   string `json:"id,omitempty"`
+}
+
+// File is a durable queue. Every item is appended to a file before it's handed out and stays there until it was
+// acknowledged. When the file gets opened again, e.g. after a restart, all unacknowledged items are redelivered.
+//
+// The file is compacted every time it's opened, so that it only contains the pending items. It's also compacted by Ack
+// as soon as acknowledged items make up more than half of it.
+type File struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	pending *Memory
+	// unacked holds the items that weren't acknowledged yet, including those handed out by Get, in order.
+	unacked []Item
+	// acked is the number of acknowledged items that are still in the file.
+	acked int
+}
+
+// NewFile opens the queue file at path, creating it if necessary, and returns a durable queue that holds up to capacity
+// items in memory. Items that weren't acknowledged before the file was last closed are queued again first.
+func NewFile(path string, capacity int) (*File, error) {
+	items, err := readPending(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if capacity < len(items) {
+		capacity = len(items)
+	}
+
+	f := &File{
+		path:    path,
+		pending: NewMemory(capacity),
+	}
+
+	if err = f.compact(items); err != nil {
+		return nil, err
+	}
+	f.unacked = items
+
+	for _, item := range items {
+		if err = f.pending.Put(context.Background(), item); err != nil {
+			_ = f.file.Close()
+			return nil, errors.Wrap(err, "restore pending items")
+		}
+	}
+
+	return f, nil
+}
+
+// readPending replays the records of the file at path and returns all items that weren't acknowledged, in order.
+func readPending(path string) ([]Item, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "open queue file")
+	}
+	defer func() { _ = file.Close() }()
+
+	var order []string
+	items := make(map[string]Item)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var r record
+		if err = json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			// A torn write at the end of the file, e.g. after a crash. Everything before it is still valid.
+			break
+		}
+
+		switch {
+		case r.Op == opPut && r.Item != nil:
+			order = append(order, r.Item.ID)
+			items[r.Item.ID] = *r.Item
+		case r.Op == opAck:
+			delete(items, r.ID)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "read queue file")
+	}
+
+	pending := make([]Item, 0, len(items))
+	for _, id := range order {
+		if item, ok := items[id]; ok {
+			pending = append(pending, item)
+			delete(items, id) // Guard against duplicate put records.
+		}
+	}
+
+	return pending, nil
+}
+
+// compact atomically replaces the file with one that only contains the given items and opens it for appending. The
+// previously opened file, if any, is closed.
+func (f *File) compact(items []Item) error {
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "create temporary queue file")
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	w := bufio.NewWriter(tmp)
+	for i := range items {
+		if err = writeRecord(w, record{Op: opPut, Item: &items[i]}); err != nil {
+			_ = tmp.Close()
+			return err
+		}
+	}
+	if err = w.Flush(); err != nil {
+		_ = tmp.Close()
+		return errors.Wrap(err, "write temporary queue file")
+	}
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return errors.Wrap(err, "sync temporary queue file")
+	}
+	if err = tmp.Close(); err != nil {
+		return errors.Wrap(err, "close temporary queue file")
+	}
+	if err = os.Rename(tmp.Name(), f.path); err != nil {
+		return errors.Wrap(err, "replace queue file")
+	}
+
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return errors.Wrap(err, "open queue file")
+	}
+
+	if f.file != nil {
+		_ = f.file.Close()
+	}
+	f.file = file
+	f.acked = 0
+
+	return nil
+}
+
+func writeRecord(w interface{ Write([]byte) (int, error) }, r record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return errors.Wrap(err, "marshal queue record")
+	}
+
+	if _, err = w.Write(append(line, '\n')); err != nil {
+		return errors.Wrap(err, "write queue record")
+	}
+
+	return nil
+}
+
+// append durably writes the given record to the file.
+func (f *File) append(r record) error {
+	if f.file == nil {
+		return ErrClosed
+	}
+
+	if err := writeRecord(f.file, r); err != nil {
+		return err
+	}
+
+	return errors.Wrap(f.file.Sync(), "sync queue file")
+}
+
+// Put durably stores the item and appends it to the queue. It returns ErrFull if the queue reached its capacity.
+func (f *File) Put(ctx context.Context, item Item) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.pending.Len() == len(f.pending.buf) {
+		return ErrFull
+	}
+
+	item = prepare(item)
+	if err := f.append(record{Op: opPut, Item: &item}); err != nil {
+		return err
+	}
+	if err := f.pending.Put(ctx, item); err != nil {
+		return err
+	}
+	f.unacked = append(f.unacked, item)
+
+	return nil
+}
+
+// Get takes the next item out of the queue. It blocks until an item is available, ctx is done or the queue is closed.
+// The item stays in the file until it gets acknowledged.
+func (f *File) Get(ctx context.Context) (Item, error) {
+	return f.pending.Get(ctx)
+}
+
+// Ack durably marks the item with the given ID as processed, so that it won't be redelivered. If acknowledged items make
+// up more than half of the file afterwards, it gets compacted. The item stays acknowledged if compacting fails.
+func (f *File) Ack(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.append(record{Op: opAck, ID: id}); err != nil {
+		return err
+	}
+
+	for i := range f.unacked {
+		if f.unacked[i].ID == id {
+			f.unacked = append(f.unacked[:i], f.unacked[i+1:]...)
+			f.acked++
+			break
+		}
+	}
+
+	// Every acknowledged item takes two records, its put and its ack record.
+	if f.acked < minCompactAcks || 2*f.acked <= len(f.unacked) {
+		return nil
+	}
+
+	return errors.Wrap(f.compact(f.unacked), "compact queue file")
+}
+
+// Len returns the number of items waiting in the queue.
+func (f *File) Len() int {
+	return f.pending.Len()
+}
+
+// Close closes the queue and its file. Items that weren't acknowledged yet are redelivered by the next call to NewFile
+// with the same path.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_ = f.pending.Close()
+
+	if f.file == nil {
+		return nil
+	}
+
+	err := f.file.Close()
+	f.file = nil
+
+	return errors.Wrap(err, "close queue file")
+}
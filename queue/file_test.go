@@ -0,0 +1,102 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile_Redelivery(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "queue.log")
+
+	q, err := NewFile(path, 10)
+	assert.NoError(err)
+
+	for _, id := range []string{"a", "b", "c"} {
+		assert.NoError(q.Put(ctx, Item{ID: id, Payload: []byte(id)}))
+	}
+	assert.Equal(3, q.Len())
+
+	// Process "a" completely, take "b" out without acknowledging it and leave "c" untouched.
+	item, err := q.Get(ctx)
+	assert.NoError(err)
+	assert.NoError(q.Ack(item.ID))
+	_, err = q.Get(ctx)
+	assert.NoError(err)
+	assert.NoError(q.Close())
+	assert.NoError(q.Close())
+	assert.ErrorIs(q.Ack("c"), ErrClosed)
+
+	// Simulate a torn write at the end of the file.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	assert.NoError(err)
+	_, err = f.WriteString(`{"op":"ack","i`)
+	assert.NoError(err)
+	assert.NoError(f.Close())
+
+	q, err = NewFile(path, 1)
+	assert.NoError(err)
+	defer func() { _ = q.Close() }()
+	assert.Equal(2, q.Len())
+
+	item, err = q.Get(ctx)
+	assert.NoError(err)
+	assert.Equal("b", item.ID)
+	assert.Equal("b", string(item.Payload))
+	item, err = q.Get(ctx)
+	assert.NoError(err)
+	assert.Equal("c", item.ID)
+
+	// The capacity grew to hold the restored items.
+	assert.NoError(q.Put(ctx, Item{ID: "d"}))
+	assert.NoError(q.Put(ctx, Item{ID: "e"}))
+	assert.ErrorIs(q.Put(ctx, Item{ID: "f"}), ErrFull)
+}
+
+func TestFile_Compaction(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "queue.log")
+
+	q, err := NewFile(path, 1)
+	assert.NoError(err)
+
+	for i := 0; i < 10*minCompactAcks; i++ {
+		assert.NoError(q.Put(ctx, Item{Payload: []byte("payload")}))
+		item, err := q.Get(ctx)
+		assert.NoError(err)
+		assert.NoError(q.Ack(item.ID))
+	}
+	assert.NoError(q.Put(ctx, Item{ID: "pending"}))
+	assert.NoError(q.Close())
+
+	// The file only holds the records since the last compaction.
+	data, err := os.ReadFile(path)
+	assert.NoError(err)
+	assert.LessOrEqual(bytes.Count(data, []byte("\n")), 2*minCompactAcks+1)
+
+	q, err = NewFile(path, 1)
+	assert.NoError(err)
+	defer func() { _ = q.Close() }()
+	item, err := q.Get(ctx)
+	assert.NoError(err)
+	assert.Equal("pending", item.ID)
+	assert.Equal(0, q.Len())
+}
+
+func TestNewFile_InvalidPath(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewFile(filepath.Join(t.TempDir(), "missing", "queue.log"), 1)
+	require.Error(t, err)
+}
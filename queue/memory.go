@@ -0,0 +1,104 @@
+package queue
+
+import (
+	"context"
+	"sync"
+)
+
+// Compile-time check to ensure Memory implements Queue.
+var _ Queue = (*Memory)(nil)
+
+// DefaultCapacity is the capacity of a Memory queue that was created with a capacity of zero or less.
+const DefaultCapacity = 1024
+
+// Memory is a bounded, in-memory queue backed by a ring buffer. Its items are lost when the process exits.
+type Memory struct {
+	mu     sync.Mutex
+	buf    []Item
+	head   int
+	count  int
+	closed bool
+
+	// ready holds one token per item in the buffer, so that Get can wait for items and ctx at the same time.
+	ready chan struct{}
+	done  chan struct{}
+}
+
+// NewMemory returns a new in-memory queue that holds up to capacity items.
+func NewMemory(capacity int) *Memory {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+
+	return &Memory{
+		buf:   make([]Item, capacity),
+		ready: make(chan struct{}, capacity),
+		done:  make(chan struct{}),
+	}
+}
+
+// Put appends an item to the queue. It returns ErrFull if the queue reached its capacity.
+func (m *Memory) Put(_ context.Context, item Item) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	if m.count == len(m.buf) {
+		return ErrFull
+	}
+
+	m.buf[(m.head+m.count)%len(m.buf)] = prepare(item)
+	m.count++
+	m.ready <- struct{}{} // Never blocks, there's room for one token per item.
+
+	return nil
+}
+
+// Get takes the next item out of the queue. It blocks until an item is available, ctx is done or the queue is closed.
+func (m *Memory) Get(ctx context.Context) (Item, error) {
+	select {
+	case <-ctx.Done():
+		return Item{}, ctx.Err()
+	case <-m.done:
+		return Item{}, ErrClosed
+	case <-m.ready:
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item := m.buf[m.head]
+	m.buf[m.head] = Item{} // Don't keep a reference to the payload.
+	m.head = (m.head + 1) % len(m.buf)
+	m.count--
+
+	return item, nil
+}
+
+// Ack is a no-op, items are removed from a Memory queue as soon as they are taken out by Get.
+func (m *Memory) Ack(string) error {
+	return nil
+}
+
+// Len returns the number of items waiting in the queue.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.count
+}
+
+// Close closes the queue. Subsequent calls to Put and Get return ErrClosed.
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.closed {
+		m.closed = true
+		close(m.done)
+	}
+
+	return nil
+}
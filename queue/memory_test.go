@@ -0,0 +1,76 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+	ctx := context.Background()
+
+	q := NewMemory(2)
+	assert.Equal(0, q.Len())
+
+	assert.NoError(q.Put(ctx, Item{Payload: []byte("1")}))
+	assert.NoError(q.Put(ctx, Item{ID: "custom", Payload: []byte("2")}))
+	assert.ErrorIs(q.Put(ctx, Item{Payload: []byte("3")}), ErrFull)
+	assert.Equal(2, q.Len())
+
+	item, err := q.Get(ctx)
+	assert.NoError(err)
+	assert.Equal("1", string(item.Payload))
+	assert.NotEmpty(item.ID)
+	assert.False(item.EnqueuedAt.IsZero())
+	assert.NoError(q.Ack(item.ID))
+
+	// The ring buffer wraps around.
+	assert.NoError(q.Put(ctx, Item{Payload: []byte("3")}))
+
+	item, err = q.Get(ctx)
+	assert.NoError(err)
+	assert.Equal("custom", item.ID)
+
+	item, err = q.Get(ctx)
+	assert.NoError(err)
+	assert.Equal("3", string(item.Payload))
+	assert.Equal(0, q.Len())
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = q.Get(timeoutCtx)
+	assert.ErrorIs(err, context.DeadlineExceeded)
+
+	assert.NoError(q.Close())
+	assert.NoError(q.Close())
+	assert.ErrorIs(q.Put(ctx, Item{}), ErrClosed)
+	_, err = q.Get(ctx)
+	assert.ErrorIs(err, ErrClosed)
+}
+
+func TestMemory_GetBlocks(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+	ctx := context.Background()
+
+	q := NewMemory(0)
+	got := make(chan Item)
+	go func() {
+		item, _ := q.Get(ctx)
+		got <- item
+	}()
+
+	assert.NoError(q.Put(ctx, Item{ID: "late"}))
+	select {
+	case item := <-got:
+		assert.Equal("late", item.ID)
+	case <-time.After(time.Second):
+		t.Fatal("Get() did not return the item")
+	}
+}
@@ -0,0 +1,67 @@
+// Package queue provides the queues used by notify to send notifications asynchronously. A queue holds serialized
+// notifications until a worker takes them out and acknowledges that they were processed.
+//
+// Two implementations are provided: Memory, a bounded in-memory ring buffer, and File, which additionally keeps every
+// notification in an append-only file until it was acknowledged, so that it can be redelivered after a restart.
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrFull is returned by Put if the queue reached its capacity.
+	ErrFull = errors.New("queue is full")
+	// ErrClosed is returned by all operations of a closed queue.
+	ErrClosed = errors.New("queue is closed")
+)
+
+// Item is a single queued notification.
+type Item struct {
+	// ID uniquely identifies the item. Put assigns a new ID if it's empty.
+	ID string `json:"id"`
+	// Payload is the serialized notification.
+	Payload []byte `json:"payload"`
+	// EnqueuedAt is the time the item was put into the queue. Put sets it if it's zero.
+	EnqueuedAt time.Time `json:"enqueued_at"`
+}
+
+// Queue defines the behavior of a queue that is used to send notifications asynchronously.
+type Queue interface {
+	// Put appends an item to the queue. It must not block if the queue is full, but return ErrFull instead.
+	Put(ctx context.Context, item Item) error
+	// Get takes the next item out of the queue. It blocks until an item is available or ctx is done.
+	Get(ctx context.Context) (Item, error)
+	// Ack acknowledges that the item with the given ID was processed. Durable queues won't redeliver it afterwards.
+	Ack(id string) error
+	// Len returns the number of items waiting in the queue.
+	Len() int
+	// Close releases all resources held by the queue. Items that weren't acknowledged yet are redelivered by durable
+	// queues when they get opened again.
+	Close() error
+}
+
+// NewID returns a new random item ID.
+func NewID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// prepare fills in the ID and the enqueue time of an item if they are missing.
+func prepare(item Item) Item {
+	if item.ID == "" {
+		item.ID = NewID()
+	}
+	if item.EnqueuedAt.IsZero() {
+		item.EnqueuedAt = time.Now()
+	}
+
+	return item
+}
@@ -0,0 +1,19 @@
+package notify
+
+import "github.com/casdoor/notify/ratelimit"
+
+// WithRateLimiter is an Option that paces the deliveries of all services with the given limiter, e.g. to stay within
+// the limits of Telegram or Discord. Use ratelimit.New to create a limiter with the defaults for known platforms. A
+// nil limiter disables rate limiting again.
+//
+// The limiter applies to every single delivery to a receiver, including retries. Deliveries dropped by the limiter
+// fail with an error matching ratelimit.ErrLimited or ratelimit.ErrOverflow and aren't retried.
+func WithRateLimiter(limiter *ratelimit.Limiter) Option {
+	return func(n *Notify) {
+		if n == nil {
+			return
+		}
+
+		n.rateLimiter = limiter
+	}
+}
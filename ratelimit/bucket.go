@@ -0,0 +1,76 @@
+package ratelimit
+
+import "time"
+
+// bucket is a token bucket. It holds up to Burst tokens and refills at Rate tokens per second. Every delivery takes a
+// token. On top of that, a bucket can be paused, e.g. when a platform asked to retry after a while.
+type bucket struct {
+	limit       Limit
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+	waiting     int
+}
+
+// newBucket returns a full bucket for the given limit.
+func newBucket(limit Limit, now time.Time) *bucket {
+	return &bucket{limit: limit, tokens: float64(limit.burst()), last: now}
+}
+
+// advance refills the bucket up to now.
+func (b *bucket) advance(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.limit.Rate
+		b.last = now
+	}
+	if burst := float64(b.limit.burst()); b.tokens > burst {
+		b.tokens = burst
+	}
+}
+
+// delay returns how long a delivery at now would have to wait, without taking a token.
+func (b *bucket) delay(now time.Time) time.Duration {
+	b.advance(now)
+
+	var d time.Duration
+	if b.limit.Rate > 0 && b.tokens < 1 {
+		d = time.Duration((1 - b.tokens) / b.limit.Rate * float64(time.Second))
+	}
+	if paused := b.pausedUntil.Sub(now); paused > d {
+		d = paused
+	}
+
+	return d
+}
+
+// reserve takes a token and returns how long the delivery has to wait for it.
+func (b *bucket) reserve(now time.Time) time.Duration {
+	d := b.delay(now)
+	if b.limit.Rate > 0 {
+		b.tokens--
+	}
+
+	return d
+}
+
+// cancel returns a token taken by reserve, e.g. because the delivery was canceled while waiting.
+func (b *bucket) cancel() {
+	if b.limit.Rate > 0 {
+		b.tokens++
+	}
+}
+
+// idle reports whether the bucket is full, not paused and nobody waits for it at now. Such a bucket is just like a new
+// one, so it can be dropped.
+func (b *bucket) idle(now time.Time) bool {
+	b.advance(now)
+
+	return b.waiting == 0 && !b.pausedUntil.After(now) && b.tokens >= float64(b.limit.burst())
+}
+
+// pause makes deliveries wait until the given time.
+func (b *bucket) pause(until time.Time) {
+	if until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+}
@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucket(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	now := time.Unix(0, 0)
+	b := newBucket(Every(time.Second, 2), now)
+
+	assert.Zero(b.reserve(now))
+	assert.Zero(b.reserve(now))
+	assert.Equal(time.Second, b.reserve(now), "the third delivery has to wait for a refill")
+
+	b.cancel()
+	assert.Equal(time.Second, b.delay(now))
+	assert.Zero(b.delay(now.Add(time.Second)))
+
+	// A full bucket doesn't exceed its burst.
+	assert.Zero(b.reserve(now.Add(time.Hour)))
+	assert.Zero(b.reserve(now.Add(time.Hour)))
+	assert.Equal(time.Second, b.delay(now.Add(time.Hour)))
+}
+
+func TestBucket_Pause(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	now := time.Unix(0, 0)
+	b := newBucket(Limit{}, now)
+	assert.Zero(b.reserve(now), "a zero limit doesn't limit anything")
+
+	b.pause(now.Add(5 * time.Second))
+	b.pause(now.Add(time.Second)) // An earlier pause doesn't shorten the current one.
+	assert.Equal(5*time.Second, b.delay(now))
+	assert.Zero(b.delay(now.Add(5 * time.Second)))
+}
@@ -0,0 +1,31 @@
+package ratelimit
+
+import "time"
+
+// Defaults are the limits of the platforms that publish them, keyed by service name. They're applied by New unless
+// WithoutDefaults is used, and can be overridden per service with WithLimits. The values err on the safe side.
+var Defaults = map[string]Limits{
+	// Telegram allows about 30 messages per second overall and recommends to avoid more than one message per second
+	// in a single chat, see https://core.telegram.org/bots/faq#my-bot-is-hitting-limits-how-do-i-avoid-this.
+	"telegram": {Service: Every(time.Second/30, 30), Receiver: Every(time.Second, 1)},
+	// Discord allows 50 requests per second overall and 5 messages per 5 seconds in a single channel, see
+	// https://discord.com/developers/docs/topics/rate-limits.
+	"discord": {Service: Every(time.Second/50, 50), Receiver: Every(time.Second, 5)},
+	// Slack allows about one message per second in a single channel, see
+	// https://api.slack.com/methods/chat.postMessage#rate_limiting.
+	"slack": {Receiver: Every(time.Second, 1)},
+	// Twilio queues messages sent from a single long code number at one message per second, see
+	// https://help.twilio.com/articles/115002943027.
+	"twilio": {Service: Every(time.Second, 1)},
+	// Reddit allows 60 requests per minute per OAuth client, see https://github.com/reddit-archive/reddit/wiki/API.
+	"reddit": {Service: Every(time.Second, 1)},
+	// Twitter allows 1000 direct messages per 24 hours, see
+	// https://developer.twitter.com/en/docs/twitter-api/rate-limits.
+	"twitter": {Service: Every(24*time.Hour/1000, 15)},
+	// Microsoft Teams allows 4 requests per second on a single incoming webhook, see
+	// https://learn.microsoft.com/en-us/microsoftteams/platform/webhooks-and-connectors/how-to/connectors-using.
+	"msteams": {Receiver: Every(time.Second/4, 4)},
+	// Google Chat allows one message per second in a single space, see
+	// https://developers.google.com/chat/limits.
+	"googlechat": {Receiver: Every(time.Second, 1)},
+}
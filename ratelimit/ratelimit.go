@@ -0,0 +1,282 @@
+// Package ratelimit paces the deliveries of the notification services with token buckets, so that bots don't get
+// throttled or banned for bursting. A Limiter holds one bucket per service and one per receiver of a service, and comes
+// with defaults for the platforms that publish their limits. It also pauses a receiver when a platform answers with a
+// Retry-After, see retry.After and retry.HTTPError.
+//
+// A Limiter implements receiver.Throttle, so it applies to every service delivering through receiver.Each. Use
+// notify.WithRateLimiter to enable it. Services sending to all of their receivers in a single request, like the mail
+// services, aren't paced.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/receiver"
+	"github.com/casdoor/notify/retry"
+)
+
+// Compile-time check to ensure Limiter implements receiver.Throttle.
+var _ receiver.Throttle = (*Limiter)(nil)
+
+var (
+	// ErrLimited is returned in Drop mode for deliveries that exceed the limits.
+	ErrLimited = errors.New("rate limit exceeded")
+	// ErrOverflow is returned in Queue mode for deliveries that exceed the limits while the queue is full.
+	ErrOverflow = errors.New("rate limit queue overflow")
+)
+
+// Limit is the rate of a token bucket. The zero value means no limit.
+type Limit struct {
+	// Rate is the number of deliveries per second.
+	Rate float64
+	// Burst is the number of deliveries that may happen at once. It's at least 1.
+	Burst int
+}
+
+// Every returns a limit of one delivery per interval, allowing bursts of up to burst deliveries.
+func Every(interval time.Duration, burst int) Limit {
+	if interval <= 0 {
+		return Limit{}
+	}
+
+	return Limit{Rate: float64(time.Second) / float64(interval), Burst: burst}
+}
+
+// IsZero reports whether the limit doesn't limit anything.
+func (l Limit) IsZero() bool {
+	return l.Rate <= 0
+}
+
+func (l Limit) burst() int {
+	if l.Burst < 1 {
+		return 1
+	}
+
+	return l.Burst
+}
+
+// Limits are the limits of a single service.
+type Limits struct {
+	// Service limits the deliveries of the service to all of its receivers together.
+	Service Limit
+	// Receiver limits the deliveries of the service to every single receiver.
+	Receiver Limit
+}
+
+// Mode tells what happens to deliveries exceeding the limits.
+type Mode int
+
+const (
+	// Block makes deliveries wait until they're within the limits again. It's the default.
+	Block Mode = iota
+	// Drop fails deliveries exceeding the limits right away with ErrLimited.
+	Drop
+	// Queue works like Block, but fails deliveries with ErrOverflow if too many are waiting already, see WithMaxQueue.
+	Queue
+)
+
+// DefaultMaxQueue is the default number of deliveries that may wait for a single bucket in Queue mode.
+const DefaultMaxQueue = 100
+
+// sweepInterval is how often a Limiter drops its idle buckets, so that the buckets of receivers that aren't sent to
+// anymore don't pile up.
+const sweepInterval = time.Minute
+
+// Limiter paces deliveries with one token bucket per service and one per receiver of a service. Buckets that are full
+// again and not paused are dropped from time to time, so they don't pile up for receivers that aren't sent to anymore.
+type Limiter struct {
+	mu       sync.Mutex
+	limits   map[string]Limits
+	mode     Mode
+	maxQueue int
+	buckets  map[bucketKey]*bucket
+	swept    time.Time
+	now      func() time.Time
+}
+
+// bucketKey identifies a bucket. The receiver is empty for service buckets.
+type bucketKey struct {
+	service  string
+	receiver string
+}
+
+// Option is a function that can be used to configure a Limiter. It is used by the New function.
+type Option func(*Limiter)
+
+// WithLimits is an Option that sets the limits of the service with the given name, e.g. "telegram", replacing its
+// defaults.
+func WithLimits(service string, limits Limits) Option {
+	return func(l *Limiter) {
+		l.limits[service] = limits
+	}
+}
+
+// WithoutDefaults is an Option that drops the default limits. Only the limits set with WithLimits apply.
+func WithoutDefaults(l *Limiter) {
+	for service := range Defaults {
+		delete(l.limits, service)
+	}
+}
+
+// WithMode is an Option that sets what happens to deliveries exceeding the limits. The default is Block.
+func WithMode(mode Mode) Option {
+	return func(l *Limiter) {
+		l.mode = mode
+	}
+}
+
+// WithMaxQueue is an Option that sets the number of deliveries that may wait for a single bucket in Queue mode.
+func WithMaxQueue(n int) Option {
+	return func(l *Limiter) {
+		l.maxQueue = n
+	}
+}
+
+// New returns a new Limiter with the default limits, configured by the given options. Options are applied in order, so
+// WithoutDefaults should come first.
+func New(options ...Option) *Limiter {
+	l := &Limiter{
+		limits:   make(map[string]Limits, len(Defaults)),
+		mode:     Block,
+		maxQueue: DefaultMaxQueue,
+		buckets:  make(map[bucketKey]*bucket),
+		now:      time.Now,
+	}
+	for service, limits := range Defaults {
+		l.limits[service] = limits
+	}
+
+	for _, option := range options {
+		if option != nil {
+			option(l)
+		}
+	}
+
+	return l
+}
+
+// sweep drops the idle buckets, see bucket.idle, unless it already did so within the sweep interval.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < sweepInterval {
+		return
+	}
+	l.swept = now
+
+	for key, b := range l.buckets {
+		if b.idle(now) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// bucket returns the bucket with the given key, creating it if needed. It returns nil if the limit doesn't limit
+// anything and the bucket doesn't exist yet.
+func (l *Limiter) bucket(key bucketKey, limit Limit, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok && !limit.IsZero() {
+		b = newBucket(limit, now)
+		l.buckets[key] = b
+	}
+
+	return b
+}
+
+// bucketsFor returns the existing buckets that apply to a delivery.
+func (l *Limiter) bucketsFor(service, rcv string, now time.Time) []*bucket {
+	limits := l.limits[service]
+
+	buckets := make([]*bucket, 0, 2)
+	if b := l.bucket(bucketKey{service: service}, limits.Service, now); b != nil {
+		buckets = append(buckets, b)
+	}
+	if b := l.bucket(bucketKey{service: service, receiver: rcv}, limits.Receiver, now); b != nil {
+		buckets = append(buckets, b)
+	}
+
+	return buckets
+}
+
+// Wait blocks until a delivery of the given service to the given receiver is within the limits. Depending on the mode,
+// it fails right away instead, see Mode. It implements receiver.Throttle.
+func (l *Limiter) Wait(ctx context.Context, service, rcv string) error {
+	l.mu.Lock()
+	now := l.now()
+	l.sweep(now)
+	buckets := l.bucketsFor(service, rcv, now)
+
+	for _, b := range buckets {
+		if b.delay(now) <= 0 {
+			continue
+		}
+
+		switch {
+		case l.mode == Drop:
+			l.mu.Unlock()
+			return retry.Permanent(errors.Wrapf(ErrLimited, "%s receiver %q", service, rcv))
+		case l.mode == Queue && b.waiting >= l.maxQueue:
+			l.mu.Unlock()
+			return retry.Permanent(errors.Wrapf(ErrOverflow, "%s receiver %q", service, rcv))
+		}
+	}
+
+	var d time.Duration
+	for _, b := range buckets {
+		if bd := b.reserve(now); bd > d {
+			d = bd
+		}
+	}
+	if d <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	for _, b := range buckets {
+		b.waiting++
+	}
+	l.mu.Unlock()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timer.C:
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, b := range buckets {
+		b.waiting--
+		if err != nil {
+			b.cancel()
+		}
+	}
+
+	return err
+}
+
+// Done pauses the receiver if the delivery failed with an error asking to retry after a while, like a HTTP 429 response
+// with a Retry-After header. It implements receiver.Throttle.
+func (l *Limiter) Done(service, rcv string, err error) {
+	d, ok := retry.RetryAfter(err)
+	if !ok || d <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	key := bucketKey{service: service, receiver: rcv}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = newBucket(l.limits[service].Receiver, now)
+		l.buckets[key] = b
+	}
+	b.pause(now.Add(d))
+}
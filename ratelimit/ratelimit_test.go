@@ -0,0 +1,151 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/casdoor/notify/retry"
+)
+
+func TestLimiter_Defaults(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	l := New()
+	assert.Equal(Defaults["telegram"], l.limits["telegram"])
+
+	l = New(WithoutDefaults, WithLimits("slack", Limits{Receiver: Every(time.Minute, 1)}))
+	assert.Len(l.limits, 1)
+	assert.Equal(Every(time.Minute, 1), l.limits["slack"].Receiver)
+}
+
+func TestLimiter_Drop(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	now := time.Unix(0, 0)
+	l := New(WithoutDefaults, WithMode(Drop), WithLimits("telegram", Limits{
+		Service:  Every(time.Second, 3),
+		Receiver: Every(time.Second, 1),
+	}))
+	l.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	assert.NoError(l.Wait(ctx, "telegram", "1"))
+	err := l.Wait(ctx, "telegram", "1")
+	assert.ErrorIs(err, ErrLimited)
+	assert.True(retry.IsPermanent(err), "dropped deliveries must not be retried")
+
+	assert.NoError(l.Wait(ctx, "telegram", "2"))
+	assert.NoError(l.Wait(ctx, "telegram", "3"))
+	assert.ErrorIs(l.Wait(ctx, "telegram", "4"), ErrLimited, "the service limit applies to all receivers")
+
+	assert.NoError(l.Wait(ctx, "discord", "1"), "services without limits are not limited")
+
+	now = now.Add(time.Second)
+	assert.NoError(l.Wait(ctx, "telegram", "1"))
+}
+
+func TestLimiter_Block(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	l := New(WithoutDefaults, WithLimits("slack", Limits{Receiver: Every(50*time.Millisecond, 1)}))
+
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.NoError(l.Wait(ctx, "slack", "C1"))
+	}
+	assert.GreaterOrEqual(time.Since(start), 90*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(l.Wait(ctx, "slack", "C1"), context.Canceled)
+}
+
+func TestLimiter_Queue(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	l := New(WithoutDefaults, WithMode(Queue), WithMaxQueue(1),
+		WithLimits("slack", Limits{Receiver: Every(100*time.Millisecond, 1)}),
+	)
+
+	ctx := context.Background()
+	assert.NoError(l.Wait(ctx, "slack", "C1"))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.NoError(l.Wait(ctx, "slack", "C1"))
+	}()
+
+	// Wait until the goroutine is queued.
+	assert.Eventually(func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.buckets[bucketKey{service: "slack", receiver: "C1"}].waiting == 1
+	}, time.Second, time.Millisecond)
+
+	assert.ErrorIs(l.Wait(ctx, "slack", "C1"), ErrOverflow)
+	wg.Wait()
+}
+
+func TestLimiter_Sweep(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	now := time.Unix(0, 0)
+	l := New(WithoutDefaults, WithMode(Drop), WithLimits("telegram", Limits{Receiver: Every(time.Second, 1)}))
+	l.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	for _, rcv := range []string{"1", "2", "3"} {
+		assert.NoError(l.Wait(ctx, "telegram", rcv))
+	}
+	header := http.Header{"Retry-After": []string{"3600"}}
+	l.Done("telegram", "3", retry.HTTPError(http.StatusTooManyRequests, header, errors.New("429")))
+	assert.Len(l.buckets, 3)
+
+	// Receivers 1 and 2 got their tokens back, but 3 is still paused.
+	now = now.Add(sweepInterval)
+	assert.NoError(l.Wait(ctx, "telegram", "4"))
+	assert.Len(l.buckets, 2)
+	assert.Contains(l.buckets, bucketKey{service: "telegram", receiver: "3"})
+	assert.ErrorIs(l.Wait(ctx, "telegram", "3"), ErrLimited)
+}
+
+func TestLimiter_Done(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	now := time.Unix(0, 0)
+	l := New(WithoutDefaults, WithMode(Drop))
+	l.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	l.Done("bark", "https://api.day.app/", errors.New("some error"))
+	assert.NoError(l.Wait(ctx, "bark", "https://api.day.app/"))
+
+	header := http.Header{"Retry-After": []string{"10"}}
+	l.Done("bark", "https://api.day.app/", retry.HTTPError(http.StatusTooManyRequests, header, errors.New("429")))
+	assert.ErrorIs(l.Wait(ctx, "bark", "https://api.day.app/"), ErrLimited)
+	assert.NoError(l.Wait(ctx, "bark", "https://example.com/"), "only the throttled receiver is paused")
+
+	now = now.Add(10 * time.Second)
+	assert.NoError(l.Wait(ctx, "bark", "https://api.day.app/"))
+}
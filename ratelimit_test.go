@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/casdoor/notify/ratelimit"
+	"github.com/casdoor/notify/receiver"
+)
+
+func TestWithRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	limiter := ratelimit.New(
+		ratelimit.WithoutDefaults,
+		ratelimit.WithMode(ratelimit.Drop),
+		ratelimit.WithLimits("fake", ratelimit.Limits{Receiver: ratelimit.Every(time.Hour, 1)}),
+	)
+
+	sent := 0
+	n := NewWithOptions(WithRateLimiter(limiter))
+	n.UseServices(NotifierFunc(func(ctx context.Context, _, _ string) error {
+		return receiver.Each(ctx, "fake", []string{"a", "a"}, func(context.Context, string) error {
+			sent++
+			return nil
+		})
+	}))
+
+	report, err := n.SendWithReport(context.Background(), "subject", "message")
+	if !errors.Is(err, ratelimit.ErrLimited) {
+		t.Errorf("SendWithReport() error = %v, want %v", err, ratelimit.ErrLimited)
+	}
+	if sent != 1 {
+		t.Errorf("the service sent %d messages, want 1", sent)
+	}
+	if got := len(report.Services[0].Receivers); got != 2 {
+		t.Errorf("SendWithReport() was expected to report 2 receivers but reported %d", got)
+	}
+
+	WithRateLimiter(nil)(n)
+	if err := n.Send(context.Background(), "subject", "message"); err != nil {
+		t.Errorf("Send() without rate limiter unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package receiver
+
+import (
+	"context"
+	"strings"
+)
+
+// Delivery controls how Each delivers a message to the receivers of a service.
+type Delivery struct {
+	// Parallelism is the maximum number of receivers that are delivered to at the same time. Values below 2 deliver
+	// to one receiver after the other, which is the default. Services using Each must be safe for concurrent use,
+	// including changes to the service made while it sends, like the hooks added to the http service.
+	Parallelism int
+	// ContinueOnError makes Each attempt every receiver, instead of stopping at the first error. The failures are
+	// combined into an Errors value if more than one delivery failed.
+	ContinueOnError bool
+}
+
+type deliveryKey struct{}
+
+// WithDelivery returns a copy of ctx that carries the given Delivery. Every call to Each with the returned context, or a
+// context derived from it, delivers according to d.
+func WithDelivery(ctx context.Context, d Delivery) context.Context {
+	return context.WithValue(ctx, deliveryKey{}, d)
+}
+
+// DeliveryFromContext returns the Delivery bound to ctx, if any.
+func DeliveryFromContext(ctx context.Context) (Delivery, bool) {
+	d, ok := ctx.Value(deliveryKey{}).(Delivery)
+	return d, ok
+}
+
+// Errors combines the failures of several deliveries made by Each in ContinueOnError mode. They're kept in the order
+// of the receivers.
+type Errors []error
+
+// Error returns the messages of all errors, separated by semicolons. It implements the error interface.
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the combined errors, so that errors.Is and errors.As look at every single one of them.
+func (e Errors) Unwrap() []error {
+	return e
+}
+
+// combine returns nil if errs holds no error, the error itself if it holds exactly one, and an Errors value otherwise.
+func combine(errs []error) error {
+	var combined Errors
+	for _, err := range errs {
+		if err != nil {
+			combined = append(combined, err)
+		}
+	}
+
+	switch len(combined) {
+	case 0:
+		return nil
+	case 1:
+		return combined[0]
+	default:
+		return combined
+	}
+}
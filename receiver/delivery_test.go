@@ -0,0 +1,135 @@
+package receiver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEach_ContinueOnError(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	ctx := WithDelivery(context.Background(), Delivery{ContinueOnError: true})
+
+	errA, errC := errors.New("a failed"), errors.New("c failed")
+	var sent []string
+	err := Each(ctx, "test", []string{"a", "b", "c"}, func(_ context.Context, r string) error {
+		sent = append(sent, r)
+		switch r {
+		case "a":
+			return errA
+		case "c":
+			return errC
+		}
+		return nil
+	})
+	assert.Equal([]string{"a", "b", "c"}, sent)
+	assert.EqualError(err, "a failed; c failed")
+	assert.ErrorIs(err, errA)
+	assert.ErrorIs(err, errC)
+
+	var errs Errors
+	assert.ErrorAs(err, &errs)
+	assert.Len(errs, 2)
+
+	// A single failure is returned unchanged.
+	err = Each(ctx, "test", []string{"a", "b"}, func(_ context.Context, r string) error {
+		if r == "a" {
+			return errA
+		}
+		return nil
+	})
+	assert.Equal(errA, err)
+}
+
+func TestEach_Parallel(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	var mu sync.Mutex
+	var results []Result
+	ctx := WithRecorder(context.Background(), func(r Result) {
+		mu.Lock()
+		defer mu.Unlock()
+		results = append(results, r)
+	})
+	ctx = WithDelivery(ctx, Delivery{Parallelism: 2})
+
+	var running, maxRunning int32
+	err := Each(ctx, "test", []int{1, 2, 3, 4, 5, 6}, func(context.Context, int) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	assert.NoError(err)
+	assert.Len(results, 6)
+	assert.Equal(int32(2), atomic.LoadInt32(&maxRunning))
+}
+
+func TestEach_ParallelStopsAtFirstError(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	ctx := WithDelivery(context.Background(), Delivery{Parallelism: 2})
+
+	wantErr := errors.New("some error")
+	var sent int32
+	err := Each(ctx, "test", []int{1, 2, 3, 4, 5, 6}, func(_ context.Context, r int) error {
+		atomic.AddInt32(&sent, 1)
+		if r == 1 {
+			return wantErr
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	assert.Equal(wantErr, err)
+	assert.Less(atomic.LoadInt32(&sent), int32(6))
+}
+
+func TestEach_ParallelContinueOnError(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	ctx := WithDelivery(context.Background(), Delivery{Parallelism: 3, ContinueOnError: true})
+
+	var sent int32
+	err := Each(ctx, "test", []int{1, 2, 3, 4, 5}, func(_ context.Context, r int) error {
+		atomic.AddInt32(&sent, 1)
+		if r%2 == 0 {
+			return errors.New("even")
+		}
+		return nil
+	})
+	assert.EqualError(err, "even; even")
+	assert.Equal(int32(5), atomic.LoadInt32(&sent))
+}
+
+func TestDeliveryFromContext(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	_, ok := DeliveryFromContext(context.Background())
+	assert.False(ok)
+
+	d, ok := DeliveryFromContext(WithDelivery(context.Background(), Delivery{Parallelism: 4}))
+	assert.True(ok)
+	assert.Equal(4, d.Parallelism)
+}
@@ -0,0 +1,192 @@
+package receiver
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// Receiver is a typed receiver address, e.g. a chat ID, a phone number or an email address. Kind tells addresses of
+// different types apart for services that accept more than one, like the Lark receiver ID types "open_id" and
+// "email". Services with a single kind of receiver ignore it.
+type Receiver struct {
+	// Kind is the type of the address. It's empty for services with a single kind of receiver.
+	Kind string `json:"kind,omitempty"`
+	// ID is the address itself.
+	ID string `json:"id"`
+}
+
+// New returns a receiver with the given address and without a kind.
+func New(id string) Receiver {
+	return Receiver{ID: id}
+}
+
+// Typed returns a receiver with the given kind and address.
+func Typed(kind, id string) Receiver {
+	return Receiver{Kind: kind, ID: id}
+}
+
+// String returns the receiver as "kind:id", or just the ID if it has no kind. It implements the fmt.Stringer
+// interface.
+func (r Receiver) String() string {
+	if r.Kind == "" {
+		return r.ID
+	}
+
+	return r.Kind + ":" + r.ID
+}
+
+// Split returns the kind and the ID of the receiver. If the receiver has no kind, the ID is split at the first colon,
+// as long as the part in front of it is one of the given kinds. This way services accept both typed receivers and
+// plain strings like "email:jane@example.com".
+func (r Receiver) Split(kinds ...string) (kind, id string) {
+	if r.Kind != "" {
+		return r.Kind, r.ID
+	}
+
+	if k, i, found := strings.Cut(r.ID, ":"); found {
+		for _, kind := range kinds {
+			if k == kind {
+				return k, i
+			}
+		}
+	}
+
+	return "", r.ID
+}
+
+// Mode tells how the receivers bound to a context relate to the receivers configured on a service.
+type Mode int
+
+const (
+	// Replace sends to the bound receivers only.
+	Replace Mode = iota
+	// Append sends to the configured receivers and then to the bound receivers.
+	Append
+)
+
+type override struct {
+	mode      Mode
+	receivers []Receiver
+}
+
+type overridesKey struct{}
+
+// WithOverride returns a copy of ctx that carries receivers for the service with the given name, e.g. "slack". Services
+// send to them instead of, or in addition to, their configured receivers, depending on mode. A later call for the
+// same service takes precedence over an earlier one.
+func WithOverride(ctx context.Context, service string, mode Mode, receivers ...Receiver) context.Context {
+	parent, _ := ctx.Value(overridesKey{}).(map[string]override)
+
+	overrides := make(map[string]override, len(parent)+1)
+	for k, v := range parent {
+		overrides[k] = v
+	}
+	overrides[service] = override{mode: mode, receivers: append([]Receiver(nil), receivers...)}
+
+	return context.WithValue(ctx, overridesKey{}, overrides)
+}
+
+// Override returns the receivers bound to ctx for the service with the given name and how they relate to the
+// configured ones. The last return value is false if there are none.
+func Override(ctx context.Context, service string) (Mode, []Receiver, bool) {
+	overrides, _ := ctx.Value(overridesKey{}).(map[string]override)
+
+	o, ok := overrides[service]
+	return o.mode, o.receivers, ok
+}
+
+// Scope returns a copy of ctx that only carries the receivers bound for the service with the given name. Services that
+// send through other services, like Mattermost does through the HTTP service, pass it on to them, so that receivers
+// meant for the inner service by name can't redirect the requests of the outer one.
+func Scope(ctx context.Context, service string) context.Context {
+	overrides, _ := ctx.Value(overridesKey{}).(map[string]override)
+	if len(overrides) == 0 {
+		return ctx
+	}
+
+	scoped := make(map[string]override, 1)
+	if o, ok := overrides[service]; ok {
+		scoped[service] = o
+	}
+
+	return context.WithValue(ctx, overridesKey{}, scoped)
+}
+
+// Overridden returns the names of the services with receivers bound to ctx with WithOverride, sorted.
+func Overridden(ctx context.Context) []string {
+	overrides, _ := ctx.Value(overridesKey{}).(map[string]override)
+
+	services := make([]string, 0, len(overrides))
+	for service := range overrides {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	return services
+}
+
+// Binding holds the receivers bound to a context for a single service, see WithOverride.
+type Binding struct {
+	// Service is the name of the service.
+	Service string
+	// Mode tells how the receivers relate to the configured ones.
+	Mode Mode
+	// Receivers are the bound receivers.
+	Receivers []Receiver
+}
+
+// Bindings returns the receivers bound to ctx with WithOverride, sorted by service. Together with WithBindings, it
+// carries them over to sends that outlive ctx, like the ones of buffered or summarized messages.
+func Bindings(ctx context.Context) []Binding {
+	overrides, _ := ctx.Value(overridesKey{}).(map[string]override)
+
+	bindings := make([]Binding, 0, len(overrides))
+	for _, service := range Overridden(ctx) {
+		o := overrides[service]
+		bindings = append(bindings, Binding{Service: service, Mode: o.mode, Receivers: o.receivers})
+	}
+
+	return bindings
+}
+
+// WithBindings returns a copy of ctx that carries the given bindings, as if WithOverride was called for each of them.
+func WithBindings(ctx context.Context, bindings ...Binding) context.Context {
+	for _, b := range bindings {
+		ctx = WithOverride(ctx, b.Service, b.Mode, b.Receivers...)
+	}
+
+	return ctx
+}
+
+// Resolve returns the receivers a service should send to: the configured receivers, the ones bound to ctx with
+// WithOverride, or both. The bound receivers are converted to the native receiver type of the service with convert.
+func Resolve[T any](ctx context.Context, service string, configured []T, convert func(Receiver) (T, error)) ([]T, error) {
+	mode, receivers, ok := Override(ctx, service)
+	if !ok {
+		return configured, nil
+	}
+
+	resolved := make([]T, 0, len(configured)+len(receivers))
+	if mode == Append {
+		resolved = append(resolved, configured...)
+	}
+	for _, r := range receivers {
+		converted, err := convert(r)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, converted)
+	}
+
+	return resolved, nil
+}
+
+// ResolveIDs works like Resolve for services whose receivers are plain strings. It uses the IDs of the bound receivers.
+func ResolveIDs(ctx context.Context, service string, configured []string) []string {
+	resolved, _ := Resolve(ctx, service, configured, func(r Receiver) (string, error) {
+		return r.ID, nil
+	})
+
+	return resolved
+}
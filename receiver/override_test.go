@@ -0,0 +1,65 @@
+package receiver
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceiver_Split(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	kind, id := Typed("email", "a@example.com").Split()
+	assert.Equal("email", kind)
+	assert.Equal("a@example.com", id)
+
+	kind, id = New("email:a@example.com").Split("email", "open_id")
+	assert.Equal("email", kind)
+	assert.Equal("a@example.com", id)
+
+	kind, id = New("https://example.com").Split("email")
+	assert.Equal("", kind)
+	assert.Equal("https://example.com", id)
+
+	assert.Equal("email:a@example.com", Typed("email", "a@example.com").String())
+	assert.Equal("C123", New("C123").String())
+}
+
+func TestResolve(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	configured := []string{"a", "b"}
+	ctx := context.Background()
+	assert.Equal(configured, ResolveIDs(ctx, "slack", configured))
+
+	ctx = WithOverride(ctx, "slack", Replace, New("c"))
+	assert.Equal([]string{"c"}, ResolveIDs(ctx, "slack", configured))
+	assert.Equal(configured, ResolveIDs(ctx, "discord", configured), "overrides must only apply to their service")
+
+	appended := WithOverride(ctx, "slack", Append, New("d"))
+	assert.Equal([]string{"a", "b", "d"}, ResolveIDs(appended, "slack", configured))
+	assert.Equal([]string{"c"}, ResolveIDs(ctx, "slack", configured), "parent contexts must not be modified")
+
+	toInt := func(r Receiver) (int, error) { return strconv.Atoi(r.ID) }
+	ints, err := Resolve(WithOverride(ctx, "telegram", Append, New("3")), "telegram", []int{1, 2}, toInt)
+	assert.NoError(err)
+	assert.Equal([]int{1, 2, 3}, ints)
+
+	_, err = Resolve(WithOverride(ctx, "telegram", Replace, New("x")), "telegram", []int{1}, toInt)
+	assert.Error(err)
+
+	nested := Scope(WithOverride(ctx, "http", Replace, New("https://example.com")), "slack")
+	assert.Equal([]string{"c"}, ResolveIDs(nested, "slack", configured))
+	assert.Equal(configured, ResolveIDs(nested, "http", configured), "scoped overrides must only apply to their service")
+	assert.Equal([]string{"slack"}, Overridden(nested))
+
+	carried := WithBindings(context.Background(), Bindings(appended)...)
+	assert.Equal([]string{"a", "b", "d"}, ResolveIDs(carried, "slack", configured))
+	assert.Equal(Bindings(appended), Bindings(carried))
+}
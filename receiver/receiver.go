@@ -0,0 +1,295 @@
+// Package receiver provides the per-receiver delivery loop shared by the notification services. Services hand their
+// receivers to Each, which takes care of cancellation and reports the outcome of every single delivery to whoever is
+// listening on the context, e.g. notify.Notify.SendWithReport. Receivers bound to the context with WithOverride replace
+// or extend the receivers configured on a service for a single send, see Resolve.
+//
+// This package deliberately doesn't depend on the notify package itself, so that every service is able to use it.
+package receiver
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/casdoor/notify/logging"
+)
+
+// Result describes the outcome of delivering a message to a single receiver of a service.
+type Result struct {
+	// Service is the name of the service that delivered the message, e.g. "telegram".
+	Service string
+	// Receiver is the string representation of the receiver, e.g. a chat ID or an email address.
+	Receiver string
+	// Err is the error returned by the delivery. It's nil if the delivery succeeded.
+	Err error
+	// Duration is the time it took to deliver the message to the receiver.
+	Duration time.Duration
+}
+
+// Succeeded reports whether the delivery to the receiver succeeded.
+func (r Result) Succeeded() bool {
+	return r.Err == nil
+}
+
+// RecordFn is called with the outcome of every delivery to a single receiver.
+type RecordFn func(Result)
+
+type recorderKey struct{}
+
+// WithRecorder returns a copy of ctx that carries the given RecordFn. Every call to Each with the returned context, or a
+// context derived from it, reports its per-receiver outcomes to fn. Recorders are chained, so that an outer recorder
+// still receives the results when an inner one was added.
+func WithRecorder(ctx context.Context, fn RecordFn) context.Context {
+	if fn == nil {
+		return ctx
+	}
+
+	if parent, ok := ctx.Value(recorderKey{}).(RecordFn); ok {
+		inner := fn
+		fn = func(r Result) {
+			inner(r)
+			parent(r)
+		}
+	}
+
+	return context.WithValue(ctx, recorderKey{}, fn)
+}
+
+// Record reports the given result to the recorder bound to ctx, if any. Services that don't deliver their messages
+// through Each can use it to report their outcomes manually.
+func Record(ctx context.Context, result Result) {
+	if fn, ok := ctx.Value(recorderKey{}).(RecordFn); ok {
+		fn(result)
+	}
+}
+
+// Name returns the string representation of a receiver as used in a Result. Receivers implementing fmt.Stringer are
+// represented by their String method, all others by their default format.
+func Name(receiver any) string {
+	return fmt.Sprint(receiver)
+}
+
+type deliveredKey struct{}
+
+// WithDelivered returns a copy of ctx that makes Each skip the receivers of the given successful results, because they
+// already got the message, e.g. in an earlier attempt of a retried send. Results of failed deliveries are ignored.
+func WithDelivered(ctx context.Context, results ...Result) context.Context {
+	if len(results) == 0 {
+		return ctx
+	}
+
+	parent, _ := ctx.Value(deliveredKey{}).(map[string]map[string]bool)
+	delivered := make(map[string]map[string]bool, len(parent))
+	for service, receivers := range parent {
+		delivered[service] = receivers
+	}
+	for _, r := range results {
+		if !r.Succeeded() {
+			continue
+		}
+		receivers := make(map[string]bool, len(delivered[r.Service])+1)
+		for name := range delivered[r.Service] {
+			receivers[name] = true
+		}
+		receivers[r.Receiver] = true
+		delivered[r.Service] = receivers
+	}
+
+	return context.WithValue(ctx, deliveredKey{}, delivered)
+}
+
+// undelivered returns the receivers of the named service that weren't marked as delivered with WithDelivered.
+func undelivered[T any](ctx context.Context, service string, receivers []T) []T {
+	delivered, _ := ctx.Value(deliveredKey{}).(map[string]map[string]bool)
+	if len(delivered[service]) == 0 {
+		return receivers
+	}
+
+	remaining := make([]T, 0, len(receivers))
+	for _, r := range receivers {
+		if !delivered[service][Name(r)] {
+			remaining = append(remaining, r)
+		}
+	}
+
+	return remaining
+}
+
+// Throttle controls the pace of the deliveries made by Each, e.g. to respect the rate limits of a platform.
+type Throttle interface {
+	// Wait is called before every delivery. It blocks until the delivery may proceed, or returns an error if it must
+	// not proceed at all.
+	Wait(ctx context.Context, service, receiver string) error
+	// Done is called after every delivery with its outcome.
+	Done(service, receiver string, err error)
+}
+
+type throttleKey struct{}
+
+// WithThrottle returns a copy of ctx that carries the given Throttle. Every call to Each with the returned context, or a
+// context derived from it, consults t around every single delivery.
+func WithThrottle(ctx context.Context, t Throttle) context.Context {
+	if t == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, throttleKey{}, t)
+}
+
+// Observer is called before every delivery made by Each, with the name of the service and of the receiver. It returns
+// the context the delivery is made with, and a function that is called with the outcome of the delivery. Observers let
+// tracing wrap every single delivery in a span, which the requests made by the service become children of.
+type Observer func(ctx context.Context, service, receiver string) (context.Context, func(err error))
+
+type observerKey struct{}
+
+// WithObserver returns a copy of ctx that carries the given Observer. Every call to Each with the returned context, or a
+// context derived from it, calls o around every single delivery. Observers are chained, the outer one is called first
+// and its function last.
+func WithObserver(ctx context.Context, o Observer) context.Context {
+	if o == nil {
+		return ctx
+	}
+
+	if parent, ok := ctx.Value(observerKey{}).(Observer); ok {
+		inner := o
+		o = func(ctx context.Context, service, receiver string) (context.Context, func(error)) {
+			ctx, parentDone := parent(ctx, service, receiver)
+			ctx, innerDone := inner(ctx, service, receiver)
+			return ctx, func(err error) {
+				innerDone(err)
+				parentDone(err)
+			}
+		}
+	}
+
+	return context.WithValue(ctx, observerKey{}, o)
+}
+
+// Each calls send for every receiver in receivers, in order. It stops at the first error and returns it unchanged, so
+// services keep full control over their error messages. Each also stops as soon as ctx is done. The outcome of every
+// delivery is reported to the recorder bound to ctx and logged with the logger bound to ctx, see logging.Delivery. The
+// Throttle bound to ctx, if any, paces the deliveries, and the Observer bound to ctx, if any, is called around every
+// delivery.
+//
+// Receivers marked as delivered with WithDelivered are skipped. The Delivery bound to ctx, if any, lets Each deliver to
+// several receivers at the same time, and attempt every receiver despite errors. Services using Each must therefore
+// make sure that send is safe for concurrent use.
+func Each[T any](ctx context.Context, service string, receivers []T, send func(ctx context.Context, receiver T) error) error {
+	receivers = undelivered(ctx, service, receivers)
+	throttle, _ := ctx.Value(throttleKey{}).(Throttle)
+	observe, _ := ctx.Value(observerKey{}).(Observer)
+	delivery, _ := DeliveryFromContext(ctx)
+
+	deliver := func(r T) error {
+		name := Name(r)
+		start := time.Now()
+
+		deliveryCtx, done := ctx, func(error) {}
+		if observe != nil {
+			deliveryCtx, done = observe(ctx, service, name)
+		}
+
+		var err error
+		if throttle != nil {
+			err = throttle.Wait(deliveryCtx, service, name)
+		}
+		if err == nil {
+			err = send(deliveryCtx, r)
+			if throttle != nil {
+				throttle.Done(service, name, err)
+			}
+		}
+		done(err)
+
+		duration := time.Since(start)
+		Record(ctx, Result{
+			Service:  service,
+			Receiver: name,
+			Err:      err,
+			Duration: duration,
+		})
+		logging.Delivery(deliveryCtx, service, name, err, duration)
+
+		return err
+	}
+
+	if delivery.Parallelism > 1 && len(receivers) > 1 {
+		return eachParallel(ctx, receivers, delivery, deliver)
+	}
+
+	var errs []error
+	for _, r := range receivers {
+		select {
+		case <-ctx.Done():
+			return combine(append(errs, ctx.Err()))
+		default:
+		}
+
+		if err := deliver(r); err != nil {
+			if !delivery.ContinueOnError {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	return combine(errs)
+}
+
+// eachParallel calls deliver for every receiver, running at most delivery.Parallelism calls at the same time. Unless
+// delivery.ContinueOnError is set, no more calls are started after the first error, which is returned once the running
+// calls returned.
+func eachParallel[T any](ctx context.Context, receivers []T, delivery Delivery, deliver func(T) error) error {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		first error
+		errs  = make([]error, len(receivers)+1)
+		slots = make(chan struct{}, delivery.Parallelism)
+	)
+
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return first != nil
+	}
+
+loop:
+	for i, r := range receivers {
+		select {
+		case <-ctx.Done():
+			errs[len(receivers)] = ctx.Err()
+			break loop
+		case slots <- struct{}{}:
+		}
+
+		if !delivery.ContinueOnError && failed() {
+			<-slots
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, r T) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if err := deliver(r); err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+				errs[i] = err
+				if first == nil {
+					first = err
+				}
+			}
+		}(i, r)
+	}
+	wg.Wait()
+
+	if !delivery.ContinueOnError && first != nil {
+		return first
+	}
+
+	return combine(errs)
+}
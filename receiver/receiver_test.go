@@ -0,0 +1,190 @@
+package receiver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type stringer string
+
+func (s stringer) String() string { return "stringer:" + string(s) }
+
+func TestEach(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	var results []Result
+	ctx := WithRecorder(context.Background(), func(r Result) {
+		results = append(results, r)
+	})
+
+	var sent []int
+	err := Each(ctx, "test", []int{1, 2, 3}, func(_ context.Context, r int) error {
+		sent = append(sent, r)
+		return nil
+	})
+	assert.NoError(err)
+	assert.Equal([]int{1, 2, 3}, sent)
+	assert.Len(results, 3)
+	for i, r := range results {
+		assert.Equal("test", r.Service)
+		assert.Equal([]string{"1", "2", "3"}[i], r.Receiver)
+		assert.True(r.Succeeded())
+	}
+}
+
+func TestEach_StopsAtFirstError(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	var results []Result
+	ctx := WithRecorder(context.Background(), func(r Result) {
+		results = append(results, r)
+	})
+
+	wantErr := errors.New("some error")
+	err := Each(ctx, "test", []stringer{"a", "b", "c"}, func(_ context.Context, r stringer) error {
+		if r == "b" {
+			return wantErr
+		}
+		return nil
+	})
+	assert.ErrorIs(err, wantErr)
+	assert.Len(results, 2)
+	assert.Equal("stringer:a", results[0].Receiver)
+	assert.Equal("stringer:b", results[1].Receiver)
+	assert.ErrorIs(results[1].Err, wantErr)
+}
+
+func TestEach_ContextDone(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	err := Each(ctx, "test", []string{"a"}, func(context.Context, string) error {
+		called = true
+		return nil
+	})
+	assert.ErrorIs(err, context.Canceled)
+	assert.False(called)
+}
+
+func TestWithDelivered(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	ctx := WithDelivered(context.Background(),
+		Result{Service: "test", Receiver: "a"},
+		Result{Service: "test", Receiver: "b", Err: errors.New("some error")},
+		Result{Service: "other", Receiver: "c"},
+	)
+	ctx = WithDelivered(ctx, Result{Service: "test", Receiver: "c"})
+
+	var sent []string
+	err := Each(ctx, "test", []string{"a", "b", "c", "d"}, func(_ context.Context, r string) error {
+		sent = append(sent, r)
+		return nil
+	})
+	assert.NoError(err)
+	assert.Equal([]string{"b", "d"}, sent)
+}
+
+func TestWithRecorder_Chained(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	var outer, inner int
+	ctx := WithRecorder(context.Background(), func(Result) { outer++ })
+	ctx = WithRecorder(ctx, func(Result) { inner++ })
+	ctx = WithRecorder(ctx, nil)
+
+	Record(ctx, Result{})
+	assert.Equal(1, outer)
+	assert.Equal(1, inner)
+
+	// No recorder bound, must not panic.
+	Record(context.Background(), Result{})
+}
+
+// countingThrottle counts the calls of Wait and Done and fails Wait for the receiver "blocked".
+type countingThrottle struct {
+	waits, dones int
+}
+
+func (c *countingThrottle) Wait(_ context.Context, _, receiver string) error {
+	c.waits++
+	if receiver == "blocked" {
+		return errors.New("blocked")
+	}
+	return nil
+}
+
+func (c *countingThrottle) Done(string, string, error) {
+	c.dones++
+}
+
+func TestEach_Throttle(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	throttle := &countingThrottle{}
+	ctx := WithThrottle(context.Background(), throttle)
+
+	var sent []string
+	err := Each(ctx, "test", []string{"a", "blocked", "c"}, func(_ context.Context, r string) error {
+		sent = append(sent, r)
+		return nil
+	})
+	assert.EqualError(err, "blocked")
+	assert.Equal([]string{"a"}, sent)
+	assert.Equal(2, throttle.waits)
+	assert.Equal(1, throttle.dones)
+}
+
+type observedKey struct{}
+
+func TestEach_Observer(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	var calls []string
+	observer := func(name string) Observer {
+		return func(ctx context.Context, service, receiver string) (context.Context, func(error)) {
+			calls = append(calls, name+" start "+service+"/"+receiver)
+			ctx = context.WithValue(ctx, observedKey{}, name)
+			return ctx, func(err error) {
+				calls = append(calls, fmt.Sprintf("%s done %v", name, err))
+			}
+		}
+	}
+	ctx := WithObserver(context.Background(), observer("outer"))
+	ctx = WithObserver(ctx, observer("inner"))
+	ctx = WithObserver(ctx, nil)
+
+	err := Each(ctx, "test", []string{"a", "b"}, func(ctx context.Context, r string) error {
+		assert.Equal("inner", ctx.Value(observedKey{}), "the delivery gets the context of the observers")
+		if r == "b" {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	assert.EqualError(err, "failed")
+	assert.Equal([]string{
+		"outer start test/a", "inner start test/a", "inner done <nil>", "outer done <nil>",
+		"outer start test/b", "inner start test/b", "inner done failed", "outer done failed",
+	}, calls)
+}
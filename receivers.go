@@ -0,0 +1,36 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/casdoor/notify/receiver"
+)
+
+// Receiver is a typed receiver address. See the receiver package for details.
+type Receiver = receiver.Receiver
+
+// WithReceivers returns a copy of ctx that makes the service with the given name send to the given receivers instead of
+// its configured ones. The name is the one services use in per-receiver results, e.g. "slack", "telegram" or "mail".
+// Receivers are passed as strings and converted by the service, e.g. Telegram expects numeric chat IDs and Lark
+// expects its receiver ID types as prefix, like "email:xyz@example.com".
+//
+// Values bound to the context aren't available in asynchronous mode, use the Receivers field of Message instead.
+func WithReceivers(ctx context.Context, service string, receivers ...string) context.Context {
+	return receiver.WithOverride(ctx, service, receiver.Replace, ids(receivers)...)
+}
+
+// WithAdditionalReceivers works like WithReceivers, but makes the service send to the given receivers in addition to
+// its configured ones.
+func WithAdditionalReceivers(ctx context.Context, service string, receivers ...string) context.Context {
+	return receiver.WithOverride(ctx, service, receiver.Append, ids(receivers)...)
+}
+
+// ids turns receiver addresses into receivers without a kind.
+func ids(receivers []string) []Receiver {
+	converted := make([]Receiver, 0, len(receivers))
+	for _, r := range receivers {
+		converted = append(converted, receiver.New(r))
+	}
+
+	return converted
+}
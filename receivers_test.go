@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casdoor/notify/receiver"
+)
+
+func TestWithReceivers(t *testing.T) {
+	t.Parallel()
+
+	var got []string
+	n := NewWithServices(NotifierFunc(func(ctx context.Context, _, _ string) error {
+		got = receiver.ResolveIDs(ctx, "slack", []string{"C1"})
+		return nil
+	}))
+
+	ctx := WithReceivers(context.Background(), "slack", "C2", "C3")
+	if err := n.Send(ctx, "subject", "message"); err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0] != "C2" || got[1] != "C3" {
+		t.Errorf("WithReceivers() resolved to %v", got)
+	}
+
+	ctx = WithAdditionalReceivers(context.Background(), "slack", "C2")
+	if err := n.Send(ctx, "subject", "message"); err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0] != "C1" || got[1] != "C2" {
+		t.Errorf("WithAdditionalReceivers() resolved to %v", got)
+	}
+
+	msg := NewMessage("subject", "message")
+	msg.Receivers = map[string][]string{"slack": {"C4"}}
+	if err := n.SendMessage(ctx, msg); err != nil {
+		t.Fatalf("SendMessage() unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0] != "C4" {
+		t.Errorf("Message.Receivers resolved to %v", got)
+	}
+}
@@ -0,0 +1,272 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/receiver"
+)
+
+// ServiceResult describes the outcome of a send for a single registered service.
+type ServiceResult struct {
+	// Index is the position of the service in the order it was registered with the Notify instance.
+	Index int
+	// Name identifies the service. It's the name given with UseNamedService or, by default, the Go type of the service,
+	// e.g. "*telegram.Telegram".
+	Name string
+	// Service is the registered service itself.
+	Service Notifier
+	// Err is the error returned by the service. It's nil if the service succeeded.
+	Err error
+	// Duration is the time it took the service to send the message, including all retries.
+	Duration time.Duration
+	// Attempts is the number of times the service was called. It's greater than 1 only if retries are enabled, see
+	// WithRetry.
+	Attempts int
+	// Receivers holds the per-receiver outcomes reported by the service. It's empty for services that don't report
+	// them, see the receiver package.
+	Receivers []receiver.Result
+	// Hops holds the hops tried by composite services, in the order they were tried, see Fallback. It's empty for
+	// other services.
+	Hops []HopResult
+}
+
+// Succeeded reports whether the service sent the message successfully.
+func (r ServiceResult) Succeeded() bool {
+	return r.Err == nil
+}
+
+// DeliveredBy returns the hop of a composite service that delivered the message, see Fallback. The second return value
+// is false if no hop delivered it.
+func (r ServiceResult) DeliveredBy() (HopResult, bool) {
+	for _, hop := range r.Hops {
+		if hop.Err == nil {
+			return hop, true
+		}
+	}
+
+	return HopResult{}, false
+}
+
+// Report is a structured summary of a send. It holds one ServiceResult per registered service, in the order in which
+// the services were registered.
+type Report struct {
+	Services []ServiceResult
+}
+
+// Succeeded returns the results of all services that sent the message successfully.
+func (r *Report) Succeeded() []ServiceResult {
+	return r.filter(true)
+}
+
+// Failed returns the results of all services that failed to send the message.
+func (r *Report) Failed() []ServiceResult {
+	return r.filter(false)
+}
+
+func (r *Report) filter(succeeded bool) []ServiceResult {
+	if r == nil {
+		return nil
+	}
+
+	var results []ServiceResult
+	for _, result := range r.Services {
+		if result.Succeeded() == succeeded {
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
+
+// Err returns a *SendError holding the errors of all failed services, or nil if no service failed.
+func (r *Report) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+
+	errs := make([]error, 0, len(failed))
+	for _, result := range failed {
+		errs = append(errs, result.Err)
+	}
+
+	return &SendError{Errors: errs}
+}
+
+// SendError is returned when one or more services failed to send a notification. Unlike a plain wrapped error, it keeps
+// every single failure. It matches ErrSendNotification when used with errors.Is.
+type SendError struct {
+	Errors []error
+}
+
+// Error returns the messages of all errors, separated by semicolons and followed by the ErrSendNotification message.
+func (e *SendError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "; ") + ": " + ErrSendNotification.Error()
+}
+
+// Unwrap returns all errors held by the SendError. It allows errors.Is and errors.As to inspect each of them.
+func (e *SendError) Unwrap() []error {
+	return e.Errors
+}
+
+// Is reports whether target is ErrSendNotification. It's what keeps the SendError compatible with the error previously
+// returned by Send.
+func (e *SendError) Is(target error) bool {
+	return target == ErrSendNotification //nolint:errorlint // Comparing the sentinel itself on purpose.
+}
+
+// serviceName returns the name used to identify the given service in a Report.
+func serviceName(service Notifier) string {
+	return fmt.Sprintf("%T", service)
+}
+
+// name returns the name of the service at index i, see UseNamedService.
+func (n *Notify) name(i int, service Notifier) string {
+	if name, ok := n.names[i]; ok {
+		return name
+	}
+
+	return serviceName(service)
+}
+
+// sendWithReport calls the notification services selected by selector to send the given message to their respective
+// endpoints and collects the outcome of each of them. A nil selector lets the routes pick the services. The returned
+// error is the one of Report.Err, or the error of an invalid route, see WithRoutes.
+func (n *Notify) sendWithReport(ctx context.Context, m *Message, selector *Selector) (*Report, error) {
+	report := &Report{Services: make([]ServiceResult, 0, len(n.notifiers))}
+	if n.Disabled {
+		return report, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// A template that fails to render makes every service fail with the error, see sendTo.
+	registry := n.registry(ctx)
+	if rendered, err := render(registry, m, FormatPlain); err == nil {
+		m = rendered
+	}
+	if registry != nil {
+		ctx = context.WithValue(ctx, templatesKey{}, registry)
+	}
+
+	if selector == nil {
+		var err error
+		if selector, err = n.selector(m); err != nil {
+			return report, err
+		}
+	}
+	dryRun := dryRunFrom(ctx) != nil
+	tel := n.telemetry
+	if dryRun {
+		tel = nil
+	} else {
+		ctx = logging.WithLogger(ctx, n.logger)
+	}
+	ctx, endSend := tel.startSend(ctx, m)
+	ctx = n.withDelivery(ctx)
+	chained := n.chained()
+
+	var wg sync.WaitGroup
+	results := make([]*ServiceResult, len(n.notifiers))
+	for i, service := range n.notifiers {
+		if service == nil || !n.selected(i, selector) {
+			continue
+		}
+		wrapped := n.wrap(i, service)
+
+		result := &ServiceResult{
+			Index:   i,
+			Name:    n.name(i, service),
+			Service: service,
+		}
+		results[i] = result
+
+		wg.Add(1)
+		go func(service Notifier) {
+			defer wg.Done()
+
+			var mu sync.Mutex
+			serviceCtx := receiver.WithRecorder(ctx, func(r receiver.Result) {
+				mu.Lock()
+				defer mu.Unlock()
+				result.Receivers = append(result.Receivers, r)
+			})
+			serviceCtx = withHopRecorder(serviceCtx, func(hop HopResult) {
+				mu.Lock()
+				defer mu.Unlock()
+				result.Hops = append(result.Hops, hop)
+			})
+			if n.rateLimiter != nil {
+				serviceCtx = receiver.WithThrottle(serviceCtx, n.rateLimiter)
+			}
+			serviceCtx = withDryRunService(serviceCtx, result.Name, chained)
+			serviceCtx, endService := tel.startService(serviceCtx, result.Name)
+
+			attempts := 0
+			send := func(ctx context.Context) error {
+				attempts++
+				err := sendTo(ctx, wrapped, m)
+				if err != nil && n.retryPolicy != nil && !dryRun {
+					logAttempt(ctx, result.Name, attempts, err)
+				}
+				return err
+			}
+
+			release, err := n.acquire(serviceCtx)
+			start := time.Now()
+			if err == nil {
+				if n.retryPolicy != nil {
+					err = retrySend(serviceCtx, *n.retryPolicy, send)
+				} else {
+					err = send(serviceCtx)
+				}
+				release()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			result.Err = err
+			result.Duration = time.Since(start)
+			result.Attempts = attempts
+			endService(result)
+			if !dryRun {
+				logResult(serviceCtx, result)
+				n.emitResult(serviceCtx, m, result)
+			}
+		}(service)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if result != nil {
+			report.Services = append(report.Services, *result)
+		}
+	}
+	endSend(report)
+
+	return report, report.Err()
+}
+
+// SendWithReport calls the underlying notification services to send the given subject and message to their respective
+// endpoints. Unlike Send, it returns a Report with the outcome of every single service. The returned error is the same
+// one Send would have returned.
+func (n *Notify) SendWithReport(ctx context.Context, subject, message string) (*Report, error) {
+	return n.sendWithReport(ctx, NewMessage(subject, message), nil)
+}
+
+// SendWithReport calls the underlying notification services to send the given subject and message to their respective
+// endpoints and returns a Report with the outcome of every single service.
+func SendWithReport(ctx context.Context, subject, message string) (*Report, error) {
+	return std.SendWithReport(ctx, subject, message)
+}
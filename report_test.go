@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/casdoor/notify/receiver"
+)
+
+func TestSendWithReport(t *testing.T) {
+	t.Parallel()
+
+	errFirst := errors.New("first failure")
+	errSecond := errors.New("second failure")
+
+	n := NewWithServices(
+		NotifierFunc(func(ctx context.Context, _, _ string) error {
+			return receiver.Each(ctx, "fake", []string{"a", "b"}, func(context.Context, string) error { return nil })
+		}),
+		NotifierFunc(func(context.Context, string, string) error { return errFirst }),
+		NotifierFunc(func(context.Context, string, string) error { return nil }),
+		NotifierFunc(func(context.Context, string, string) error { return errSecond }),
+	)
+
+	report, err := n.SendWithReport(context.Background(), "subject", "message")
+	if report == nil {
+		t.Fatal("SendWithReport() returned nil report")
+	}
+	if len(report.Services) != 4 {
+		t.Fatalf("SendWithReport() was expected to report 4 services but reported %d", len(report.Services))
+	}
+	for i, result := range report.Services {
+		if result.Index != i {
+			t.Errorf("Services[%d].Index = %d", i, result.Index)
+		}
+		if result.Name != "notify.NotifierFunc" {
+			t.Errorf("Services[%d].Name = %q", i, result.Name)
+		}
+	}
+	if got := len(report.Services[0].Receivers); got != 2 {
+		t.Errorf("Services[0] was expected to report 2 receivers but reported %d", got)
+	}
+	if got := len(report.Succeeded()); got != 2 {
+		t.Errorf("Succeeded() was expected to return 2 results but returned %d", got)
+	}
+	if got := len(report.Failed()); got != 2 {
+		t.Errorf("Failed() was expected to return 2 results but returned %d", got)
+	}
+
+	if !errors.Is(err, ErrSendNotification) {
+		t.Errorf("SendWithReport() error does not match ErrSendNotification: %v", err)
+	}
+	if !errors.Is(err, errFirst) || !errors.Is(err, errSecond) {
+		t.Errorf("SendWithReport() error does not keep every failure: %v", err)
+	}
+	if want := "first failure; second failure: send notification"; err.Error() != want {
+		t.Errorf("SendWithReport() error = %q, want %q", err, want)
+	}
+
+	var sendErr *SendError
+	if !errors.As(err, &sendErr) || len(sendErr.Errors) != 2 {
+		t.Errorf("SendWithReport() error is not a SendError with 2 errors: %v", err)
+	}
+
+	if err := n.Send(context.Background(), "subject", "message"); !errors.Is(err, errSecond) {
+		t.Errorf("Send() error does not keep every failure: %v", err)
+	}
+}
+
+func TestSendWithReport_Disabled(t *testing.T) {
+	t.Parallel()
+
+	n := NewWithServices(NotifierFunc(func(context.Context, string, string) error {
+		return errors.New("some error")
+	}))
+	n.WithOptions(Disable)
+
+	report, err := n.SendWithReport(context.Background(), "subject", "message")
+	if err != nil {
+		t.Errorf("SendWithReport() of disabled Notifier returned error: %v", err)
+	}
+	if len(report.Services) != 0 {
+		t.Errorf("SendWithReport() of disabled Notifier reported %d services", len(report.Services))
+	}
+}
@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"context"
+	"sync"
+
+	"github.com/casdoor/notify/receiver"
+	"github.com/casdoor/notify/retry"
+)
+
+// retryNotifier is a Notifier that retries the sends of the wrapped service according to a retry.Policy.
+type retryNotifier struct {
+	service Notifier
+	policy  retry.Policy
+}
+
+// Retry wraps the given service, so that its failed sends are retried according to the given policy. Services that
+// deliver to their receivers with receiver.Each only retry the receivers that didn't get the message yet. Other
+// services send the message to all of their receivers again, including those that already received it.
+func Retry(service Notifier, policy retry.Policy) Notifier {
+	return &retryNotifier{service: service, policy: policy}
+}
+
+// Send calls the wrapped service until it succeeds or the policy gives up. It returns the error of the last attempt.
+func (r *retryNotifier) Send(ctx context.Context, subject, message string) error {
+	return r.SendMessage(ctx, messageFor(ctx, subject, message))
+}
+
+// SendMessage works like Send, but passes the full message on to the wrapped service.
+func (r *retryNotifier) SendMessage(ctx context.Context, m *Message) error {
+	return retrySend(ctx, r.policy, func(ctx context.Context) error {
+		return sendTo(ctx, r.service, m)
+	})
+}
+
+// Unwrap returns the wrapped service. It implements Wrapper.
+func (r *retryNotifier) Unwrap() []Notifier {
+	return []Notifier{r.service}
+}
+
+// retrySend calls send according to the given policy. Receivers that got the message in one attempt are skipped by the
+// following ones, see receiver.WithDelivered.
+func retrySend(ctx context.Context, policy retry.Policy, send func(ctx context.Context) error) error {
+	var (
+		mu        sync.Mutex
+		delivered []receiver.Result
+	)
+	ctx = receiver.WithRecorder(ctx, func(r receiver.Result) {
+		if r.Succeeded() {
+			mu.Lock()
+			defer mu.Unlock()
+			delivered = append(delivered, r)
+		}
+	})
+
+	return retry.Do(ctx, policy, func(ctx context.Context) error {
+		mu.Lock()
+		attemptCtx := receiver.WithDelivered(ctx, delivered...)
+		mu.Unlock()
+
+		return send(attemptCtx)
+	})
+}
+
+// WithRetry is an Option that retries the failed sends of every service according to the given policy. Each service is
+// retried on its own, so a failing service never causes a message to be sent twice by another one, and services
+// delivering with receiver.Each only retry their failed receivers. The number of attempts is reported in
+// ServiceResult.Attempts.
+func WithRetry(policy retry.Policy) Option {
+	return func(n *Notify) {
+		if n != nil {
+			n.retryPolicy = &policy
+		}
+	}
+}
+
+// WithoutRetry is an Option that disables retries again. This is the default behavior.
+func WithoutRetry(n *Notify) {
+	if n != nil {
+		n.retryPolicy = nil
+	}
+}
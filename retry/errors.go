@@ -0,0 +1,164 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// classifiedError wraps an error with the information whether it's worth retrying.
+type classifiedError struct {
+	err           error
+	permanent     bool
+	retryAfter    time.Duration
+	hasRetryAfter bool
+	statusCode    int
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks err as permanent. Permanent errors, e.g. an invalid receiver or rejected credentials, are never
+// retried. It returns nil if err is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &classifiedError{err: err, permanent: true}
+}
+
+// Transient marks err as transient. Transient errors, e.g. a timeout or an unavailable server, are retried. It returns
+// nil if err is nil.
+func Transient(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &classifiedError{err: err}
+}
+
+// After marks err as transient and asks to wait at least the given duration before the next attempt. It's meant for
+// responses like HTTP 429 with a Retry-After header. It returns nil if err is nil.
+func After(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+
+	return &classifiedError{err: err, retryAfter: d, hasRetryAfter: true}
+}
+
+// HTTPError classifies err by the given HTTP status code of the response that caused it:
+//
+//   - 429 Too Many Requests is transient and honors the Retry-After header.
+//   - 408 Request Timeout and all 5xx codes are transient.
+//   - All other 4xx codes are permanent.
+//
+// Errors with any other status code are returned unchanged. The status code can be read back with StatusCode. It
+// returns nil if err is nil.
+func HTTPError(statusCode int, header http.Header, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	classified := &classifiedError{err: err, statusCode: statusCode}
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		classified.retryAfter, classified.hasRetryAfter = ParseRetryAfter(header.Get("Retry-After"))
+	case statusCode == http.StatusRequestTimeout, statusCode >= 500:
+	case statusCode >= 400:
+		classified.permanent = true
+	default:
+		return err
+	}
+
+	return classified
+}
+
+// ParseRetryAfter parses the value of a Retry-After header, given either in seconds or as an HTTP date.
+func ParseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		d := time.Until(date)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+
+	return 0, false
+}
+
+func classification(err error) (*classifiedError, bool) {
+	var classified *classifiedError
+	ok := errors.As(err, &classified)
+
+	return classified, ok
+}
+
+// IsPermanent reports whether err, or any error it wraps, was marked as permanent.
+func IsPermanent(err error) bool {
+	classified, ok := classification(err)
+	return ok && classified.permanent
+}
+
+// IsTransient reports whether err, or any error it wraps, was marked as transient.
+func IsTransient(err error) bool {
+	classified, ok := classification(err)
+	return ok && !classified.permanent
+}
+
+// RetryAfter returns the minimum delay before the next attempt requested by err, if any.
+func RetryAfter(err error) (time.Duration, bool) {
+	classified, ok := classification(err)
+	if !ok || !classified.hasRetryAfter {
+		return 0, false
+	}
+
+	return classified.retryAfter, true
+}
+
+// StatusCode returns the HTTP status code err was classified with by HTTPError, if any.
+func StatusCode(err error) (int, bool) {
+	classified, ok := classification(err)
+	if !ok || classified.statusCode == 0 {
+		return 0, false
+	}
+
+	return classified.statusCode, true
+}
+
+// IsRetryable is the default classifier of a Policy. Errors marked as permanent and canceled contexts are not
+// retryable. Everything else is, since most services don't classify their errors and a failed send is usually worth
+// another attempt.
+func IsRetryable(err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case IsPermanent(err):
+		return false
+	case IsTransient(err):
+		return true
+	case errors.Is(err, context.Canceled):
+		return false
+	default:
+		return true
+	}
+}
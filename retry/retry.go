@@ -0,0 +1,146 @@
+// Package retry provides the retry policy used by notify to retry failed sends, together with helpers that allow
+// services to classify their errors as permanent or transient.
+//
+// This package deliberately doesn't depend on the notify package itself, so that every service is able to use it.
+package retry
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Policy configures how failed sends are retried.
+type Policy struct {
+	// MaxAttempts is the total number of attempts, including the first one. A value of 1 or less disables retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts. A Retry-After hint of a transient error takes precedence over it,
+	// up to MaxRetryAfter.
+	MaxBackoff time.Duration
+	// MaxRetryAfter caps the Retry-After hints of transient errors, which are supplied by the server. If zero, MaxBackoff
+	// caps them, too. If both are zero, hints aren't capped.
+	MaxRetryAfter time.Duration
+	// Multiplier is the factor by which the delay grows after each attempt. Values below 1 are treated as 1.
+	Multiplier float64
+	// Jitter is the fraction, between 0 and 1, by which each delay is randomly reduced. It spreads out the retries of
+	// concurrent senders.
+	Jitter float64
+	// AttemptTimeout limits the duration of every single attempt. Zero means no limit besides the one of the context.
+	AttemptTimeout time.Duration
+	// Retryable decides whether an error is worth another attempt. If nil, IsRetryable is used.
+	Retryable func(error) bool
+	// OnRetry is called before waiting for the next attempt. It's optional.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// DefaultPolicy returns a policy with three attempts and an exponential backoff starting at 500ms.
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// Backoff returns the delay to wait after the given failed attempt, starting at 1. It includes the jitter.
+func (p Policy) Backoff(attempt int) time.Duration {
+	if attempt < 1 || p.InitialBackoff <= 0 {
+		return 0
+	}
+
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		delay -= delay * jitter * rand.Float64() //nolint:gosec // No need for a secure random number here.
+	}
+
+	return time.Duration(delay)
+}
+
+// capRetryAfter returns the given Retry-After hint capped at MaxRetryAfter, or at MaxBackoff if MaxRetryAfter is zero.
+func (p Policy) capRetryAfter(d time.Duration) time.Duration {
+	limit := p.MaxRetryAfter
+	if limit <= 0 {
+		limit = p.MaxBackoff
+	}
+	if limit > 0 && d > limit {
+		return limit
+	}
+
+	return d
+}
+
+func (p Policy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+
+	return IsRetryable(err)
+}
+
+type attemptKey struct{}
+
+// Attempt returns the number of the current attempt, starting at 1, when called with the context passed to the
+// function given to Do. It returns 0 if ctx doesn't belong to a retried call.
+func Attempt(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptKey{}).(int)
+	return attempt
+}
+
+// Do calls fn until it succeeds, the policy gives up or ctx is done. Each attempt gets its own context, limited by the
+// AttemptTimeout of the policy. Do returns the error of the last attempt.
+func Do(ctx context.Context, p Policy, fn func(ctx context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := doAttempt(ctx, p, attempt, fn)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= p.MaxAttempts || ctx.Err() != nil || !p.retryable(err) {
+			return err
+		}
+
+		delay := p.Backoff(attempt)
+		if retryAfter, ok := RetryAfter(err); ok && retryAfter > delay {
+			delay = p.capRetryAfter(retryAfter)
+		}
+
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func doAttempt(ctx context.Context, p Policy, attempt int, fn func(ctx context.Context) error) error {
+	ctx = context.WithValue(ctx, attemptKey{}, attempt)
+
+	if p.AttemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.AttemptTimeout)
+		defer cancel()
+	}
+
+	return fn(ctx)
+}
@@ -0,0 +1,230 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_Backoff(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	p := Policy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+	assert.Equal(time.Duration(0), p.Backoff(0))
+	assert.Equal(100*time.Millisecond, p.Backoff(1))
+	assert.Equal(200*time.Millisecond, p.Backoff(2))
+	assert.Equal(400*time.Millisecond, p.Backoff(3))
+	assert.Equal(time.Second, p.Backoff(10))
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := p.Backoff(2)
+		assert.GreaterOrEqual(d, 100*time.Millisecond)
+		assert.LessOrEqual(d, 200*time.Millisecond)
+	}
+}
+
+func TestDo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		errs         []error
+		maxAttempts  int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{name: "Succeeds at once", errs: []error{nil}, maxAttempts: 3, wantAttempts: 1},
+		{name: "Succeeds after retry", errs: []error{errors.New("a"), nil}, maxAttempts: 3, wantAttempts: 2},
+		{
+			name:         "Gives up after max attempts",
+			errs:         []error{errors.New("a"), errors.New("b"), errors.New("c"), nil},
+			maxAttempts:  3,
+			wantAttempts: 3,
+			wantErr:      true,
+		},
+		{name: "Permanent error", errs: []error{Permanent(errors.New("a")), nil}, maxAttempts: 3, wantAttempts: 1, wantErr: true},
+		{name: "Retries disabled", errs: []error{errors.New("a"), nil}, maxAttempts: 0, wantAttempts: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert := require.New(t)
+
+			var retried []int
+			p := Policy{
+				MaxAttempts:    tt.maxAttempts,
+				InitialBackoff: time.Millisecond,
+				OnRetry:        func(attempt int, _ error, _ time.Duration) { retried = append(retried, attempt) },
+			}
+
+			attempts := 0
+			err := Do(context.Background(), p, func(ctx context.Context) error {
+				attempts++
+				assert.Equal(attempts, Attempt(ctx))
+				return tt.errs[attempts-1]
+			})
+			assert.Equal(tt.wantErr, err != nil)
+			assert.Equal(tt.wantAttempts, attempts)
+			assert.Len(retried, tt.wantAttempts-1)
+		})
+	}
+}
+
+func TestDo_AttemptTimeout(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	p := Policy{MaxAttempts: 2, AttemptTimeout: 10 * time.Millisecond}
+	attempts := 0
+	err := Do(context.Background(), p, func(ctx context.Context) error {
+		attempts++
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	assert.ErrorIs(err, context.DeadlineExceeded)
+	assert.Equal(2, attempts)
+}
+
+func TestDo_ContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := Policy{MaxAttempts: 5, InitialBackoff: time.Hour}
+	attempts := 0
+	err := Do(ctx, p, func(context.Context) error {
+		attempts++
+		cancel()
+		return errors.New("some error")
+	})
+	assert.Error(err)
+	assert.Equal(1, attempts)
+}
+
+func TestDo_RetryAfter(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	var delays []time.Duration
+	p := Policy{
+		MaxAttempts: 2,
+		OnRetry:     func(_ int, _ error, delay time.Duration) { delays = append(delays, delay) },
+	}
+	attempts := 0
+	err := Do(context.Background(), p, func(context.Context) error {
+		attempts++
+		if attempts == 1 {
+			return After(errors.New("slow down"), 20*time.Millisecond)
+		}
+		return nil
+	})
+	assert.NoError(err)
+	assert.Equal([]time.Duration{20 * time.Millisecond}, delays)
+}
+
+func TestDo_MaxRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	var delays []time.Duration
+	p := Policy{
+		MaxAttempts: 3,
+		MaxBackoff:  10 * time.Millisecond,
+		OnRetry:     func(_ int, _ error, delay time.Duration) { delays = append(delays, delay) },
+	}
+	err := Do(context.Background(), p, func(context.Context) error {
+		return After(errors.New("slow down"), time.Hour)
+	})
+	assert.Error(err)
+	assert.Equal([]time.Duration{10 * time.Millisecond, 10 * time.Millisecond}, delays, "hints are capped at MaxBackoff")
+
+	delays = nil
+	p.MaxRetryAfter = 20 * time.Millisecond
+	err = Do(context.Background(), p, func(context.Context) error {
+		return After(errors.New("slow down"), time.Hour)
+	})
+	assert.Error(err)
+	assert.Equal([]time.Duration{20 * time.Millisecond, 20 * time.Millisecond}, delays, "hints are capped at MaxRetryAfter")
+}
+
+func TestHTTPError(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	base := errors.New("request failed")
+	assert.Nil(HTTPError(http.StatusBadRequest, nil, nil))
+
+	err := HTTPError(http.StatusBadRequest, nil, base)
+	assert.True(IsPermanent(err))
+	assert.False(IsRetryable(err))
+	assert.ErrorIs(err, base)
+	assert.Equal(base.Error(), err.Error())
+	code, ok := StatusCode(err)
+	assert.True(ok)
+	assert.Equal(http.StatusBadRequest, code)
+
+	err = HTTPError(http.StatusServiceUnavailable, nil, base)
+	assert.True(IsTransient(err))
+	assert.True(IsRetryable(err))
+
+	header := http.Header{}
+	header.Set("Retry-After", "7")
+	err = HTTPError(http.StatusTooManyRequests, header, base)
+	assert.True(IsTransient(err))
+	retryAfter, ok := RetryAfter(err)
+	assert.True(ok)
+	assert.Equal(7*time.Second, retryAfter)
+
+	err = HTTPError(http.StatusFound, nil, base)
+	assert.Equal(base, err)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	_, ok := ParseRetryAfter("")
+	assert.False(ok)
+	_, ok = ParseRetryAfter("-1")
+	assert.False(ok)
+	_, ok = ParseRetryAfter("soon")
+	assert.False(ok)
+
+	d, ok := ParseRetryAfter("120")
+	assert.True(ok)
+	assert.Equal(2*time.Minute, d)
+
+	d, ok = ParseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	assert.True(ok)
+	assert.Greater(d, 59*time.Minute)
+}
+
+func TestIsRetryable(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	assert.False(IsRetryable(nil))
+	assert.False(IsRetryable(context.Canceled))
+	assert.True(IsRetryable(errors.New("unknown")))
+	assert.True(IsRetryable(Transient(context.Canceled)))
+	assert.Nil(Permanent(nil))
+	assert.Nil(Transient(nil))
+	assert.Nil(After(nil, time.Second))
+}
@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/casdoor/notify/receiver"
+	"github.com/casdoor/notify/retry"
+)
+
+func TestRetry(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	service := Retry(NotifierFunc(func(context.Context, string, string) error {
+		calls++
+		if calls < 3 {
+			return errors.New("transient failure")
+		}
+		return nil
+	}), retry.Policy{MaxAttempts: 3})
+
+	if err := service.Send(context.Background(), "subject", "message"); err != nil {
+		t.Errorf("Send() returned error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Send() was expected to call the service 3 times but called it %d times", calls)
+	}
+}
+
+func TestRetry_FailedReceivers(t *testing.T) {
+	t.Parallel()
+
+	sent := map[string]int{}
+	failures := 0
+	service := Retry(NotifierFunc(func(ctx context.Context, _, _ string) error {
+		return receiver.Each(ctx, "test", []string{"a", "b", "c"}, func(_ context.Context, r string) error {
+			if r == "b" && failures < 2 {
+				failures++
+				return errors.New("transient failure")
+			}
+			sent[r]++
+			return nil
+		})
+	}), retry.Policy{MaxAttempts: 3})
+
+	if err := service.Send(context.Background(), "subject", "message"); err != nil {
+		t.Errorf("Send() returned error: %v", err)
+	}
+	for _, r := range []string{"a", "b", "c"} {
+		if sent[r] != 1 {
+			t.Errorf("Send() delivered the message %d times to receiver %q, want 1", sent[r], r)
+		}
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	t.Parallel()
+
+	var failingCalls, permanentCalls, healthyCalls int
+	n := NewWithServices(
+		NotifierFunc(func(context.Context, string, string) error {
+			failingCalls++
+			return errors.New("transient failure")
+		}),
+		NotifierFunc(func(context.Context, string, string) error {
+			permanentCalls++
+			return retry.Permanent(errors.New("permanent failure"))
+		}),
+		NotifierFunc(func(context.Context, string, string) error {
+			healthyCalls++
+			return nil
+		}),
+	)
+	n.WithOptions(WithRetry(retry.Policy{MaxAttempts: 4, InitialBackoff: time.Millisecond}))
+
+	report, err := n.SendWithReport(context.Background(), "subject", "message")
+	if err == nil {
+		t.Fatal("SendWithReport() returned no error")
+	}
+	if failingCalls != 4 || permanentCalls != 1 || healthyCalls != 1 {
+		t.Errorf("unexpected number of calls: failing=%d, permanent=%d, healthy=%d",
+			failingCalls, permanentCalls, healthyCalls)
+	}
+	for i, want := range []int{4, 1, 1} {
+		if got := report.Services[i].Attempts; got != want {
+			t.Errorf("Services[%d].Attempts = %d, want %d", i, got, want)
+		}
+	}
+
+	n.WithOptions(WithoutRetry)
+	failingCalls = 0
+	_ = n.Send(context.Background(), "subject", "message")
+	if failingCalls != 1 {
+		t.Errorf("Send() without retries called the failing service %d times", failingCalls)
+	}
+}
@@ -0,0 +1,249 @@
+package notify
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Selector selects services by their tags, see UseServiceWithTags. The zero value selects every service.
+type Selector struct {
+	// Any selects services having at least one of these tags. It's ignored if empty.
+	Any []string `json:"any,omitempty"`
+	// All selects services having every one of these tags.
+	All []string `json:"all,omitempty"`
+	// None excludes services having any of these tags.
+	None []string `json:"none,omitempty"`
+}
+
+// ParseSelector parses a tag expression into a Selector. The expression is a list of terms separated by whitespace or
+// commas:
+//
+//   - "tag" selects services tagged with tag. If there are multiple such terms, a service needs to have one of them.
+//   - "+tag" selects services only if they're tagged with tag.
+//   - "!tag" or "-tag" excludes services tagged with tag.
+//
+// For example, "critical ops !staging" selects all services tagged with critical or ops, except the ones tagged with
+// staging. The empty expression selects every service.
+func ParseSelector(expr string) (Selector, error) {
+	var s Selector
+
+	terms := strings.FieldsFunc(expr, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+	for _, term := range terms {
+		tag := strings.TrimLeft(term, "+!-")
+		if tag == "" || len(term)-len(tag) > 1 {
+			return Selector{}, errors.Errorf("invalid tag expression term %q", term)
+		}
+
+		switch term[0] {
+		case '+':
+			s.All = append(s.All, tag)
+		case '!', '-':
+			s.None = append(s.None, tag)
+		default:
+			s.Any = append(s.Any, tag)
+		}
+	}
+
+	return s, nil
+}
+
+// MustParseSelector is like ParseSelector but panics if the expression can't be parsed.
+func MustParseSelector(expr string) Selector {
+	s, err := ParseSelector(expr)
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+// String returns the tag expression of the selector. It implements the fmt.Stringer interface.
+func (s Selector) String() string {
+	terms := make([]string, 0, len(s.Any)+len(s.All)+len(s.None))
+	terms = append(terms, s.Any...)
+	for _, tag := range s.All {
+		terms = append(terms, "+"+tag)
+	}
+	for _, tag := range s.None {
+		terms = append(terms, "!"+tag)
+	}
+
+	return strings.Join(terms, " ")
+}
+
+// Matches reports whether a service with the given tags is selected.
+func (s Selector) Matches(tags []string) bool {
+	has := func(tag string) bool {
+		for _, t := range tags {
+			if t == tag {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, tag := range s.None {
+		if has(tag) {
+			return false
+		}
+	}
+	for _, tag := range s.All {
+		if !has(tag) {
+			return false
+		}
+	}
+	if len(s.Any) == 0 {
+		return true
+	}
+	for _, tag := range s.Any {
+		if has(tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Route sends the messages it matches to the services selected by its Services expression. A route matches a message
+// if all of its conditions hold; a route without conditions matches every message.
+type Route struct {
+	// Priorities matches messages with one of these priorities. It's ignored if empty.
+	Priorities []Priority
+	// Subject matches messages whose subject matches the pattern. It's ignored if nil.
+	Subject *regexp.Regexp
+	// Tags matches messages tagged with at least one of these tags. It's ignored if empty.
+	Tags []string
+	// Services is the tag expression selecting the services, see ParseSelector.
+	Services string
+}
+
+// Validate reports whether the Services expression of the route can be parsed, see ParseSelector.
+func (r Route) Validate() error {
+	_, err := ParseSelector(r.Services)
+	return err
+}
+
+// matches reports whether the route matches the given message.
+func (r Route) matches(m *Message) bool {
+	if len(r.Priorities) > 0 {
+		found := false
+		for _, p := range r.Priorities {
+			found = found || p == m.Priority
+		}
+		if !found {
+			return false
+		}
+	}
+	if r.Subject != nil && !r.Subject.MatchString(m.Subject) {
+		return false
+	}
+	if len(r.Tags) > 0 {
+		found := false
+		for _, tag := range r.Tags {
+			found = found || m.HasTag(tag)
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// route is a Route with its parsed selector. If the Services expression can't be parsed, err holds the parse error.
+type route struct {
+	Route
+	selector Selector
+	err      error
+}
+
+// WithRoutes is an Option that adds routing rules. Messages sent with Send or SendMessage go to the services selected
+// by the first matching route; routes are evaluated in the order they were added. Messages that don't match any route
+// go to all services, as if there were no routes. End the list with a route without conditions to change that default.
+// SendTo and SendMessageTo bypass the routes.
+//
+// A route whose Services expression can't be parsed selects no service: sending a message it matches fails with the
+// parse error. Use Route.Validate to check the routes beforehand.
+func WithRoutes(routes ...Route) Option {
+	return func(n *Notify) {
+		if n == nil {
+			return
+		}
+
+		for _, r := range routes {
+			selector, err := ParseSelector(r.Services)
+			if err != nil {
+				err = errors.Wrapf(err, "route #%d", len(n.routes)+1)
+			}
+			n.routes = append(n.routes, route{Route: r, selector: selector, err: err})
+		}
+	}
+}
+
+// selector returns the selector of the first route matching the given message, or nil if there is none. It fails if
+// the matching route is invalid, see WithRoutes.
+func (n *Notify) selector(m *Message) (*Selector, error) {
+	for _, r := range n.routes {
+		if r.matches(m) {
+			if r.err != nil {
+				return nil, r.err
+			}
+			selector := r.selector
+			return &selector, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// selected reports whether the service at index i is selected by the given selector. A nil selector selects every
+// service.
+func (n *Notify) selected(i int, selector *Selector) bool {
+	return selector == nil || selector.Matches(n.tags[i])
+}
+
+// SendTo calls the notification services selected by the given tag expression to send the given subject and message
+// to their respective endpoints. See ParseSelector for the syntax of the expression and UseServiceWithTags for
+// tagging services.
+func (n *Notify) SendTo(ctx context.Context, selector, subject, message string) error {
+	return n.SendMessageTo(ctx, selector, NewMessage(subject, message))
+}
+
+// SendMessageTo calls the notification services selected by the given tag expression to send the given message to
+// their respective endpoints.
+func (n *Notify) SendMessageTo(ctx context.Context, selector string, m *Message) error {
+	s, err := ParseSelector(selector)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return nil
+	}
+
+	return n.sendMessage(ctx, m, &s)
+}
+
+// SendMessageToWithReport works like SendMessageTo, but returns a Report with the outcome of every selected service. It
+// always sends synchronously.
+func (n *Notify) SendMessageToWithReport(ctx context.Context, selector string, m *Message) (*Report, error) {
+	s, err := ParseSelector(selector)
+	if err != nil {
+		return &Report{}, err
+	}
+	if m == nil {
+		return &Report{}, nil
+	}
+
+	return n.sendWithReport(ctx, m, &s)
+}
+
+// SendTo calls the notification services selected by the given tag expression to send the given subject and message
+// to their respective endpoints.
+func SendTo(ctx context.Context, selector, subject, message string) error {
+	return std.SendTo(ctx, selector, subject, message)
+}
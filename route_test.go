@@ -0,0 +1,191 @@
+package notify
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/casdoor/notify/queue"
+)
+
+func TestParseSelector(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expr    string
+		tags    []string
+		want    bool
+		wantErr bool
+	}{
+		{expr: "", tags: nil, want: true},
+		{expr: "critical", tags: []string{"critical"}, want: true},
+		{expr: "critical", tags: []string{"info"}, want: false},
+		{expr: "critical, ops", tags: []string{"ops"}, want: true},
+		{expr: "critical ops !staging", tags: []string{"ops", "staging"}, want: false},
+		{expr: "-staging", tags: []string{"ops"}, want: true},
+		{expr: "+ops +prod", tags: []string{"ops"}, want: false},
+		{expr: "+ops +prod", tags: []string{"prod", "ops"}, want: true},
+		{expr: "!", wantErr: true},
+		{expr: "!!ops", wantErr: true},
+	}
+	for _, tt := range tests {
+		s, err := ParseSelector(tt.expr)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSelector(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if got := s.Matches(tt.tags); got != tt.want {
+			t.Errorf("ParseSelector(%q).Matches(%v) = %v, want %v", tt.expr, tt.tags, got, tt.want)
+		}
+
+		reparsed, err := ParseSelector(s.String())
+		if err != nil || reparsed.Matches(tt.tags) != tt.want {
+			t.Errorf("ParseSelector(%q) does not round trip through String(): %q", tt.expr, s.String())
+		}
+	}
+}
+
+// taggedServices registers one recording service per name, tagged with the given tags, and returns the names of the
+// services that were called by the last send.
+func taggedServices(n *Notify, services map[string][]string) func() map[string]bool {
+	var mu sync.Mutex
+	called := map[string]bool{}
+
+	for name, tags := range services {
+		name := name
+		n.UseServiceWithTags(NotifierFunc(func(context.Context, string, string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			called[name] = true
+			return nil
+		}), tags...)
+	}
+
+	return func() map[string]bool {
+		mu.Lock()
+		defer mu.Unlock()
+		got := called
+		called = map[string]bool{}
+		return got
+	}
+}
+
+func TestSendTo(t *testing.T) {
+	t.Parallel()
+
+	n := New()
+	called := taggedServices(n, map[string][]string{
+		"pushover": {"critical", "ops"},
+		"twilio":   {"critical"},
+		"slack":    {"info"},
+	})
+	n.UseServices(NotifierFunc(func(context.Context, string, string) error { return nil }))
+
+	if err := n.SendTo(context.Background(), "critical", "subject", "message"); err != nil {
+		t.Fatalf("SendTo() unexpected error: %v", err)
+	}
+	if got := called(); len(got) != 2 || !got["pushover"] || !got["twilio"] {
+		t.Errorf("SendTo(critical) called %v", got)
+	}
+
+	if err := n.SendTo(context.Background(), "critical !ops", "subject", "message"); err != nil {
+		t.Fatalf("SendTo() unexpected error: %v", err)
+	}
+	if got := called(); len(got) != 1 || !got["twilio"] {
+		t.Errorf("SendTo(critical !ops) called %v", got)
+	}
+
+	if err := n.SendTo(context.Background(), "!", "subject", "message"); err == nil {
+		t.Error("SendTo() with an invalid expression was expected to fail")
+	}
+}
+
+func TestWithRoutes(t *testing.T) {
+	t.Parallel()
+
+	n := NewWithOptions(WithRoutes(
+		Route{Priorities: []Priority{PriorityHigh, PriorityCritical}, Services: "critical"},
+		Route{Subject: regexp.MustCompile(`^\[deploy\]`), Services: "info"},
+	))
+	called := taggedServices(n, map[string][]string{
+		"pushover": {"critical"},
+		"slack":    {"info"},
+	})
+
+	msg := NewMessage("disk full", "message")
+	msg.Priority = PriorityCritical
+	if err := n.SendMessage(context.Background(), msg); err != nil {
+		t.Fatalf("SendMessage() unexpected error: %v", err)
+	}
+	if got := called(); len(got) != 1 || !got["pushover"] {
+		t.Errorf("critical message was routed to %v", got)
+	}
+
+	if err := n.Send(context.Background(), "[deploy] v1.2.3", "message"); err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+	if got := called(); len(got) != 1 || !got["slack"] {
+		t.Errorf("deploy message was routed to %v", got)
+	}
+
+	// Messages without a matching route go to every service.
+	if err := n.Send(context.Background(), "hello", "message"); err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+	if got := called(); len(got) != 2 {
+		t.Errorf("unrouted message was sent to %v", got)
+	}
+}
+
+func TestWithRoutes_Invalid(t *testing.T) {
+	t.Parallel()
+
+	if err := (Route{Services: "!"}).Validate(); err == nil {
+		t.Error("Validate() with an invalid expression was expected to fail")
+	}
+
+	n := NewWithOptions(WithRoutes(
+		Route{Services: "!"},
+		Route{Services: "info"},
+	))
+	called := taggedServices(n, map[string][]string{
+		"pushover": {"critical"},
+		"slack":    {"info"},
+	})
+
+	err := n.Send(context.Background(), "subject", "message")
+	if err == nil || !strings.Contains(err.Error(), "route #1") {
+		t.Errorf("Send() error = %v, want the error of the invalid route", err)
+	}
+	if _, err = n.SendWithReport(context.Background(), "subject", "message"); err == nil {
+		t.Error("SendWithReport() with an invalid route was expected to fail")
+	}
+	if got := called(); len(got) != 0 {
+		t.Errorf("message matching an invalid route was sent to %v", got)
+	}
+}
+
+func TestSendTo_Async(t *testing.T) {
+	t.Parallel()
+
+	n := NewWithOptions(WithAsync(queue.NewMemory(10), 1))
+	called := taggedServices(n, map[string][]string{
+		"pushover": {"critical"},
+		"slack":    {"info"},
+	})
+
+	if err := n.SendTo(context.Background(), "info", "subject", "message"); err != nil {
+		t.Fatalf("SendTo() unexpected error: %v", err)
+	}
+	if err := n.Close(context.Background()); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+	if got := called(); len(got) != 1 || !got["slack"] {
+		t.Errorf("queued SendTo(info) called %v", got)
+	}
+}
@@ -1,39 +1,12 @@
 package notify
 
-import (
-	"context"
-
-	"github.com/pkg/errors"
-	"golang.org/x/sync/errgroup"
-)
+import "context"
 
 // send calls the underlying notification services to send the given subject and message to their respective endpoints.
+// If one or more services fail, the returned error is a *SendError holding all of their errors. In asynchronous mode,
+// the notification is only queued, see WithAsync.
 func (n *Notify) send(ctx context.Context, subject, message string) error {
-	if n.Disabled {
-		return nil
-	}
-	if ctx == nil {
-		ctx = context.Background()
-	}
-
-	var eg errgroup.Group
-	for _, service := range n.notifiers {
-		if service == nil {
-			continue
-		}
-
-		service := service
-		eg.Go(func() error {
-			return service.Send(ctx, subject, message)
-		})
-	}
-
-	err := eg.Wait()
-	if err != nil {
-		err = errors.Wrap(ErrSendNotification, err.Error())
-	}
-
-	return err
+	return n.sendMessage(ctx, NewMessage(subject, message), nil)
 }
 
 // Send calls the underlying notification services to send the given subject and message to their respective endpoints.
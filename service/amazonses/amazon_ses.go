@@ -2,6 +2,9 @@ package amazonses
 
 import (
 	"context"
+	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -9,8 +12,14 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ses"
 	"github.com/aws/aws-sdk-go-v2/service/ses/types"
 	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/receiver"
 )
 
+// serviceName identifies the Amazon SES service in receivers bound to the context.
+const serviceName = "amazonses"
+
 //go:generate mockery --name=sesClient --output=. --case=underscore --inpackage
 type sesClient interface {
 	SendEmail(ctx context.Context, params *ses.SendEmailInput, optFns ...func(options *ses.Options)) (*ses.SendEmailOutput, error)
@@ -24,6 +33,8 @@ type AmazonSES struct {
 	client            sesClient
 	senderAddress     *string
 	receiverAddresses []string
+	secretKey         string
+	logger            *slog.Logger
 }
 
 // New returns a new instance of a AmazonSES notification service.
@@ -45,6 +56,7 @@ func New(accessKeyID, secretKey, region, senderAddress string) (*AmazonSES, erro
 		client:            ses.NewFromConfig(cfg),
 		senderAddress:     aws.String(senderAddress),
 		receiverAddresses: []string{},
+		secretKey:         secretKey,
 	}, nil
 }
 
@@ -54,13 +66,24 @@ func (a *AmazonSES) AddReceivers(addresses ...string) {
 	a.receiverAddresses = append(a.receiverAddresses, addresses...)
 }
 
+// SetLogger sets the logger of the service. The secret key is redacted from all logs.
+func (a *AmazonSES) SetLogger(logger *slog.Logger) {
+	a.logger = logger
+}
+
 // Send takes a message subject and a message body and sends them to all previously set chats. Message body supports
 // html as markup language.
-func (a AmazonSES) Send(ctx context.Context, subject, message string) error {
+func (a AmazonSES) Send(ctx context.Context, subject, message string) (err error) {
+	ctx = logging.Bind(ctx, a.logger, a.secretKey)
+	receiverAddresses := receiver.ResolveIDs(ctx, serviceName, a.receiverAddresses)
+	defer func(start time.Time) {
+		logging.Delivery(ctx, serviceName, strings.Join(receiverAddresses, ", "), err, time.Since(start))
+	}(time.Now())
+
 	input := &ses.SendEmailInput{
 		Source: a.senderAddress,
 		Destination: &types.Destination{
-			ToAddresses: a.receiverAddresses,
+			ToAddresses: receiverAddresses,
 		},
 		Message: &types.Message{
 			Body: &types.Body{
@@ -77,7 +100,7 @@ func (a AmazonSES) Send(ctx context.Context, subject, message string) error {
 		},
 	}
 
-	_, err := a.client.SendEmail(ctx, input)
+	_, err = a.client.SendEmail(ctx, input)
 	if err != nil {
 		return errors.Wrap(err, "failed to send mail using Amazon SES service")
 	}
@@ -2,14 +2,21 @@ package amazonsns
 
 import (
 	"context"
+	"log/slog"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/receiver"
 )
 
+// serviceName identifies the Amazon SNS service in per-receiver results.
+const serviceName = "amazonsns"
+
 // snsSendMessageAPI Basic interface to send messages through SNS.
 //
 //go:generate mockery --name=snsSendMessageAPI --output=. --case=underscore --inpackage
@@ -36,6 +43,8 @@ func (s snsSendMessageClient) SendMessage(ctx context.Context,
 type AmazonSNS struct {
 	sendMessageClient snsSendMessageAPI
 	queueTopics       []string
+	secretKey         string
+	logger            *slog.Logger
 }
 
 // New creates a new AmazonSNS
@@ -52,6 +61,7 @@ func New(accessKeyID, secretKey, region string) (*AmazonSNS, error) {
 	client := sns.NewFromConfig(cfg)
 	return &AmazonSNS{
 		sendMessageClient: snsSendMessageClient{client: client},
+		secretKey:         secretKey,
 	}, nil
 }
 
@@ -62,10 +72,19 @@ func (s *AmazonSNS) AddReceivers(queues ...string) {
 	s.queueTopics = append(s.queueTopics, queues...)
 }
 
+// SetLogger sets the logger of the service. The secret key is redacted from all logs.
+func (s *AmazonSNS) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
 // Send message to everyone on all topics
 func (s AmazonSNS) Send(ctx context.Context, subject, message string) error {
+	ctx = logging.Bind(ctx, s.logger, s.secretKey)
+
 	// For each topic
-	for _, topic := range s.queueTopics {
+	queueTopics := receiver.ResolveIDs(ctx, serviceName, s.queueTopics)
+
+	return receiver.Each(ctx, serviceName, queueTopics, func(ctx context.Context, topic string) error {
 		// Create new input with subject, message and the specific topic
 		input := &sns.PublishInput{
 			Subject:  aws.String(subject),
@@ -77,6 +96,6 @@ func (s AmazonSNS) Send(ctx context.Context, subject, message string) error {
 		if err != nil {
 			return errors.Wrapf(err, "failed to send message using Amazon SNS to ARN TOPIC '%s'", topic)
 		}
-	}
-	return nil
+		return nil
+	})
 }
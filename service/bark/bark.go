@@ -6,18 +6,31 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/markup"
+	"github.com/casdoor/notify/message"
+	"github.com/casdoor/notify/receiver"
+	"github.com/casdoor/notify/retry"
 )
 
+// serviceName identifies the Bark service in per-receiver results.
+const serviceName = "bark"
+
 // Service allow you to configure Bark service.
 type Service struct {
 	deviceKey  string
 	client     *http.Client
 	serverURLs []string
+	logger     *slog.Logger
 }
 
 func defaultHTTPClient() *http.Client {
@@ -77,6 +90,11 @@ func NewWithServers(deviceKey string, serverURLs ...string) *Service {
 	return s
 }
 
+// SetLogger sets the logger of the service. The device key is redacted from all logs.
+func (s *Service) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
 // New returns a new instance of Bark service. You can use this service to send messages to bark. By default, the
 // service will use the default server (https://api.day.app/).
 func New(deviceKey string) *Service {
@@ -92,23 +110,57 @@ type postData struct {
 	Sound     string `json:"sound,omitempty"`
 	Icon      string `json:"icon,omitempty"`
 	Group     string `json:"group,omitempty"`
-	URL       string `json:"pushURL,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Level     string `json:"level,omitempty"`
 }
 
-func (s *Service) send(ctx context.Context, serverURL, subject, content string) (err error) {
-	if serverURL == "" {
-		return errors.New("server url is empty")
-	}
+// defaultSound is the sound played by bark if the message doesn't specify one.
+const defaultSound = "alarm.caf"
 
-	// Marshal the message to post
-	message := &postData{
+// levels maps the priority of a message to a bark interruption level.
+var levels = map[message.Priority]string{
+	message.PriorityLow:      "passive",
+	message.PriorityHigh:     "timeSensitive",
+	message.PriorityCritical: "critical",
+}
+
+// newPostData builds the data to post for the given message. See SendMessage for the mapping of the message fields.
+func (s *Service) newPostData(msg *message.Message) *postData {
+	data := &postData{
 		DeviceKey: s.deviceKey,
-		Title:     subject,
-		Body:      content,
-		Sound:     "alarm.caf",
+		Title:     msg.Subject,
+		Body:      markup.Convert(msg.Body, msg.Format, message.FormatPlain),
+		Sound:     defaultSound,
+		Level:     levels[msg.Priority],
 	}
 
-	messageJSON, err := json.Marshal(message)
+	if badge, ok := msg.MetadataInt("badge"); ok {
+		data.Badge = badge
+	}
+	if sound, ok := msg.MetadataString("sound"); ok {
+		data.Sound = sound
+	}
+	if icon, ok := msg.MetadataString("icon"); ok {
+		data.Icon = icon
+	}
+	if group, ok := msg.MetadataString("group"); ok {
+		data.Group = group
+	} else if len(msg.Tags) > 0 {
+		data.Group = msg.Tags[0]
+	}
+	if len(msg.Links) > 0 {
+		data.URL = msg.Links[0].URL
+	}
+
+	return data
+}
+
+func (s *Service) send(ctx context.Context, serverURL string, data *postData) (err error) {
+	if serverURL == "" {
+		return errors.New("server url is empty")
+	}
+
+	messageJSON, err := json.Marshal(data)
 	if err != nil {
 		return errors.Wrap(err, "marshal message")
 	}
@@ -122,6 +174,7 @@ func (s *Service) send(ctx context.Context, serverURL, subject, content string)
 	}
 
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
 	// Send request
 	resp, err := s.client.Do(req)
@@ -137,29 +190,84 @@ func (s *Service) send(ctx context.Context, serverURL, subject, content string)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("bark returned status code %d: %s", resp.StatusCode, string(result))
+		logging.Response(ctx, serviceName, serverURL, resp.StatusCode, result)
+		err = fmt.Errorf("bark returned status code %d: %s", resp.StatusCode, string(result))
+		return retry.HTTPError(resp.StatusCode, resp.Header, err)
 	}
 
 	return nil
 }
 
-// Send takes a message subject and a message content and sends them to bark application.
-func (s *Service) Send(ctx context.Context, subject, content string) error {
+// Check pings all servers, without sending a message. Bark servers don't validate device keys before a push, so only
+// their availability is checked.
+func (s *Service) Check(ctx context.Context) error {
 	if s.client == nil {
 		return errors.New("client is nil")
 	}
 
 	for _, serverURL := range s.serverURLs {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			err := s.send(ctx, serverURL, subject, content)
-			if err != nil {
-				return errors.Wrapf(err, "failed to send message to bark server %q", serverURL)
-			}
+		if err := s.ping(ctx, serverURL); err != nil {
+			return errors.Wrapf(err, "failed to check bark server %q", serverURL)
 		}
 	}
 
 	return nil
 }
+
+// ping calls the ping endpoint of the given server.
+func (s *Service) ping(ctx context.Context, serverURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, serverURL+"ping", http.NoBody)
+	if err != nil {
+		return errors.Wrap(err, "create request")
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "send request")
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("bark returned status code %d", resp.StatusCode)
+		return retry.HTTPError(resp.StatusCode, resp.Header, err)
+	}
+
+	return nil
+}
+
+// Send takes a message subject and a message content and sends them to bark application.
+func (s *Service) Send(ctx context.Context, subject, content string) error {
+	return s.SendMessage(ctx, message.New(subject, content))
+}
+
+// SendMessage sends the given message to the bark application. Besides the subject and the body, it maps the following
+// message fields to bark features:
+//
+//   - A body in another format is converted to plain text.
+//   - The priority sets the interruption level: low is "passive", high is "timeSensitive" and critical is "critical".
+//   - The first link is opened when the notification gets tapped.
+//   - The metadata keys "badge" (number), "sound", "icon" and "group" (strings) set the respective bark options. If no
+//     group is set, the first tag is used as group.
+func (s *Service) SendMessage(ctx context.Context, msg *message.Message) error {
+	if s.client == nil {
+		return errors.New("client is nil")
+	}
+
+	ctx = logging.Bind(ctx, s.logger, s.deviceKey)
+	data := s.newPostData(msg)
+
+	serverURLs, _ := receiver.Resolve(ctx, serviceName, s.serverURLs, func(r receiver.Receiver) (string, error) {
+		return normalizeServerURL(r.ID), nil
+	})
+
+	return receiver.Each(ctx, serviceName, serverURLs, func(ctx context.Context, serverURL string) error {
+		err := s.send(ctx, serverURL, data)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send message to bark server %q", serverURL)
+		}
+
+		return nil
+	})
+}
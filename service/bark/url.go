@@ -0,0 +1,83 @@
+package bark
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/urlscheme"
+)
+
+// Scheme is the scheme of Bark notification URLs, see ParseURL.
+const Scheme = "bark"
+
+func init() {
+	urlscheme.Register(Scheme, func(rawURL string) (urlscheme.Sender, error) {
+		return NewFromURL(rawURL)
+	})
+}
+
+// URLConfig is the configuration described by a Bark notification URL.
+type URLConfig struct {
+	// DeviceKey is the key of the device to send to.
+	DeviceKey string
+	// ServerURL is the URL of the Bark server, e.g. DefaultServerURL.
+	ServerURL string
+}
+
+// ParseURL parses a Bark notification URL of the form:
+//
+//	bark://{device_key}@[{server}[:{port}][/{path}]][?tls=no]
+//
+// The server defaults to api.day.app. It's connected to via HTTPS, unless the tls query parameter is "no".
+func ParseURL(rawURL string) (URLConfig, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		// The error of url.Parse contains the URL and thereby the device key.
+		return URLConfig{}, errors.New("invalid Bark URL")
+	}
+	if !strings.EqualFold(u.Scheme, Scheme) {
+		return URLConfig{}, errors.Errorf("not a %s URL", Scheme)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return URLConfig{}, errors.New("missing device key in Bark URL")
+	}
+
+	cfg := URLConfig{DeviceKey: u.User.Username(), ServerURL: DefaultServerURL}
+	if u.Host != "" {
+		scheme := "https"
+		if strings.EqualFold(u.Query().Get("tls"), "no") {
+			scheme = "http"
+		}
+		cfg.ServerURL = normalizeServerURL(scheme + "://" + u.Host + u.EscapedPath())
+	}
+
+	return cfg, nil
+}
+
+// FormatURL returns the notification URL describing the given configuration, see ParseURL.
+func FormatURL(cfg URLConfig) string {
+	u := url.URL{Scheme: Scheme, User: url.User(cfg.DeviceKey)}
+
+	server, err := url.Parse(normalizeServerURL(cfg.ServerURL))
+	if err == nil {
+		u.Host = server.Host
+		u.Path = server.Path
+		if server.Scheme == "http" {
+			u.RawQuery = "tls=no"
+		}
+	}
+
+	return u.String()
+}
+
+// NewFromURL returns a new instance of Bark service configured by the given notification URL, see ParseURL.
+func NewFromURL(rawURL string) (*Service, error) {
+	cfg, err := ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithServers(cfg.DeviceKey, cfg.ServerURL), nil
+}
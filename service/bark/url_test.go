@@ -0,0 +1,57 @@
+package bark
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseURL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		rawURL    string
+		want      URLConfig
+		formatted string
+	}{
+		{
+			rawURL:    "bark://key@",
+			want:      URLConfig{DeviceKey: "key", ServerURL: DefaultServerURL},
+			formatted: "bark://key@api.day.app/",
+		},
+		{
+			rawURL:    "bark://key@bark.example.com:8080/push-server",
+			want:      URLConfig{DeviceKey: "key", ServerURL: "https://bark.example.com:8080/push-server/"},
+			formatted: "bark://key@bark.example.com:8080/push-server/",
+		},
+		{
+			rawURL:    "bark://key@localhost?tls=no",
+			want:      URLConfig{DeviceKey: "key", ServerURL: "http://localhost/"},
+			formatted: "bark://key@localhost/?tls=no",
+		},
+	}
+	for _, tt := range tests {
+		cfg, err := ParseURL(tt.rawURL)
+		require.NoError(t, err, tt.rawURL)
+		require.Equal(t, tt.want, cfg, tt.rawURL)
+		require.Equal(t, tt.formatted, FormatURL(cfg), tt.rawURL)
+
+		roundTrip, err := ParseURL(FormatURL(cfg))
+		require.NoError(t, err, tt.rawURL)
+		require.Equal(t, cfg, roundTrip, tt.rawURL)
+	}
+
+	for _, rawURL := range []string{"bark://api.day.app", "json://key@api.day.app", "bark://key@%zz"} {
+		_, err := ParseURL(rawURL)
+		require.Error(t, err, rawURL)
+	}
+}
+
+func TestNewFromURL(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewFromURL("bark://key@bark.example.com")
+	require.NoError(t, err)
+	require.Equal(t, "key", s.deviceKey)
+	require.Equal(t, []string{"https://bark.example.com/"}, s.serverURLs)
+}
@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"sort"
@@ -30,8 +31,13 @@ import (
 	"time"
 
 	"golang.org/x/exp/maps"
+
+	"github.com/casdoor/notify/logging"
 )
 
+// serviceName identifies the CuCloud service in logs.
+const serviceName = "cucloud"
+
 type CuCloud struct {
 	AccessKey       string
 	SecretKey       string
@@ -41,6 +47,7 @@ type CuCloud struct {
 	AccountId       string
 	NotifyType      string
 	Client          http.Client
+	logger          *slog.Logger
 }
 
 type CuCloudResp struct {
@@ -51,18 +58,28 @@ type CuCloudResp struct {
 
 func New(accessKey, secretKey, topicName, messageTitle, cloudRegionCode, accountId, notifyType string) *CuCloud {
 	return &CuCloud{
-		accessKey,
-		secretKey,
-		topicName,
-		messageTitle,
-		cloudRegionCode,
-		accountId,
-		notifyType,
-		http.Client{},
+		AccessKey:       accessKey,
+		SecretKey:       secretKey,
+		TopicName:       topicName,
+		MessageTitle:    messageTitle,
+		CloudRegionCode: cloudRegionCode,
+		AccountId:       accountId,
+		NotifyType:      notifyType,
+		Client:          http.Client{},
 	}
 }
 
-func (c *CuCloud) Send(ctx context.Context, subject, content string) error {
+// SetLogger sets the logger of the service. The secret key is redacted from all logs.
+func (c *CuCloud) SetLogger(logger *slog.Logger) {
+	c.logger = logger
+}
+
+func (c *CuCloud) Send(ctx context.Context, subject, content string) (err error) {
+	ctx = logging.Bind(ctx, c.logger, c.SecretKey)
+	defer func(start time.Time) {
+		logging.Delivery(ctx, serviceName, c.TopicName, err, time.Since(start))
+	}(time.Now())
+
 	timeNow := time.Now().UnixMilli()
 
 	reqHeader := map[string]string{}
@@ -125,6 +142,7 @@ func (c *CuCloud) Send(ctx context.Context, subject, content string) error {
 	}
 
 	if respBody.Code != 200 {
+		logging.Response(ctx, serviceName, c.TopicName, resp.StatusCode, respBodyRaw)
 		return fmt.Errorf(respBody.Message)
 	}
 
@@ -2,15 +2,20 @@ package dingding
 
 import (
 	"context"
+	"log/slog"
+	"time"
 
 	"github.com/blinkbean/dingtalk"
 	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/logging"
 )
 
 // Service encapsulates the DingTalk client.
 type Service struct {
 	config Config
 	client *dingtalk.DingTalk
+	logger *slog.Logger
 }
 
 // Config is the Service configuration.
@@ -29,14 +34,24 @@ func New(cfg *Config) *Service {
 	return &s
 }
 
+// SetLogger sets the logger of the service. The token and the secret are redacted from all logs.
+func (s *Service) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
 // Send takes a message subject and a message content and sends them to all previously set users.
-func (s *Service) Send(ctx context.Context, subject, content string) error {
+func (s *Service) Send(ctx context.Context, subject, content string) (err error) {
+	ctx = logging.Bind(ctx, s.logger, s.config.Token, s.config.Secret)
+	defer func(start time.Time) {
+		logging.Delivery(ctx, "dingding", "", err, time.Since(start))
+	}(time.Now())
+
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
 		text := subject + "\n" + content
-		err := s.client.SendTextMessage(text)
+		err = s.client.SendTextMessage(text)
 		if err != nil {
 			return errors.Wrapf(err, "failed to send message")
 		}
@@ -2,12 +2,23 @@ package discord
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/internal/apiurl"
+	"github.com/casdoor/notify/logging"
+	notifymsg "github.com/casdoor/notify/message"
+	"github.com/casdoor/notify/receiver"
+	"github.com/casdoor/notify/retry"
+	"github.com/casdoor/notify/split"
 )
 
+// serviceName identifies the Discord service in per-receiver results.
+const serviceName = "discord"
+
 //go:generate mockery --name=discordSession --output=. --case=underscore --inpackage
 type discordSession interface {
 	ChannelMessageSend(channelID string, content string, options ...discordgo.RequestOption) (*discordgo.Message, error)
@@ -20,6 +31,10 @@ var _ discordSession = new(discordgo.Session)
 type Discord struct {
 	client     discordSession
 	channelIDs []string
+	splitter   *split.Splitter
+	token      string
+	apiURL     string
+	logger     *slog.Logger
 }
 
 // New returns a new instance of a Discord notification service.
@@ -27,6 +42,7 @@ func New() *Discord {
 	return &Discord{
 		client:     &discordgo.Session{},
 		channelIDs: []string{},
+		splitter:   split.New(split.DiscordLimit),
 	}
 }
 
@@ -39,6 +55,9 @@ func (d *Discord) authenticate(token string) error {
 
 	client.Identify.Intents = discordgo.IntentsGuildMessageTyping
 
+	if err := d.useAPIURL(client); err != nil {
+		return err
+	}
 	d.client = client
 
 	return nil
@@ -47,6 +66,7 @@ func (d *Discord) authenticate(token string) error {
 // AuthenticateWithBotToken authenticates you as a bot to Discord via the given access token.
 // For more info, see here: https://pkg.go.dev/github.com/bwmarrin/discordgo@v0.22.1#New
 func (d *Discord) AuthenticateWithBotToken(token string) error {
+	d.token = token
 	token = parseBotToken(token)
 
 	return d.authenticate(token)
@@ -55,6 +75,7 @@ func (d *Discord) AuthenticateWithBotToken(token string) error {
 // AuthenticateWithOAuth2Token authenticates you to Discord via the given OAUTH2 token.
 // For more info, see here: https://pkg.go.dev/github.com/bwmarrin/discordgo@v0.22.1#New
 func (d *Discord) AuthenticateWithOAuth2Token(token string) error {
+	d.token = token
 	token = parseOAuthToken(token)
 
 	return d.authenticate(token)
@@ -75,31 +96,99 @@ func parseOAuthToken(token string) string {
 func (d *Discord) SetHttpClient(client *http.Client) {
 	if discordClient, ok := d.client.(*discordgo.Session); ok {
 		discordClient.Client = client
+		_ = d.useAPIURL(discordClient) // The API URL was validated by SetAPIURL.
 		d.client = discordClient
 	}
 }
 
+// SetAPIURL makes the service send all requests to the Discord API at the given URL instead of https://discord.com, e.g.
+// to a fake server in tests. The paths of the API endpoints, like /api/v9/channels, are kept. It applies to the current
+// session as well as to sessions created by authenticating later on.
+func (d *Discord) SetAPIURL(apiURL string) error {
+	if _, err := apiurl.Parse(apiURL); err != nil {
+		return err
+	}
+
+	d.apiURL = apiURL
+	if session, ok := d.client.(*discordgo.Session); ok {
+		return d.useAPIURL(session)
+	}
+
+	return nil
+}
+
+// useAPIURL points the HTTP client of the given session at the API URL, if one was set.
+func (d *Discord) useAPIURL(session *discordgo.Session) error {
+	if d.apiURL == "" {
+		return nil
+	}
+
+	client, err := apiurl.Client(session.Client, d.apiURL)
+	if err != nil {
+		return err
+	}
+	session.Client = client
+
+	return nil
+}
+
+// classifyError classifies err by the status code of the Discord API response, if there is one.
+func classifyError(err error) error {
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Response != nil {
+		return retry.HTTPError(restErr.Response.StatusCode, restErr.Response.Header, err)
+	}
+
+	return err
+}
+
+// logResponse logs the response of the Discord API carried by err, if there is one.
+func logResponse(ctx context.Context, channelID string, err error) {
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Response != nil {
+		logging.Response(ctx, serviceName, channelID, restErr.Response.StatusCode, restErr.ResponseBody)
+	}
+}
+
 // AddReceivers takes Discord channel IDs and adds them to the internal channel ID list. The Send method will send
 // a given message to all those channels.
 func (d *Discord) AddReceivers(channelIDs ...string) {
 	d.channelIDs = append(d.channelIDs, channelIDs...)
 }
 
+// SetSplitter sets the splitter that fits messages into the length limit of Discord. By default, messages longer than
+// 2000 characters are sent as several numbered messages. A nil splitter disables splitting.
+func (d *Discord) SetSplitter(splitter *split.Splitter) {
+	d.splitter = splitter
+}
+
+// SetLogger sets the logger of the service. The token is redacted from all logs.
+func (d *Discord) SetLogger(logger *slog.Logger) {
+	d.logger = logger
+}
+
+// PreferredFormat returns Markdown, which Discord renders. Notify uses it to render templates.
+func (d Discord) PreferredFormat() notifymsg.BodyFormat {
+	return notifymsg.FormatMarkdown
+}
+
 // Send takes a message subject and a message body and sends them to all previously set chats.
 func (d Discord) Send(ctx context.Context, subject, message string) error {
+	ctx = logging.Bind(ctx, d.logger, d.token)
 	fullMessage := subject + "\n" + message // Treating subject as message title
 
-	for _, channelID := range d.channelIDs {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			_, err := d.client.ChannelMessageSend(channelID, fullMessage)
+	channelIDs := receiver.ResolveIDs(ctx, serviceName, d.channelIDs)
+	parts := d.splitter.Split(fullMessage, notifymsg.FormatMarkdown)
+
+	return receiver.Each(ctx, serviceName, channelIDs, func(ctx context.Context, channelID string) error {
+		for _, part := range parts {
+			_, err := d.client.ChannelMessageSend(channelID, part)
 			if err != nil {
-				return errors.Wrapf(err, "failed to send message to Discord channel '%s'", channelID)
+				logResponse(ctx, channelID, err)
+				return classifyError(errors.Wrapf(err, "failed to send message to Discord channel '%s'", channelID))
 			}
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
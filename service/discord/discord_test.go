@@ -2,10 +2,13 @@ package discord
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
+
+	"github.com/casdoor/notify/split"
 )
 
 func TestDiscord_New(t *testing.T) {
@@ -94,3 +97,40 @@ func TestDiscord_Send(t *testing.T) {
 	assert.Nil(err)
 	mockClient.AssertExpectations(t)
 }
+
+func TestDiscord_SendSplit(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+	service := New()
+	service.AddReceivers("1234")
+
+	body := strings.Repeat("a", split.DiscordLimit-20) + "\n\n" + strings.Repeat("b", 100)
+	mockClient := newMockDiscordSession(t)
+	mockClient.
+		On("ChannelMessageSend", "1234", "(1/2) subject\n"+strings.Repeat("a", split.DiscordLimit-20)).
+		Return(nil, nil).
+		Once()
+	mockClient.
+		On("ChannelMessageSend", "1234", "(2/2) "+strings.Repeat("b", 100)).
+		Return(nil, nil).
+		Once()
+	service.client = mockClient
+
+	err := service.Send(context.Background(), "subject", body)
+	assert.Nil(err)
+	mockClient.AssertExpectations(t)
+
+	// Splitting can be disabled
+	mockClient = newMockDiscordSession(t)
+	mockClient.
+		On("ChannelMessageSend", "1234", "subject\n"+body).
+		Return(nil, nil).
+		Once()
+	service.client = mockClient
+	service.SetSplitter(nil)
+
+	err = service.Send(context.Background(), "subject", body)
+	assert.Nil(err)
+	mockClient.AssertExpectations(t)
+}
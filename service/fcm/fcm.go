@@ -2,9 +2,15 @@ package fcm
 
 import (
 	"context"
+	"log/slog"
 
 	"github.com/appleboy/go-fcm"
 	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/markup"
+	notifymsg "github.com/casdoor/notify/message"
+	"github.com/casdoor/notify/receiver"
 )
 
 // Compile-time check that fcm.Client satisfies fcmClient interface.
@@ -17,6 +23,9 @@ var (
 	RetriesKey = msgRetriesKey{}
 )
 
+// serviceName identifies the FCM service in per-receiver results.
+const serviceName = "fcm"
+
 type (
 	msgDataKey    struct{}
 	msgRetriesKey struct{}
@@ -33,6 +42,8 @@ type fcmClient interface {
 type Service struct {
 	client       fcmClient
 	deviceTokens []string
+	serverAPIKey string
+	logger       *slog.Logger
 }
 
 // New returns a new instance of a FCM notification service.
@@ -45,6 +56,7 @@ func New(serverAPIKey string) (*Service, error) {
 	s := &Service{
 		client:       client,
 		deviceTokens: []string{},
+		serverAPIKey: serverAPIKey,
 	}
 	return s, nil
 }
@@ -55,36 +67,69 @@ func (s *Service) AddReceivers(deviceTokens ...string) {
 	s.deviceTokens = append(s.deviceTokens, deviceTokens...)
 }
 
+// SetLogger sets the logger of the service. The server API key is redacted from all logs.
+func (s *Service) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
 // Send takes a message subject and a message body and sends them to all previously set devices.
 func (s *Service) Send(ctx context.Context, subject, message string) error {
+	return s.SendMessage(ctx, notifymsg.New(subject, message))
+}
+
+// SendMessage sends the given message to all previously set devices. Besides the subject and the body, it maps the
+// following message fields to FCM features:
+//
+//   - A body in another format is converted to plain text.
+//   - The metadata is sent as data payload. Data bound to the context with DataKey takes precedence over it.
+//   - A high or critical priority sets the FCM priority to "high".
+//   - The first link becomes the click action of the notification.
+func (s *Service) SendMessage(ctx context.Context, m *notifymsg.Message) error {
+	ctx = logging.Bind(ctx, s.logger, s.serverAPIKey)
 	msg := &fcm.Message{
 		Notification: &fcm.Notification{
-			Title: subject,
-			Body:  message,
+			Title: m.Subject,
+			Body:  markup.Convert(m.Body, m.Format, notifymsg.FormatPlain),
 		},
 	}
 
+	if len(m.Metadata) > 0 {
+		msg.Data = make(map[string]interface{}, len(m.Metadata))
+		for k, v := range m.Metadata {
+			msg.Data[k] = v
+		}
+	}
 	if data, ok := getMessageData(ctx); ok {
-		msg.Data = data
+		if msg.Data == nil {
+			msg.Data = data
+		} else {
+			for k, v := range data {
+				msg.Data[k] = v
+			}
+		}
+	}
+	if m.Priority >= notifymsg.PriorityHigh {
+		msg.Priority = "high"
+	}
+	if len(m.Links) > 0 {
+		msg.Notification.ClickAction = m.Links[0].URL
 	}
 
 	retryAttempts := getMessageRetryAttempts(ctx)
 
-	for _, deviceToken := range s.deviceTokens {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			msg.To = deviceToken
+	deviceTokens := receiver.ResolveIDs(ctx, serviceName, s.deviceTokens)
 
-			_, err := s.client.SendWithRetry(msg, retryAttempts)
-			if err != nil {
-				return errors.Wrapf(err, "failed to send message to FCM device with token '%s'", deviceToken)
-			}
+	return receiver.Each(ctx, serviceName, deviceTokens, func(_ context.Context, deviceToken string) error {
+		msg := *msg
+		msg.To = deviceToken
+
+		_, err := s.client.SendWithRetry(&msg, retryAttempts)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send message to FCM device with token '%s'", deviceToken)
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
 
 func getMessageData(ctx context.Context) (data map[string]interface{}, ok bool) {
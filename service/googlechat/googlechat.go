@@ -5,13 +5,20 @@ package googlechat
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/pkg/errors"
 	"google.golang.org/api/chat/v1"
 	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
+
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/receiver"
 )
 
+// serviceName identifies the Google Chat service in per-receiver results.
+const serviceName = "googlechat"
+
 //go:generate mockery --name=spacesMessageCreator --output=. --case=underscore --inpackage
 type spacesMessageCreator interface {
 	Create(string, *chat.Message) createCall
@@ -56,6 +63,7 @@ func (m *messageCreator) Create(parent string, message *chat.Message) createCall
 type Service struct {
 	messageCreator spacesMessageCreator
 	spaces         []string
+	logger         *slog.Logger
 }
 
 // New returns an instance of the google chat notification service
@@ -93,21 +101,25 @@ func (s *Service) AddReceivers(spaces ...string) {
 	s.spaces = append(s.spaces, spaces...)
 }
 
+// SetLogger sets the logger of the service.
+func (s *Service) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
 // Send takes a message subject and a message body and sends them to all the spaces
 // previously set.
 func (s *Service) Send(ctx context.Context, subject, message string) error {
+	ctx = logging.Bind(ctx, s.logger)
+
 	// Treating subject as message title
 	msg := &chat.Message{Text: subject + "\n" + message}
-	for _, space := range s.spaces {
+	spaces := receiver.ResolveIDs(ctx, serviceName, s.spaces)
+
+	return receiver.Each(ctx, serviceName, spaces, func(_ context.Context, space string) error {
 		parent := fmt.Sprintf("spaces/%s", space)
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			if _, err := s.messageCreator.Create(parent, msg).Do(); err != nil {
-				return errors.Wrapf(err, "failed to send message to the google chat space: %s", space)
-			}
+		if _, err := s.messageCreator.Create(parent, msg).Do(); err != nil {
+			return errors.Wrapf(err, "failed to send message to the google chat space: %s", space)
 		}
-	}
-	return nil
+		return nil
+	})
 }
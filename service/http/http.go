@@ -6,12 +6,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 
 	"github.com/casdoor/notify"
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/receiver"
+	"github.com/casdoor/notify/retry"
 )
 
 type (
@@ -44,15 +52,19 @@ type (
 	// list of receivers. The receivers are represented by Webhooks and are expected to be valid HTTP endpoints. The
 	// Service also allows
 	Service struct {
-		client        *http.Client
-		webhooks      []*Webhook
+		client   *http.Client
+		webhooks []*Webhook
+		// hooksMu guards the hooks, which may be added while messages are being sent, e.g. by a login.
+		hooksMu       sync.RWMutex
 		preSendHooks  []PreSendHookFn
 		postSendHooks []PostSendHookFn
+		logger        *slog.Logger
 		Serializer    Serializer
 	}
 )
 
 const (
+	serviceName          = "http"
 	defaultUserAgent     = "notify/" + notify.Version
 	defaultContentType   = "application/json; charset=utf-8"
 	defaultRequestMethod = http.MethodPost
@@ -150,10 +162,21 @@ func (s *Service) WithClient(client *http.Client) {
 	}
 }
 
+// SetLogger sets the logger of the service. Unsuccessful responses are logged at warn level, with their status code and
+// the beginning of their body. Webhooks often carry their credentials in the path of their URL, so the paths and the
+// queries of the webhook URLs are redacted from all logs.
+func (s *Service) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
 // doPreSendHooks executes all the pre-send hooks. If any of the hooks returns an error, the execution is stopped and
 // the error is returned.
 func (s *Service) doPreSendHooks(req *http.Request) error {
-	for _, hook := range s.preSendHooks {
+	s.hooksMu.RLock()
+	hooks := s.preSendHooks
+	s.hooksMu.RUnlock()
+
+	for _, hook := range hooks {
 		if err := hook(req); err != nil {
 			return err
 		}
@@ -165,7 +188,11 @@ func (s *Service) doPreSendHooks(req *http.Request) error {
 // doPostSendHooks executes all the post-send hooks. If any of the hooks returns an error, the execution is stopped and
 // the error is returned.
 func (s *Service) doPostSendHooks(req *http.Request, resp *http.Response) error {
-	for _, hook := range s.postSendHooks {
+	s.hooksMu.RLock()
+	hooks := s.postSendHooks
+	s.hooksMu.RUnlock()
+
+	for _, hook := range hooks {
 		if err := hook(req, resp); err != nil {
 			return err
 		}
@@ -174,25 +201,39 @@ func (s *Service) doPostSendHooks(req *http.Request, resp *http.Response) error
 	return nil
 }
 
-// PreSend adds a pre-send hook to the service. The hook will be executed before sending a request to a receiver.
+// PreSend adds a pre-send hook to the service. The hook will be executed before sending a request to a receiver. It's
+// safe to add hooks while messages are being sent.
 func (s *Service) PreSend(hook PreSendHookFn) {
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+
 	s.preSendHooks = append(s.preSendHooks, hook)
 }
 
-// PostSend adds a post-send hook to the service. The hook will be executed after sending a request to a receiver.
+// PostSend adds a post-send hook to the service. The hook will be executed after sending a request to a receiver. It's
+// safe to add hooks while messages are being sent.
 func (s *Service) PostSend(hook PostSendHookFn) {
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+
 	s.postSendHooks = append(s.postSendHooks, hook)
 }
 
 // newRequest creates a new http request with the given method, content-type, url and payload. Request created by this
-// function will usually be passed to the Service.do method.
+// function will usually be passed to the Service.do method. The trace context of ctx is propagated to the receiver with
+// the global propagator, see otel.SetTextMapPropagator.
 func newRequest(ctx context.Context, hook *Webhook, payload io.Reader) (*http.Request, error) {
 	req, err := http.NewRequestWithContext(ctx, hook.Method, hook.URL, payload)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header = hook.Header
+	// Clone the headers, the webhook may be used by several sends at the same time.
+	req.Header = hook.Header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
 	if req.Header.Get("User-Agent") == "" {
 		req.Header.Set("User-Agent", defaultUserAgent)
@@ -227,7 +268,11 @@ func (s *Service) do(req *http.Request) error {
 
 	// Check if response code is 2xx. Should this be configurable?
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("responded with status code: %d", resp.StatusCode)
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		logging.Response(req.Context(), serviceName, req.URL.Redacted(), resp.StatusCode, body)
+
+		err = fmt.Errorf("responded with status code: %d", resp.StatusCode)
+		return retry.HTTPError(resp.StatusCode, resp.Header, err)
 	}
 
 	return nil
@@ -248,32 +293,59 @@ func (s *Service) send(ctx context.Context, webhook *Webhook, payload []byte) er
 
 // Send takes a message and sends it to all webhooks.
 func (s *Service) Send(ctx context.Context, subject, message string) error {
-	// Send message to all webhooks.
+	// Skip webhooks that are nil.
+	webhooks := make([]*Webhook, 0, len(s.webhooks))
 	for _, webhook := range s.webhooks {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			// Skip webhook if it is nil.
-			if webhook == nil {
-				continue
-			}
-
-			// Build the payload for the current webhook.
-			payload := webhook.BuildPayload(subject, message)
-
-			// Marshal the message into a payload.
-			payloadRaw, err := s.Serializer.Marshal(webhook.ContentType, payload)
-			if err != nil {
-				return errors.Wrap(err, "marshal payload")
-			}
-
-			// Send the payload to the webhook.
-			if err = s.send(ctx, webhook, payloadRaw); err != nil {
-				return errors.Wrapf(err, "send request %q", webhook)
-			}
+		if webhook != nil {
+			webhooks = append(webhooks, webhook)
 		}
 	}
 
-	return nil
+	webhooks, _ = receiver.Resolve(ctx, serviceName, webhooks, func(r receiver.Receiver) (*Webhook, error) {
+		return newWebhook(r.ID), nil
+	})
+
+	secrets := make([]string, 0, 2*len(webhooks))
+	for _, webhook := range webhooks {
+		secrets = append(secrets, urlSecrets(webhook.URL)...)
+	}
+	ctx = logging.Bind(ctx, s.logger, secrets...)
+
+	// Send message to all webhooks.
+	return receiver.Each(ctx, serviceName, webhooks, func(ctx context.Context, webhook *Webhook) error {
+		// Build the payload for the current webhook.
+		payload := webhook.BuildPayload(subject, message)
+
+		// Marshal the message into a payload.
+		payloadRaw, err := s.Serializer.Marshal(webhook.ContentType, payload)
+		if err != nil {
+			return errors.Wrap(err, "marshal payload")
+		}
+
+		// Send the payload to the webhook.
+		if err = s.send(ctx, webhook, payloadRaw); err != nil {
+			return errors.Wrapf(err, "send request %q", webhook)
+		}
+
+		return nil
+	})
+}
+
+// urlSecrets returns the parts of the given URL that may carry credentials: its path, unless it's the root, and its
+// query.
+func urlSecrets(rawURL string) []string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return []string{rawURL}
+	}
+
+	var secrets []string
+	if path := u.EscapedPath(); path != "" && path != "/" {
+		secrets = append(secrets, path)
+	}
+	if u.RawQuery != "" {
+		secrets = append(secrets, u.RawQuery)
+	}
+
+	return secrets
 }
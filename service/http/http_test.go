@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -13,6 +14,11 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/casdoor/notify/retry"
 )
 
 // Set up a test server to handle the requests
@@ -292,6 +298,7 @@ func TestService_Send(t *testing.T) {
 
 	err = service.Send(ctx, "test subject", "test message")
 	assert.Error(t, err, "error should not be nil")
+	assert.True(t, retry.IsTransient(err), "server errors should be transient")
 
 	// Reset again, add a functioning receiver again for further tests
 	service.webhooks = make([]*Webhook, 0)
@@ -443,3 +450,56 @@ func Test_defaultMarshaller_Marshal(t *testing.T) {
 		})
 	}
 }
+
+func TestService_SendPropagatesTrace(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator()) })
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("Traceparent")
+	}))
+	defer server.Close()
+
+	tp := sdktrace.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "send")
+	defer span.End()
+
+	service := New()
+	service.AddReceiversURLs(server.URL)
+	assert.NoError(t, service.Send(ctx, "subject", "message"))
+	assert.Contains(t, traceparent, span.SpanContext().TraceID().String())
+}
+
+func TestService_SendLogsResponse(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error":"invalid key"}`))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	service := New()
+	service.SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "duration" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})))
+	service.AddReceiversURLs(server.URL + "/hooks/s3cr3t-path?key=s3cr3t-key")
+
+	err := service.Send(context.Background(), "subject", "message")
+	assert.Error(t, err)
+
+	// The path and the query of webhook URLs may carry credentials.
+	hook := server.URL + "[REDACTED]?[REDACTED]"
+	assert.Equal(t, `level=WARN msg="unexpected response" service=http receiver=`+hook+` status=403 `+
+		`response="{\"error\":\"invalid key\"}"`+"\n"+
+		`level=WARN msg="failed to deliver notification" service=http receiver="POST `+hook+
+		` application/json; charset=utf-8" status=403 error="send request \"POST `+hook+
+		` application/json; charset=utf-8\": responded with status code: 403"`+"\n", buf.String())
+}
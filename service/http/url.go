@@ -0,0 +1,145 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/urlscheme"
+)
+
+// Schemes of the notification URLs of JSON webhooks, see ParseURL.
+const (
+	SchemeJSON       = "json"
+	SchemeJSONSecure = "jsons"
+)
+
+func init() {
+	for _, scheme := range []string{SchemeJSON, SchemeJSONSecure} {
+		urlscheme.Register(scheme, func(rawURL string) (urlscheme.Sender, error) {
+			return NewFromURL(rawURL)
+		})
+	}
+}
+
+// URLConfig is the configuration described by the notification URL of a JSON webhook.
+type URLConfig struct {
+	// URL is the HTTP URL of the webhook.
+	URL string
+	// Method is the request method, POST by default.
+	Method string
+	// Header holds additional request headers.
+	Header http.Header
+}
+
+// ParseURL parses the notification URL of a JSON webhook of the form:
+//
+//	json[s]://[{user}:{password}@]{host}[:{port}][/{path}][?method={method}][&+{header}={value}...]
+//
+// The json scheme sends requests via HTTP, the jsons scheme via HTTPS. Query parameters starting with "+" set request
+// headers, e.g. "+X-Token=abc". The method parameter sets the request method. Other query parameters are kept in the
+// URL of the webhook. A user and password are sent via basic authentication.
+func ParseURL(rawURL string) (URLConfig, error) {
+	scheme, rest, _ := strings.Cut(rawURL, "://")
+	switch strings.ToLower(scheme) {
+	case SchemeJSON:
+		scheme = "http"
+	case SchemeJSONSecure:
+		scheme = "https"
+	default:
+		return URLConfig{}, errors.Errorf("not a %s or %s URL", SchemeJSON, SchemeJSONSecure)
+	}
+
+	// The query is split by hand, since url.ParseQuery decodes the "+" of header parameters as space.
+	rest, rawQuery, _ := strings.Cut(rest, "?")
+	cfg := URLConfig{Method: defaultRequestMethod, Header: http.Header{}}
+
+	var params []string
+	for _, param := range strings.Split(rawQuery, "&") {
+		rawKey, rawValue, _ := strings.Cut(param, "=")
+		switch {
+		case param == "":
+		case strings.HasPrefix(rawKey, "+"):
+			key, err := url.QueryUnescape(rawKey[1:])
+			if err != nil || key == "" {
+				return URLConfig{}, errors.Errorf("invalid header parameter %q in webhook URL", rawKey)
+			}
+			value, err := url.QueryUnescape(rawValue)
+			if err != nil {
+				return URLConfig{}, errors.Errorf("invalid value of header %q in webhook URL", key)
+			}
+			cfg.Header.Add(key, value)
+		case rawKey == "method":
+			cfg.Method = strings.ToUpper(rawValue)
+		default:
+			params = append(params, param)
+		}
+	}
+
+	u, err := url.Parse(scheme + "://" + rest)
+	if err != nil || u.Host == "" {
+		// The error of url.Parse contains the URL and thereby a possible password.
+		return URLConfig{}, errors.New("invalid webhook URL")
+	}
+	u.RawQuery = strings.Join(params, "&")
+	cfg.URL = u.String()
+
+	return cfg, nil
+}
+
+// FormatURL returns the notification URL describing the given configuration, see ParseURL.
+func FormatURL(cfg URLConfig) string {
+	scheme, rest, _ := strings.Cut(cfg.URL, "://")
+	if strings.EqualFold(scheme, "https") {
+		scheme = SchemeJSONSecure
+	} else {
+		scheme = SchemeJSON
+	}
+
+	var params []string
+	if cfg.Method != "" && cfg.Method != defaultRequestMethod {
+		params = append(params, "method="+url.QueryEscape(cfg.Method))
+	}
+
+	keys := make([]string, 0, len(cfg.Header))
+	for key := range cfg.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range cfg.Header[key] {
+			params = append(params, "+"+url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+
+	if len(params) == 0 {
+		return scheme + "://" + rest
+	}
+	separator := "?"
+	if strings.Contains(rest, "?") {
+		separator = "&"
+	}
+
+	return scheme + "://" + rest + separator + strings.Join(params, "&")
+}
+
+// NewFromURL returns a new instance of a Service notification service sending to the JSON webhook described by the
+// given notification URL, see ParseURL.
+func NewFromURL(rawURL string) (*Service, error) {
+	cfg, err := ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := newWebhook(cfg.URL)
+	webhook.Method = cfg.Method
+	webhook.Header = cfg.Header
+
+	s := New()
+	s.AddReceivers(webhook)
+
+	return s, nil
+}
@@ -1,5 +1,11 @@
 package lark
 
+import (
+	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/receiver"
+)
+
 // sender is an interface for sending a message to an already defined receiver.
 //
 //go:generate mockery --name=sender --output=. --case=underscore --inpackage
@@ -20,6 +26,29 @@ type ReceiverID struct {
 	typ receiverIDType
 }
 
+// String returns the receiver ID prefixed with its type, e.g. "email:xyz@example.com". It implements the fmt.Stringer
+// interface.
+func (r *ReceiverID) String() string {
+	if r == nil {
+		return ""
+	}
+
+	return string(r.typ) + ":" + r.id
+}
+
+// receiverIDFromReceiver converts a receiver bound to the context into a Lark receiver ID. The kind of the receiver
+// has to be one of the Lark receiver ID types, e.g. "open_id" or "email". Receivers without a kind may carry the type
+// as prefix of the ID instead, e.g. "email:xyz@example.com".
+func receiverIDFromReceiver(r receiver.Receiver) (*ReceiverID, error) {
+	kind, id := r.Split(string(openID), string(userID), string(unionID), string(email), string(chatID))
+	switch typ := receiverIDType(kind); typ {
+	case openID, userID, unionID, email, chatID:
+		return &ReceiverID{id: id, typ: typ}, nil
+	default:
+		return nil, errors.Errorf("invalid Lark receiver %q: unknown receiver ID type", r)
+	}
+}
+
 // OpenID specifies an ID as a Lark Open ID.
 func OpenID(s string) *ReceiverID {
 	return &ReceiverID{s, openID}
@@ -3,18 +3,26 @@ package lark
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/go-lark/lark"
 
 	"github.com/casdoor/notify"
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/receiver"
 )
 
+// customAppServiceName identifies the Lark custom app service in per-receiver results.
+const customAppServiceName = "lark"
+
 // CustomAppService is a Lark notify service using a Lark custom app.
 type CustomAppService struct {
 	receiveIDs []*ReceiverID
 	cli        sendToer
+	appSecret  string
+	logger     *slog.Logger
 }
 
 // Compile time check that larkCustomAppService implements notify.Notifer.
@@ -42,6 +50,7 @@ func NewCustomAppService(appID, appSecret string) *CustomAppService {
 		cli: &larkClientGoLarkChatBot{
 			bot: bot,
 		},
+		appSecret: appSecret,
 	}
 }
 
@@ -60,20 +69,24 @@ func (c *CustomAppService) AddReceivers(ids ...*ReceiverID) {
 	c.receiveIDs = append(c.receiveIDs, ids...)
 }
 
+// SetLogger sets the logger of the service. The app secret is redacted from all
+// logs.
+func (c *CustomAppService) SetLogger(logger *slog.Logger) {
+	c.logger = logger
+}
+
 // Send takes a message subject and a message body and sends them to all
 // previously registered recipient IDs.
 func (c *CustomAppService) Send(ctx context.Context, subject, message string) error {
-	for _, id := range c.receiveIDs {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			if err := c.cli.SendTo(subject, message, id.id, string(id.typ)); err != nil {
-				return err
-			}
-		}
+	ctx = logging.Bind(ctx, c.logger, c.appSecret)
+	receiveIDs, err := receiver.Resolve(ctx, customAppServiceName, c.receiveIDs, receiverIDFromReceiver)
+	if err != nil {
+		return err
 	}
-	return nil
+
+	return receiver.Each(ctx, customAppServiceName, receiveIDs, func(_ context.Context, id *ReceiverID) error {
+		return c.cli.SendTo(subject, message, id.id, string(id.typ))
+	})
 }
 
 // larkClientGoLarkChatBot is a wrapper around go-lark/lark's Bot, to be used
@@ -7,6 +7,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/casdoor/notify/receiver"
 )
 
 func TestLark_NewCustomAppService(t *testing.T) {
@@ -88,3 +90,36 @@ func TestLark_SendCustomApp(t *testing.T) {
 		mockSendToer.AssertExpectations(t)
 	}
 }
+
+func TestLark_SendCustomAppWithReceivers(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	mockSendToer := newMockSendToer(t)
+	mockSendToer.
+		On("SendTo", "subject", "message", "xyz@example.com", string(email)).
+		Return(nil)
+	mockSendToer.
+		On("SendTo", "subject", "message", "oc_a0553eda9014c201e6969b478895c230", string(chatID)).
+		Return(nil)
+
+	svc := NewCustomAppService("", "")
+	svc.cli = mockSendToer
+	svc.AddReceivers(OpenID("ou_c99c5f35d542efc7ee492afe11af19ef"))
+
+	// Receivers bound to the context replace the configured ones.
+	ctx := receiver.WithOverride(context.Background(), customAppServiceName, receiver.Replace,
+		receiver.New("email:xyz@example.com"),
+		receiver.Typed("chat_id", "oc_a0553eda9014c201e6969b478895c230"),
+	)
+	err := svc.Send(ctx, "subject", "message")
+	assert.Nil(err)
+	mockSendToer.AssertExpectations(t)
+
+	// Receivers without a valid type are rejected before anything is sent.
+	ctx = receiver.WithOverride(context.Background(), customAppServiceName, receiver.Replace,
+		receiver.New("xyz@example.com"),
+	)
+	err = svc.Send(ctx, "subject", "message")
+	assert.NotNil(err)
+}
@@ -3,15 +3,20 @@ package lark
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/go-lark/lark"
 
 	"github.com/casdoor/notify"
+	"github.com/casdoor/notify/logging"
 )
 
 // WebhookService is a Notify service that uses a Lark webhook to send messages.
 type WebhookService struct {
-	cli sender
+	cli        sender
+	webhookURL string
+	logger     *slog.Logger
 }
 
 // Compile time check that larkCustomAppService implements notify.Notifer.
@@ -27,12 +32,24 @@ func NewWebhookService(webhookURL string) *WebhookService {
 		cli: &larkClientGoLarkNotificationBot{
 			bot: bot,
 		},
+		webhookURL: webhookURL,
 	}
 }
 
+// SetLogger sets the logger of the service. The webhook URL carries its
+// credentials, so it's redacted from all logs.
+func (w *WebhookService) SetLogger(logger *slog.Logger) {
+	w.logger = logger
+}
+
 // Send sends the message subject and body to the group chat.
-func (w *WebhookService) Send(_ context.Context, subject, message string) error {
-	return w.cli.Send(subject, message)
+func (w *WebhookService) Send(ctx context.Context, subject, message string) error {
+	ctx = logging.Bind(ctx, w.logger, w.webhookURL)
+	start := time.Now()
+	err := w.cli.Send(subject, message)
+	logging.Delivery(ctx, "lark_webhook", "", err, time.Since(start))
+
+	return err
 }
 
 // larkClientGoLarkNotificationBot is a wrapper around go-lark/lark's Bot, to
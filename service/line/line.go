@@ -2,16 +2,25 @@ package line
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
 
 	"github.com/line/line-bot-sdk-go/linebot"
 	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/receiver"
 )
 
+// serviceName identifies the LINE service in per-receiver results.
+const serviceName = "line"
+
 // Line struct holds info about client and destination ID for communicating with line API
 type Line struct {
 	client      *linebot.Client
 	receiverIDs []string
+	secrets     []string
+	logger      *slog.Logger
 }
 
 // New creates a new instance of Line notifier service
@@ -24,7 +33,8 @@ func New(channelSecret, channelAccessToken string) (*Line, error) {
 	}
 
 	l := &Line{
-		client: bot,
+		client:  bot,
+		secrets: []string{channelSecret, channelAccessToken},
 	}
 
 	return l, nil
@@ -39,7 +49,8 @@ func NewWithHttpClient(channelSecret, channelAccessToken string, client *http.Cl
 	}
 
 	l := &Line{
-		client: bot,
+		client:  bot,
+		secrets: []string{channelSecret, channelAccessToken},
 	}
 
 	return l, nil
@@ -50,24 +61,27 @@ func (l *Line) AddReceivers(receiverIDs ...string) {
 	l.receiverIDs = append(l.receiverIDs, receiverIDs...)
 }
 
+// SetLogger sets the logger of the service. The channel secret and the channel access token are redacted from all logs.
+func (l *Line) SetLogger(logger *slog.Logger) {
+	l.logger = logger
+}
+
 // Send receives message subject and body then sends it to all receivers set previously
 // Subject will be on the first line followed by message on the next line
 func (l *Line) Send(ctx context.Context, subject, message string) error {
+	ctx = logging.Bind(ctx, l.logger, l.secrets...)
 	lineMessage := &linebot.TextMessage{
 		Text: subject + "\n" + message,
 	}
 
-	for _, receiverID := range l.receiverIDs {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			_, err := l.client.PushMessage(receiverID, lineMessage).WithContext(ctx).Do()
-			if err != nil {
-				return errors.Wrapf(err, "failed to send message to LINE contact '%s'", receiverID)
-			}
+	receiverIDs := receiver.ResolveIDs(ctx, serviceName, l.receiverIDs)
+
+	return receiver.Each(ctx, serviceName, receiverIDs, func(ctx context.Context, receiverID string) error {
+		_, err := l.client.PushMessage(receiverID, lineMessage).WithContext(ctx).Do()
+		if err != nil {
+			return errors.Wrapf(err, "failed to send message to LINE contact '%s'", receiverID)
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
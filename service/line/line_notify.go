@@ -2,16 +2,24 @@ package line
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
 
 	"github.com/pkg/errors"
 	"github.com/utahta/go-linenotify"
+
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/receiver"
 )
 
+// notifyServiceName identifies the LINE Notify service in per-receiver results.
+const notifyServiceName = "linenotify"
+
 // Line Notify struct holds info about client and destination token for communicating with line API
 type Notify struct {
 	client         *linenotify.Client
 	receiverTokens []string
+	logger         *slog.Logger
 }
 
 // New creates a new instance of Line notify service
@@ -29,6 +37,12 @@ func (ln *Notify) SetHttpClient(client *http.Client) {
 	ln.client.HTTPClient = client
 }
 
+// SetLogger sets the logger of the service. The receivers of LINE Notify are access tokens, so they're redacted from
+// all logs.
+func (ln *Notify) SetLogger(logger *slog.Logger) {
+	ln.logger = logger
+}
+
 // AddReceivers receives token then add them to internal receivers list
 func (ln *Notify) AddReceivers(receiverTokens ...string) {
 	ln.receiverTokens = append(ln.receiverTokens, receiverTokens...)
@@ -39,17 +53,15 @@ func (ln *Notify) AddReceivers(receiverTokens ...string) {
 func (ln *Notify) Send(ctx context.Context, subject, message string) error {
 	lineMessage := subject + "\n" + message
 
-	for _, receiverToken := range ln.receiverTokens {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			_, err := ln.client.NotifyMessage(ctx, receiverToken, lineMessage)
-			if err != nil {
-				return errors.Wrapf(err, "failed to send message to LINE contact '%s'", receiverToken)
-			}
+	receiverTokens := receiver.ResolveIDs(ctx, notifyServiceName, ln.receiverTokens)
+	ctx = logging.Bind(ctx, ln.logger, receiverTokens...)
+
+	return receiver.Each(ctx, notifyServiceName, receiverTokens, func(ctx context.Context, receiverToken string) error {
+		_, err := ln.client.NotifyMessage(ctx, receiverToken, lineMessage)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send message to LINE contact '%s'", receiverToken)
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
@@ -1,21 +1,37 @@
 package mail
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"log/slog"
+	"net"
 	"net/smtp"
 	"net/textproto"
+	"strings"
+	"time"
 
 	"github.com/jordan-wright/email"
 	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/markup"
+	notifymsg "github.com/casdoor/notify/message"
+	"github.com/casdoor/notify/receiver"
 )
 
+// serviceName identifies the mail service in receivers bound to the context.
+const serviceName = "mail"
+
 // Mail struct holds necessary data to send emails.
 type Mail struct {
 	usePlainText      bool
 	senderAddress     string
 	smtpHostAddr      string
 	smtpAuth          smtp.Auth
+	smtpPassword      string
 	receiverAddresses []string
+	logger            *slog.Logger
 }
 
 // New returns a new instance of a Mail notification service.
@@ -45,6 +61,7 @@ const (
 //	-> https://pkg.go.dev/net/smtp#PlainAuth
 func (m *Mail) AuthenticateSMTP(identity, userName, password, host string) {
 	m.smtpAuth = smtp.PlainAuth(identity, userName, password, host)
+	m.smtpPassword = password
 }
 
 // AddReceivers takes email addresses and adds them to the internal address list. The Send method will send
@@ -53,6 +70,11 @@ func (m *Mail) AddReceivers(addresses ...string) {
 	m.receiverAddresses = append(m.receiverAddresses, addresses...)
 }
 
+// SetLogger sets the logger of the service. The SMTP password is redacted from all logs.
+func (m *Mail) SetLogger(logger *slog.Logger) {
+	m.logger = logger
+}
+
 // BodyFormat can be used to specify the format of the body.
 // Default BodyType is HTML.
 func (m *Mail) BodyFormat(format BodyType) {
@@ -64,6 +86,15 @@ func (m *Mail) BodyFormat(format BodyType) {
 	}
 }
 
+// PreferredFormat returns the body format set with BodyFormat, HTML by default. Notify uses it to render templates.
+func (m Mail) PreferredFormat() notifymsg.BodyFormat {
+	if m.usePlainText {
+		return notifymsg.FormatPlain
+	}
+
+	return notifymsg.FormatHTML
+}
+
 func (m *Mail) newEmail(subject, message string) *email.Email {
 	msg := &email.Email{
 		To:      m.receiverAddresses,
@@ -80,12 +111,107 @@ func (m *Mail) newEmail(subject, message string) *email.Email {
 	return msg
 }
 
+// priorityHeaders maps the priority of a message to the X-Priority and Importance headers understood by most mail
+// clients. Normal priority messages don't get any headers.
+var priorityHeaders = map[notifymsg.Priority][2]string{
+	notifymsg.PriorityLow:      {"5", "low"},
+	notifymsg.PriorityHigh:     {"1", "high"},
+	notifymsg.PriorityCritical: {"1", "high"},
+}
+
+// newMessageEmail builds the email for the given message. See SendMessage for the mapping of the message fields.
+func (m *Mail) newMessageEmail(msg *notifymsg.Message) (*email.Email, error) {
+	e := m.newEmail(msg.Subject, msg.Body)
+
+	switch msg.Format {
+	case "":
+		// Keep the format set with BodyFormat.
+	case notifymsg.FormatPlain:
+		e.Text, e.HTML = []byte(msg.Body), nil
+	default:
+		e.Text = []byte(markup.Convert(msg.Body, msg.Format, notifymsg.FormatPlain))
+		e.HTML = []byte(markup.Convert(msg.Body, msg.Format, notifymsg.FormatHTML))
+	}
+
+	if headers, ok := priorityHeaders[msg.Priority]; ok {
+		e.Headers.Set("X-Priority", headers[0])
+		e.Headers.Set("Importance", headers[1])
+	}
+
+	for _, attachment := range msg.Attachments {
+		if len(attachment.Data) == 0 {
+			continue
+		}
+		_, err := e.Attach(bytes.NewReader(attachment.Data), attachment.Name, attachment.ContentType)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to attach %q", attachment.Name)
+		}
+	}
+
+	return e, nil
+}
+
+// Check connects to the SMTP server and authenticates, without sending a message. Like sending does, it upgrades the
+// connection with STARTTLS if the server supports it.
+func (m Mail) Check(ctx context.Context) error {
+	host, _, err := net.SplitHostPort(m.smtpHostAddr)
+	if err != nil {
+		return errors.Wrap(err, "invalid SMTP host address")
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.smtpHostAddr)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to SMTP server")
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return errors.Wrap(err, "failed to connect to SMTP server")
+	}
+	defer func() { _ = client.Close() }()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}); err != nil {
+			return errors.Wrap(err, "failed to start TLS")
+		}
+	}
+	if m.smtpAuth != nil {
+		if err = client.Auth(m.smtpAuth); err != nil {
+			return errors.Wrap(err, "failed to authenticate to SMTP server")
+		}
+	}
+
+	return errors.Wrap(client.Quit(), "failed to quit SMTP session")
+}
+
 // Send takes a message subject and a message body and sends them to all previously set chats. Message body supports
 // html as markup language.
 func (m Mail) Send(ctx context.Context, subject, message string) error {
-	msg := m.newEmail(subject, message)
+	return m.SendMessage(ctx, notifymsg.New(subject, message))
+}
+
+// SendMessage sends the given message to all previously set addresses. Besides the subject and the body, it maps the
+// following message fields to email features:
+//
+//   - A body format overrides the format set with BodyFormat for this message. Plain text bodies are sent as is, all
+//     others are converted to HTML with a plain text alternative, see the markup package.
+//   - A low, high or critical priority sets the X-Priority and Importance headers.
+//   - Attachments with data are attached to the email; attachments with a URL only are skipped.
+func (m Mail) SendMessage(ctx context.Context, message *notifymsg.Message) error {
+	msg, err := m.newMessageEmail(message)
+	if err != nil {
+		return err
+	}
+	msg.To = receiver.ResolveIDs(ctx, serviceName, m.receiverAddresses)
+
+	ctx = logging.Bind(ctx, m.logger, m.smtpPassword)
+	start := time.Now()
 
-	var err error
 	select {
 	case <-ctx.Done():
 		err = ctx.Err()
@@ -95,6 +221,7 @@ func (m Mail) Send(ctx context.Context, subject, message string) error {
 			err = errors.Wrap(err, "failed to send mail")
 		}
 	}
+	logging.Delivery(ctx, serviceName, strings.Join(msg.To, ", "), err, time.Since(start))
 
 	return err
 }
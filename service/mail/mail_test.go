@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	notifymsg "github.com/casdoor/notify/message"
 )
 
 func TestMail_newEmailHtml(t *testing.T) {
@@ -50,3 +52,40 @@ func TestMail_AuthenticateSMTP(t *testing.T) {
 	m.AuthenticateSMTP("test", "test", "test", "test")
 	assert.NotNil(t, m.smtpAuth)
 }
+
+func TestMail_PreferredFormat(t *testing.T) {
+	t.Parallel()
+
+	m := New("foo", "server")
+	assert.Equal(t, notifymsg.FormatHTML, m.PreferredFormat())
+
+	m.BodyFormat(PlainText)
+	assert.Equal(t, notifymsg.FormatPlain, m.PreferredFormat())
+}
+
+func TestMail_newMessageEmailFormats(t *testing.T) {
+	t.Parallel()
+
+	m := New("foo", "server")
+
+	msg := notifymsg.New("test", "a **b** & c")
+	msg.Format = notifymsg.FormatMarkdown
+	email, err := m.newMessageEmail(msg)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("a b & c"), email.Text)
+	assert.Equal(t, []byte("<p>a <strong>b</strong> &amp; c</p>"), email.HTML)
+
+	msg = notifymsg.New("test", "<p>a <b>b</b></p>")
+	msg.Format = notifymsg.FormatHTML
+	email, err = m.newMessageEmail(msg)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("a b"), email.Text)
+	assert.Equal(t, []byte(msg.Body), email.HTML)
+
+	msg = notifymsg.New("test", "a **b**")
+	msg.Format = notifymsg.FormatPlain
+	email, err = m.newMessageEmail(msg)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("a **b**"), email.Text)
+	assert.Nil(t, email.HTML)
+}
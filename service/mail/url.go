@@ -0,0 +1,144 @@
+package mail
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/urlscheme"
+)
+
+// Scheme is the scheme of mail notification URLs, see ParseURL.
+const Scheme = "mailto"
+
+// defaultPort is the SMTP port used if a notification URL doesn't specify one. It's the submission port, where
+// servers offer STARTTLS, which smtp.PlainAuth requires to send credentials.
+const defaultPort = "587"
+
+func init() {
+	urlscheme.Register(Scheme, func(rawURL string) (urlscheme.Sender, error) {
+		return NewFromURL(rawURL)
+	})
+}
+
+// URLConfig is the configuration described by a mail notification URL.
+type URLConfig struct {
+	// Username and Password authenticate to the SMTP server. No authentication is done if Username is empty.
+	Username string
+	Password string
+	// SMTPHostAddress is the address of the SMTP server including the port, e.g. "smtp.example.com:587".
+	SMTPHostAddress string
+	// SenderAddress is the address the emails are sent from.
+	SenderAddress string
+	// ReceiverAddresses are the addresses the emails are sent to.
+	ReceiverAddresses []string
+	// PlainText sends the bodies as plain text instead of HTML, see BodyFormat.
+	PlainText bool
+}
+
+// ParseURL parses a mail notification URL of the form:
+//
+//	mailto://[{user}[:{password}]@]{smtp_host}[:{port}][?to={address}[,{address}...]][&from={address}][&format=text]
+//
+// The port defaults to 587. The sender defaults to the user if it's an email address, and to the user at the SMTP
+// host otherwise. The receivers default to the sender. The user and password need to be escaped if they contain
+// special characters, e.g. "@" as "%40".
+func ParseURL(rawURL string) (URLConfig, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		// The error of url.Parse contains the URL and thereby the password.
+		return URLConfig{}, errors.New("invalid mail URL")
+	}
+	if !strings.EqualFold(u.Scheme, Scheme) {
+		return URLConfig{}, errors.Errorf("not a %s URL", Scheme)
+	}
+	if u.Hostname() == "" {
+		return URLConfig{}, errors.New("missing SMTP host in mail URL")
+	}
+
+	cfg := URLConfig{SMTPHostAddress: u.Host}
+	if u.Port() == "" {
+		cfg.SMTPHostAddress = net.JoinHostPort(u.Hostname(), defaultPort)
+	}
+	if u.User != nil {
+		cfg.Username = u.User.Username()
+		cfg.Password, _ = u.User.Password()
+	}
+
+	query := u.Query()
+	cfg.SenderAddress = query.Get("from")
+	switch {
+	case cfg.SenderAddress != "":
+	case strings.Contains(cfg.Username, "@"):
+		cfg.SenderAddress = cfg.Username
+	case cfg.Username != "":
+		cfg.SenderAddress = cfg.Username + "@" + u.Hostname()
+	default:
+		return URLConfig{}, errors.New("missing sender in mail URL")
+	}
+
+	for _, to := range query["to"] {
+		for _, address := range strings.Split(to, ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				cfg.ReceiverAddresses = append(cfg.ReceiverAddresses, address)
+			}
+		}
+	}
+	if len(cfg.ReceiverAddresses) == 0 {
+		cfg.ReceiverAddresses = []string{cfg.SenderAddress}
+	}
+
+	switch format := strings.ToLower(query.Get("format")); format {
+	case "", "html":
+	case "text":
+		cfg.PlainText = true
+	default:
+		return URLConfig{}, errors.Errorf("unknown mail format %q", format)
+	}
+
+	return cfg, nil
+}
+
+// FormatURL returns the notification URL describing the given configuration, see ParseURL.
+func FormatURL(cfg URLConfig) string {
+	u := url.URL{Scheme: Scheme, Host: cfg.SMTPHostAddress}
+	switch {
+	case cfg.Password != "":
+		u.User = url.UserPassword(cfg.Username, cfg.Password)
+	case cfg.Username != "":
+		u.User = url.User(cfg.Username)
+	}
+
+	query := url.Values{}
+	query.Set("from", cfg.SenderAddress)
+	query.Set("to", strings.Join(cfg.ReceiverAddresses, ","))
+	if cfg.PlainText {
+		query.Set("format", "text")
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// NewFromURL returns a new instance of a mail notification service configured by the given notification URL, see
+// ParseURL.
+func NewFromURL(rawURL string) (*Mail, error) {
+	cfg, err := ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	m := New(cfg.SenderAddress, cfg.SMTPHostAddress)
+	m.AddReceivers(cfg.ReceiverAddresses...)
+	if cfg.Username != "" {
+		host, _, _ := net.SplitHostPort(cfg.SMTPHostAddress)
+		m.AuthenticateSMTP("", cfg.Username, cfg.Password, host)
+	}
+	if cfg.PlainText {
+		m.BodyFormat(PlainText)
+	}
+
+	return m, nil
+}
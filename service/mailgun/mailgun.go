@@ -2,16 +2,27 @@ package mailgun
 
 import (
 	"context"
+	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/mailgun/mailgun-go/v4"
 	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/receiver"
 )
 
+// serviceName identifies the Mailgun service in receivers bound to the context.
+const serviceName = "mailgun"
+
 // Mailgun struct holds necessary data to communicate with the Mailgun API.
 type Mailgun struct {
 	client            mailgun.Mailgun
 	senderAddress     string
 	receiverAddresses []string
+	apiKey            string
+	logger            *slog.Logger
 }
 
 // New returns a new instance of a Mailgun notification service.
@@ -22,6 +33,7 @@ func New(domain, apiKey, senderAddress string, opts ...Option) *Mailgun {
 		client:            mailgun.NewMailgun(domain, apiKey),
 		senderAddress:     senderAddress,
 		receiverAddresses: []string{},
+		apiKey:            apiKey,
 	}
 
 	for _, opt := range opts {
@@ -37,12 +49,23 @@ func (m *Mailgun) AddReceivers(addresses ...string) {
 	m.receiverAddresses = append(m.receiverAddresses, addresses...)
 }
 
+// SetLogger sets the logger of the service. The API key is redacted from all logs.
+func (m *Mailgun) SetLogger(logger *slog.Logger) {
+	m.logger = logger
+}
+
 // Send takes a message subject and a message body and sends them to all previously set chats. Message body supports
 // html as markup language.
-func (m Mailgun) Send(ctx context.Context, subject, message string) error {
-	mailMessage := m.client.NewMessage(m.senderAddress, subject, message, m.receiverAddresses...)
+func (m Mailgun) Send(ctx context.Context, subject, message string) (err error) {
+	ctx = logging.Bind(ctx, m.logger, m.apiKey)
+	receiverAddresses := receiver.ResolveIDs(ctx, serviceName, m.receiverAddresses)
+	defer func(start time.Time) {
+		logging.Delivery(ctx, serviceName, strings.Join(receiverAddresses, ", "), err, time.Since(start))
+	}(time.Now())
+
+	mailMessage := m.client.NewMessage(m.senderAddress, subject, message, receiverAddresses...)
 
-	_, _, err := m.client.Send(ctx, mailMessage)
+	_, _, err = m.client.Send(ctx, mailMessage)
 	if err != nil {
 		return errors.Wrap(err, "failed to send mail using Mailgun service")
 	}
@@ -3,11 +3,17 @@ package matrix
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"net/http"
+	"time"
 
 	matrix "maunium.net/go/mautrix"
 	"maunium.net/go/mautrix/event"
 	"maunium.net/go/mautrix/id"
+
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/markup"
+	notifymsg "github.com/casdoor/notify/message"
 )
 
 //go:generate mockery --name=matrixClient --output=. --case=underscore --inpackage
@@ -47,17 +53,32 @@ func (s *Matrix) SetHttpClient(client *http.Client) {
 	}
 }
 
+// SetLogger sets the logger of the service. The access token is redacted from all logs.
+func (s *Matrix) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
 // Send takes a message body and sends them to the previously set channel.
 // you will need an account, access token and roomID
 // see https://matrix.org
 func (s *Matrix) Send(ctx context.Context, _, message string) error {
 	messageBody := createMessage(message)
 
+	return s.send(ctx, &messageBody)
+}
+
+// send sends the given message event to the previously set room.
+func (s *Matrix) send(ctx context.Context, messageBody *Message) (err error) {
+	ctx = logging.Bind(ctx, s.logger, s.options.accessToken)
+	defer func(start time.Time) {
+		logging.Delivery(ctx, "matrix", s.options.roomID.String(), err, time.Since(start))
+	}(time.Now())
+
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
-		_, err := s.client.SendMessageEvent(s.options.roomID, event.EventMessage, &messageBody)
+		_, err = s.client.SendMessageEvent(s.options.roomID, event.EventMessage, messageBody)
 		if err != nil {
 			return errors.New("failed to send message to the room using Matrix")
 		}
@@ -65,6 +86,26 @@ func (s *Matrix) Send(ctx context.Context, _, message string) error {
 	return nil
 }
 
+// PreferredFormat returns HTML, which Matrix clients render. Notify uses it to render templates.
+func (s *Matrix) PreferredFormat() notifymsg.BodyFormat {
+	return notifymsg.FormatHTML
+}
+
+// SendMessage sends the body of the given message to the previously set room. A message without body format is sent
+// like Send does. Otherwise, the body is converted to plain text and, unless it's plain text already, to HTML for the
+// formatted_body of the event, see the markup package.
+func (s *Matrix) SendMessage(ctx context.Context, message *notifymsg.Message) error {
+	if message.Format == "" || message.Format == notifymsg.FormatPlain {
+		return s.Send(ctx, message.Subject, message.Body)
+	}
+
+	messageBody := createMessage(markup.Convert(message.Body, message.Format, notifymsg.FormatPlain))
+	messageBody.Format = "org.matrix.custom.html"
+	messageBody.FormattedBody = markup.Convert(message.Body, message.Format, notifymsg.FormatHTML)
+
+	return s.send(ctx, &messageBody)
+}
+
 func createMessage(message string) Message {
 	return Message{
 		Body:    message,
@@ -9,6 +9,8 @@ import (
 	matrix "maunium.net/go/mautrix"
 	"maunium.net/go/mautrix/event"
 	"maunium.net/go/mautrix/id"
+
+	notifymsg "github.com/casdoor/notify/message"
 )
 
 func TestMatrix_New(t *testing.T) {
@@ -47,3 +49,34 @@ func TestService_Send(t *testing.T) {
 	assert.NotNil(err)
 	mockClient.AssertExpectations(t)
 }
+
+func TestService_SendMessage(t *testing.T) {
+	t.Parallel()
+	assert := require.New(t)
+
+	mockClient := newMockMatrixClient(t)
+	mockClient.
+		On("SendMessageEvent", id.RoomID("fake-room-id"), event.EventMessage, &Message{
+			Body:          "fake-message is bold",
+			Format:        "org.matrix.custom.html",
+			FormattedBody: "<p>fake-message is <strong>bold</strong></p>",
+			Msgtype:       event.MsgText,
+		}).Return(&matrix.RespSendEvent{}, nil)
+	service, _ := New("fake-user-id", "fake-room-id", "fake-home-server", "fake-access-token")
+	service.client = mockClient
+
+	message := notifymsg.New("", "fake-message is **bold**")
+	message.Format = notifymsg.FormatMarkdown
+	err := service.SendMessage(context.Background(), message)
+	assert.Nil(err)
+	mockClient.AssertExpectations(t)
+
+	// Messages without body format are sent as is.
+	mockClient = newMockMatrixClient(t)
+	mockClient.
+		On("SendMessageEvent", id.RoomID("fake-room-id"), event.EventMessage, &Message{Body: "**fake-message**", Msgtype: event.MsgText}).Return(&matrix.RespSendEvent{}, nil)
+	service.client = mockClient
+	err = service.SendMessage(context.Background(), notifymsg.New("", "**fake-message**"))
+	assert.Nil(err)
+	mockClient.AssertExpectations(t)
+}
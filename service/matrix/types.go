@@ -1,6 +1,8 @@
 package matrix
 
 import (
+	"log/slog"
+
 	"maunium.net/go/mautrix/event"
 	"maunium.net/go/mautrix/id"
 )
@@ -25,4 +27,5 @@ type Message struct {
 type Matrix struct {
 	client  matrixClient
 	options ServiceOptions
+	logger  *slog.Logger
 }
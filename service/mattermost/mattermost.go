@@ -4,13 +4,23 @@ package mattermost
 import (
 	"context"
 	"io"
+	"log/slog"
 	stdhttp "net/http"
+	"sort"
+	"sync"
 
 	"github.com/pkg/errors"
 
+	"github.com/casdoor/notify/logging"
+	notifymsg "github.com/casdoor/notify/message"
+	"github.com/casdoor/notify/receiver"
+	"github.com/casdoor/notify/retry"
 	"github.com/casdoor/notify/service/http"
 )
 
+// serviceName identifies the Mattermost service in per-receiver results.
+const serviceName = "mattermost"
+
 //go:generate mockery --name=httpClient --output=. --case=underscore --inpackage
 type httpClient interface {
 	AddReceivers(wh ...*http.Webhook)
@@ -23,21 +33,53 @@ type httpClient interface {
 type Service struct {
 	loginClient   httpClient
 	messageClient httpClient
+	session       *session
 	channelIDs    map[string]bool
+	logger        *slog.Logger
+}
+
+// session holds the token of the latest login.
+type session struct {
+	mu    sync.RWMutex
+	token string
+}
+
+// get returns the token, or an empty string if there was no login yet.
+func (s *session) get() string {
+	if s == nil {
+		return ""
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.token
+}
+
+// set replaces the token.
+func (s *session) set(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.token = token
 }
 
 // New returns a new instance of a Mattermost notification service.
 func New(url string) *Service {
 	httpService := setupMsgService(url)
+	sess := &session{}
 	return &Service{
-		setupLoginService(url, httpService),
-		httpService,
-		make(map[string]bool),
+		loginClient:   setupLoginService(url, httpService, sess),
+		messageClient: httpService,
+		session:       sess,
+		channelIDs:    make(map[string]bool),
 	}
 }
 
 // LoginWithCredentials provides helper for authentication using Mattermost user/admin credentials.
 func (s *Service) LoginWithCredentials(ctx context.Context, loginID, password string) error {
+	ctx = receiver.Scope(logging.Bind(ctx, s.logger, password), serviceName)
+
 	// request login
 	if err := s.loginClient.Send(ctx, loginID, password); err != nil {
 		return errors.Wrapf(err, "failed login to Mattermost server")
@@ -53,22 +95,36 @@ func (s *Service) AddReceivers(channelIDs ...string) {
 	}
 }
 
+// SetLogger sets the logger of the service, which logs the deliveries and the login. The password and the session token
+// are redacted from all logs.
+func (s *Service) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
+// PreferredFormat returns Markdown, which Mattermost renders. Notify uses it to render templates.
+func (s *Service) PreferredFormat() notifymsg.BodyFormat {
+	return notifymsg.FormatMarkdown
+}
+
 // Send takes a message subject and a message body and send them to added channel ids.
 // you will need a 'create_post' permission for your username.
 // refer https://api.mattermost.com/ for more info
 func (s *Service) Send(ctx context.Context, subject, message string) error {
+	ctx = logging.Bind(ctx, s.logger, s.session.get())
+	channelIDs := make([]string, 0, len(s.channelIDs))
 	for id := range s.channelIDs {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			// create post
-			if err := s.messageClient.Send(ctx, id, subject+"\n"+message); err != nil {
-				return errors.Wrapf(err, "failed to send message")
-			}
-		}
+		channelIDs = append(channelIDs, id)
 	}
-	return nil
+	sort.Strings(channelIDs)
+	channelIDs = receiver.ResolveIDs(ctx, serviceName, channelIDs)
+
+	return receiver.Each(ctx, serviceName, channelIDs, func(ctx context.Context, id string) error {
+		// create post, without the receivers meant for the HTTP service
+		if err := s.messageClient.Send(receiver.Scope(ctx, serviceName), id, subject+"\n"+message); err != nil {
+			return errors.Wrapf(err, "failed to send message")
+		}
+		return nil
+	})
 }
 
 // PreSend adds a pre-send hook to the service. The hook will be executed before sending a request to a receiver.
@@ -104,7 +160,9 @@ func setupMsgService(url string) *http.Service {
 	httpService.PostSend(func(req *stdhttp.Request, resp *stdhttp.Response) error {
 		if resp.StatusCode != stdhttp.StatusCreated {
 			b, _ := io.ReadAll(resp.Body)
-			return errors.New("failed to create post with status: " + resp.Status + " body: " + string(b))
+			logging.Response(req.Context(), serviceName, req.URL.Redacted(), resp.StatusCode, b)
+			err := errors.New("failed to create post with status: " + resp.Status + " body: " + string(b))
+			return retry.HTTPError(resp.StatusCode, resp.Header, err)
 		}
 		return nil
 	})
@@ -112,7 +170,7 @@ func setupMsgService(url string) *http.Service {
 }
 
 // setups login service to get token
-func setupLoginService(url string, msgService *http.Service) *http.Service {
+func setupLoginService(url string, msgService *http.Service, sess *session) *http.Service {
 	// create another new http client for login request call.
 	httpService := http.New()
 
@@ -130,25 +188,32 @@ func setupLoginService(url string, msgService *http.Service) *http.Service {
 		},
 	})
 
+	// Authenticate the requests of the main http client with the token of the latest login. A single hook reads it, so
+	// that logins don't add hooks while messages are being sent.
+	msgService.PreSend(func(req *stdhttp.Request) error {
+		if token := sess.get(); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return nil
+	})
+
 	// Add post-send hook to do error checks and log the response after it is received.
-	// Also extract token from response header and set it as part of pre-send hook of main http client for further requests.
+	// Also extract token from response header for further requests of the main http client.
 	httpService.PostSend(func(req *stdhttp.Request, resp *stdhttp.Response) error {
 		if resp.StatusCode != stdhttp.StatusOK {
 			b, _ := io.ReadAll(resp.Body)
-			return errors.New("login failed with status: " + resp.Status + " body: " + string(b))
+			logging.Response(req.Context(), serviceName, req.URL.Redacted(), resp.StatusCode, b)
+			err := errors.New("login failed with status: " + resp.Status + " body: " + string(b))
+			return retry.HTTPError(resp.StatusCode, resp.Header, err)
 		}
 
 		// get token from header
-		token := resp.Header.Get("Token")
-		if token == "" {
+		newToken := resp.Header.Get("Token")
+		if newToken == "" {
 			return errors.New("received empty token")
 		}
 
-		// set token as pre-send hook
-		msgService.PreSend(func(req *stdhttp.Request) error {
-			req.Header.Set("Authorization", "Bearer "+token)
-			return nil
-		})
+		sess.set(newToken)
 		return nil
 	})
 	return httpService
@@ -2,11 +2,19 @@ package msteams
 
 import (
 	"context"
+	"log/slog"
 
 	teams "github.com/atc0005/go-teams-notify/v2"
 	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/logging"
+	notifymsg "github.com/casdoor/notify/message"
+	"github.com/casdoor/notify/receiver"
 )
 
+// serviceName identifies the Microsoft Teams service in per-receiver results.
+const serviceName = "msteams"
+
 //go:generate mockery --name=teamsClient --output=. --case=underscore --inpackage
 type teamsClient interface {
 	SendWithContext(ctx context.Context, webhookURL string, webhookMessage teams.MessageCard) error
@@ -20,6 +28,7 @@ var _ teamsClient = teams.NewClient()
 type MSTeams struct {
 	client   teamsClient
 	webHooks []string
+	logger   *slog.Logger
 }
 
 // New returns a new instance of a MSTeams notification service.
@@ -52,6 +61,16 @@ func (m *MSTeams) AddReceivers(webHooks ...string) {
 	m.webHooks = append(m.webHooks, webHooks...)
 }
 
+// SetLogger sets the logger of the service. Webhook URLs carry their credentials, so they're redacted from all logs.
+func (m *MSTeams) SetLogger(logger *slog.Logger) {
+	m.logger = logger
+}
+
+// PreferredFormat returns Markdown, which Microsoft Teams renders in message cards. Notify uses it to render templates.
+func (m MSTeams) PreferredFormat() notifymsg.BodyFormat {
+	return notifymsg.FormatMarkdown
+}
+
 // Send accepts a subject and a message body and sends them to all previously specified channels. Message body supports
 // html as markup language.
 // For more information about telegram api token:
@@ -62,17 +81,15 @@ func (m MSTeams) Send(ctx context.Context, subject, message string) error {
 	msgCard.Title = subject
 	msgCard.Text = message
 
-	for _, webHook := range m.webHooks {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			err := m.client.SendWithContext(ctx, webHook, msgCard)
-			if err != nil {
-				return errors.Wrapf(err, "failed to send message to Microsoft Teams via webhook '%s'", webHook)
-			}
+	webHooks := receiver.ResolveIDs(ctx, serviceName, m.webHooks)
+	ctx = logging.Bind(ctx, m.logger, webHooks...)
+
+	return receiver.Each(ctx, serviceName, webHooks, func(ctx context.Context, webHook string) error {
+		err := m.client.SendWithContext(ctx, webHook, msgCard)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send message to Microsoft Teams via webhook '%s'", webHook)
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
@@ -3,12 +3,22 @@ package plivo
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	plivo "github.com/plivo/plivo-go/v7"
+
+	"github.com/casdoor/notify/logging"
+	notifymsg "github.com/casdoor/notify/message"
+	"github.com/casdoor/notify/receiver"
+	"github.com/casdoor/notify/split"
 )
 
+// serviceName identifies the Plivo service in receivers bound to the context.
+const serviceName = "plivo"
+
 // ClientOptions allow you to configure a Plivo SDK client.
 type ClientOptions struct {
 	AuthID    string // If empty, env variable PLIVO_AUTH_ID will be used
@@ -39,6 +49,9 @@ type Service struct {
 	client       plivoMsgClient
 	mopts        MessageOptions
 	destinations []string
+	splitter     *split.Splitter
+	authToken    string
+	logger       *slog.Logger
 }
 
 // New creates a new instance of plivo service.
@@ -67,8 +80,10 @@ func New(cOpts *ClientOptions, mOpts *MessageOptions) (*Service, error) {
 	}
 
 	return &Service{
-		client: client.Messages,
-		mopts:  *mOpts,
+		client:    client.Messages,
+		mopts:     *mOpts,
+		splitter:  split.NewSMS(),
+		authToken: cOpts.AuthToken,
 	}, nil
 }
 
@@ -77,35 +92,57 @@ func (s *Service) AddReceivers(phoneNumbers ...string) {
 	s.destinations = append(s.destinations, phoneNumbers...)
 }
 
+// SetSplitter sets the splitter that fits messages into the length limit of SMS. By default, messages longer than 10
+// segments are sent as several numbered messages. A nil splitter disables splitting.
+func (s *Service) SetSplitter(splitter *split.Splitter) {
+	s.splitter = splitter
+}
+
+// SetLogger sets the logger of the service. The auth token is redacted from all logs.
+func (s *Service) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
 // Send sends a SMS via Plivo to all previously added receivers.
-func (s *Service) Send(ctx context.Context, subject, message string) error {
+func (s *Service) Send(ctx context.Context, subject, message string) (err error) {
+	ctx = logging.Bind(ctx, s.logger, s.authToken)
 	text := subject + "\n" + message
 
+	destinations := receiver.ResolveIDs(ctx, serviceName, s.destinations)
+
 	var dst string
-	switch len(s.destinations) {
+	switch len(destinations) {
 	case 0:
 		return fmt.Errorf("no receivers added")
 	case 1:
-		dst = s.destinations[0]
+		dst = destinations[0]
 	default:
 		// multiple destinations, use bulk message syntax
 		// see: https://www.plivo.com/docs/sms/api/message#bulk-messaging
-		dst = strings.Join(s.destinations, "<")
+		dst = strings.Join(destinations, "<")
 	}
+	defer func(start time.Time) {
+		logging.Delivery(ctx, serviceName, strings.Join(destinations, ", "), err, time.Since(start))
+	}(time.Now())
+
+	for _, part := range s.splitter.Split(text, notifymsg.FormatPlain) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
 
-	var err error
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	default:
 		_, err = s.client.Create(plivo.MessageCreateParams{
 			Dst:    dst,
-			Text:   text,
+			Text:   part,
 			Src:    s.mopts.Source,
 			URL:    s.mopts.CallbackURL,
 			Method: s.mopts.CallbackMethod,
 		})
+		if err != nil {
+			return err
+		}
 	}
 
-	return err
+	return nil
 }
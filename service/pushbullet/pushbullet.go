@@ -2,15 +2,24 @@ package pushbullet
 
 import (
 	"context"
+	"log/slog"
 
 	"github.com/cschomburg/go-pushbullet"
 	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/receiver"
 )
 
+// serviceName identifies the Pushbullet service in per-receiver results.
+const serviceName = "pushbullet"
+
 // Pushbullet struct holds necessary data to communicate with the Pushbullet API.
 type Pushbullet struct {
 	client          *pushbullet.Client
 	deviceNicknames []string
+	token           string
+	logger          *slog.Logger
 }
 
 // New returns a new instance of a Pushbullet notification service.
@@ -23,6 +32,7 @@ func New(apiToken string) *Pushbullet {
 	pb := &Pushbullet{
 		client:          client,
 		deviceNicknames: []string{},
+		token:           apiToken,
 	}
 
 	return pb
@@ -34,27 +44,30 @@ func (pb *Pushbullet) AddReceivers(deviceNicknames ...string) {
 	pb.deviceNicknames = append(pb.deviceNicknames, deviceNicknames...)
 }
 
+// SetLogger sets the logger of the service. The API token is redacted from all logs.
+func (pb *Pushbullet) SetLogger(logger *slog.Logger) {
+	pb.logger = logger
+}
+
 // Send takes a message subject and a message body and sends them to all valid devices.
 // you will need Pushbullet installed on the relevant devices
 // (android, chrome, firefox, windows)
 // see https://www.pushbullet.com/apps
 func (pb Pushbullet) Send(ctx context.Context, subject, message string) error {
-	for _, deviceNickname := range pb.deviceNicknames {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			dev, err := pb.client.Device(deviceNickname)
-			if err != nil {
-				return errors.Wrapf(err, "failed to find Pushbullet device with nickname '%s'", deviceNickname)
-			}
-
-			err = dev.PushNote(subject, message)
-			if err != nil {
-				return errors.Wrapf(err, "failed to send message to Pushbullet device with nickname '%s'", deviceNickname)
-			}
+	ctx = logging.Bind(ctx, pb.logger, pb.token)
+	deviceNicknames := receiver.ResolveIDs(ctx, serviceName, pb.deviceNicknames)
+
+	return receiver.Each(ctx, serviceName, deviceNicknames, func(_ context.Context, deviceNickname string) error {
+		dev, err := pb.client.Device(deviceNickname)
+		if err != nil {
+			return errors.Wrapf(err, "failed to find Pushbullet device with nickname '%s'", deviceNickname)
+		}
+
+		err = dev.PushNote(subject, message)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send message to Pushbullet device with nickname '%s'", deviceNickname)
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
@@ -2,16 +2,25 @@ package pushbullet
 
 import (
 	"context"
+	"log/slog"
 
 	"github.com/cschomburg/go-pushbullet"
 	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/receiver"
 )
 
+// smsServiceName identifies the Pushbullet SMS service in per-receiver results.
+const smsServiceName = "pushbullet_sms"
+
 // SMS struct holds necessary data to communicate with the Pushbullet SMS API.
 type SMS struct {
 	client           *pushbullet.Client
 	deviceIdentifier string
 	phoneNumbers     []string
+	token            string
+	logger           *slog.Logger
 }
 
 // NewSMS returns a new instance of a SMS notification service
@@ -33,6 +42,7 @@ func NewSMS(apiToken, deviceNickname string) (*SMS, error) {
 		client:           client,
 		deviceIdentifier: dev.Iden,
 		phoneNumbers:     []string{},
+		token:            apiToken,
 	}
 
 	return sms, nil
@@ -44,26 +54,29 @@ func (sms *SMS) AddReceivers(phoneNumbers ...string) {
 	sms.phoneNumbers = append(sms.phoneNumbers, phoneNumbers...)
 }
 
+// SetLogger sets the logger of the service. The API token is redacted from all logs.
+func (sms *SMS) SetLogger(logger *slog.Logger) {
+	sms.logger = logger
+}
+
 // Send takes a message subject and a message body and sends them to all phone numbers.
 // see https://help.pushbullet.com/articles/how-do-i-send-text-messages-from-my-computer/
 func (sms SMS) Send(ctx context.Context, subject, message string) error {
+	ctx = logging.Bind(ctx, sms.logger, sms.token)
 	fullMessage := subject + "\n" + message // Treating subject as message title
 	user, err := sms.client.Me()
 	if err != nil {
 		return errors.Wrapf(err, "failed to find valid pushbullet user")
 	}
 
-	for _, phoneNumber := range sms.phoneNumbers {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			err = sms.client.PushSMS(user.Iden, sms.deviceIdentifier, phoneNumber, fullMessage)
-			if err != nil {
-				return errors.Wrapf(err, "failed to send SMS message to %s via Pushbullet", phoneNumber)
-			}
+	phoneNumbers := receiver.ResolveIDs(ctx, smsServiceName, sms.phoneNumbers)
+
+	return receiver.Each(ctx, smsServiceName, phoneNumbers, func(_ context.Context, phoneNumber string) error {
+		err := sms.client.PushSMS(user.Iden, sms.deviceIdentifier, phoneNumber, fullMessage)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send SMS message to %s via Pushbullet", phoneNumber)
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
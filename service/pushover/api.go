@@ -0,0 +1,148 @@
+package pushover
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gregdel/pushover"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/retry"
+)
+
+// apiClient sends messages to the Pushover API. The Pushover client library only supports a single, global API endpoint,
+// see pushover.APIEndpoint, and neither takes a context nor an HTTP client, so the service talks to the API itself.
+type apiClient struct {
+	client *http.Client
+	url    string
+	token  string
+}
+
+// newAPIClient returns a new apiClient for the Pushover API at the given URL.
+func newAPIClient(apiURL, appToken string) *apiClient {
+	return &apiClient{
+		client: &http.Client{Timeout: 10 * time.Second},
+		url:    strings.TrimSuffix(apiURL, "/"),
+		token:  appToken,
+	}
+}
+
+// params returns the form parameters of the given message, like the Pushover client library sends them.
+func (c *apiClient) params(m *pushover.Message, recipient string) url.Values {
+	params := url.Values{}
+	params.Set("token", c.token)
+	params.Set("user", recipient)
+	params.Set("message", m.Message)
+	params.Set("priority", strconv.Itoa(m.Priority))
+
+	if m.Title != "" {
+		params.Set("title", m.Title)
+	}
+	if m.URL != "" {
+		params.Set("url", m.URL)
+	}
+	if m.URLTitle != "" {
+		params.Set("url_title", m.URLTitle)
+	}
+	if m.Sound != "" {
+		params.Set("sound", m.Sound)
+	}
+	if m.HTML {
+		params.Set("html", "1")
+	}
+	if m.Priority == pushover.PriorityEmergency {
+		params.Set("retry", strconv.FormatFloat(m.Retry.Seconds(), 'f', -1, 64))
+		params.Set("expire", strconv.FormatFloat(m.Expire.Seconds(), 'f', -1, 64))
+	}
+
+	return params
+}
+
+// newRequest builds the request sending the given message to the recipient. Messages with an attachment are sent as
+// multipart form, all others as URL encoded form.
+func (c *apiClient) newRequest(
+	ctx context.Context, m *pushover.Message, attachment []byte, recipient string,
+) (*http.Request, error) {
+	endpoint := c.url + "/messages.json"
+	params := c.params(m, recipient)
+
+	if attachment == nil {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(params.Encode()))
+		if err != nil {
+			return nil, errors.Wrap(err, "create request")
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		return req, nil
+	}
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	fw, err := w.CreateFormFile("attachment", "attachment")
+	if err != nil {
+		return nil, errors.Wrap(err, "create attachment")
+	}
+	if _, err = fw.Write(attachment); err != nil {
+		return nil, errors.Wrap(err, "write attachment")
+	}
+	for key := range params {
+		if err = w.WriteField(key, params.Get(key)); err != nil {
+			return nil, errors.Wrapf(err, "write field %q", key)
+		}
+	}
+	if err = w.Close(); err != nil {
+		return nil, errors.Wrap(err, "close multipart form")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, body)
+	if err != nil {
+		return nil, errors.Wrap(err, "create request")
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	return req, nil
+}
+
+// send sends the given message with an optional attachment to the recipient.
+func (c *apiClient) send(ctx context.Context, m *pushover.Message, attachment []byte, recipient string) error {
+	req, err := c.newRequest(ctx, m, attachment, recipient)
+	if err != nil {
+		return err
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "send request")
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "read response")
+	}
+
+	var result pushover.Response
+	if resp.StatusCode >= http.StatusInternalServerError || json.Unmarshal(body, &result) != nil {
+		err = errors.Errorf("pushover returned status code %d", resp.StatusCode)
+	} else if result.Status != 1 {
+		err = result.Errors
+	}
+	if err != nil {
+		logging.Response(ctx, serviceName, recipient, resp.StatusCode, body)
+		return retry.HTTPError(resp.StatusCode, resp.Header, err)
+	}
+
+	return nil
+}
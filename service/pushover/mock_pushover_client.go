@@ -3,6 +3,8 @@
 package pushover
 
 import (
+	context "context"
+
 	gregdelpushover "github.com/gregdel/pushover"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -12,27 +14,18 @@ type mockPushoverClient struct {
 	mock.Mock
 }
 
-// SendMessage provides a mock function with given fields: _a0, _a1
-func (_m *mockPushoverClient) SendMessage(_a0 *gregdelpushover.Message, _a1 *gregdelpushover.Recipient) (*gregdelpushover.Response, error) {
-	ret := _m.Called(_a0, _a1)
-
-	var r0 *gregdelpushover.Response
-	if rf, ok := ret.Get(0).(func(*gregdelpushover.Message, *gregdelpushover.Recipient) *gregdelpushover.Response); ok {
-		r0 = rf(_a0, _a1)
-	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*gregdelpushover.Response)
-		}
-	}
+// send provides a mock function with given fields: ctx, m, attachment, recipient
+func (_m *mockPushoverClient) send(ctx context.Context, m *gregdelpushover.Message, attachment []byte, recipient string) error {
+	ret := _m.Called(ctx, m, attachment, recipient)
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(*gregdelpushover.Message, *gregdelpushover.Recipient) error); ok {
-		r1 = rf(_a0, _a1)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *gregdelpushover.Message, []byte, string) error); ok {
+		r0 = rf(ctx, m, attachment, recipient)
 	} else {
-		r1 = ret.Error(1)
+		r0 = ret.Error(0)
 	}
 
-	return r0, r1
+	return r0
 }
 
 type mockConstructorTestingTnewMockPushoverClient interface {
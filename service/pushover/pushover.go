@@ -4,23 +4,37 @@ package pushover
 
 import (
 	"context"
+	"log/slog"
+	"time"
 
 	"github.com/gregdel/pushover"
 	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/markup"
+	notifymsg "github.com/casdoor/notify/message"
+	"github.com/casdoor/notify/receiver"
+	"github.com/casdoor/notify/split"
 )
 
+// serviceName identifies the Pushover service in per-receiver results.
+const serviceName = "pushover"
+
 //go:generate mockery --name=pushoverClient --output=. --case=underscore --inpackage
 type pushoverClient interface {
-	SendMessage(*pushover.Message, *pushover.Recipient) (*pushover.Response, error)
+	send(ctx context.Context, m *pushover.Message, attachment []byte, recipient string) error
 }
 
-// Compile-time check to ensure that pushover.Pushover implements the pushoverClient interface.
-var _ pushoverClient = new(pushover.Pushover)
+// Compile-time check to ensure that apiClient implements the pushoverClient interface.
+var _ pushoverClient = (*apiClient)(nil)
 
 // Pushover struct holds necessary data to communicate with the Pushover API.
 type Pushover struct {
 	client     pushoverClient
-	recipients []pushover.Recipient
+	recipients []string
+	splitter   *split.Splitter
+	token      string
+	logger     *slog.Logger
 }
 
 // New returns a new instance of a Pushover notification service.
@@ -28,39 +42,125 @@ type Pushover struct {
 //
 //	-> https://support.pushover.net/i175-how-do-i-get-an-api-or-application-token
 func New(appToken string) *Pushover {
-	client := pushover.New(appToken)
-
 	s := &Pushover{
-		client:     client,
-		recipients: []pushover.Recipient{},
+		client:     newAPIClient(pushover.APIEndpoint, appToken),
+		recipients: []string{},
+		splitter:   split.New(split.PushoverLimit, split.WithMode(split.Truncate)),
+		token:      appToken,
 	}
 
 	return s
 }
 
+// NewWithAPIURL returns a new instance of a Pushover notification service that sends all messages to the Pushover API
+// at the given URL instead of https://api.pushover.net/1, e.g. to a fake server in tests.
+func NewWithAPIURL(appToken, apiURL string) *Pushover {
+	p := New(appToken)
+	p.client = newAPIClient(apiURL, appToken)
+
+	return p
+}
+
 // AddReceivers takes Pushover user/group IDs and adds them to the internal recipient list. The Send method will send
 // a given message to all of those recipients.
 func (p *Pushover) AddReceivers(recipientIDs ...string) {
-	for _, recipient := range recipientIDs {
-		p.recipients = append(p.recipients, *pushover.NewRecipient(recipient))
-	}
+	p.recipients = append(p.recipients, recipientIDs...)
+}
+
+// SetSplitter sets the splitter that fits message bodies into the length limit of Pushover. Pushover shows a single
+// notification per message, so bodies longer than the limit are always truncated, see split.Splitter.Truncate. By
+// default, the limit is 1024 characters. A nil splitter disables truncation.
+func (p *Pushover) SetSplitter(splitter *split.Splitter) {
+	p.splitter = splitter
+}
+
+// SetLogger sets the logger of the service. The app token is redacted from all logs.
+func (p *Pushover) SetLogger(logger *slog.Logger) {
+	p.logger = logger
 }
 
 // Send takes a message subject and a message body and sends them to all previously set recipients.
 func (p Pushover) Send(ctx context.Context, subject, message string) error {
-	for i := range p.recipients {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			_, err := p.client.SendMessage(
-				pushover.NewMessageWithTitle(message, subject),
-				&p.recipients[i],
-			)
-			if err != nil {
-				return errors.Wrapf(err, "failed to send message to Pushover recipient '%s'", p.recipients[i])
-			}
+	return p.SendMessage(ctx, notifymsg.New(subject, message))
+}
+
+// Emergency priority messages are repeated every emergencyRetry until they get acknowledged, but at most for
+// emergencyExpire.
+const (
+	emergencyRetry  = time.Minute
+	emergencyExpire = time.Hour
+)
+
+// priorities maps the priority of a message to a Pushover priority.
+var priorities = map[notifymsg.Priority]int{
+	notifymsg.PriorityLow:      pushover.PriorityLow,
+	notifymsg.PriorityNormal:   pushover.PriorityNormal,
+	notifymsg.PriorityHigh:     pushover.PriorityHigh,
+	notifymsg.PriorityCritical: pushover.PriorityEmergency,
+}
+
+// newMessage builds the Pushover message for the given message. See SendMessage for the mapping of the message
+// fields, the attachment is sent separately, see attachmentOf.
+func (p Pushover) newMessage(msg *notifymsg.Message) *pushover.Message {
+	body, format := msg.Body, notifymsg.FormatPlain
+	if msg.Format != "" && msg.Format != notifymsg.FormatPlain {
+		body, format = markup.Convert(msg.Body, msg.Format, notifymsg.FormatHTML), notifymsg.FormatHTML
+	}
+
+	m := pushover.NewMessageWithTitle(p.splitter.Truncate(body, format), msg.Subject)
+	m.Priority = priorities[msg.Priority]
+	if m.Priority == pushover.PriorityEmergency {
+		m.Retry = emergencyRetry
+		m.Expire = emergencyExpire
+	}
+	m.HTML = format == notifymsg.FormatHTML
+
+	if len(msg.Links) > 0 {
+		m.URL = msg.Links[0].URL
+		m.URLTitle = msg.Links[0].Title
+	}
+	if sound, ok := msg.MetadataString("sound"); ok {
+		m.Sound = sound
+	}
+
+	return m
+}
+
+// attachmentOf returns the first attachment of msg with data, if any. Pushover supports a single attachment only.
+func attachmentOf(msg *notifymsg.Message) *notifymsg.Attachment {
+	for i := range msg.Attachments {
+		if len(msg.Attachments[i].Data) > 0 {
+			return &msg.Attachments[i]
 		}
 	}
+
 	return nil
 }
+
+// SendMessage sends the given message to all previously set recipients. Besides the subject and the body, it maps the
+// following message fields to Pushover features:
+//
+//   - The priority maps to the Pushover priority of the same name; critical maps to the emergency priority, which
+//     repeats the notification until it gets acknowledged.
+//   - A body in HTML or one of the Markdown dialects is sent as HTML. Bodies exceeding the length limit are truncated,
+//     see SetSplitter.
+//   - The first link becomes the supplementary URL.
+//   - The first attachment with data becomes the image attachment.
+//   - The metadata key "sound" (string) sets the notification sound.
+func (p Pushover) SendMessage(ctx context.Context, msg *notifymsg.Message) error {
+	ctx = logging.Bind(ctx, p.logger, p.token)
+	recipients := receiver.ResolveIDs(ctx, serviceName, p.recipients)
+
+	m := p.newMessage(msg)
+	var attachment []byte
+	if a := attachmentOf(msg); a != nil {
+		attachment = a.Data
+	}
+
+	return receiver.Each(ctx, serviceName, recipients, func(ctx context.Context, recipient string) error {
+		if err := p.client.send(ctx, m, attachment, recipient); err != nil {
+			return errors.Wrapf(err, "failed to send message to Pushover recipient '%s'", recipient)
+		}
+		return nil
+	})
+}
@@ -2,11 +2,16 @@ package pushover
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/gregdel/pushover"
 	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+
+	notifymsg "github.com/casdoor/notify/message"
+	"github.com/casdoor/notify/split"
 )
 
 func TestPushover_New(t *testing.T) {
@@ -16,6 +21,11 @@ func TestPushover_New(t *testing.T) {
 
 	service := New("")
 	assert.NotNil(service)
+
+	// All messages are sent by the same client, only its URL differs.
+	assert.Equal(pushover.APIEndpoint, service.client.(*apiClient).url)
+	service = NewWithAPIURL("", "http://127.0.0.1:8080/1/")
+	assert.Equal("http://127.0.0.1:8080/1", service.client.(*apiClient).url)
 }
 
 func TestPushover_AddReceivers(t *testing.T) {
@@ -49,8 +59,8 @@ func TestPushover_Send(t *testing.T) {
 	// Test error response
 	mockClient := newMockPushoverClient(t)
 	mockClient.
-		On("SendMessage", &pushover.Message{Title: "subject", Message: "message"}, pushover.NewRecipient("1234")).
-		Return(&pushover.Response{}, errors.New("some error"))
+		On("send", mock.Anything, &pushover.Message{Title: "subject", Message: "message"}, []byte(nil), "1234").
+		Return(errors.New("some error"))
 
 	service.client = mockClient
 	service.AddReceivers("1234")
@@ -61,12 +71,12 @@ func TestPushover_Send(t *testing.T) {
 	// Test success response
 	mockClient = newMockPushoverClient(t)
 	mockClient.
-		On("SendMessage", &pushover.Message{Title: "subject", Message: "message"}, pushover.NewRecipient("1234")).
-		Return(&pushover.Response{}, nil)
+		On("send", mock.Anything, &pushover.Message{Title: "subject", Message: "message"}, []byte(nil), "1234").
+		Return(nil)
 
 	mockClient.
-		On("SendMessage", &pushover.Message{Title: "subject", Message: "message"}, pushover.NewRecipient("5678")).
-		Return(&pushover.Response{}, nil)
+		On("send", mock.Anything, &pushover.Message{Title: "subject", Message: "message"}, []byte(nil), "5678").
+		Return(nil)
 
 	service.client = mockClient
 	service.AddReceivers("5678")
@@ -74,3 +84,93 @@ func TestPushover_Send(t *testing.T) {
 	assert.Nil(err)
 	mockClient.AssertExpectations(t)
 }
+
+func TestPushover_SendMessage(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	msg := notifymsg.New("subject", "<b>message</b>")
+	msg.Format = notifymsg.FormatHTML
+	msg.Priority = notifymsg.PriorityCritical
+	msg.Links = []notifymsg.Link{{Title: "Dashboard", URL: "https://example.com"}}
+	msg.Metadata = map[string]any{"sound": "siren"}
+	msg.Attachments = []notifymsg.Attachment{{Name: "empty.png"}, {Name: "graph.png", Data: []byte("png")}}
+
+	expected := &pushover.Message{
+		Title:    "subject",
+		Message:  "<b>message</b>",
+		HTML:     true,
+		Priority: pushover.PriorityEmergency,
+		Retry:    emergencyRetry,
+		Expire:   emergencyExpire,
+		URL:      "https://example.com",
+		URLTitle: "Dashboard",
+		Sound:    "siren",
+	}
+
+	mockClient := newMockPushoverClient(t)
+	mockClient.
+		On("send", mock.Anything, expected, []byte("png"), "1234").
+		Return(nil)
+
+	service := New("")
+	service.client = mockClient
+	service.AddReceivers("1234")
+
+	err := service.SendMessage(context.Background(), msg)
+	assert.Nil(err)
+	mockClient.AssertExpectations(t)
+}
+
+func TestPushover_SendMessageTruncate(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	msg := notifymsg.New("subject", "<b>"+strings.Repeat("word ", split.PushoverLimit)+"</b>")
+	msg.Format = notifymsg.FormatHTML
+
+	var sent *pushover.Message
+	mockClient := newMockPushoverClient(t)
+	mockClient.
+		On("send", mock.Anything, mock.Anything, []byte(nil), "1234").
+		Run(func(args mock.Arguments) { sent = args.Get(1).(*pushover.Message) }).
+		Return(nil)
+
+	service := New("")
+	service.client = mockClient
+	service.AddReceivers("1234")
+
+	err := service.SendMessage(context.Background(), msg)
+	assert.Nil(err)
+	assert.LessOrEqual(len([]rune(sent.Message)), split.PushoverLimit)
+	assert.True(strings.HasPrefix(sent.Message, "<b>word word"))
+	assert.True(strings.HasSuffix(sent.Message, "word…</b>"))
+}
+
+func TestPushover_SendMessageMarkdown(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	msg := notifymsg.New("subject", "**disk** full")
+	msg.Format = notifymsg.FormatMarkdown
+
+	mockClient := newMockPushoverClient(t)
+	mockClient.
+		On("send", mock.Anything, &pushover.Message{
+			Title:   "subject",
+			Message: "<p><strong>disk</strong> full</p>",
+			HTML:    true,
+		}, []byte(nil), "1234").
+		Return(nil)
+
+	service := New("")
+	service.client = mockClient
+	service.AddReceivers("1234")
+
+	err := service.SendMessage(context.Background(), msg)
+	assert.Nil(err)
+	mockClient.AssertExpectations(t)
+}
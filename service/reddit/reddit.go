@@ -4,12 +4,19 @@ package reddit
 import (
 	"context"
 	"crypto/tls"
+	"log/slog"
 	"net/http"
 
 	"github.com/casdoor/go-reddit/v2/reddit"
 	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/receiver"
 )
 
+// serviceName identifies the Reddit service in per-receiver results.
+const serviceName = "reddit"
+
 //go:generate mockery --name=redditMessageClient --output=. --case=underscore --inpackage
 type redditMessageClient interface {
 	Send(context.Context, *reddit.SendMessageRequest) (*reddit.Response, error)
@@ -22,6 +29,8 @@ var _ redditMessageClient = new(reddit.MessageService)
 type Reddit struct {
 	client     redditMessageClient
 	recipients []string
+	secrets    []string
+	logger     *slog.Logger
 }
 
 // New returns a new instance of a Reddit notification service.
@@ -53,6 +62,7 @@ func New(clientID, clientSecret, username, password string) (*Reddit, error) {
 	r := &Reddit{
 		client:     rClient.Message,
 		recipients: []string{},
+		secrets:    []string{clientSecret, password},
 	}
 
 	return r, nil
@@ -64,24 +74,27 @@ func (r *Reddit) AddReceivers(recipients ...string) {
 	r.recipients = append(r.recipients, recipients...)
 }
 
+// SetLogger sets the logger of the service. The client secret and the password are redacted from all logs.
+func (r *Reddit) SetLogger(logger *slog.Logger) {
+	r.logger = logger
+}
+
 // Send takes a message subject and a message body and sends them to all previously set recipients.
 func (r *Reddit) Send(ctx context.Context, subject, message string) error {
-	for i := range r.recipients {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			m := reddit.SendMessageRequest{
-				To:      r.recipients[i],
-				Subject: subject,
-				Text:    message,
-			}
+	ctx = logging.Bind(ctx, r.logger, r.secrets...)
+	recipients := receiver.ResolveIDs(ctx, serviceName, r.recipients)
 
-			_, err := r.client.Send(ctx, &m)
-			if err != nil {
-				return errors.Wrapf(err, "failed to send message to Reddit recipient '%s'", r.recipients[i])
-			}
+	return receiver.Each(ctx, serviceName, recipients, func(ctx context.Context, recipient string) error {
+		m := reddit.SendMessageRequest{
+			To:      recipient,
+			Subject: subject,
+			Text:    message,
 		}
-	}
-	return nil
+
+		_, err := r.client.Send(ctx, &m)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send message to Reddit recipient '%s'", recipient)
+		}
+		return nil
+	})
 }
@@ -2,17 +2,27 @@ package rocketchat
 
 import (
 	"context"
+	"log/slog"
 	"net/url"
 
 	"github.com/RocketChat/Rocket.Chat.Go.SDK/models"
 	"github.com/RocketChat/Rocket.Chat.Go.SDK/rest"
 	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/logging"
+	notifymsg "github.com/casdoor/notify/message"
+	"github.com/casdoor/notify/receiver"
 )
 
+// serviceName identifies the RocketChat service in per-receiver results.
+const serviceName = "rocketchat"
+
 // RocketChat struct holds necessary data to communicate with the RocketChat API.
 type RocketChat struct {
 	client       *rest.Client
 	channelNames []string
+	token        string
+	logger       *slog.Logger
 }
 
 // New returns a new instance of a RocketChat notification service.
@@ -36,6 +46,7 @@ func New(serverURL, scheme, userID, token string) (*RocketChat, error) {
 	rc := RocketChat{
 		client:       c,
 		channelNames: []string{},
+		token:        token,
 	}
 
 	return &rc, nil
@@ -47,26 +58,34 @@ func (r *RocketChat) AddReceivers(channelNames ...string) {
 	r.channelNames = append(r.channelNames, channelNames...)
 }
 
+// SetLogger sets the logger of the service. The token is redacted from all logs.
+func (r *RocketChat) SetLogger(logger *slog.Logger) {
+	r.logger = logger
+}
+
+// PreferredFormat returns Markdown, which Rocket.Chat renders. Notify uses it to render templates.
+func (r *RocketChat) PreferredFormat() notifymsg.BodyFormat {
+	return notifymsg.FormatMarkdown
+}
+
 // Send takes a message subject and a message body and sends them to all previously set channels.
 // user used for sending the message has to be a member of the channel.
 // https://docs.rocket.chat/api/rest-api/methods/chat/postmessage
 func (r *RocketChat) Send(ctx context.Context, subject, message string) error {
+	ctx = logging.Bind(ctx, r.logger, r.token)
 	fullMessage := subject + "\n" + message // Treating subject as message title
 
-	for _, channelName := range r.channelNames {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			msg := models.PostMessage{
-				Channel: channelName,
-				Text:    fullMessage,
-			}
-			_, err := r.client.PostMessage(&msg)
-			if err != nil {
-				return errors.Wrapf(err, "failed to send message to RocketChat channel '%s'", channelName)
-			}
+	channelNames := receiver.ResolveIDs(ctx, serviceName, r.channelNames)
+
+	return receiver.Each(ctx, serviceName, channelNames, func(_ context.Context, channelName string) error {
+		msg := models.PostMessage{
+			Channel: channelName,
+			Text:    fullMessage,
 		}
-	}
-	return nil
+		_, err := r.client.PostMessage(&msg)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send message to RocketChat channel '%s'", channelName)
+		}
+		return nil
+	})
 }
@@ -2,19 +2,31 @@ package sendgrid
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sendgrid/sendgrid-go"
 	"github.com/sendgrid/sendgrid-go/helpers/mail"
+
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/receiver"
+	"github.com/casdoor/notify/retry"
 )
 
+// serviceName identifies the SendGrid service in receivers bound to the context.
+const serviceName = "sendgrid"
+
 // SendGrid struct holds necessary data to communicate with the SendGrid API.
 type SendGrid struct {
 	client            *sendgrid.Client
 	senderAddress     string
 	senderName        string
 	receiverAddresses []string
+	apiKey            string
+	logger            *slog.Logger
 }
 
 // New returns a new instance of a SendGrid notification service.
@@ -26,6 +38,7 @@ func New(apiKey, senderAddress, senderName string) *SendGrid {
 		senderAddress:     senderAddress,
 		senderName:        senderName,
 		receiverAddresses: []string{},
+		apiKey:            apiKey,
 	}
 }
 
@@ -35,9 +48,20 @@ func (s *SendGrid) AddReceivers(addresses ...string) {
 	s.receiverAddresses = append(s.receiverAddresses, addresses...)
 }
 
+// SetLogger sets the logger of the service. The API key is redacted from all logs.
+func (s *SendGrid) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
 // Send takes a message subject and a message body and sends them to all previously set chats. Message body supports
 // html as markup language.
-func (s SendGrid) Send(ctx context.Context, subject, message string) error {
+func (s SendGrid) Send(ctx context.Context, subject, message string) (err error) {
+	ctx = logging.Bind(ctx, s.logger, s.apiKey)
+	receiverAddresses := receiver.ResolveIDs(ctx, serviceName, s.receiverAddresses)
+	defer func(start time.Time) {
+		logging.Delivery(ctx, serviceName, strings.Join(receiverAddresses, ", "), err, time.Since(start))
+	}(time.Now())
+
 	from := mail.NewEmail(s.senderName, s.senderAddress)
 	content := mail.NewContent("text/html", message)
 
@@ -45,7 +69,7 @@ func (s SendGrid) Send(ctx context.Context, subject, message string) error {
 	personalization := mail.NewPersonalization()
 	personalization.Subject = subject
 
-	for _, receiverAddress := range s.receiverAddresses {
+	for _, receiverAddress := range receiverAddresses {
 		personalization.AddTos(mail.NewEmail(receiverAddress, receiverAddress))
 	}
 
@@ -64,7 +88,9 @@ func (s SendGrid) Send(ctx context.Context, subject, message string) error {
 		}
 
 		if resp.StatusCode != http.StatusAccepted {
-			return errors.New("the SendGrid endpoint did not accept the message")
+			logging.Response(ctx, serviceName, strings.Join(receiverAddresses, ", "), resp.StatusCode, []byte(resp.Body))
+			err = errors.New("the SendGrid endpoint did not accept the message")
+			return retry.HTTPError(resp.StatusCode, resp.Headers, err)
 		}
 	}
 
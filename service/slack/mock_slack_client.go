@@ -14,6 +14,29 @@ type mockSlackClient struct {
 	mock.Mock
 }
 
+// AuthTestContext provides a mock function with given fields: ctx
+func (_m *mockSlackClient) AuthTestContext(ctx context.Context) (*slack_goslack.AuthTestResponse, error) {
+	ret := _m.Called(ctx)
+
+	var r0 *slack_goslack.AuthTestResponse
+	if rf, ok := ret.Get(0).(func(context.Context) *slack_goslack.AuthTestResponse); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*slack_goslack.AuthTestResponse)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // PostMessageContext provides a mock function with given fields: ctx, channelID, options
 func (_m *mockSlackClient) PostMessageContext(ctx context.Context, channelID string, options ...slack_goslack.MsgOption) (string, string, error) {
 	_va := make([]interface{}, len(options))
@@ -2,13 +2,24 @@ package slack
 
 import (
 	"context"
+	"log/slog"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/slack-go/slack"
+
+	"github.com/casdoor/notify/logging"
+	notifymsg "github.com/casdoor/notify/message"
+	"github.com/casdoor/notify/receiver"
+	"github.com/casdoor/notify/retry"
 )
 
+// serviceName identifies the Slack service in per-receiver results.
+const serviceName = "slack"
+
 //go:generate mockery --name=slackClient --output=. --case=underscore --inpackage
 type slackClient interface {
+	AuthTestContext(ctx context.Context) (*slack.AuthTestResponse, error)
 	PostMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (string, string, error)
 }
 
@@ -19,6 +30,8 @@ var _ slackClient = new(slack.Client)
 type Slack struct {
 	client     slackClient
 	channelIDs []string
+	token      string
+	logger     *slog.Logger
 }
 
 // New returns a new instance of a Slack notification service.
@@ -31,38 +44,86 @@ func New(apiToken string) *Slack {
 	s := &Slack{
 		client:     client,
 		channelIDs: []string{},
+		token:      apiToken,
 	}
 
 	return s
 }
 
+// NewWithAPIURL returns a new instance of a Slack notification service that sends all requests to the Slack API at the
+// given URL instead of https://slack.com/api/, e.g. to a Slack compatible server or to a fake server in tests.
+func NewWithAPIURL(apiToken, apiURL string) *Slack {
+	if !strings.HasSuffix(apiURL, "/") {
+		apiURL += "/"
+	}
+
+	s := New(apiToken)
+	s.client = slack.New(apiToken, slack.OptionAPIURL(apiURL))
+
+	return s
+}
+
+// classifyError marks rate limits and server side failures of the Slack API as transient and client errors as
+// permanent.
+func classifyError(err error) error {
+	var rateLimitErr *slack.RateLimitedError
+	if errors.As(err, &rateLimitErr) {
+		return retry.After(err, rateLimitErr.RetryAfter)
+	}
+
+	var statusErr slack.StatusCodeError
+	if errors.As(err, &statusErr) {
+		return retry.HTTPError(statusErr.Code, nil, err)
+	}
+
+	return err
+}
+
 // AddReceivers takes Slack channel IDs and adds them to the internal channel ID list. The Send method will send
 // a given message to all those channels.
 func (s *Slack) AddReceivers(channelIDs ...string) {
 	s.channelIDs = append(s.channelIDs, channelIDs...)
 }
 
+// SetLogger sets the logger of the service. The API token is redacted from all logs.
+func (s *Slack) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
+// PreferredFormat returns mrkdwn, the Markdown dialect of Slack. Notify uses it to render templates.
+func (s Slack) PreferredFormat() notifymsg.BodyFormat {
+	return notifymsg.FormatMrkdwn
+}
+
+// Check validates the API token with the Slack API, without sending a message.
+func (s Slack) Check(ctx context.Context) error {
+	_, err := s.client.AuthTestContext(ctx)
+	if err != nil {
+		return classifyError(errors.Wrap(err, "failed to check Slack API token"))
+	}
+
+	return nil
+}
+
 // Send takes a message subject and a message body and sends them to all previously set channels.
 // you will need a slack app with the chat:write.public and chat:write permissions.
 // see https://api.slack.com/
 func (s Slack) Send(ctx context.Context, subject, message string) error {
+	ctx = logging.Bind(ctx, s.logger, s.token)
 	fullMessage := subject + "\n" + message // Treating subject as message title
 
-	for _, channelID := range s.channelIDs {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			id, timestamp, err := s.client.PostMessageContext(
-				ctx,
-				channelID,
-				slack.MsgOptionText(fullMessage, false),
-			)
-			if err != nil {
-				return errors.Wrapf(err, "failed to send message to Slack channel '%s' at time '%s'", id, timestamp)
-			}
+	channelIDs := receiver.ResolveIDs(ctx, serviceName, s.channelIDs)
+
+	return receiver.Each(ctx, serviceName, channelIDs, func(ctx context.Context, channelID string) error {
+		id, timestamp, err := s.client.PostMessageContext(
+			ctx,
+			channelID,
+			slack.MsgOptionText(fullMessage, false),
+		)
+		if err != nil {
+			return classifyError(errors.Wrapf(err, "failed to send message to Slack channel '%s' at time '%s'", id, timestamp))
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/pkg/errors"
+	"github.com/slack-go/slack"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -82,3 +83,23 @@ func TestSlack_Send(t *testing.T) {
 	assert.Nil(err)
 	mockClient.AssertExpectations(t)
 }
+
+func TestSlack_Check(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	ctx := context.Background()
+	service := New("")
+
+	mockClient := newMockSlackClient(t)
+	mockClient.On("AuthTestContext", ctx).Return(&slack.AuthTestResponse{}, nil).Once()
+	mockClient.On("AuthTestContext", ctx).Return(nil, errors.New("invalid_auth")).Once()
+	service.client = mockClient
+
+	assert.NoError(service.Check(ctx))
+
+	err := service.Check(ctx)
+	assert.ErrorContains(err, "failed to check Slack API token: invalid_auth")
+	mockClient.AssertExpectations(t)
+}
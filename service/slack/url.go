@@ -0,0 +1,86 @@
+package slack
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/urlscheme"
+)
+
+// Scheme is the scheme of Slack notification URLs, see ParseURL.
+const Scheme = "slack"
+
+func init() {
+	urlscheme.Register(Scheme, func(rawURL string) (urlscheme.Sender, error) {
+		return NewFromURL(rawURL)
+	})
+}
+
+// URLConfig is the configuration described by a Slack notification URL.
+type URLConfig struct {
+	// Token is the API token of the Slack app, e.g. "xoxb-...".
+	Token string
+	// ChannelIDs are the channels to send to.
+	ChannelIDs []string
+}
+
+// ParseURL parses a Slack notification URL of the form:
+//
+//	slack://{token}/{channel}[/{channel}...]
+//
+// Channels may be given by ID or by name, e.g. "C123" or "#alerts". The URL isn't parsed with net/url, so that channel
+// names don't need to be escaped.
+func ParseURL(rawURL string) (URLConfig, error) {
+	rest, ok := urlscheme.Cut(rawURL, Scheme)
+	if !ok {
+		return URLConfig{}, errors.Errorf("not a %s URL", Scheme)
+	}
+	path, _, _ := strings.Cut(rest, "?")
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	token, err := url.PathUnescape(parts[0])
+	if err != nil || token == "" {
+		return URLConfig{}, errors.New("missing or invalid token in Slack URL")
+	}
+
+	cfg := URLConfig{Token: token}
+	for _, part := range parts[1:] {
+		channelID, err := url.PathUnescape(part)
+		if err != nil || channelID == "" {
+			return URLConfig{}, errors.Errorf("invalid Slack channel %q", part)
+		}
+		cfg.ChannelIDs = append(cfg.ChannelIDs, channelID)
+	}
+	if len(cfg.ChannelIDs) == 0 {
+		return URLConfig{}, errors.New("missing channel in Slack URL")
+	}
+
+	return cfg, nil
+}
+
+// FormatURL returns the notification URL describing the given configuration, see ParseURL.
+func FormatURL(cfg URLConfig) string {
+	var b strings.Builder
+	b.WriteString(Scheme + "://" + url.PathEscape(cfg.Token))
+	for _, channelID := range cfg.ChannelIDs {
+		b.WriteString("/" + url.PathEscape(channelID))
+	}
+
+	return b.String()
+}
+
+// NewFromURL returns a new instance of a Slack notification service configured by the given notification URL, see
+// ParseURL.
+func NewFromURL(rawURL string) (*Slack, error) {
+	cfg, err := ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	s := New(cfg.Token)
+	s.AddReceivers(cfg.ChannelIDs...)
+
+	return s, nil
+}
@@ -0,0 +1,33 @@
+package slack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseURL(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := ParseURL("slack://xoxb-1234-abcd/C123/#alerts")
+	require.NoError(t, err)
+	require.Equal(t, URLConfig{Token: "xoxb-1234-abcd", ChannelIDs: []string{"C123", "#alerts"}}, cfg)
+	require.Equal(t, "slack://xoxb-1234-abcd/C123/%23alerts", FormatURL(cfg))
+
+	roundTrip, err := ParseURL(FormatURL(cfg))
+	require.NoError(t, err)
+	require.Equal(t, cfg, roundTrip)
+
+	for _, rawURL := range []string{"tgram://xoxb-1234-abcd/C123", "slack://xoxb-1234-abcd", "slack:///C123"} {
+		_, err = ParseURL(rawURL)
+		require.Error(t, err, rawURL)
+	}
+}
+
+func TestNewFromURL(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewFromURL("slack://xoxb-1234-abcd/C123")
+	require.NoError(t, err)
+	require.Equal(t, []string{"C123"}, s.channelIDs)
+}
@@ -3,10 +3,14 @@ package syslog
 import (
 	"context"
 	"io"
+	"log/slog"
 	"log/syslog"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/logging"
 )
 
 // mockSyslogWriter abstracts log/syslog for writing unit tests.
@@ -19,6 +23,7 @@ type syslogWriter interface {
 // Service encapsulates a syslog daemon writer.
 type Service struct {
 	writer syslogWriter
+	logger *slog.Logger
 }
 
 // dial is a wrapper function around syslog.Dial. It normalizes the prefix tag in case that it's empty and returns
@@ -53,6 +58,11 @@ func NewFromDial(network, raddr string, priority syslog.Priority, tag string) (*
 	return dial(network, raddr, priority, tag)
 }
 
+// SetLogger sets the logger of the service.
+func (s *Service) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
 // Close the underlying syslog writer.
 func (s *Service) Close() error {
 	return s.writer.Close()
@@ -60,12 +70,17 @@ func (s *Service) Close() error {
 
 // Send takes a message subject and a message body and sends them to all previously set channels.
 // user used for sending the message has to be a member of the channel.
-func (s *Service) Send(ctx context.Context, subject, message string) error {
+func (s *Service) Send(ctx context.Context, subject, message string) (err error) {
+	ctx = logging.Bind(ctx, s.logger)
+	defer func(start time.Time) {
+		logging.Delivery(ctx, "syslog", "", err, time.Since(start))
+	}(time.Now())
+
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
-		_, err := s.writer.Write([]byte(subject + ": " + message))
+		_, err = s.writer.Write([]byte(subject + ": " + message))
 		if err != nil {
 			return errors.Wrap(err, "failed to write message to syslog")
 		}
@@ -2,22 +2,39 @@ package telegram
 
 import (
 	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/internal/apiurl"
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/markup"
+	notifymsg "github.com/casdoor/notify/message"
+	"github.com/casdoor/notify/receiver"
+	"github.com/casdoor/notify/retry"
+	"github.com/casdoor/notify/split"
 )
 
 const (
-	ModeMarkdown = tgbotapi.ModeMarkdown
-	ModeHTML     = tgbotapi.ModeHTML
+	ModeMarkdown   = tgbotapi.ModeMarkdown
+	ModeMarkdownV2 = "MarkdownV2"
+	ModeHTML       = tgbotapi.ModeHTML
 )
 
-var parseMode = ModeHTML // HTML is the default mode.
+// serviceName identifies the Telegram service in per-receiver results.
+const serviceName = "telegram"
 
 // Telegram struct holds necessary data to communicate with the Telegram API.
 type Telegram struct {
-	client  *tgbotapi.BotAPI
-	chatIDs []int64
+	client    *tgbotapi.BotAPI
+	chatIDs   []int64
+	parseMode string
+	splitter  *split.Splitter
+	logger    *slog.Logger
 }
 
 // New returns a new instance of a Telegram notification service.
@@ -30,12 +47,45 @@ func New(apiToken string) (*Telegram, error) {
 		return nil, err
 	}
 
-	t := &Telegram{
-		client:  client,
-		chatIDs: []int64{},
+	return newWithClient(client), nil
+}
+
+// NewWithAPIURL returns a new instance of a Telegram notification service that sends all requests to the Telegram Bot
+// API at the given URL instead of https://api.telegram.org, e.g. to a local Bot API server or to a fake server in tests.
+// Like New, it validates the token by requesting the bot's profile.
+func NewWithAPIURL(apiToken, apiURL string) (*Telegram, error) {
+	httpClient, err := apiurl.Client(nil, apiURL)
+	if err != nil {
+		return nil, err
 	}
 
-	return t, nil
+	client, err := tgbotapi.NewBotAPIWithClient(apiToken, httpClient)
+	if err != nil {
+		return nil, redactToken(err, apiToken)
+	}
+
+	return newWithClient(client), nil
+}
+
+// newWithClient returns a new instance of a Telegram notification service using the given client.
+func newWithClient(client *tgbotapi.BotAPI) *Telegram {
+	return &Telegram{
+		client:    client,
+		chatIDs:   []int64{},
+		parseMode: ModeHTML, // HTML is the default mode.
+		splitter:  split.New(split.TelegramLimit, split.WithCounter(split.UTF16Length)),
+	}
+}
+
+// classifyError marks err as transient if Telegram asked us to slow down. The Bot API does so by setting the retry_after
+// response parameter when a bot exceeds its flood limits.
+func classifyError(err error) error {
+	var apiErr tgbotapi.Error
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return retry.After(err, time.Duration(apiErr.RetryAfter)*time.Second)
+	}
+
+	return err
 }
 
 // SetClient set a new custom BotAPI instance.
@@ -51,7 +101,37 @@ func (t *Telegram) SetClient(client *tgbotapi.BotAPI) {
 //
 //	-> https://pkg.go.dev/github.com/go-telegram-bot-api/telegram-bot-api#pkg-constants
 func (t *Telegram) SetParseMode(mode string) {
-	parseMode = mode
+	t.parseMode = mode
+}
+
+// SetSplitter sets the splitter that fits messages into the length limit of Telegram. By default, messages longer than
+// 4096 characters are sent as several numbered messages. A nil splitter disables splitting.
+func (t *Telegram) SetSplitter(splitter *split.Splitter) {
+	t.splitter = splitter
+}
+
+// SetLogger sets the logger of the service. The bot token is redacted from all logs.
+func (t *Telegram) SetLogger(logger *slog.Logger) {
+	t.logger = logger
+}
+
+// PreferredFormat returns the body format matching the parse mode, see SetParseMode. Notify uses it to render templates.
+func (t Telegram) PreferredFormat() notifymsg.BodyFormat {
+	return formatOf(t.parseMode)
+}
+
+// formatOf returns the body format matching the given parse mode.
+func formatOf(mode string) notifymsg.BodyFormat {
+	switch mode {
+	case ModeHTML:
+		return notifymsg.FormatHTML
+	case ModeMarkdownV2:
+		return notifymsg.FormatMarkdownV2
+	case ModeMarkdown:
+		return notifymsg.FormatMarkdown
+	default:
+		return notifymsg.FormatPlain
+	}
 }
 
 // AddReceivers takes Telegram chat IDs and adds them to the internal chat ID list. The Send method will send
@@ -60,26 +140,78 @@ func (t *Telegram) AddReceivers(chatIDs ...int64) {
 	t.chatIDs = append(t.chatIDs, chatIDs...)
 }
 
+// Check validates the bot token with the Telegram API, without sending a message. The Telegram API client doesn't
+// support contexts, so ctx is only checked before the request.
+func (t Telegram) Check(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, err := t.client.GetMe()
+	if err != nil {
+		err = redactToken(err, t.client.Token)
+		return classifyError(errors.Wrap(err, "failed to check Telegram bot token"))
+	}
+
+	return nil
+}
+
+// redactToken returns err without the given bot token. Errors of the Telegram API client may contain request URLs,
+// which contain the token. Errors returned by the Telegram API itself never do, so they're kept as they are.
+func redactToken(err error, token string) error {
+	if token == "" || !strings.Contains(err.Error(), token) {
+		return err
+	}
+
+	return errors.New(strings.ReplaceAll(err.Error(), token, logging.Redacted))
+}
+
 // Send takes a message subject and a message body and sends them to all previously set chats. Message body supports
 // html as markup language.
 func (t Telegram) Send(ctx context.Context, subject, message string) error {
 	fullMessage := subject + "\n" + message // Treating subject as message title
 
-	msg := tgbotapi.NewMessage(0, fullMessage)
-	msg.ParseMode = parseMode
+	return t.send(ctx, fullMessage, t.parseMode)
+}
+
+// SendMessage sends the given message to all previously set chats. A message without body format is sent like Send
+// does. Otherwise, the body is converted to MarkdownV2 and the subject is escaped, so that Telegram never rejects the
+// message because of its markup, see the markup package.
+func (t Telegram) SendMessage(ctx context.Context, message *notifymsg.Message) error {
+	if message.Format == "" {
+		return t.Send(ctx, message.Subject, message.Body)
+	}
+
+	body := markup.Convert(message.Body, message.Format, notifymsg.FormatMarkdownV2)
+	subject := markup.Escape(message.Subject, notifymsg.FormatMarkdownV2)
+
+	return t.send(ctx, subject+"\n"+body, ModeMarkdownV2)
+}
+
+// send sends text with the given parse mode to all previously set chats.
+func (t Telegram) send(ctx context.Context, text, mode string) error {
+	ctx = logging.Bind(ctx, t.logger, t.client.Token)
+	chatIDs, err := receiver.Resolve(ctx, serviceName, t.chatIDs, func(r receiver.Receiver) (int64, error) {
+		chatID, err := strconv.ParseInt(r.ID, 10, 64)
+		return chatID, errors.Wrapf(err, "invalid Telegram chat ID %q", r.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	parts := t.splitter.Split(text, formatOf(mode))
+
+	return receiver.Each(ctx, serviceName, chatIDs, func(ctx context.Context, chatID int64) error {
+		for _, part := range parts {
+			msg := tgbotapi.NewMessage(chatID, part)
+			msg.ParseMode = mode
 
-	for _, chatID := range t.chatIDs {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			msg.ChatID = chatID
 			_, err := t.client.Send(msg)
 			if err != nil {
-				return errors.Wrapf(err, "failed to send message to Telegram chat '%d'", chatID)
+				return classifyError(errors.Wrapf(err, "failed to send message to Telegram chat '%d'", chatID))
 			}
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
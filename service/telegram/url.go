@@ -0,0 +1,119 @@
+package telegram
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/urlscheme"
+)
+
+// Scheme is the scheme of Telegram notification URLs, see ParseURL.
+const Scheme = "tgram"
+
+func init() {
+	urlscheme.Register(Scheme, func(rawURL string) (urlscheme.Sender, error) {
+		return NewFromURL(rawURL)
+	})
+}
+
+// URLConfig is the configuration described by a Telegram notification URL.
+type URLConfig struct {
+	// Token is the API token of the bot.
+	Token string
+	// ChatIDs are the chats to send to.
+	ChatIDs []int64
+	// ParseMode is the parse mode of the message bodies, see SetParseMode. It's left as is if empty.
+	ParseMode string
+}
+
+// formats maps the values of the format query parameter to parse modes.
+var formats = map[string]string{
+	"html":       ModeHTML,
+	"markdown":   ModeMarkdown,
+	"markdownv2": ModeMarkdownV2,
+}
+
+// ParseURL parses a Telegram notification URL of the form:
+//
+//	tgram://{bot_token}/{chat_id}[/{chat_id}...][?format=html|markdown|markdownv2]
+//
+// The URL isn't parsed with net/url, since bot tokens contain a colon, which would be mistaken for a port.
+func ParseURL(rawURL string) (URLConfig, error) {
+	rest, ok := urlscheme.Cut(rawURL, Scheme)
+	if !ok {
+		return URLConfig{}, errors.Errorf("not a %s URL", Scheme)
+	}
+	path, rawQuery, _ := strings.Cut(rest, "?")
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	token, err := url.PathUnescape(parts[0])
+	if err != nil || token == "" {
+		return URLConfig{}, errors.New("missing or invalid bot token in Telegram URL")
+	}
+
+	cfg := URLConfig{Token: token}
+	for _, part := range parts[1:] {
+		chatID, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return URLConfig{}, errors.Errorf("invalid Telegram chat ID %q", part)
+		}
+		cfg.ChatIDs = append(cfg.ChatIDs, chatID)
+	}
+	if len(cfg.ChatIDs) == 0 {
+		return URLConfig{}, errors.New("missing chat ID in Telegram URL")
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return URLConfig{}, errors.Wrap(err, "invalid Telegram URL query")
+	}
+	if format := query.Get("format"); format != "" {
+		mode, ok := formats[strings.ToLower(format)]
+		if !ok {
+			return URLConfig{}, errors.Errorf("unknown Telegram format %q", format)
+		}
+		cfg.ParseMode = mode
+	}
+
+	return cfg, nil
+}
+
+// FormatURL returns the notification URL describing the given configuration, see ParseURL.
+func FormatURL(cfg URLConfig) string {
+	var b strings.Builder
+	b.WriteString(Scheme + "://" + url.PathEscape(cfg.Token))
+	for _, chatID := range cfg.ChatIDs {
+		b.WriteString("/" + strconv.FormatInt(chatID, 10))
+	}
+	for format, mode := range formats {
+		if mode == cfg.ParseMode {
+			b.WriteString("?format=" + format)
+		}
+	}
+
+	return b.String()
+}
+
+// NewFromURL returns a new instance of a Telegram notification service configured by the given notification URL, see
+// ParseURL. Unlike New, it doesn't call the Telegram API, so parsing never does network I/O; use Check to validate the
+// bot token.
+func NewFromURL(rawURL string) (*Telegram, error) {
+	cfg, err := ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// The fields NewBotAPI would set, except for the bot's profile, which is only needed to receive updates.
+	t := newWithClient(&tgbotapi.BotAPI{Token: cfg.Token, Client: &http.Client{}, Buffer: 100})
+	t.AddReceivers(cfg.ChatIDs...)
+	if cfg.ParseMode != "" {
+		t.SetParseMode(cfg.ParseMode)
+	}
+
+	return t, nil
+}
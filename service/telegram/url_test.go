@@ -0,0 +1,51 @@
+package telegram
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseURL(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := ParseURL("tgram://123456:ABC-DEF/-100123456/42?format=markdownv2")
+	require.NoError(t, err)
+	require.Equal(t, URLConfig{
+		Token:     "123456:ABC-DEF",
+		ChatIDs:   []int64{-100123456, 42},
+		ParseMode: ModeMarkdownV2,
+	}, cfg)
+	require.Equal(t, "tgram://123456:ABC-DEF/-100123456/42?format=markdownv2", FormatURL(cfg))
+
+	cfg, err = ParseURL("TGRAM://123456:ABC-DEF/42/")
+	require.NoError(t, err)
+	require.Equal(t, URLConfig{Token: "123456:ABC-DEF", ChatIDs: []int64{42}}, cfg)
+
+	roundTrip, err := ParseURL(FormatURL(cfg))
+	require.NoError(t, err)
+	require.Equal(t, cfg, roundTrip)
+
+	for _, rawURL := range []string{
+		"slack://123456:ABC-DEF/42",
+		"tgram://123456:ABC-DEF",
+		"tgram:///42",
+		"tgram://123456:ABC-DEF/general",
+		"tgram://123456:ABC-DEF/42?format=rtf",
+	} {
+		_, err = ParseURL(rawURL)
+		require.Error(t, err, rawURL)
+		require.NotContains(t, err.Error(), "ABC-DEF", rawURL)
+	}
+}
+
+func TestNewFromURL(t *testing.T) {
+	t.Parallel()
+
+	// Parsing doesn't validate the token with the Telegram API.
+	s, err := NewFromURL("tgram://123456:ABC-DEF/42?format=markdownv2")
+	require.NoError(t, err)
+	require.Equal(t, "123456:ABC-DEF", s.client.Token)
+	require.Equal(t, []int64{42}, s.chatIDs)
+	require.Equal(t, ModeMarkdownV2, s.parseMode)
+}
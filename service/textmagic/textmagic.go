@@ -2,17 +2,29 @@ package textmagic
 
 import (
 	"context"
+	"log/slog"
 	"strings"
+	"time"
 
 	textMagic "github.com/textmagic/textmagic-rest-go-v2/v2"
+
+	"github.com/casdoor/notify/logging"
+	notifymsg "github.com/casdoor/notify/message"
+	"github.com/casdoor/notify/receiver"
+	"github.com/casdoor/notify/split"
 )
 
+// serviceName identifies the TextMagic service in receivers bound to the context.
+const serviceName = "textmagic"
+
 // Service allow you to configure a TextMagic SDK client.
 type Service struct {
 	userName     string
 	apiKey       string
 	phoneNumbers []string
 	client       *textMagic.APIClient
+	splitter     *split.Splitter
+	logger       *slog.Logger
 }
 
 // New creates a new text magic client. Use your user-name and API key from
@@ -25,6 +37,7 @@ func New(userName, apiKey string) *Service {
 		client:   client,
 		userName: userName,
 		apiKey:   apiKey,
+		splitter: split.NewSMS(),
 	}
 }
 
@@ -33,18 +46,39 @@ func (s *Service) AddReceivers(phoneNumbers ...string) {
 	s.phoneNumbers = append(s.phoneNumbers, phoneNumbers...)
 }
 
+// SetSplitter sets the splitter that fits messages into the length limit of SMS. By default, messages longer than 10
+// segments are sent as several numbered messages. A nil splitter disables splitting.
+func (s *Service) SetSplitter(splitter *split.Splitter) {
+	s.splitter = splitter
+}
+
+// SetLogger sets the logger of the service. The API key is redacted from all logs.
+func (s *Service) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
 // Send sends a SMS via TextMagic to all previously added receivers.
-func (s *Service) Send(ctx context.Context, subject, message string) error {
+func (s *Service) Send(ctx context.Context, subject, message string) (err error) {
+	ctx = logging.Bind(ctx, s.logger, s.apiKey)
 	auth := context.WithValue(ctx, textMagic.ContextBasicAuth, textMagic.BasicAuth{
 		UserName: s.userName,
 		Password: s.apiKey,
 	})
 
-	text := subject + "\n" + message
-	_, _, err := s.client.TextMagicApi.SendMessage(auth, textMagic.SendMessageInputObject{
-		Text:   text,
-		Phones: strings.Join(s.phoneNumbers, ","),
-	})
+	phones := strings.Join(receiver.ResolveIDs(ctx, serviceName, s.phoneNumbers), ",")
+	defer func(start time.Time) {
+		logging.Delivery(ctx, serviceName, phones, err, time.Since(start))
+	}(time.Now())
+
+	for _, part := range s.splitter.Split(subject+"\n"+message, notifymsg.FormatPlain) {
+		_, _, err = s.client.TextMagicApi.SendMessage(auth, textMagic.SendMessageInputObject{
+			Text:   part,
+			Phones: phones,
+		})
+		if err != nil {
+			return err
+		}
+	}
 
-	return err
+	return nil
 }
@@ -2,12 +2,22 @@ package twilio
 
 import (
 	"context"
+	"log/slog"
 	"net/url"
 
 	"github.com/kevinburke/twilio-go"
 	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/markup"
+	notifymsg "github.com/casdoor/notify/message"
+	"github.com/casdoor/notify/receiver"
+	"github.com/casdoor/notify/split"
 )
 
+// serviceName identifies the Twilio service in per-receiver results.
+const serviceName = "twilio"
+
 // Compile-time check that twilio.MessageService satisfies twilioClient interface.
 var _ twilioClient = &twilio.MessageService{}
 
@@ -24,6 +34,9 @@ type Service struct {
 
 	fromPhoneNumber string
 	toPhoneNumbers  []string
+	splitter        *split.Splitter
+	authToken       string
+	logger          *slog.Logger
 }
 
 // New returns a new instance of Twilio notification service.
@@ -34,6 +47,8 @@ func New(accountSID, authToken, fromPhoneNumber string) (*Service, error) {
 		client:          client.Messages,
 		fromPhoneNumber: fromPhoneNumber,
 		toPhoneNumbers:  []string{},
+		splitter:        split.NewSMS(),
+		authToken:       authToken,
 	}
 	return s, nil
 }
@@ -44,22 +59,58 @@ func (s *Service) AddReceivers(phoneNumbers ...string) {
 	s.toPhoneNumbers = append(s.toPhoneNumbers, phoneNumbers...)
 }
 
+// SetSplitter sets the splitter that fits messages into the length limit of SMS. By default, messages longer than 10
+// segments are sent as several numbered messages. A nil splitter disables splitting.
+func (s *Service) SetSplitter(splitter *split.Splitter) {
+	s.splitter = splitter
+}
+
+// SetLogger sets the logger of the service. The auth token is redacted from all logs.
+func (s *Service) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
 // Send takes a message subject and a message body and sends them to all previously set phone numbers.
 func (s *Service) Send(ctx context.Context, subject, message string) error {
-	body := subject + "\n" + message
+	return s.SendMessage(ctx, notifymsg.New(subject, message))
+}
+
+// SendMessage sends the given message to all previously set phone numbers. Bodies in other formats are converted to
+// plain text. Attachments with a URL are sent as media (MMS) with the first part of the message, attachments without
+// one are skipped.
+func (s *Service) SendMessage(ctx context.Context, msg *notifymsg.Message) error {
+	ctx = logging.Bind(ctx, s.logger, s.authToken)
+	text := msg.Subject + "\n" + markup.Convert(msg.Body, msg.Format, notifymsg.FormatPlain)
+	parts := s.splitter.Split(text, notifymsg.FormatPlain)
 
-	for _, toPhoneNumber := range s.toPhoneNumbers {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
+	mediaURLs := make([]*url.URL, 0, len(msg.Attachments))
+	for _, attachment := range msg.Attachments {
+		if attachment.URL == "" {
+			continue
+		}
+
+		mediaURL, err := url.Parse(attachment.URL)
+		if err != nil {
+			return errors.Wrapf(err, "invalid media URL of attachment %q", attachment.Name)
+		}
+		mediaURLs = append(mediaURLs, mediaURL)
+	}
 
-			_, err := s.client.SendMessage(s.fromPhoneNumber, toPhoneNumber, body, []*url.URL{})
+	toPhoneNumbers := receiver.ResolveIDs(ctx, serviceName, s.toPhoneNumbers)
+
+	return receiver.Each(ctx, serviceName, toPhoneNumbers, func(_ context.Context, toPhoneNumber string) error {
+		for i, part := range parts {
+			media := mediaURLs
+			if i > 0 {
+				media = nil
+			}
+
+			_, err := s.client.SendMessage(s.fromPhoneNumber, toPhoneNumber, part, media)
 			if err != nil {
 				return errors.Wrapf(err, "failed to send message to phone number '%s' using Twilio", toPhoneNumber)
 			}
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
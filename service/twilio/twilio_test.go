@@ -5,10 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"strings"
 	testing "testing"
 
 	twilio "github.com/kevinburke/twilio-go"
 	"github.com/stretchr/testify/require"
+
+	notifymsg "github.com/casdoor/notify/message"
+	"github.com/casdoor/notify/split"
 )
 
 func TestTwilio_New(t *testing.T) {
@@ -98,3 +102,61 @@ func TestTwilio_Send(t *testing.T) {
 	assert.Nil(err)
 	mockClient.AssertExpectations(t)
 }
+
+func TestTwilio_SendMessageSplit(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	svc := &Service{
+		fromPhoneNumber: "my_phone_number",
+		splitter:        split.New(1, split.WithCounter(split.SMSSegments)),
+	}
+	svc.AddReceivers("recipient_phone_number")
+
+	mediaURL, _ := url.Parse("https://example.com/image.png")
+	msg := notifymsg.New("subject", strings.Repeat("word ", 40))
+	msg.Attachments = []notifymsg.Attachment{{Name: "image.png", URL: mediaURL.String()}}
+
+	// Media is only sent with the first part.
+	mockClient := newMockTwilioClient(t)
+	mockClient.On("SendMessage",
+		svc.fromPhoneNumber,
+		"recipient_phone_number",
+		"(1/2) subject\n"+strings.TrimSpace(strings.Repeat("word ", 29)),
+		[]*url.URL{mediaURL}).Return(&twilio.Message{}, nil).Once()
+	mockClient.On("SendMessage",
+		svc.fromPhoneNumber,
+		"recipient_phone_number",
+		"(2/2) "+strings.TrimSpace(strings.Repeat("word ", 11)),
+		[]*url.URL(nil)).Return(&twilio.Message{}, nil).Once()
+	svc.client = mockClient
+
+	err := svc.SendMessage(context.Background(), msg)
+	assert.Nil(err)
+	mockClient.AssertExpectations(t)
+}
+
+func TestTwilio_SendMessageFormat(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	svc := &Service{fromPhoneNumber: "my_phone_number", splitter: split.NewSMS()}
+	svc.AddReceivers("recipient_phone_number")
+
+	msg := notifymsg.New("subject", "**Deploy** failed, see [the logs](https://example.com/logs).")
+	msg.Format = notifymsg.FormatMarkdown
+
+	mockClient := newMockTwilioClient(t)
+	mockClient.On("SendMessage",
+		svc.fromPhoneNumber,
+		"recipient_phone_number",
+		"subject\nDeploy failed, see the logs (https://example.com/logs).",
+		[]*url.URL{}).Return(&twilio.Message{}, nil).Once()
+	svc.client = mockClient
+
+	err := svc.SendMessage(context.Background(), msg)
+	assert.Nil(err)
+	mockClient.AssertExpectations(t)
+}
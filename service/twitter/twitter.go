@@ -2,17 +2,29 @@ package twitter
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
 
 	"github.com/dghubble/oauth1"
 	"github.com/drswork/go-twitter/twitter"
 	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/logging"
+	notifymsg "github.com/casdoor/notify/message"
+	"github.com/casdoor/notify/receiver"
+	"github.com/casdoor/notify/split"
 )
 
+// serviceName identifies the Twitter service in per-receiver results.
+const serviceName = "twitter"
+
 // Twitter struct holds necessary data to communicate with the Twitter API
 type Twitter struct {
 	client     *twitter.Client
 	twitterIDs []string
+	splitter   *split.Splitter
+	logger     *slog.Logger
+	secrets    []string
 }
 
 // Credentials contains the authentication credentials needed for twitter
@@ -60,6 +72,8 @@ func New(credentials Credentials) (*Twitter, error) {
 	t := &Twitter{
 		client:     client,
 		twitterIDs: []string{},
+		splitter:   split.New(split.TwitterDMLimit),
+		secrets:    []string{credentials.ConsumerSecret, credentials.AccessToken, credentials.AccessTokenSecret},
 	}
 
 	return t, nil
@@ -88,6 +102,8 @@ func NewWithHttpClient(credentials Credentials, h *http.Client) (*Twitter, error
 	t := &Twitter{
 		client:     client,
 		twitterIDs: []string{},
+		splitter:   split.New(split.TwitterDMLimit),
+		secrets:    []string{credentials.ConsumerSecret, credentials.AccessToken, credentials.AccessTokenSecret},
 	}
 
 	return t, nil
@@ -98,18 +114,28 @@ func (t *Twitter) AddReceivers(twitterIDs ...string) {
 	t.twitterIDs = append(t.twitterIDs, twitterIDs...)
 }
 
+// SetSplitter sets the splitter that fits messages into the length limit of direct messages. By default, messages
+// longer than 10000 characters are sent as several numbered messages. A nil splitter disables splitting.
+func (t *Twitter) SetSplitter(splitter *split.Splitter) {
+	t.splitter = splitter
+}
+
+// SetLogger sets the logger of the service. The consumer secret, the access token and its secret are redacted from all
+// logs.
+func (t *Twitter) SetLogger(logger *slog.Logger) {
+	t.logger = logger
+}
+
 // Send takes a message subject and a message body and sends them to all previously set twitterIDs as a DM.
 // See https://developer.twitter.com/en/docs/twitter-api/v1/direct-messages/sending-and-receiving/api-reference/new-event
 func (t Twitter) Send(ctx context.Context, subject, message string) error {
-	directMessageData := &twitter.DirectMessageData{
-		Text: subject + "\n" + message,
-	}
+	ctx = logging.Bind(ctx, t.logger, t.secrets...)
+	parts := t.splitter.Split(subject+"\n"+message, notifymsg.FormatPlain)
 
-	for _, twitterID := range t.twitterIDs {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
+	twitterIDs := receiver.ResolveIDs(ctx, serviceName, t.twitterIDs)
+
+	return receiver.Each(ctx, serviceName, twitterIDs, func(_ context.Context, twitterID string) error {
+		for _, part := range parts {
 			directMessageTarget := &twitter.DirectMessageTarget{
 				RecipientID: twitterID,
 			}
@@ -117,7 +143,7 @@ func (t Twitter) Send(ctx context.Context, subject, message string) error {
 				Type: "message_create",
 				Message: &twitter.DirectMessageEventMessage{
 					Target: directMessageTarget,
-					Data:   directMessageData,
+					Data:   &twitter.DirectMessageData{Text: part},
 				},
 			}
 
@@ -130,7 +156,7 @@ func (t Twitter) Send(ctx context.Context, subject, message string) error {
 				return errors.Wrapf(err, "failed to send direct message to twitter ID '%s'", twitterID)
 			}
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
@@ -2,11 +2,18 @@ package viber
 
 import (
 	"context"
+	"log/slog"
 
 	vb "github.com/mileusna/viber"
 	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/receiver"
 )
 
+// serviceName identifies the Viber service in per-receiver results.
+const serviceName = "viber"
+
 //go:generate mockery --name=viberClient --output=. --case=underscore --inpackage
 type viberClient interface {
 	SetWebhook(url string, eventTypes []string) (vb.WebhookResp, error)
@@ -20,6 +27,8 @@ var _ viberClient = new(vb.Viber)
 type Viber struct {
 	Client            viberClient
 	SubscribedUserIDs []string
+	appKey            string
+	logger            *slog.Logger
 }
 
 // New returns a new instance of Viber notification service
@@ -27,6 +36,7 @@ func New(appKey, senderName, senderAvatar string) *Viber {
 	return &Viber{
 		Client:            vb.New(appKey, senderName, senderAvatar),
 		SubscribedUserIDs: []string{},
+		appKey:            appKey,
 	}
 }
 
@@ -41,21 +51,24 @@ func (v *Viber) SetWebhook(webhookURL string) error {
 	return err
 }
 
+// SetLogger sets the logger of the service. The app key is redacted from all logs.
+func (v *Viber) SetLogger(logger *slog.Logger) {
+	v.logger = logger
+}
+
 // Send takes a message subject and a message body and sends them to all previously set userIds
 func (v *Viber) Send(ctx context.Context, subject, message string) error {
+	ctx = logging.Bind(ctx, v.logger, v.appKey)
 	fullMessage := subject + "\n" + message // Treating subject as message title
 
-	for _, subscribedUserID := range v.SubscribedUserIDs {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			_, err := v.Client.SendTextMessage(subscribedUserID, fullMessage)
-			if err != nil {
-				return errors.Wrapf(err, "failed to send message to User ID '%s'", subscribedUserID)
-			}
+	subscribedUserIDs := receiver.ResolveIDs(ctx, serviceName, v.SubscribedUserIDs)
+
+	return receiver.Each(ctx, serviceName, subscribedUserIDs, func(_ context.Context, subscribedUserID string) error {
+		_, err := v.Client.SendTextMessage(subscribedUserID, fullMessage)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send message to User ID '%s'", subscribedUserID)
 		}
-	}
 
-	return nil
+		return nil
+	})
 }
@@ -5,10 +5,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 
 	"github.com/SherClockHolmes/webpush-go"
 	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/markup"
+	notifymsg "github.com/casdoor/notify/message"
+	"github.com/casdoor/notify/receiver"
+	"github.com/casdoor/notify/retry"
 )
 
 type (
@@ -32,6 +39,9 @@ type (
 	msgOptionsKey struct{}
 )
 
+// serviceName identifies the webpush service in per-receiver results.
+const serviceName = "webpush"
+
 // optionsKey is used as a context.Context key to optionally add options to the messagePayload payload.
 var optionsKey = msgOptionsKey{}
 
@@ -57,6 +67,7 @@ var (
 type Service struct {
 	subscriptions []webpush.Subscription
 	options       webpush.Options
+	logger        *slog.Logger
 }
 
 // New returns a new instance of the Service
@@ -75,6 +86,11 @@ func (s *Service) AddReceivers(subscriptions ...Subscription) {
 	s.subscriptions = append(s.subscriptions, subscriptions...)
 }
 
+// SetLogger sets the logger of the service. The VAPID private key is redacted from all logs.
+func (s *Service) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
 // withOptions returns a new Options struct with the incoming options merged with the Service's options. The incoming
 // options take precedence, except for the VAPID keys. Existing VAPID keys are only replaced if the incoming VAPID keys
 // are not empty.
@@ -159,31 +175,94 @@ func (s *Service) send(ctx context.Context, message []byte, subscription *Subscr
 	if err != nil {
 		baseErr = errors.Wrap(errors.Wrap(err, "failed to read response body"), baseErr.Error())
 	} else {
+		logging.Response(ctx, serviceName, subscription.Endpoint, res.StatusCode, body)
 		baseErr = fmt.Errorf("%w: %s", baseErr, body)
 	}
 
-	return baseErr
+	// Let retries tell an expired subscription (404, 410) apart from an overloaded push service.
+	return retry.HTTPError(res.StatusCode, res.Header, baseErr)
 }
 
 // Send sends a message to all the webpush subscriptions that have been added to the Service. The subject and message
 // arguments are the subject and message of the messagePayload payload. The context can be used to optionally add
 // options and data to the messagePayload payload. See the WithOptions and WithData functions.
 func (s *Service) Send(ctx context.Context, subject, message string) error {
+	return s.SendMessage(ctx, notifymsg.New(subject, message))
+}
+
+// urgencies maps the priority of a message to a webpush urgency.
+var urgencies = map[notifymsg.Priority]Urgency{
+	notifymsg.PriorityLow:      UrgencyLow,
+	notifymsg.PriorityNormal:   UrgencyNormal,
+	notifymsg.PriorityHigh:     UrgencyHigh,
+	notifymsg.PriorityCritical: UrgencyHigh,
+}
+
+// SendMessage sends the given message to all the webpush subscriptions that have been added to the Service. Besides
+// the subject and the body, it maps the following message fields to webpush features:
+//
+//   - A body in another format is converted to plain text.
+//   - The metadata is sent as data of the messagePayload payload. Data bound to the context with WithData takes
+//     precedence over it.
+//   - The priority sets the urgency, unless an urgency has been set through the options.
+func (s *Service) SendMessage(ctx context.Context, msg *notifymsg.Message) error {
 	// Get the options from the context and merge them with the service's initial options
 	options := optionsFromContext(ctx)
 	options = s.withOptions(options)
+	ctx = logging.Bind(ctx, s.logger, options.VAPIDPrivateKey)
+	if options.Urgency == "" {
+		options.Urgency = urgencies[msg.Priority]
+	}
+
+	if len(msg.Metadata) > 0 {
+		data := make(map[string]interface{}, len(msg.Metadata))
+		for k, v := range msg.Metadata {
+			data[k] = v
+		}
+		for k, v := range dataFromContext(ctx) {
+			data[k] = v
+		}
+		ctx = WithData(ctx, data)
+	}
 
-	payload, err := payloadFromContext(ctx, subject, message)
+	payload, err := payloadFromContext(ctx, msg.Subject, markup.Convert(msg.Body, msg.Format, notifymsg.FormatPlain))
 	if err != nil {
 		return err
 	}
 
-	for _, subscription := range s.subscriptions {
-		subscription := subscription // Capture the subscription in the closure
-		if err := s.send(ctx, payload, &subscription, &options); err != nil {
-			return err
-		}
+	receivers := make([]subscriptionReceiver, 0, len(s.subscriptions))
+	for i := range s.subscriptions {
+		receivers = append(receivers, subscriptionReceiver{&s.subscriptions[i]})
+	}
+	receivers, err = receiver.Resolve(ctx, serviceName, receivers, subscriptionFromReceiver)
+	if err != nil {
+		return err
+	}
+
+	return receiver.Each(ctx, serviceName, receivers, func(ctx context.Context, r subscriptionReceiver) error {
+		subscription := *r.Subscription // Copy the subscription, the webpush package is allowed to modify it
+		return s.send(ctx, payload, &subscription, &options)
+	})
+}
+
+// subscriptionFromReceiver converts a receiver bound to the context into a subscription. The ID of the receiver has to
+// be the JSON representation of the subscription, as provided by the PushSubscription.toJSON method in browsers.
+func subscriptionFromReceiver(r receiver.Receiver) (subscriptionReceiver, error) {
+	var subscription Subscription
+	if err := json.Unmarshal([]byte(r.ID), &subscription); err != nil {
+		return subscriptionReceiver{}, errors.Wrap(err, "invalid webpush subscription")
 	}
 
-	return nil
+	return subscriptionReceiver{&subscription}, nil
+}
+
+// subscriptionReceiver wraps a subscription so that it is identified by its endpoint only in per-receiver results. This
+// keeps the subscription keys out of reports and logs.
+type subscriptionReceiver struct {
+	*Subscription
+}
+
+// String returns the endpoint of the subscription. It implements the fmt.Stringer interface.
+func (r subscriptionReceiver) String() string {
+	return r.Endpoint
 }
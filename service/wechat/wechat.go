@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"html"
+	"log/slog"
 	"net/http"
 	"sync"
 	"time"
@@ -14,8 +15,14 @@ import (
 	"github.com/silenceper/wechat/v2/officialaccount/config"
 	"github.com/silenceper/wechat/v2/officialaccount/message"
 	"github.com/silenceper/wechat/v2/util"
+
+	"github.com/casdoor/notify/logging"
+	"github.com/casdoor/notify/receiver"
 )
 
+// serviceName identifies the WeChat service in per-receiver results.
+const serviceName = "wechat"
+
 type verificationCallbackFunc func(r *http.Request, verified bool)
 
 // Config is the Service configuration.
@@ -39,6 +46,7 @@ type Service struct {
 	config         *Config
 	messageManager wechatMessageManager
 	userIDs        []string
+	logger         *slog.Logger
 }
 
 // New returns a new instance of a WeChat notification service.
@@ -159,20 +167,28 @@ func (s *Service) AddReceivers(userIDs ...string) {
 	s.userIDs = append(s.userIDs, userIDs...)
 }
 
+// SetLogger sets the logger of the service. The app secret, the token and the encoding AES key are redacted from all
+// logs.
+func (s *Service) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
 // Send takes a message subject and a message content and sends them to all previously set users.
 func (s *Service) Send(ctx context.Context, subject, content string) error {
-	for _, userID := range s.userIDs {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			text := fmt.Sprintf("%s\n%s", subject, content)
-			err := s.messageManager.Send(message.NewCustomerTextMessage(userID, text))
-			if err != nil {
-				return errors.Wrapf(err, "failed to send message to WeChat user '%s'", userID)
-			}
-		}
+	if s.config != nil {
+		ctx = logging.Bind(ctx, s.logger, s.config.AppSecret, s.config.Token, s.config.EncodingAESKey)
+	} else {
+		ctx = logging.Bind(ctx, s.logger)
 	}
+	userIDs := receiver.ResolveIDs(ctx, serviceName, s.userIDs)
+
+	return receiver.Each(ctx, serviceName, userIDs, func(_ context.Context, userID string) error {
+		text := fmt.Sprintf("%s\n%s", subject, content)
+		err := s.messageManager.Send(message.NewCustomerTextMessage(userID, text))
+		if err != nil {
+			return errors.Wrapf(err, "failed to send message to WeChat user '%s'", userID)
+		}
 
-	return nil
+		return nil
+	})
 }
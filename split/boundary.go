@@ -0,0 +1,91 @@
+package split
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// graphemeBoundaries returns the byte offsets in s, excluding 0 and including len(s), at which s may be split without
+// breaking up a grapheme cluster. It implements the rules of Unicode Standard Annex #29 that matter in practice:
+// combining marks, variation selectors, emoji modifiers and tags stay with the preceding character, sequences joined
+// by a zero width joiner stay together, regional indicators (flags) are paired and CRLF is never split.
+func graphemeBoundaries(s string) []int {
+	var bounds []int
+	var prev rune = -1
+	regional := 0
+	for i, r := range s {
+		if i > 0 && !joins(prev, r, regional) {
+			bounds = append(bounds, i)
+		}
+
+		if isRegionalIndicator(r) {
+			regional++
+		} else {
+			regional = 0
+		}
+		prev = r
+	}
+	if len(s) > 0 {
+		bounds = append(bounds, len(s))
+	}
+
+	return bounds
+}
+
+// joins reports whether r belongs to the same grapheme cluster as the preceding rune prev. regional is the number of
+// consecutive regional indicators that end with prev.
+func joins(prev, r rune, regional int) bool {
+	switch {
+	case prev == '\r' && r == '\n':
+		return true
+	case prev == zeroWidthJoiner:
+		return true
+	case isRegionalIndicator(r):
+		return regional%2 == 1
+	default:
+		return isExtend(r)
+	}
+}
+
+const zeroWidthJoiner = '‍'
+
+// isExtend reports whether r never starts a grapheme cluster.
+func isExtend(r rune) bool {
+	switch {
+	case r == zeroWidthJoiner:
+		return true
+	case r >= 0xfe00 && r <= 0xfe0f, r >= 0xe0100 && r <= 0xe01ef: // variation selectors
+		return true
+	case r >= 0x1f3fb && r <= 0x1f3ff: // emoji skin tone modifiers
+		return true
+	case r >= 0xe0020 && r <= 0xe007f: // tags, e.g. of subdivision flags
+		return true
+	default:
+		return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc)
+	}
+}
+
+// isRegionalIndicator reports whether r is one of the letters that form flags in pairs.
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
+
+// UTF16Length returns the length of s in UTF-16 code units. Telegram measures the length of messages this way, so that
+// most emoji count twice.
+func UTF16Length(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+
+	return n
+}
+
+// RuneCount returns the number of characters in s. It's the default length of texts.
+func RuneCount(s string) int {
+	return utf8.RuneCountInString(s)
+}
@@ -0,0 +1,68 @@
+package split
+
+import "strings"
+
+// gsm7Basic holds the characters of the GSM 03.38 basic character set, which take a single septet.
+const gsm7Basic = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+
+// gsm7Extension holds the characters of the GSM 03.38 extension table, which take two septets.
+const gsm7Extension = "^{}\\[~]|€\f"
+
+// These are the sizes of SMS segments in characters.
+const (
+	gsm7Single = 160
+	gsm7Multi  = 153
+	ucs2Single = 70
+	ucs2Multi  = 67
+)
+
+// SMSSegments returns the number of SMS segments needed to send s. Texts that only use the GSM 7-bit alphabet fit 160
+// characters into a single segment and 153 into each segment of a concatenated message. Any other character switches
+// the whole text to UCS-2, which fits 70 and 67 UTF-16 code units respectively.
+//
+// Use it with WithCounter and a limit like SMSSegmentLimit to split texts for SMS services.
+func SMSSegments(s string) int {
+	if s == "" {
+		return 0
+	}
+
+	septets, ok := gsm7Length(s)
+	if ok {
+		return segments(septets, gsm7Single, gsm7Multi)
+	}
+
+	return segments(UTF16Length(s), ucs2Single, ucs2Multi)
+}
+
+// gsm7Length returns the length of s in septets, and whether s can be encoded with the GSM 7-bit alphabet at all.
+func gsm7Length(s string) (int, bool) {
+	n := 0
+	for _, r := range s {
+		switch {
+		case strings.ContainsRune(gsm7Basic, r):
+			n++
+		case strings.ContainsRune(gsm7Extension, r):
+			n += 2
+		default:
+			return 0, false
+		}
+	}
+
+	return n, true
+}
+
+// segments returns the number of segments needed for n characters.
+func segments(n, single, multi int) int {
+	if n <= single {
+		return 1
+	}
+
+	return (n + multi - 1) / multi
+}
+
+// NewSMS returns a Splitter that fits texts into SMSSegmentLimit segments, see SMSSegments. Options are applied on
+// top, e.g. WithMode or WithNumbering.
+func NewSMS(options ...Option) *Splitter {
+	return New(SMSSegmentLimit, append([]Option{WithCounter(SMSSegments)}, options...)...)
+}
@@ -0,0 +1,251 @@
+// Package split fits notifications into the length limits of platforms like Telegram, Discord or SMS. A Splitter
+// either chunks a long text into numbered parts, or truncates it with an ellipsis and an optional "view more" link.
+//
+// Texts are only split between grapheme clusters, so that characters made of several code points, like emoji with skin
+// tones or flags, stay intact. Depending on the body format, Markdown and HTML constructs like links, inline code,
+// escape sequences, entities and tags are never split either. HTML tags and Markdown code blocks that are still open
+// at the end of a part are closed, and opened again at the start of the next part.
+//
+// This package deliberately doesn't depend on the notify package itself, so that every service is able to use it.
+package split
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/casdoor/notify/markup"
+	"github.com/casdoor/notify/message"
+)
+
+// These are the length limits of well-known platforms.
+const (
+	// TelegramLimit is the maximum length of a Telegram message in UTF-16 code units, see UTF16Length.
+	TelegramLimit = 4096
+	// DiscordLimit is the maximum length of a Discord message in characters.
+	DiscordLimit = 2000
+	// PushoverLimit is the maximum length of a Pushover message in characters.
+	PushoverLimit = 1024
+	// TwitterDMLimit is the maximum length of a Twitter direct message in characters.
+	TwitterDMLimit = 10000
+	// SMSSegmentLimit is the maximum number of segments of a concatenated SMS accepted by most providers, see
+	// SMSSegments.
+	SMSSegmentLimit = 10
+)
+
+// Mode describes what a Splitter does with texts that exceed the limit.
+type Mode int
+
+const (
+	// Chunk splits texts into several numbered parts. It's the default.
+	Chunk Mode = iota
+	// Truncate cuts texts off, see WithEllipsis and WithMoreLink.
+	Truncate
+)
+
+// DefaultNumbering is the default format of the part numbers put in front of every part, see WithNumbering.
+const DefaultNumbering = "(%d/%d) "
+
+// DefaultEllipsis is the default text put at the end of truncated texts, see WithEllipsis.
+const DefaultEllipsis = "…"
+
+// Splitter fits texts into a length limit. It's safe for concurrent use.
+type Splitter struct {
+	limit     int
+	count     func(string) int
+	mode      Mode
+	numbering string
+	ellipsis  string
+	more      message.Link
+	maxParts  int
+}
+
+// Option configures a Splitter.
+type Option func(*Splitter)
+
+// New returns a Splitter that fits texts into the given limit. By default, the length of a text is its number of
+// characters, and texts exceeding the limit are chunked into numbered parts.
+func New(limit int, options ...Option) *Splitter {
+	s := &Splitter{
+		limit:     limit,
+		count:     RuneCount,
+		numbering: DefaultNumbering,
+		ellipsis:  DefaultEllipsis,
+	}
+	for _, option := range options {
+		if option != nil {
+			option(s)
+		}
+	}
+
+	return s
+}
+
+// WithCounter is an Option that sets the function measuring the length of texts, e.g. UTF16Length or SMSSegments.
+func WithCounter(count func(string) int) Option {
+	return func(s *Splitter) {
+		if count != nil {
+			s.count = count
+		}
+	}
+}
+
+// WithMode is an Option that sets what happens to texts that exceed the limit.
+func WithMode(mode Mode) Option {
+	return func(s *Splitter) {
+		s.mode = mode
+	}
+}
+
+// WithNumbering is an Option that sets the format of the part numbers put in front of every part when a text is
+// chunked. It's formatted with the number of the part and the total number of parts, e.g. "[%d of %d] ". An empty
+// format disables the numbering.
+func WithNumbering(format string) Option {
+	return func(s *Splitter) {
+		s.numbering = format
+	}
+}
+
+// WithEllipsis is an Option that sets the text put at the end of truncated texts.
+func WithEllipsis(ellipsis string) Option {
+	return func(s *Splitter) {
+		s.ellipsis = ellipsis
+	}
+}
+
+// WithMoreLink is an Option that appends a link to the full text to truncated texts, e.g. to a dashboard. The title
+// defaults to "View more".
+func WithMoreLink(title, url string) Option {
+	return func(s *Splitter) {
+		if title == "" {
+			title = "View more"
+		}
+		s.more = message.Link{Title: title, URL: url}
+	}
+}
+
+// WithMaxParts is an Option that limits the number of parts a text is chunked into. The last part is truncated if the
+// text doesn't fit into that many parts. Zero means no limit.
+func WithMaxParts(n int) Option {
+	return func(s *Splitter) {
+		s.maxParts = n
+	}
+}
+
+// Limit returns the length limit of the Splitter.
+func (s *Splitter) Limit() int {
+	return s.limit
+}
+
+// Split fits text written in the given body format into the limit. It returns text itself if it fits, and otherwise
+// either the numbered parts of text or the truncated text, depending on the mode. A nil Splitter returns text itself.
+func (s *Splitter) Split(text string, format message.BodyFormat) []string {
+	if s == nil || s.limit <= 0 || s.count(text) <= s.limit {
+		return []string{text}
+	}
+
+	t := newText(text, format)
+	if s.mode == Truncate || s.maxParts == 1 {
+		return []string{s.truncate(t, 0, state{}, "")}
+	}
+
+	// The length of the part numbers depends on the total number of parts, so chunk until the total is stable.
+	total := 2
+	for {
+		parts := s.chunk(t, total)
+		if len(parts) <= total || len(fmt.Sprint(len(parts))) == len(fmt.Sprint(total)) {
+			return s.number(parts, format)
+		}
+		total = len(parts)
+	}
+}
+
+// Truncate fits text written in the given body format into the limit by cutting it off, whatever the mode of the
+// Splitter. A nil Splitter returns text itself.
+func (s *Splitter) Truncate(text string, format message.BodyFormat) string {
+	if s == nil || s.limit <= 0 || s.count(text) <= s.limit {
+		return text
+	}
+
+	return s.truncate(newText(text, format), 0, state{}, "")
+}
+
+// prefix returns the escaped part number for the given part.
+func (s *Splitter) prefix(i, total int, format message.BodyFormat) string {
+	if s.numbering == "" {
+		return ""
+	}
+
+	return markup.Escape(fmt.Sprintf(s.numbering, i, total), format)
+}
+
+// number puts the part numbers in front of the given parts.
+func (s *Splitter) number(parts []string, format message.BodyFormat) []string {
+	for i := range parts {
+		parts[i] = s.prefix(i+1, len(parts), format) + parts[i]
+	}
+
+	return parts
+}
+
+// chunk splits t into parts, reserving room for the part numbers of the given total number of parts.
+func (s *Splitter) chunk(t *text, total int) []string {
+	prefix := s.prefix(total, total, t.format)
+
+	var parts []string
+	var st state
+	for start := t.skipSpace(0); start < len(t.s); {
+		if s.maxParts > 0 && len(parts) == s.maxParts-1 {
+			rest := t.render(start, len(t.s), st, "")
+			if s.count(prefix+rest) > s.limit {
+				return append(parts, s.truncate(t, start, st, prefix))
+			}
+		}
+
+		end := t.fit(start, st, func(candidate string) bool {
+			return s.count(prefix+candidate) <= s.limit
+		}, "")
+		if end == start {
+			// Not even a single character fits, so exceed the limit rather than loop forever.
+			end = t.graphemes[sort.Search(len(t.graphemes), func(i int) bool { return t.graphemes[i].pos > start })].pos
+		}
+		parts = append(parts, t.render(start, end, st, ""))
+
+		st = t.scan(start, end, st)
+		start = t.skipSpace(end)
+	}
+
+	return parts
+}
+
+// truncate cuts off t after start, keeping room for the given prefix.
+func (s *Splitter) truncate(t *text, start int, st state, prefix string) string {
+	suffix := markup.Escape(s.ellipsis, t.format)
+	more := s.moreLink(t.format)
+
+	end := t.fit(start, st, func(candidate string) bool {
+		return s.count(prefix+candidate+more) <= s.limit
+	}, suffix)
+
+	return t.render(start, end, st, suffix) + more
+}
+
+// moreLink returns the "view more" link in the given body format, or an empty string if there is none.
+func (s *Splitter) moreLink(format message.BodyFormat) string {
+	if s.more.URL == "" {
+		return ""
+	}
+
+	source := "[" + escapeMarkdown(s.more.Title) + "](" + strings.NewReplacer("(", "%28", ")", "%29").Replace(s.more.URL) + ")"
+	link := markup.Convert(source, message.FormatMarkdown, format)
+	if format == message.FormatHTML {
+		link = strings.TrimSuffix(strings.TrimPrefix(link, "<p>"), "</p>")
+	}
+
+	return "\n" + link
+}
+
+// escapeMarkdown escapes the characters of s that would be taken as Markdown markup.
+func escapeMarkdown(s string) string {
+	return markup.Escape(s, message.FormatMarkdown)
+}
@@ -0,0 +1,219 @@
+package split
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/casdoor/notify/message"
+)
+
+func TestSplitter_Split_FitsUnchanged(t *testing.T) {
+	t.Parallel()
+
+	s := New(10)
+	require.Equal(t, []string{"short"}, s.Split("short", message.FormatPlain))
+	require.Equal(t, []string{"0123456789"}, s.Split("0123456789", message.FormatPlain))
+
+	var nilSplitter *Splitter
+	require.Equal(t, []string{strings.Repeat("a", 100)}, nilSplitter.Split(strings.Repeat("a", 100), ""))
+}
+
+func TestSplitter_Split_Chunk(t *testing.T) {
+	t.Parallel()
+
+	s := New(30)
+	parts := s.Split("The quick brown fox jumps over the lazy dog.\n\nIt barks.", message.FormatPlain)
+	require.Equal(t, []string{
+		"(1/3) The quick brown fox",
+		"(2/3) jumps over the lazy dog.",
+		"(3/3) It barks.",
+	}, parts)
+	for _, part := range parts {
+		require.LessOrEqual(t, RuneCount(part), 30)
+	}
+}
+
+func TestSplitter_Split_PrefersParagraphs(t *testing.T) {
+	t.Parallel()
+
+	s := New(40, WithNumbering(""))
+	parts := s.Split("First paragraph here.\n\nSecond one with more words in it.", message.FormatPlain)
+	require.Equal(t, []string{"First paragraph here.", "Second one with more words in it."}, parts)
+}
+
+func TestSplitter_Split_NumberingWidth(t *testing.T) {
+	t.Parallel()
+
+	s := New(20)
+	parts := s.Split(strings.Repeat("word ", 60), message.FormatPlain)
+	require.Greater(t, len(parts), 9)
+	for _, part := range parts {
+		require.LessOrEqual(t, RuneCount(part), 20, part)
+	}
+	require.True(t, strings.HasPrefix(parts[0], "(1/"), parts[0])
+	require.True(t, strings.HasPrefix(parts[len(parts)-1], fmt.Sprintf("(%d/%d)", len(parts), len(parts))))
+}
+
+func TestSplitter_Split_Graphemes(t *testing.T) {
+	t.Parallel()
+
+	family := "👨‍👩‍👧"
+	flag := "🇩🇪"
+	thumbs := "👍🏽"
+	accent := "e\u0301"
+
+	text := strings.Repeat(family+flag+thumbs+accent, 5)
+	s := New(12, WithNumbering(""))
+	parts := s.Split(text, message.FormatPlain)
+	require.Greater(t, len(parts), 1)
+	require.Equal(t, text, strings.Join(parts, ""))
+	for _, part := range parts {
+		require.LessOrEqual(t, RuneCount(part), 12)
+		require.False(t, strings.HasPrefix(part, "\u200d"), part)
+		require.False(t, strings.HasPrefix(part, "\u0301"), part)
+		require.False(t, strings.HasPrefix(part, "🏽"), part)
+	}
+	for _, part := range parts {
+		// Every part holds whole flags.
+		require.Zero(t, strings.Count(part, "🇩")-strings.Count(part, "🇪"), part)
+	}
+}
+
+func TestSplitter_Split_HTML(t *testing.T) {
+	t.Parallel()
+
+	s := New(41, WithNumbering(""))
+	parts := s.Split(`<b>Alert &amp; warning for the cluster</b> <a href="https://example.com">details</a>`,
+		message.FormatHTML)
+	require.Equal(t, []string{
+		"<b>Alert &amp; warning for the</b>",
+		"<b>cluster</b>",
+		`<a href="https://example.com">details</a>`,
+	}, parts)
+}
+
+func TestSplitter_Split_HTMLEntities(t *testing.T) {
+	t.Parallel()
+
+	text := strings.Repeat("&lt;&gt;", 10)
+	for _, part := range New(13, WithNumbering("")).Split(text, message.FormatHTML) {
+		require.Zero(t, len(strings.ReplaceAll(strings.ReplaceAll(part, "&lt;", ""), "&gt;", "")), part)
+	}
+}
+
+func TestSplitter_Split_Markdown(t *testing.T) {
+	t.Parallel()
+
+	s := New(40, WithNumbering(""))
+	parts := s.Split("See [the logs](https://example.com/a) and `some code` now", message.FormatMarkdown)
+	require.Equal(t, []string{
+		"See [the logs](https://example.com/a)",
+		"and `some code` now",
+	}, parts)
+}
+
+func TestSplitter_Split_LongEmphasis(t *testing.T) {
+	t.Parallel()
+
+	text := "intro **bold " + strings.Repeat("word ", 60) + "end** and _a `code*span` that is long enough " +
+		strings.Repeat("x ", 130) + "end_ done"
+	parts := New(40, WithNumbering("")).Split(text, message.FormatMarkdown)
+	require.Greater(t, len(parts), 2)
+	for _, part := range parts {
+		// Every part has balanced markers, the ones in the code span aside.
+		part = strings.ReplaceAll(part, "`code*span`", "")
+		require.Zero(t, strings.Count(part, "**")%2, part)
+		require.Zero(t, strings.Count(strings.ReplaceAll(part, "**", ""), "_")%2, part)
+	}
+	require.Equal(t, "intro **bold word word word word word**", parts[0])
+	require.Equal(t, "**word word word word word word word**", parts[1])
+	require.True(t, strings.HasSuffix(parts[len(parts)-2], "x end_"), parts[len(parts)-2])
+}
+
+func TestSplitter_Split_CodeFence(t *testing.T) {
+	t.Parallel()
+
+	s := New(30, WithNumbering(""))
+	parts := s.Split("Output:\n```go\nline one\nline two\nline three\n```", message.FormatMarkdown)
+	require.Equal(t, []string{
+		"Output:\n```go\nline one\n```",
+		"```go\nline two\nline three\n```",
+	}, parts)
+}
+
+func TestSplitter_Split_MarkdownV2Numbering(t *testing.T) {
+	t.Parallel()
+
+	s := New(15)
+	parts := s.Split("one two three four five", message.FormatMarkdownV2)
+	require.Equal(t, []string{"\\(1/4\\) one two", "\\(2/4\\) three", "\\(3/4\\) four", "\\(4/4\\) five"}, parts)
+}
+
+func TestSplitter_Split_MaxParts(t *testing.T) {
+	t.Parallel()
+
+	s := New(20, WithMaxParts(2))
+	parts := s.Split(strings.Repeat("word ", 20), message.FormatPlain)
+	require.Equal(t, []string{"(1/2) word word word", "(2/2) word word…"}, parts)
+}
+
+func TestSplitter_Truncate(t *testing.T) {
+	t.Parallel()
+
+	s := New(20, WithMode(Truncate))
+	require.Equal(t, []string{"The quick brown fox…"}, s.Split("The quick brown fox jumps over the lazy dog", ""))
+	require.Equal(t, "The quick brown fox…", New(20).Truncate("The quick brown fox jumps over the lazy dog", ""))
+	require.Equal(t, "short", s.Truncate("short", ""))
+
+	s = New(30, WithEllipsis(" [...]"))
+	require.Equal(t, "<i>The quick brown [...]</i>",
+		s.Truncate("<i>The quick brown fox jumps over the lazy dog</i>", message.FormatHTML))
+}
+
+func TestSplitter_Truncate_MoreLink(t *testing.T) {
+	t.Parallel()
+
+	s := New(60, WithMoreLink("", "https://example.com/a"))
+	text := "The quick brown fox jumps over the lazy dog"
+
+	require.Equal(t, "The quick brown fox jumps…\nView more (https://example.com/a)",
+		s.Truncate(text+text, message.FormatPlain))
+	require.Equal(t, "The quick brown fox…\n[View more](https://example.com/a)",
+		s.Truncate(text+text, message.FormatMarkdown))
+	require.Equal(t, "The quick brown fox jumps…\n<https://example.com/a|View more>",
+		s.Truncate(text+text, message.FormatMrkdwn))
+	require.Equal(t, "The quick…\n"+`<a href="https://example.com/a">View more</a>`,
+		s.Truncate(text+text, message.FormatHTML))
+}
+
+func TestUTF16Length(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, 0, UTF16Length(""))
+	require.Equal(t, 3, UTF16Length("abc"))
+	require.Equal(t, 2, UTF16Length("ä€"))
+	require.Equal(t, 2, UTF16Length("👍"))
+}
+
+func TestSMSSegments(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, 0, SMSSegments(""))
+	require.Equal(t, 1, SMSSegments(strings.Repeat("a", 160)))
+	require.Equal(t, 2, SMSSegments(strings.Repeat("a", 161)))
+	require.Equal(t, 2, SMSSegments(strings.Repeat("a", 306)))
+	require.Equal(t, 3, SMSSegments(strings.Repeat("a", 307)))
+	require.Equal(t, 2, SMSSegments(strings.Repeat("€", 81)))
+	require.Equal(t, 1, SMSSegments(strings.Repeat("ä", 70)))
+	require.Equal(t, 2, SMSSegments(strings.Repeat("😀", 35)+"a"))
+
+	s := New(1, WithCounter(SMSSegments))
+	parts := s.Split(strings.Repeat("word ", 40), message.FormatPlain)
+	require.Len(t, parts, 2)
+	for _, part := range parts {
+		require.Equal(t, 1, SMSSegments(part))
+	}
+}
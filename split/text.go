@@ -0,0 +1,448 @@
+package split
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/casdoor/notify/message"
+)
+
+// These are the priorities of the positions a text can be split at. The splitter prefers to split at positions with a
+// higher priority.
+const (
+	priorityCharacter = iota
+	priorityWord
+	priorityLine
+	priorityParagraph
+)
+
+// bound is a position a text can be split at.
+type bound struct {
+	pos      int
+	priority int
+}
+
+// event changes the markup state of a text, see state.
+type event struct {
+	pos  int
+	open bool
+	// name is the name of an HTML element, or empty for Markdown markup.
+	name string
+	// markup is the opening tag of an HTML element, the opening line of a Markdown code fence, or the marker of
+	// Markdown emphasis.
+	markup string
+	// emphasis tells whether the event opens or closes Markdown emphasis instead of a code fence.
+	emphasis bool
+}
+
+// state is the markup that is open at a position of a text.
+type state struct {
+	tags     []event
+	fence    string
+	emphasis []string
+}
+
+// text is a text prepared for splitting.
+type text struct {
+	s      string
+	format message.BodyFormat
+	// bounds are the positions s can be split at without breaking up its markup.
+	bounds []bound
+	// graphemes are the positions s can be split at without breaking up characters. They're a last resort.
+	graphemes []bound
+	events    []event
+}
+
+// newText prepares s written in the given body format for splitting.
+func newText(s string, format message.BodyFormat) *text {
+	t := &text{s: s, format: format}
+
+	// atomic[i] tells whether s must not be split at i.
+	atomic := make([]bool, len(s)+1)
+	switch format {
+	case message.FormatHTML:
+		t.scanHTML(atomic)
+	case message.FormatMarkdown, message.FormatMrkdwn, message.FormatMarkdownV2:
+		t.scanMarkdown(atomic)
+		// Emphasized spans are recorded with both of their markers at once, so that nested ones get out of order.
+		sort.SliceStable(t.events, func(i, j int) bool { return t.events[i].pos < t.events[j].pos })
+	}
+
+	for _, pos := range graphemeBoundaries(s) {
+		b := bound{pos: pos, priority: t.priority(pos)}
+		t.graphemes = append(t.graphemes, b)
+		if !atomic[pos] {
+			t.bounds = append(t.bounds, b)
+		}
+	}
+
+	return t
+}
+
+// protect marks the positions inside s[from:to] as atomic.
+func protect(atomic []bool, from, to int) {
+	for i := from + 1; i < to && i < len(atomic); i++ {
+		atomic[i] = true
+	}
+}
+
+// blockEnds are HTML tags that end a line when rendered.
+var blockEnds = []string{"<br>", "<br/>", "<br />", "</p>", "</div>", "</li>", "</pre>", "</blockquote>", "</ul>", "</ol>",
+	"</h1>", "</h2>", "</h3>", "</h4>", "</h5>", "</h6>", "</tr>"}
+
+// priority returns the priority of splitting the text at pos.
+func (t *text) priority(pos int) int {
+	before := t.s[:pos]
+	switch {
+	case strings.HasSuffix(before, "\n\n"), strings.HasSuffix(before, "\r\n\r\n"):
+		return priorityParagraph
+	case strings.HasSuffix(before, "\n"):
+		return priorityLine
+	case strings.HasSuffix(before, " "), strings.HasSuffix(before, "\t"):
+		return priorityWord
+	}
+
+	if t.format == message.FormatHTML {
+		lower := strings.ToLower(before)
+		if len(lower) > 16 {
+			lower = lower[len(lower)-16:]
+		}
+		for _, end := range blockEnds {
+			if strings.HasSuffix(lower, end) {
+				return priorityLine
+			}
+		}
+	}
+
+	return priorityCharacter
+}
+
+// voidElements are the HTML elements without a closing tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true, "input": true,
+	"link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// scanHTML marks tags, comments and entities as atomic and records the elements that are opened and closed.
+func (t *text) scanHTML(atomic []bool) {
+	s := t.s
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '<':
+			if strings.HasPrefix(s[i:], "<!--") {
+				end := strings.Index(s[i:], "-->")
+				if end < 0 {
+					end = len(s) - i - 3
+				}
+				protect(atomic, i, i+end+3)
+				i += end + 2
+				continue
+			}
+
+			end := strings.IndexByte(s[i:], '>')
+			if end < 0 {
+				continue
+			}
+			tag := s[i : i+end+1]
+			protect(atomic, i, i+end+1)
+			if e, ok := htmlEvent(i, tag); ok {
+				t.events = append(t.events, e)
+			}
+			i += end
+		case '&':
+			if end := entityEnd(s[i:]); end > 0 {
+				protect(atomic, i, i+end)
+				i += end - 1
+			}
+		}
+	}
+}
+
+// htmlEvent returns the event of the given tag found at pos, if it opens or closes an element.
+func htmlEvent(pos int, tag string) (event, bool) {
+	inner := strings.TrimSuffix(strings.TrimPrefix(tag, "<"), ">")
+	closing := strings.HasPrefix(inner, "/")
+	inner = strings.TrimPrefix(inner, "/")
+
+	name := inner
+	if i := strings.IndexFunc(inner, func(r rune) bool { return unicode.IsSpace(r) || r == '/' }); i >= 0 {
+		name = inner[:i]
+	}
+	name = strings.ToLower(name)
+	if name == "" || !isLetter(name[0]) || voidElements[name] || (!closing && strings.HasSuffix(inner, "/")) {
+		return event{}, false
+	}
+
+	return event{pos: pos, open: !closing, name: name, markup: tag}, true
+}
+
+// entityEnd returns the length of the character reference at the start of s, like &amp; or &#8230;, or 0 if there
+// is none.
+func entityEnd(s string) int {
+	for i := 1; i < len(s) && i <= 32; i++ {
+		c := s[i]
+		switch {
+		case c == ';':
+			if i > 1 {
+				return i + 1
+			}
+			return 0
+		case isLetter(c), c >= '0' && c <= '9', c == '#' && i == 1:
+		default:
+			return 0
+		}
+	}
+
+	return 0
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// scanMarkdown marks escape sequences, code spans, links and emphasis as atomic and records the code fences and the
+// emphasized spans that are opened and closed. Inline markup is expected to end on the line it starts on.
+func (t *text) scanMarkdown(atomic []bool) {
+	s := t.s
+	fence := ""
+	for start := 0; start < len(s); {
+		end := strings.IndexByte(s[start:], '\n')
+		if end < 0 {
+			end = len(s)
+		} else {
+			end += start
+		}
+		line := s[start:end]
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence == "" && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")):
+			fence = trimmed
+			protect(atomic, start, end)
+			t.events = append(t.events, event{pos: start, open: true, markup: trimmed})
+		case fence != "" && strings.HasPrefix(trimmed, fence[:3]) && strings.Trim(trimmed, fence[:1]) == "":
+			fence = ""
+			protect(atomic, start, end)
+			t.events = append(t.events, event{pos: start})
+		case fence == "":
+			t.scanInlines(atomic, start, end)
+		}
+
+		start = end + 1
+	}
+}
+
+// scanInlines marks the inline markup of the line s[start:end] as atomic and records the emphasized spans.
+func (t *text) scanInlines(atomic []bool, start, end int) {
+	s := t.s
+	// closers are the positions of the markers closing emphasized spans that were already recorded.
+	closers := make(map[int]bool)
+	for i := start; i < end; i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < end:
+			protect(atomic, i, i+2)
+			i++
+		case c == '`':
+			run := markerRun(s[i:end], '`')
+			closing := strings.Index(s[i+run:end], s[i:i+run])
+			if closing < 0 {
+				protect(atomic, i, i+run)
+				i += run - 1
+				continue
+			}
+			protect(atomic, i, i+run+closing+run)
+			i += run + closing + run - 1
+		case c == '[':
+			if n := linkEnd(s[i:end]); n > 0 {
+				protect(atomic, i, i+n)
+				i += n - 1
+			}
+		case c == '<':
+			if n := strings.IndexByte(s[i:end], '>'); n > 0 {
+				protect(atomic, i, i+n+1)
+				i += n
+			}
+		case c == '&' && t.format == message.FormatMrkdwn:
+			if n := entityEnd(s[i:end]); n > 0 {
+				protect(atomic, i, i+n)
+				i += n - 1
+			}
+		case c == '*' || c == '_' || c == '~':
+			run := markerRun(s[i:end], c)
+			protect(atomic, i, i+run)
+			closing := strings.Index(s[i+run:end], s[i:i+run])
+			if closers[i] || closing <= 0 {
+				i += run - 1
+				continue
+			}
+
+			// Keep short emphasized spans together. Longer ones, or ones that don't fit into a part at all, are closed
+			// at the end of a part and opened again at the start of the next one.
+			marker, closeAt := s[i:i+run], i+run+closing
+			if closing <= maxEmphasis {
+				protect(atomic, i, closeAt+run)
+			} else {
+				// Don't split right behind the opening marker or in front of the closing one, which would leave an
+				// empty emphasized span.
+				protect(atomic, i, i+run+1)
+				protect(atomic, closeAt-1, closeAt+1)
+			}
+			t.events = append(t.events,
+				event{pos: i, open: true, markup: marker, emphasis: true},
+				event{pos: closeAt, markup: marker, emphasis: true},
+			)
+			closers[closeAt] = true
+			i += run - 1
+		}
+	}
+}
+
+// maxEmphasis is the maximum length of an emphasized span in bytes that is never split.
+const maxEmphasis = 256
+
+// markerRun returns the number of consecutive c at the start of s.
+func markerRun(s string, c byte) int {
+	n := 0
+	for n < len(s) && s[n] == c {
+		n++
+	}
+
+	return n
+}
+
+// linkEnd returns the length of the Markdown link [label](url) at the start of s, or 0 if there is none.
+func linkEnd(s string) int {
+	label := strings.Index(s, "](")
+	if label < 0 {
+		return 0
+	}
+
+	depth := 0
+	for i := label + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+
+	return 0
+}
+
+// scan returns the markup state at end, given the state at start.
+func (t *text) scan(start, end int, st state) state {
+	for _, e := range t.events {
+		if e.pos < start || e.pos >= end {
+			continue
+		}
+
+		switch {
+		case e.emphasis && e.open:
+			st.emphasis = append(st.emphasis[:len(st.emphasis):len(st.emphasis)], e.markup)
+		case e.emphasis:
+			for i := len(st.emphasis) - 1; i >= 0; i-- {
+				if st.emphasis[i] == e.markup {
+					st.emphasis = append(st.emphasis[:i:i], st.emphasis[i+1:]...)
+					break
+				}
+			}
+		case e.name == "" && e.open:
+			st.fence = e.markup
+		case e.name == "":
+			st.fence = ""
+		case e.open:
+			st.tags = append(st.tags[:len(st.tags):len(st.tags)], e)
+		default:
+			// Close the innermost matching element, and implicitly the elements it contains.
+			for i := len(st.tags) - 1; i >= 0; i-- {
+				if st.tags[i].name == e.name {
+					st.tags = st.tags[:i:i]
+					break
+				}
+			}
+		}
+	}
+
+	return st
+}
+
+// render returns the part s[start:end] of the text, followed by suffix. Markup that is open at start is opened again,
+// and markup that is still open at end is closed.
+func (t *text) render(start, end int, st state, suffix string) string {
+	var sb strings.Builder
+	if st.fence != "" {
+		sb.WriteString(st.fence + "\n")
+	}
+	for _, tag := range st.tags {
+		sb.WriteString(tag.markup)
+	}
+	for _, marker := range st.emphasis {
+		sb.WriteString(marker)
+	}
+
+	sb.WriteString(strings.TrimRightFunc(t.s[start:end], unicode.IsSpace))
+	sb.WriteString(suffix)
+
+	st = t.scan(start, end, st)
+	for i := len(st.emphasis) - 1; i >= 0; i-- {
+		sb.WriteString(st.emphasis[i])
+	}
+	for i := len(st.tags) - 1; i >= 0; i-- {
+		sb.WriteString("</" + st.tags[i].name + ">")
+	}
+	if st.fence != "" {
+		sb.WriteString("\n" + st.fence[:markerRun(st.fence, st.fence[0])])
+	}
+
+	return sb.String()
+}
+
+// skipSpace returns the position of the first character at or after pos that isn't white space.
+func (t *text) skipSpace(pos int) int {
+	if i := strings.IndexFunc(t.s[pos:], func(r rune) bool { return !unicode.IsSpace(r) }); i >= 0 {
+		return pos + i
+	}
+
+	return len(t.s)
+}
+
+// fit returns the position the text should be split at, so that the part starting at start, followed by suffix, fits.
+// It prefers positions that don't break up markup, and among those ending lines or words, as long as that keeps at
+// least half of the part. It returns start if not even a single character fits.
+func (t *text) fit(start int, st state, fits func(string) bool, suffix string) int {
+	for _, bounds := range [][]bound{t.bounds, t.graphemes} {
+		first := sort.Search(len(bounds), func(i int) bool { return bounds[i].pos > start })
+		candidates := bounds[first:]
+
+		// The length of the parts grows with their end, so search for the last candidate that fits.
+		n := sort.Search(len(candidates), func(i int) bool {
+			return !fits(t.render(start, candidates[i].pos, st, suffix))
+		})
+		if n == 0 {
+			continue
+		}
+
+		best := candidates[n-1]
+		if best.pos == len(t.s) {
+			return best.pos
+		}
+
+		half := start + (best.pos-start)/2
+		for i := n - 2; i >= 0 && candidates[i].pos >= half; i-- {
+			if candidates[i].priority > best.priority {
+				best = candidates[i]
+			}
+		}
+
+		return best.pos
+	}
+
+	return start
+}
@@ -0,0 +1,213 @@
+package notify
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/casdoor/notify/receiver"
+)
+
+// instrumentationName is the name of the tracer and the meter of Notify.
+const instrumentationName = "github.com/casdoor/notify"
+
+// Attributes of the spans and metrics recorded by Notify, see WithTracerProvider and WithMeterProvider.
+const (
+	attrServiceName   = attribute.Key("notify.service.name")
+	attrServiceCount  = attribute.Key("notify.service.count")
+	attrReceiver      = attribute.Key("notify.receiver")
+	attrReceiverCount = attribute.Key("notify.receiver.count")
+	attrAttempts      = attribute.Key("notify.attempts")
+	attrPriority      = attribute.Key("notify.message.priority")
+	attrOutcome       = attribute.Key("notify.outcome")
+)
+
+// telemetry holds the OpenTelemetry instruments of a Notify instance. Its methods are no-ops for the instruments that
+// aren't set.
+type telemetry struct {
+	tracer   trace.Tracer
+	sent     metric.Int64Counter
+	failed   metric.Int64Counter
+	retries  metric.Int64Counter
+	duration metric.Float64Histogram
+}
+
+// WithTracerProvider is an Option that traces every send with the given provider, or the global one if tp is nil:
+//
+//   - A send gets a "notify.send" span with the attributes notify.message.priority, notify.service.count and
+//     notify.outcome, which is "success", "partial" or "failure".
+//   - Every service gets a "notify.service" child span with the attributes notify.service.name, notify.attempts and
+//     notify.receiver.count.
+//   - Every receiver a service delivers to through the receiver package gets a "notify.receiver" child span of the
+//     service span, with the attribute notify.receiver.
+//
+// Spans of failed sends record the error and have an error status.
+//
+// Services making HTTP requests propagate the span of the receiver to the server, using the global propagator, see
+// otel.SetTextMapPropagator. In asynchronous mode, the trace context is kept with the queued notifications.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(n *Notify) {
+		if n == nil {
+			return
+		}
+		if tp == nil {
+			tp = otel.GetTracerProvider()
+		}
+
+		n.instruments().tracer = tp.Tracer(instrumentationName, trace.WithInstrumentationVersion(Version))
+	}
+}
+
+// WithMeterProvider is an Option that records metrics with the given provider, or the global one if mp is nil. The
+// following instruments are recorded per service, with the notify.service.name attribute:
+//
+//   - notify.sent, the number of messages sent successfully.
+//   - notify.failed, the number of messages that failed to send.
+//   - notify.retries, the number of retries, see WithRetry.
+//   - notify.duration, the time it took to send a message in seconds, retries included.
+//
+// Sends during a dry run aren't recorded, see WithDryRun.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(n *Notify) {
+		if n == nil {
+			return
+		}
+		if mp == nil {
+			mp = otel.GetMeterProvider()
+		}
+
+		meter := mp.Meter(instrumentationName, metric.WithInstrumentationVersion(Version))
+		t := n.instruments()
+
+		var err error
+		t.sent, err = meter.Int64Counter("notify.sent",
+			metric.WithDescription("Number of messages sent successfully."), metric.WithUnit("{message}"))
+		otel.Handle(err)
+		t.failed, err = meter.Int64Counter("notify.failed",
+			metric.WithDescription("Number of messages that failed to send."), metric.WithUnit("{message}"))
+		otel.Handle(err)
+		t.retries, err = meter.Int64Counter("notify.retries",
+			metric.WithDescription("Number of retried sends."), metric.WithUnit("{retry}"))
+		otel.Handle(err)
+		t.duration, err = meter.Float64Histogram("notify.duration",
+			metric.WithDescription("Time it took to send a message, retries included."), metric.WithUnit("s"))
+		otel.Handle(err)
+	}
+}
+
+// instruments returns the telemetry of the Notify instance, creating it if needed.
+func (n *Notify) instruments() *telemetry {
+	if n.telemetry == nil {
+		n.telemetry = &telemetry{}
+	}
+
+	return n.telemetry
+}
+
+// startSend starts the span of a send. The returned function ends it with the outcome of the send.
+func (t *telemetry) startSend(ctx context.Context, m *Message) (context.Context, func(*Report)) {
+	if t == nil || t.tracer == nil {
+		return ctx, func(*Report) {}
+	}
+
+	ctx, span := t.tracer.Start(ctx, "notify.send", trace.WithAttributes(attrPriority.String(m.Priority.String())))
+	ctx = receiver.WithObserver(ctx, t.observe)
+
+	return ctx, func(report *Report) {
+		span.SetAttributes(attrServiceCount.Int(len(report.Services)))
+
+		failed := len(report.Failed())
+		switch {
+		case failed == 0:
+			span.SetAttributes(attrOutcome.String("success"))
+		case failed < len(report.Services):
+			span.SetAttributes(attrOutcome.String("partial"))
+			span.SetStatus(codes.Error, "some services failed")
+		default:
+			span.SetAttributes(attrOutcome.String("failure"))
+			span.SetStatus(codes.Error, "all services failed")
+		}
+		span.End()
+	}
+}
+
+// startService starts the span of a send to the named service. The returned function ends it and records the metrics
+// of the service.
+func (t *telemetry) startService(ctx context.Context, name string) (context.Context, func(*ServiceResult)) {
+	if t == nil {
+		return ctx, func(*ServiceResult) {}
+	}
+
+	var span trace.Span
+	if t.tracer != nil {
+		ctx, span = t.tracer.Start(ctx, "notify.service", trace.WithAttributes(attrServiceName.String(name)))
+	}
+
+	return ctx, func(result *ServiceResult) {
+		if span != nil {
+			span.SetAttributes(attrReceiverCount.Int(len(result.Receivers)), attrAttempts.Int(result.Attempts))
+			endSpan(span, result.Err)
+		}
+		t.record(ctx, result)
+	}
+}
+
+// record records the metrics of a send to a single service.
+func (t *telemetry) record(ctx context.Context, result *ServiceResult) {
+	attrs := metric.WithAttributes(attrServiceName.String(result.Name))
+	if result.Err == nil && t.sent != nil {
+		t.sent.Add(ctx, 1, attrs)
+	}
+	if result.Err != nil && t.failed != nil {
+		t.failed.Add(ctx, 1, attrs)
+	}
+	if result.Attempts > 1 && t.retries != nil {
+		t.retries.Add(ctx, int64(result.Attempts-1), attrs)
+	}
+	if t.duration != nil {
+		t.duration.Record(ctx, result.Duration.Seconds(), attrs)
+	}
+}
+
+// observe starts the span of a delivery to a single receiver, see receiver.Observer.
+func (t *telemetry) observe(ctx context.Context, _, name string) (context.Context, func(error)) {
+	ctx, span := t.tracer.Start(ctx, "notify.receiver", trace.WithAttributes(attrReceiver.String(name)))
+
+	return ctx, func(err error) {
+		endSpan(span, err)
+	}
+}
+
+// endSpan ends span with the given error, if any.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// injectTrace returns the trace context of ctx, encoded by the global propagator. It's kept with queued notifications.
+func injectTrace(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+
+	return carrier
+}
+
+// extractTrace returns a copy of ctx with the trace context encoded by injectTrace.
+func extractTrace(ctx context.Context, carrier map[string]string) context.Context {
+	if len(carrier) == 0 {
+		return ctx
+	}
+
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
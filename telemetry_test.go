@@ -0,0 +1,153 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/casdoor/notify/receiver"
+	"github.com/casdoor/notify/retry"
+)
+
+// receiversService delivers to its receivers through the receiver package.
+type receiversService []string
+
+func (s receiversService) Send(ctx context.Context, _, _ string) error {
+	return receiver.Each(ctx, "test", s, func(context.Context, string) error {
+		return nil
+	})
+}
+
+func TestTelemetry(t *testing.T) {
+	t.Parallel()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	errDown := errors.New("down")
+	calls := 0
+	n := NewWithOptions(
+		WithTracerProvider(tp),
+		WithMeterProvider(mp),
+		WithRetry(retry.Policy{MaxAttempts: 3}),
+	)
+	n.UseNamedService("ok", receiversService{"a", "b"})
+	n.UseNamedService("flaky", NotifierFunc(func(context.Context, string, string) error {
+		calls++
+		if calls < 2 {
+			return retry.Transient(errDown)
+		}
+		return nil
+	}))
+	n.UseNamedService("down", NotifierFunc(func(context.Context, string, string) error {
+		return errDown
+	}))
+
+	if err := n.SendMessage(context.Background(), &Message{Subject: "s", Priority: PriorityHigh}); err == nil {
+		t.Fatal("SendMessage() error = nil, want the error of down")
+	}
+
+	spans := exporter.GetSpans()
+	byName := make(map[string][]tracetest.SpanStub)
+	for _, span := range spans {
+		byName[span.Name] = append(byName[span.Name], span)
+	}
+	if len(byName["notify.send"]) != 1 || len(byName["notify.service"]) != 3 || len(byName["notify.receiver"]) != 2 {
+		t.Fatalf("got spans %v, want 1 send, 3 service and 2 receiver spans", byName)
+	}
+
+	send := byName["notify.send"][0]
+	wantAttrs(t, send.Attributes, attrPriority.String("high"), attrServiceCount.Int(3), attrOutcome.String("partial"))
+	if send.Status.Code != codes.Error {
+		t.Errorf("send span status = %v, want error", send.Status)
+	}
+
+	services := make(map[string]tracetest.SpanStub)
+	for _, span := range byName["notify.service"] {
+		if span.Parent.SpanID() != send.SpanContext.SpanID() {
+			t.Errorf("service span %v isn't a child of the send span", span.Attributes)
+		}
+		for _, attr := range span.Attributes {
+			if attr.Key == attrServiceName {
+				services[attr.Value.AsString()] = span
+			}
+		}
+	}
+	wantAttrs(t, services["ok"].Attributes, attrReceiverCount.Int(2), attrAttempts.Int(1))
+	wantAttrs(t, services["flaky"].Attributes, attrReceiverCount.Int(0), attrAttempts.Int(2))
+	if services["down"].Status.Code != codes.Error || len(services["down"].Events) != 1 {
+		t.Errorf("down span status = %v, events = %v, want the error", services["down"].Status, services["down"].Events)
+	}
+	for _, span := range byName["notify.receiver"] {
+		if span.Parent.SpanID() != services["ok"].SpanContext.SpanID() {
+			t.Errorf("receiver span %v isn't a child of the service span", span.Attributes)
+		}
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	sums := make(map[string]map[string]int64)
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		sum, ok := m.Data.(metricdata.Sum[int64])
+		if !ok {
+			continue
+		}
+		sums[m.Name] = make(map[string]int64)
+		for _, point := range sum.DataPoints {
+			name, _ := point.Attributes.Value(attrServiceName)
+			sums[m.Name][name.AsString()] = point.Value
+		}
+	}
+	wantSums := map[string]map[string]int64{
+		"notify.sent":    {"ok": 1, "flaky": 1},
+		"notify.failed":  {"down": 1},
+		"notify.retries": {"flaky": 1, "down": 2},
+	}
+	for name, want := range wantSums {
+		for service, value := range want {
+			if got := sums[name][service]; got != value {
+				t.Errorf("%s{%s} = %d, want %d", name, service, got, value)
+			}
+		}
+	}
+}
+
+func TestTelemetry_DryRun(t *testing.T) {
+	t.Parallel()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	n := NewWithOptions(WithTracerProvider(tp))
+	n.UseServices(receiversService{"a"})
+
+	ctx := WithDryRun(context.Background(), func(string, *Message) {})
+	if err := n.Send(ctx, "s", "m"); err != nil {
+		t.Fatal(err)
+	}
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Errorf("got %d spans during a dry run, want none", len(spans))
+	}
+}
+
+// wantAttrs checks that attrs contain all wanted attributes.
+func wantAttrs(t *testing.T, attrs []attribute.KeyValue, want ...attribute.KeyValue) {
+	t.Helper()
+
+	set := attribute.NewSet(attrs...)
+	for _, w := range want {
+		if got, ok := set.Value(w.Key); !ok || got != w.Value {
+			t.Errorf("attribute %s = %v, want %v", w.Key, got.Emit(), w.Value.Emit())
+		}
+	}
+}
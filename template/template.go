@@ -0,0 +1,346 @@
+// Package template renders notifications from named templates. A template is defined once and has a variant for every
+// body format it supports, e.g. plain text for SMS, mrkdwn for Slack and HTML for emails. When a notification refers to
+// a template, every service gets the variant of the body format it prefers, see notify.WithTemplates.
+//
+// Variants are parsed with text/template, except for HTML variants, which are parsed with html/template. A variant may
+// define a "subject" template, which replaces the subject of the notification. The rest of the variant is the body:
+//
+//	{{define "subject"}}Disk almost full on {{.Host}}{{end}}
+//	Only {{.Free}} left on {{.Host}}.
+//
+// Like html/template does for HTML, the data printed by the bodies of MarkdownV2 and mrkdwn variants is escaped, see
+// markup.Escape, so that it's shown as is and never breaks the markup. Use the "raw" function to print markup from the
+// data, e.g. {{raw .Link}}. The variants of other formats, except for HTML, may escape data explicitly with the
+// "escape" function, e.g. {{escape .Host}} in a Markdown variant.
+//
+// This package deliberately doesn't depend on the notify package itself, so that every service is able to use it.
+package template
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"text/template/parse"
+
+	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/markup"
+	"github.com/casdoor/notify/message"
+)
+
+// subjectName is the name of the template that defines the subject of a variant.
+const subjectName = "subject"
+
+// Names of the functions escaping data, see the package documentation.
+const (
+	escapeFunc = "escape"
+	rawFunc    = "raw"
+)
+
+// autoEscaped lists the body formats whose variants escape all printed data.
+var autoEscaped = map[message.BodyFormat]bool{
+	message.FormatMarkdownV2: true,
+	message.FormatMrkdwn:     true,
+}
+
+// ErrNotFound is returned by Render if the registry doesn't know the template.
+var ErrNotFound = errors.New("template not found")
+
+// FuncMap is the type of the map defining the functions available to templates. See text/template.FuncMap.
+type FuncMap map[string]any
+
+// Rendered is a rendered template.
+type Rendered struct {
+	// Subject is the rendered subject. It's empty if the variant doesn't define one.
+	Subject string
+	// Body is the rendered body.
+	Body string
+	// Format is the body format of the variant that was rendered. It may differ from the requested one, see Render.
+	Format message.BodyFormat
+}
+
+// variant is a template parsed for a single body format.
+type variant struct {
+	subject *texttemplate.Template
+	body    interface {
+		Execute(w io.Writer, data any) error
+	}
+}
+
+// Registry holds named templates. It's safe for concurrent use.
+type Registry struct {
+	mu        sync.RWMutex
+	funcs     FuncMap
+	templates map[string]map[message.BodyFormat]*variant
+}
+
+// New returns a new, empty registry.
+func New() *Registry {
+	return &Registry{templates: make(map[string]map[message.BodyFormat]*variant)}
+}
+
+// Funcs adds the given functions to the functions available to templates. It only affects templates parsed afterwards.
+// It returns the registry, so that calls can be chained.
+func (r *Registry) Funcs(funcs FuncMap) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.funcs == nil {
+		r.funcs = make(FuncMap, len(funcs))
+	}
+	for name, fn := range funcs {
+		r.funcs[name] = fn
+	}
+
+	return r
+}
+
+// Parse parses text as the variant of the named template for the given body format. It replaces the variant if it
+// already exists.
+func (r *Registry) Parse(name string, format message.BodyFormat, text string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, err := texttemplate.New(name).Funcs(texttemplate.FuncMap(r.funcs)).Funcs(escapeFuncs(format)).Parse(text)
+	if err != nil {
+		return errors.Wrapf(err, "parse template %q (%s)", name, format)
+	}
+	if autoEscaped[format] {
+		for _, tmpl := range t.Templates() {
+			if tmpl.Name() != subjectName && tmpl.Tree != nil {
+				escapeActions(tmpl.Tree.Root)
+			}
+		}
+	}
+
+	v := &variant{subject: t.Lookup(subjectName), body: t}
+	if format == message.FormatHTML {
+		// The subject isn't part of the HTML document, so it's only parsed with text/template above.
+		body, err := htmltemplate.New(name).Funcs(htmltemplate.FuncMap(r.funcs)).Parse(text)
+		if err != nil {
+			return errors.Wrapf(err, "parse template %q (%s)", name, format)
+		}
+		v.body = body
+	}
+
+	if r.templates[name] == nil {
+		r.templates[name] = make(map[message.BodyFormat]*variant)
+	}
+	r.templates[name][format] = v
+
+	return nil
+}
+
+// escapeFuncs returns the functions escaping data for a variant of the given body format. HTML variants don't get any,
+// html/template escapes their data already.
+func escapeFuncs(format message.BodyFormat) texttemplate.FuncMap {
+	if format == message.FormatHTML {
+		return nil
+	}
+
+	return texttemplate.FuncMap{
+		escapeFunc: func(v any) string {
+			return markup.Escape(fmt.Sprint(v), format)
+		},
+		rawFunc: func(v any) any {
+			return v
+		},
+	}
+}
+
+// escapeActions appends the escape function to the pipelines of all actions below node that print something, unless
+// they already end with the escape or the raw function.
+func escapeActions(node parse.Node) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, n := range node.Nodes {
+			escapeActions(n)
+		}
+	case *parse.ActionNode:
+		pipe := node.Pipe
+		if len(pipe.Decl) > 0 || len(pipe.Cmds) == 0 {
+			return
+		}
+		last := pipe.Cmds[len(pipe.Cmds)-1]
+		if id, ok := last.Args[0].(*parse.IdentifierNode); ok && (id.Ident == escapeFunc || id.Ident == rawFunc) {
+			return
+		}
+		escape := parse.NewIdentifier(escapeFunc).SetPos(node.Pos)
+		pipe.Cmds = append(pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: node.Pos, Args: []parse.Node{escape}})
+	case *parse.IfNode:
+		escapeActions(node.List)
+		escapeActions(node.ElseList)
+	case *parse.RangeNode:
+		escapeActions(node.List)
+		escapeActions(node.ElseList)
+	case *parse.WithNode:
+		escapeActions(node.List)
+		escapeActions(node.ElseList)
+	}
+}
+
+// ParseFiles parses the given files, see ParseFS for how their names are interpreted.
+func (r *Registry) ParseFiles(filenames ...string) error {
+	for _, filename := range filenames {
+		text, err := os.ReadFile(filename)
+		if err != nil {
+			return errors.Wrap(err, "read template")
+		}
+		if err = r.parseFile(filepath.Base(filename), string(text)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ParseFS parses the files of fsys matching the given patterns, e.g. an embed.FS. The name of the template is the part
+// of the file name before the first dot. The body format of the variant is the first of the following parts of the
+// file name that names a body format, see message.ParseBodyFormat. It defaults to plain text. For example,
+// "disk_full.tmpl" is the plain text variant of the template "disk_full", and "disk_full.md.tmpl" and
+// "disk_full.html" are its Markdown and HTML variants.
+func (r *Registry) ParseFS(fsys fs.FS, patterns ...string) error {
+	for _, pattern := range patterns {
+		filenames, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return errors.Wrapf(err, "match templates %q", pattern)
+		}
+		if len(filenames) == 0 {
+			return errors.Errorf("pattern %q matches no templates", pattern)
+		}
+
+		for _, filename := range filenames {
+			text, err := fs.ReadFile(fsys, filename)
+			if err != nil {
+				return errors.Wrap(err, "read template")
+			}
+			if err = r.parseFile(path.Base(filename), string(text)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseFile parses text as the variant named by the given file name.
+func (r *Registry) parseFile(filename, text string) error {
+	parts := strings.Split(filename, ".")
+
+	format := message.FormatPlain
+	for _, part := range parts[1:] {
+		if f, err := message.ParseBodyFormat(part); err == nil {
+			format = f
+			break
+		}
+	}
+
+	return r.Parse(parts[0], format, text)
+}
+
+// Names returns the names of all templates, sorted alphabetically.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Has reports whether the registry knows the named template.
+func (r *Registry) Has(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.templates[name]
+	return ok
+}
+
+// fallbacks lists the body formats tried, in order, if a template has no variant for the requested format.
+var fallbacks = map[message.BodyFormat][]message.BodyFormat{
+	message.FormatPlain:      {message.FormatMarkdown},
+	message.FormatMarkdown:   {message.FormatPlain},
+	message.FormatHTML:       {message.FormatPlain, message.FormatMarkdown},
+	message.FormatMrkdwn:     {message.FormatMarkdown, message.FormatPlain},
+	message.FormatMarkdownV2: {message.FormatMarkdown, message.FormatPlain},
+}
+
+// lastResort lists the body formats tried if neither the requested format nor its fallbacks have a variant.
+var lastResort = []message.BodyFormat{
+	message.FormatPlain,
+	message.FormatMarkdown,
+	message.FormatHTML,
+	message.FormatMrkdwn,
+	message.FormatMarkdownV2,
+}
+
+// Render renders the variant of the named template for the given body format with data. If the template has no such
+// variant, the closest one is rendered instead, e.g. Markdown for Slack mrkdwn, or plain text for HTML. The format
+// of the rendered variant is returned with the result. The subject and the body are trimmed of surrounding white
+// space.
+func (r *Registry) Render(name string, format message.BodyFormat, data any) (*Rendered, error) {
+	if format == "" {
+		format = message.FormatPlain
+	}
+
+	v, format, ok := r.lookup(name, format)
+	if !ok {
+		return nil, errors.Wrapf(ErrNotFound, "render template %q", name)
+	}
+
+	return v.render(name, format, data)
+}
+
+// lookup returns the variant of the named template that is closest to the given body format, and its format.
+func (r *Registry) lookup(name string, format message.BodyFormat) (*variant, message.BodyFormat, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	variants := r.templates[name]
+	candidates := append([]message.BodyFormat{format}, fallbacks[format]...)
+	for _, f := range append(candidates, lastResort...) {
+		if v, ok := variants[f]; ok {
+			return v, f, true
+		}
+	}
+
+	return nil, format, false
+}
+
+// render executes the variant with data.
+func (v *variant) render(name string, format message.BodyFormat, data any) (*Rendered, error) {
+	rendered := &Rendered{Format: format}
+
+	var buf bytes.Buffer
+	if v.subject != nil {
+		if err := v.subject.Execute(&buf, data); err != nil {
+			return nil, errors.Wrapf(err, "render subject of template %q (%s)", name, format)
+		}
+		rendered.Subject = strings.TrimSpace(buf.String())
+		buf.Reset()
+	}
+
+	if err := v.body.Execute(&buf, data); err != nil {
+		return nil, errors.Wrapf(err, "render template %q (%s)", name, format)
+	}
+	rendered.Body = strings.TrimSpace(buf.String())
+
+	return rendered, nil
+}
@@ -0,0 +1,170 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/casdoor/notify/markup"
+	"github.com/casdoor/notify/message"
+)
+
+func TestRegistry_Render(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	r := New().Funcs(FuncMap{"upper": strings.ToUpper})
+	assert.NoError(r.Parse("disk", message.FormatPlain, `{{define "subject"}}Disk on {{.Host}}{{end}}
+Only {{.Free}} left on {{upper .Host}}.`))
+	assert.NoError(r.Parse("disk", message.FormatMarkdown, `{{define "subject"}}Disk on {{.Host}}{{end}}
+Only **{{.Free}}** left.`))
+	assert.NoError(r.Parse("disk", message.FormatHTML, `{{define "subject"}}Disk on {{.Host}}{{end}}
+<p>Only <b>{{.Free}}</b> left.</p>`))
+
+	data := map[string]string{"Host": "a&b", "Free": "<1GB"}
+
+	rendered, err := r.Render("disk", message.FormatPlain, data)
+	assert.NoError(err)
+	assert.Equal(&Rendered{Subject: "Disk on a&b", Body: "Only <1GB left on A&B.", Format: message.FormatPlain}, rendered)
+
+	// HTML bodies are escaped, the subject isn't.
+	rendered, err = r.Render("disk", message.FormatHTML, data)
+	assert.NoError(err)
+	assert.Equal("Disk on a&b", rendered.Subject)
+	assert.Equal("<p>Only <b>&lt;1GB</b> left.</p>", rendered.Body)
+
+	// mrkdwn falls back to Markdown.
+	rendered, err = r.Render("disk", message.FormatMrkdwn, data)
+	assert.NoError(err)
+	assert.Equal(message.FormatMarkdown, rendered.Format)
+	assert.Equal("Only **<1GB** left.", rendered.Body)
+
+	_, err = r.Render("missing", message.FormatPlain, data)
+	assert.ErrorIs(err, ErrNotFound)
+}
+
+func TestRegistry_RenderEscaped(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	r := New()
+	assert.NoError(r.Parse("deploy", message.FormatMarkdownV2, `{{define "subject"}}Deployed {{.Version}}{{end}}
+*{{.Version}}* deployed to {{range .Hosts}}{{.}} {{end}}by {{raw .Link}}{{if .Note}} ({{.Note | printf "%s"}}){{end}}`))
+	assert.NoError(r.Parse("deploy", message.FormatMrkdwn, `*{{.Version}}* deployed by {{.User}}`))
+	assert.NoError(r.Parse("deploy", message.FormatMarkdown, `**{{escape .Version}}** deployed by {{.User}}`))
+
+	data := map[string]any{
+		"Version": "v1.2.3-rc.1",
+		"Hosts":   []string{"web-1"},
+		"Link":    "[ci](https://ci.example.com)",
+		"Note":    "a_b",
+		"User":    "<@U123> & co",
+	}
+
+	rendered, err := r.Render("deploy", message.FormatMarkdownV2, data)
+	assert.NoError(err)
+	assert.Equal("Deployed v1.2.3-rc.1", rendered.Subject, "the subject isn't escaped")
+	assert.Equal(`*v1\.2\.3\-rc\.1* deployed to web\-1 by [ci](https://ci.example.com) (a\_b)`, rendered.Body)
+
+	rendered, err = r.Render("deploy", message.FormatMrkdwn, data)
+	assert.NoError(err)
+	assert.Equal("*v1.2.3-rc.1* deployed by &lt;@U123&gt; &amp; co", rendered.Body)
+
+	// Other formats are only escaped explicitly.
+	rendered, err = r.Render("deploy", message.FormatMarkdown, data)
+	assert.NoError(err)
+	want := "**" + markup.Escape("v1.2.3-rc.1", message.FormatMarkdown) + "** deployed by <@U123> & co"
+	assert.Equal(want, rendered.Body)
+}
+
+func TestRegistry_RenderFallback(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	r := New()
+	assert.NoError(r.Parse("html", message.FormatHTML, `<b>{{.}}</b>`))
+	assert.NoError(r.Parse("plain", message.FormatPlain, `{{.}}`))
+
+	// Plain text is the last resort for HTML, but any variant is better than none.
+	rendered, err := r.Render("html", message.FormatPlain, "x")
+	assert.NoError(err)
+	assert.Equal(&Rendered{Body: "<b>x</b>", Format: message.FormatHTML}, rendered)
+
+	rendered, err = r.Render("plain", message.FormatMarkdownV2, "x")
+	assert.NoError(err)
+	assert.Equal(message.FormatPlain, rendered.Format)
+
+	rendered, err = r.Render("plain", "", "x")
+	assert.NoError(err)
+	assert.Equal(message.FormatPlain, rendered.Format)
+}
+
+func TestRegistry_ParseErrors(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	r := New()
+	assert.Error(r.Parse("broken", message.FormatPlain, `{{.Host`))
+	assert.False(r.Has("broken"))
+
+	assert.NoError(r.Parse("missing_func", message.FormatPlain, `{{.}}`))
+	_, err := r.Render("missing_func", message.FormatPlain, func() {})
+	assert.Error(err)
+}
+
+func TestRegistry_ParseFS(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	fsys := fstest.MapFS{
+		"templates/disk.tmpl":    {Data: []byte("plain {{.}}")},
+		"templates/disk.md.tmpl": {Data: []byte("**{{.}}**")},
+		"templates/disk.html":    {Data: []byte("<b>{{.}}</b>")},
+		"templates/deploy.txt":   {Data: []byte("deployed {{.}}")},
+	}
+
+	r := New()
+	assert.NoError(r.ParseFS(fsys, "templates/*"))
+	assert.Equal([]string{"deploy", "disk"}, r.Names())
+
+	for format, want := range map[message.BodyFormat]string{
+		message.FormatPlain:    "plain x",
+		message.FormatMarkdown: "**x**",
+		message.FormatHTML:     "<b>x</b>",
+	} {
+		rendered, err := r.Render("disk", format, "x")
+		assert.NoError(err)
+		assert.Equal(want, rendered.Body)
+	}
+
+	assert.Error(r.ParseFS(fsys, "missing/*"))
+}
+
+func TestRegistry_ParseFiles(t *testing.T) {
+	t.Parallel()
+
+	assert := require.New(t)
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "welcome.markdown.tmpl")
+	assert.NoError(os.WriteFile(filename, []byte(`{{define "subject"}}Hi {{.}}{{end}}
+_Welcome_, {{.}}!`), 0o600))
+
+	r := New()
+	assert.NoError(r.ParseFiles(filename))
+
+	rendered, err := r.Render("welcome", message.FormatMarkdown, "Ann")
+	assert.NoError(err)
+	assert.Equal(&Rendered{Subject: "Hi Ann", Body: "_Welcome_, Ann!", Format: message.FormatMarkdown}, rendered)
+
+	assert.Error(r.ParseFiles(filepath.Join(dir, "missing.tmpl")))
+}
@@ -0,0 +1,95 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/template"
+)
+
+// WithTemplates is an Option that lets messages refer to the templates of the given registry, see Message.Template.
+// Before a message is sent, its subject and body are replaced with the plain text variant of its template, so that
+// routes, deduplication and middleware see the rendered text. Right before the message reaches a service, the
+// template is rendered once more in the body format the service prefers, see FormatPreferrer. Middleware that change
+// the subject or the body replace the template for the services behind them.
+func WithTemplates(registry *template.Registry) Option {
+	return func(n *Notify) {
+		if n != nil {
+			n.templates = registry
+		}
+	}
+}
+
+type templatesKey struct{}
+
+// registry returns the template registry of the Notify instance. If it has none, it returns the one bound to ctx, so
+// that a Notify instance used as a service of another one renders the templates of the outer one.
+func (n *Notify) registry(ctx context.Context) *template.Registry {
+	if n.templates != nil {
+		return n.templates
+	}
+	if ctx == nil {
+		return nil
+	}
+	registry, _ := ctx.Value(templatesKey{}).(*template.Registry)
+
+	return registry
+}
+
+// render returns a copy of m with the subject and the body rendered from its template in the given body format. It
+// returns m itself if it doesn't refer to a template or no registry is available.
+func render(registry *template.Registry, m *Message, format BodyFormat) (*Message, error) {
+	if m.Template == "" {
+		return m, nil
+	}
+	if registry == nil {
+		return nil, errors.Errorf("render message: no templates configured for template %q", m.Template)
+	}
+
+	rendered, err := registry.Render(m.Template, format, m.Data)
+	if err != nil {
+		return nil, errors.Wrap(err, "render message")
+	}
+
+	m = m.Clone()
+	if rendered.Subject != "" {
+		m.Subject = rendered.Subject
+	}
+	m.Body = rendered.Body
+	m.Format = rendered.Format
+
+	return m, nil
+}
+
+// renderFor renders the template of m in the body format preferred by the given service, using the registry bound to
+// ctx. Services that only pass messages on to other services, like middleware or Fallback, get m itself, so that the
+// template is rendered for the services behind them.
+func renderFor(ctx context.Context, service Notifier, m *Message) (*Message, error) {
+	if m.Template == "" {
+		return m, nil
+	}
+
+	if passesOn(service) {
+		return m, nil
+	}
+	registry, _ := ctx.Value(templatesKey{}).(*template.Registry)
+
+	return render(registry, m, preferredFormat(service))
+}
+
+// passesOn reports whether the given service only passes messages on to other services, see Wrapper.
+func passesOn(service Notifier) bool {
+	_, ok := service.(Wrapper)
+	return ok
+}
+
+// SendTemplate renders the named template with data and sends it to all services, see WithTemplates.
+func (n *Notify) SendTemplate(ctx context.Context, name string, data any) error {
+	return n.SendMessage(ctx, &Message{Template: name, Data: data})
+}
+
+// SendTemplate renders the named template with data and sends it to all services, see WithTemplates.
+func SendTemplate(ctx context.Context, name string, data any) error {
+	return std.SendTemplate(ctx, name, data)
+}
@@ -0,0 +1,179 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/casdoor/notify/template"
+)
+
+// preferringCollector is a collector that prefers a body format.
+type preferringCollector struct {
+	collector
+	format BodyFormat
+}
+
+func (c *preferringCollector) PreferredFormat() BodyFormat {
+	return c.format
+}
+
+func testTemplates(t *testing.T) *template.Registry {
+	t.Helper()
+
+	registry := template.New()
+	variants := map[BodyFormat]string{
+		FormatPlain:  `{{define "subject"}}Deploy of {{.App}}{{end}}{{.App}} was deployed.`,
+		FormatMrkdwn: `*{{.App}}* was deployed.`,
+		FormatHTML:   `<b>{{.App}}</b> was deployed.`,
+	}
+	for format, text := range variants {
+		if err := registry.Parse("deploy", format, text); err != nil {
+			t.Fatalf("Parse(%q) unexpected error: %v", format, err)
+		}
+	}
+
+	return registry
+}
+
+func TestWithTemplates(t *testing.T) {
+	t.Parallel()
+
+	plain := &collector{}
+	slack := &preferringCollector{format: FormatMrkdwn}
+	mail := &preferringCollector{format: FormatHTML}
+
+	var mu sync.Mutex
+	var middlewareSubjects []string
+	n := NewWithOptions(WithTemplates(testTemplates(t)))
+	n.UseServices(plain, slack)
+	n.UseServices(Fallback(mail))
+	n.Use(func(next Notifier) Notifier {
+		return NotifierFunc(func(ctx context.Context, subject, message string) error {
+			mu.Lock()
+			middlewareSubjects = append(middlewareSubjects, subject)
+			mu.Unlock()
+			return next.Send(ctx, subject, message)
+		})
+	})
+
+	if err := n.SendTemplate(context.Background(), "deploy", map[string]string{"App": "api"}); err != nil {
+		t.Fatalf("SendTemplate() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		service *collector
+		body    string
+		format  BodyFormat
+	}{
+		{plain, "api was deployed.", FormatPlain},
+		{&slack.collector, "*api* was deployed.", FormatMrkdwn},
+		{&mail.collector, "<b>api</b> was deployed.", FormatHTML},
+	}
+	for _, tt := range tests {
+		received := tt.service.received()
+		if len(received) != 1 {
+			t.Fatalf("service received %d messages, want 1", len(received))
+		}
+		if m := received[0]; m.Subject != "Deploy of api" || m.Body != tt.body || m.Format != tt.format {
+			t.Errorf("service received %q, %q (%s), want %q (%s)", m.Subject, m.Body, m.Format, tt.body, tt.format)
+		}
+	}
+	for _, subject := range middlewareSubjects {
+		if subject != "Deploy of api" {
+			t.Errorf("middleware saw the subject %q, want the rendered one", subject)
+		}
+	}
+}
+
+func TestWithTemplates_MiddlewareReplacesTemplate(t *testing.T) {
+	t.Parallel()
+
+	slack := &preferringCollector{format: FormatMrkdwn}
+	n := NewWithOptions(WithTemplates(testTemplates(t)))
+	n.UseServices(slack)
+	n.Use(func(next Notifier) Notifier {
+		return NotifierFunc(func(ctx context.Context, subject, message string) error {
+			return next.Send(ctx, subject, strings.ToUpper(message))
+		})
+	})
+
+	if err := n.SendTemplate(context.Background(), "deploy", map[string]string{"App": "api"}); err != nil {
+		t.Fatalf("SendTemplate() unexpected error: %v", err)
+	}
+	if m := slack.received()[0]; m.Body != "API WAS DEPLOYED." {
+		t.Errorf("service received %q, want the body changed by the middleware", m.Body)
+	}
+}
+
+func TestWithTemplates_Errors(t *testing.T) {
+	t.Parallel()
+
+	n := New()
+	n.UseServices(&collector{})
+	if err := n.SendTemplate(context.Background(), "deploy", nil); err == nil {
+		t.Error("SendTemplate() without templates was expected to fail")
+	}
+
+	WithTemplates(testTemplates(t))(n)
+	if err := n.SendTemplate(context.Background(), "missing", nil); !errors.Is(err, template.ErrNotFound) {
+		t.Errorf("SendTemplate() error = %v, want %v", err, template.ErrNotFound)
+	}
+
+	_, err := n.SendMessageWithReport(context.Background(), &Message{Template: "missing"})
+	if !errors.Is(err, template.ErrNotFound) {
+		t.Errorf("SendMessageWithReport() error = %v, want %v", err, template.ErrNotFound)
+	}
+}
+
+func TestWithTemplates_NestedNotify(t *testing.T) {
+	t.Parallel()
+
+	slack := &preferringCollector{format: FormatMrkdwn}
+	inner := New()
+	inner.UseServices(slack)
+
+	n := NewWithOptions(WithTemplates(testTemplates(t)))
+	n.UseServices(inner)
+
+	if err := n.SendTemplate(context.Background(), "deploy", map[string]string{"App": "api"}); err != nil {
+		t.Fatalf("SendTemplate() unexpected error: %v", err)
+	}
+	if m := slack.received()[0]; m.Body != "*api* was deployed." {
+		t.Errorf("service received %q, want the mrkdwn variant", m.Body)
+	}
+}
+
+// forwarder is a Wrapper outside of this package.
+type forwarder struct {
+	service Notifier
+}
+
+func (f forwarder) Send(ctx context.Context, subject, message string) error {
+	return Forward(ctx, f.service, NewMessage(subject, message))
+}
+
+func (f forwarder) SendMessage(ctx context.Context, m *Message) error {
+	return Forward(ctx, f.service, m)
+}
+
+func (f forwarder) Unwrap() []Notifier {
+	return []Notifier{f.service}
+}
+
+func TestWithTemplates_Wrapper(t *testing.T) {
+	t.Parallel()
+
+	slack := &preferringCollector{format: FormatMrkdwn}
+	n := NewWithOptions(WithTemplates(testTemplates(t)))
+	n.UseServices(forwarder{service: slack})
+
+	if err := n.SendTemplate(context.Background(), "deploy", map[string]string{"App": "api"}); err != nil {
+		t.Fatalf("SendTemplate() unexpected error: %v", err)
+	}
+	if m := slack.received()[0]; m.Body != "*api* was deployed." {
+		t.Errorf("service received %q, want the mrkdwn variant", m.Body)
+	}
+}
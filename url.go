@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/urlscheme"
+)
+
+// URLParser creates a service from a notification URL, e.g. "tgram://123456:ABC-DEF/-100123456".
+type URLParser func(rawURL string) (Notifier, error)
+
+// RegisterScheme makes the given parser handle notification URLs with the given scheme, see the urlscheme package.
+// The service packages supporting notification URLs register their schemes when they're imported, so a program needs
+// to import the service packages of the URLs it parses, e.g.:
+//
+//	import _ "github.com/casdoor/notify/service/telegram"
+func RegisterScheme(scheme string, parser URLParser) {
+	urlscheme.Register(scheme, func(rawURL string) (urlscheme.Sender, error) {
+		return parser(rawURL)
+	})
+}
+
+// Schemes returns the registered notification URL schemes in alphabetical order.
+func Schemes() []string {
+	return urlscheme.Schemes()
+}
+
+// ParseURL creates a service from the given notification URL, using the parser registered for its scheme. Errors don't
+// contain the URL, since it usually contains credentials.
+func ParseURL(rawURL string) (Notifier, error) {
+	return urlscheme.Parse(rawURL)
+}
+
+// UseURLs creates a service for each of the given notification URLs and adds them to the Notifier's services list, see
+// ParseURL. The services are named by their schemes, see UseNamedService. If any URL is invalid, no service is added.
+func (n *Notify) UseURLs(urls ...string) error {
+	services := make([]Notifier, 0, len(urls))
+	for i, rawURL := range urls {
+		service, err := ParseURL(rawURL)
+		if err != nil {
+			return errors.Wrapf(err, "notification URL #%d", i+1)
+		}
+		services = append(services, service)
+	}
+
+	for i, service := range services {
+		scheme, _, _ := strings.Cut(strings.TrimSpace(urls[i]), "://")
+		n.UseNamedService(strings.ToLower(scheme), service)
+	}
+
+	return nil
+}
+
+// NewFromURLs returns a new instance of Notify sending to the services described by the given notification URLs, see
+// ParseURL. The supported schemes are the ones registered by the imported service packages. Only the following
+// services support notification URLs, all others have to be created with their constructors:
+//
+//	tgram://{bot_token}/{chat_id}[/{chat_id}...]                                  (service/telegram)
+//	slack://{token}/{channel}[/{channel}...]                                      (service/slack)
+//	mailto://{user}:{password}@{smtp_host}[:{port}]?to={address}[,{address}...]   (service/mail)
+//	bark://{device_key}@{server}[/{path}]                                         (service/bark)
+//	json[s]://[{user}:{password}@]{host}[/{path}]                                 (service/http)
+//
+// See the ParseURL functions of the service packages for the details. The error of an unknown scheme lists the
+// registered ones.
+func NewFromURLs(urls ...string) (*Notify, error) {
+	n := New()
+	if err := n.UseURLs(urls...); err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/casdoor/notify/urlscheme"
+)
+
+func TestNewFromURLs(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu   sync.Mutex
+		sent []string
+	)
+	RegisterScheme("Test", func(rawURL string) (Notifier, error) {
+		target, _ := urlscheme.Cut(rawURL, "test")
+		if target == "" {
+			return nil, errors.New("missing target")
+		}
+		return NotifierFunc(func(_ context.Context, subject, _ string) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			sent = append(sent, target+": "+subject)
+			return nil
+		}), nil
+	})
+
+	n, err := NewFromURLs("test://a", " TEST://b ")
+	if err != nil {
+		t.Fatalf("NewFromURLs() error = %v", err)
+	}
+	if err = n.Send(context.Background(), "hello", "world"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	sort.Strings(sent)
+	if got := strings.Join(sent, ", "); got != "a: hello, b: hello" {
+		t.Errorf("sent = %q, want %q", got, "a: hello, b: hello")
+	}
+
+	found := false
+	for _, scheme := range Schemes() {
+		found = found || scheme == "test"
+	}
+	if !found {
+		t.Errorf("Schemes() = %v, want it to contain %q", Schemes(), "test")
+	}
+
+	tests := map[string]string{
+		"test://":               "notification URL #2: missing target",
+		"unknown://secret@host": `notification URL #2: unknown notification URL scheme "unknown", supported schemes: `,
+		"secret":                "notification URL #2: missing scheme in notification URL",
+	}
+	for rawURL, want := range tests {
+		n, err = NewFromURLs("test://a", rawURL)
+		if err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("NewFromURLs(%q) error = %v, want %q", rawURL, err, want)
+		}
+		if n != nil {
+			t.Errorf("NewFromURLs(%q) = %v, want nil", rawURL, n)
+		}
+	}
+}
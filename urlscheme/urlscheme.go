@@ -0,0 +1,89 @@
+// Package urlscheme holds the registry of notification URL schemes, e.g. "tgram://123456:ABC-DEF/-100123456". Service
+// packages supporting notification URLs register a parser for their scheme when they're imported, and
+// notify.NewFromURLs uses the registry to create services from URLs.
+//
+// This package deliberately doesn't depend on the notify package itself, so that every service is able to use it.
+package urlscheme
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Sender is a service created from a notification URL. It's the same as notify.Notifier.
+type Sender interface {
+	Send(ctx context.Context, subject, message string) error
+}
+
+// Parser creates a service from a notification URL.
+type Parser func(rawURL string) (Sender, error)
+
+// registry holds the registered parsers by scheme.
+var registry = struct {
+	sync.RWMutex
+	parsers map[string]Parser
+}{parsers: make(map[string]Parser)}
+
+// Register makes the given parser handle notification URLs with the given scheme. Registering a scheme again replaces
+// its parser.
+func Register(scheme string, parser Parser) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registry.parsers[strings.ToLower(scheme)] = parser
+}
+
+// Schemes returns the registered schemes in alphabetical order.
+func Schemes() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	schemes := make([]string, 0, len(registry.parsers))
+	for scheme := range registry.parsers {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+
+	return schemes
+}
+
+// Parse creates a service from the given notification URL, using the parser registered for its scheme. Errors don't
+// contain the URL, since it usually contains credentials. The error of an unknown scheme lists the registered ones.
+func Parse(rawURL string) (Sender, error) {
+	rawURL = strings.TrimSpace(rawURL)
+
+	scheme, _, ok := strings.Cut(rawURL, "://")
+	if !ok || scheme == "" {
+		return nil, errors.New("missing scheme in notification URL")
+	}
+	scheme = strings.ToLower(scheme)
+
+	registry.RLock()
+	parser, ok := registry.parsers[scheme]
+	registry.RUnlock()
+	if !ok {
+		schemes := Schemes()
+		if len(schemes) == 0 {
+			return nil, errors.Errorf("unknown notification URL scheme %q, no schemes are registered", scheme)
+		}
+		return nil, errors.Errorf("unknown notification URL scheme %q, supported schemes: %s", scheme,
+			strings.Join(schemes, ", "))
+	}
+
+	return parser(rawURL)
+}
+
+// Cut returns rawURL without the given scheme and the following "://". The scheme is matched case-insensitively. It
+// helps parsers of URLs that net/url can't parse, e.g. because they contain a colon in the host part.
+func Cut(rawURL, scheme string) (string, bool) {
+	prefix := scheme + "://"
+	if len(rawURL) < len(prefix) || !strings.EqualFold(rawURL[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	return rawURL[len(prefix):], true
+}
@@ -0,0 +1,53 @@
+package urlscheme
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type senderFunc func(ctx context.Context, subject, message string) error
+
+func (f senderFunc) Send(ctx context.Context, subject, message string) error {
+	return f(ctx, subject, message)
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	Register("Fake", func(rawURL string) (Sender, error) {
+		rest, _ := Cut(rawURL, "fake")
+		if rest == "" {
+			return nil, errors.New("missing target")
+		}
+		return senderFunc(func(context.Context, string, string) error { return nil }), nil
+	})
+	require.Contains(t, Schemes(), "fake")
+
+	sender, err := Parse("  FAKE://target ")
+	require.NoError(t, err)
+	require.NotNil(t, sender)
+
+	_, err = Parse("fake://")
+	require.EqualError(t, err, "missing target")
+
+	_, err = Parse("other://token@host")
+	require.ErrorContains(t, err, `unknown notification URL scheme "other", supported schemes: `)
+	require.ErrorContains(t, err, "fake")
+
+	_, err = Parse("token@host")
+	require.EqualError(t, err, "missing scheme in notification URL")
+}
+
+func TestCut(t *testing.T) {
+	t.Parallel()
+
+	rest, ok := Cut("TGram://123:abc/42", "tgram")
+	require.True(t, ok)
+	require.Equal(t, "123:abc/42", rest)
+
+	_, ok = Cut("tgram:/123", "tgram")
+	require.False(t, ok)
+}
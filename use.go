@@ -1,10 +1,18 @@
 package notify
 
-// useService adds a given service to the Notifier's services list.
-func (n *Notify) useService(service Notifier) {
-	if service != nil {
-		n.notifiers = append(n.notifiers, service)
+// useService adds a given service with the given tags to the Notifier's services list.
+func (n *Notify) useService(service Notifier, tags ...string) {
+	if service == nil {
+		return
 	}
+
+	if len(tags) > 0 {
+		if n.tags == nil {
+			n.tags = make(map[int][]string)
+		}
+		n.tags[len(n.notifiers)] = append([]string(nil), tags...)
+	}
+	n.notifiers = append(n.notifiers, service)
 }
 
 // useServices adds the given service(s) to the Notifier's services list.
@@ -14,11 +22,46 @@ func (n *Notify) useServices(services ...Notifier) {
 	}
 }
 
+// UseNamedService adds the given service to the Notifier's services list like UseServiceWithTags does, and names it. The
+// name identifies the service in a Report instead of its Go type, which helps telling several services of the same type
+// apart, e.g. "ops-telegram" and "dev-telegram".
+func (n *Notify) UseNamedService(name string, service Notifier, tags ...string) {
+	if service == nil {
+		return
+	}
+
+	if name != "" {
+		if n.names == nil {
+			n.names = make(map[int]string)
+		}
+		n.names[len(n.notifiers)] = name
+	}
+	n.useService(service, tags...)
+}
+
 // UseServices adds the given service(s) to the Notifier's services list.
 func (n *Notify) UseServices(services ...Notifier) {
 	n.useServices(services...)
 }
 
+// Unwrap returns the services of the Notify instance. It implements Wrapper, so that a Notify instance used as a service
+// of another one renders templates for, and reports dry runs of, its own services.
+func (n *Notify) Unwrap() []Notifier {
+	return append([]Notifier(nil), n.notifiers...)
+}
+
+// UseServiceWithTags adds the given service to the Notifier's services list and tags it with the given tags. Tags let
+// SendTo and routing rules select a subset of the services, see ParseSelector and WithRoutes. Services added with
+// UseServices have no tags.
+func (n *Notify) UseServiceWithTags(service Notifier, tags ...string) {
+	n.useService(service, tags...)
+}
+
+// UseServiceWithTags adds the given service to the Notifier's services list and tags it with the given tags.
+func UseServiceWithTags(service Notifier, tags ...string) {
+	std.UseServiceWithTags(service, tags...)
+}
+
 // UseServices adds the given service(s) to the Notifier's services list.
 func UseServices(services ...Notifier) {
 	std.UseServices(services...)